@@ -3,11 +3,18 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"bicicletapp/internal/config"
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/domain/notifications"
 	"bicicletapp/internal/repository"
 	"bicicletapp/internal/repository/sqlite"
 	"bicicletapp/internal/server"
@@ -27,6 +34,23 @@ func main() {
 	log.Printf("🚲 Starting %s...", cfg.Business.Name)
 	log.Printf("📋 Debug mode: %v", cfg.Debug)
 
+	// Initialize template manager
+	tmpl, err := templates.NewManager("./templates", cfg.Debug)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize templates: %v", err)
+	}
+	log.Println("✅ Templates loaded")
+
+	// Notifications are logged until real providers are configured; email
+	// bodies only in debug mode, as they carry account links
+	notifier := notifications.NewCompositeNotifier(&notifications.MockEmailProvider{LogBody: cfg.Debug}, &notifications.MockSMSProvider{})
+
+	// Several workshops on one deployment
+	if cfg.Tenancy.Enabled {
+		runPlatform(cfg, tmpl, notifier)
+		return
+	}
+
 	// Initialize database
 	db, err := sqlite.New(cfg.GetDatabasePath())
 	if err != nil {
@@ -46,37 +70,96 @@ func main() {
 	}
 
 	// Initialize repositories
-	repos := &repository.Repositories{
-		Users:    sqlite.NewUserRepo(db),
-		Brands:   sqlite.NewBrandRepo(db),
-		Models:   sqlite.NewModelRepo(db),
-		Services: sqlite.NewServiceRepo(db),
-		Bicycles: sqlite.NewBicycleRepo(db),
-		Bookings: sqlite.NewBookingRepo(db),
-		Quotes:   sqlite.NewQuoteRepo(db),
-		Tickets:  sqlite.NewTicketRepo(db),
-		Surveys:  sqlite.NewSurveyRepo(db),
-		Ads:      sqlite.NewAdRepo(db),
-		Settings: sqlite.NewSettingsRepo(db),
+	repos := sqlite.NewRepositories(db)
+
+	// Create and run the server
+	srv := server.New(cfg, repos, tmpl, notifier)
+
+	log.Printf("🌐 Server listening on http://%s", cfg.Address())
+
+	if err := srv.Run(); err != nil {
+		log.Fatalf("❌ Server error: %v", err)
 	}
+}
 
-	// Initialize template manager
-	tmpl, err := templates.NewManager("./templates", cfg.Debug)
+// runPlatform serves every tenant and the super-admin console. The platform
+// database only holds the tenant registry; each tenant's database is opened
+// (and created) on its first request. An existing single-workshop database
+// can be imported by copying it to its tenant path before creating the tenant.
+func runPlatform(cfg *config.Config, tmpl *templates.Manager, notifier notifications.Notifier) {
+	db, err := sqlite.New(cfg.PlatformDatabasePath())
 	if err != nil {
-		log.Fatalf("❌ Failed to initialize templates: %v", err)
+		log.Fatalf("❌ Failed to connect to platform database: %v", err)
 	}
-	log.Println("✅ Templates loaded")
+	defer db.Close()
 
-	// Create and run the server
-	srv := server.New(cfg, repos, tmpl)
+	if err := db.MigratePlatform(); err != nil {
+		log.Fatalf("❌ Failed to run platform migrations: %v", err)
+	}
+	log.Println("✅ Platform database initialized")
+
+	admins := sqlite.NewSuperAdminRepo(db)
+	if err := createDefaultSuperAdmin(admins); err != nil {
+		log.Printf("⚠️ Could not create default super admin: %v", err)
+	}
 
+	platform, err := server.NewPlatform(cfg, sqlite.NewTenantRepo(db), admins, openTenant, tmpl, notifier)
+	if err != nil {
+		log.Fatalf("❌ Failed to start platform: %v", err)
+	}
+
+	log.Printf("🏢 Console on http://%s (port %d)", cfg.Tenancy.ConsoleHost, cfg.Server.Port)
 	log.Printf("🌐 Server listening on http://%s", cfg.Address())
 
-	if err := srv.Run(); err != nil {
+	if err := platform.Run(); err != nil {
 		log.Fatalf("❌ Server error: %v", err)
 	}
 }
 
+// openTenant opens a tenant's database, creating and migrating it if needed
+func openTenant(path string) (*repository.Repositories, io.Closer, error) {
+	db, err := sqlite.New(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return sqlite.NewRepositories(db), db, nil
+}
+
+// createDefaultSuperAdmin creates the first console account with a random
+// password, printed once
+func createDefaultSuperAdmin(admins repository.SuperAdminRepository) error {
+	ctx := context.Background()
+	count, err := admins.Count(ctx)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	password := hex.EncodeToString(raw)
+	hashedPassword, err := sqlite.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	admin := &domain.SuperAdmin{Email: "superadmin@bicicletapp.com", PasswordHash: hashedPassword}
+	if err := admins.Create(ctx, admin); err != nil {
+		return err
+	}
+
+	log.Println("✅ Default super admin created:")
+	log.Printf("   Email: %s", admin.Email)
+	log.Printf("   Password: %s", password)
+	log.Println("   ⚠️ This password is only shown once!")
+	return nil
+}
+
 // createDefaultAdmin creates a default admin user if no users exist
 func createDefaultAdmin(db *sqlite.DB) error {
 	// Check if any users exist
@@ -150,5 +233,38 @@ func createSampleData(db *sqlite.DB) {
 			s.name, s.desc, s.price, s.hours)
 	}
 
+	// Intake inspection for the full service
+	result, err := db.Exec("INSERT INTO checklists (name, created_at) VALUES (?, ?)", "Servicio Completo", time.Now())
+	if err == nil {
+		checklistID, _ := result.LastInsertId()
+		points := []struct {
+			label    string
+			kind     string
+			unit     string
+			required bool
+		}{
+			{"Foto de la bicicleta completa al ingreso", "photo", "", true},
+			{"Cuadro y horquilla sin fisuras", "check", "", true},
+			{"Presión neumático delantero", "measurement", "psi", true},
+			{"Presión neumático trasero", "measurement", "psi", true},
+			{"Desgaste de cubiertas", "check", "", true},
+			{"Centrado y tensión de radios", "check", "", true},
+			{"Juego de bujes", "check", "", true},
+			{"Desgaste de pastillas o zapatas", "check", "", true},
+			{"Funcionamiento de frenos", "check", "", true},
+			{"Estiramiento de cadena", "measurement", "%", true},
+			{"Desgaste de piñones y platos", "check", "", true},
+			{"Ajuste de cambios", "check", "", true},
+			{"Juego de dirección", "check", "", true},
+			{"Pedalier y pedales", "check", "", true},
+			{"Apriete de tornillería", "check", "", false},
+		}
+		for i, p := range points {
+			db.Exec("INSERT INTO checklist_items (checklist_id, position, label, type, unit, required) VALUES (?, ?, ?, ?, ?, ?)",
+				checklistID, i+1, p.label, p.kind, p.unit, p.required)
+		}
+		db.Exec("UPDATE services SET checklist_id = ? WHERE name = ?", checklistID, "Servicio Completo")
+	}
+
 	log.Println("✅ Sample data created")
 }
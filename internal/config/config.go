@@ -4,9 +4,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // Config holds all application configuration
@@ -17,6 +19,8 @@ type Config struct {
 	Business Business `json:"business"`
 	Features Features `json:"features"`
 	JWT      JWT      `json:"jwt"`
+	Tenancy  Tenancy  `json:"tenancy"`
+	Storage  Storage  `json:"storage"`
 }
 
 // Server holds HTTP server configuration
@@ -25,6 +29,13 @@ type Server struct {
 	Host         string `json:"host"`
 	ReadTimeout  int    `json:"readTimeout"`
 	WriteTimeout int    `json:"writeTimeout"`
+	// BaseURL is the public address used in links sent by email (reset,
+	// verification, invitations). Defaults to http://localhost:<port>.
+	BaseURL string `json:"baseUrl"`
+	// TrustedProxies lists the addresses (IPs or CIDR ranges) of the reverse
+	// proxies in front of the app. Only requests coming from them may name
+	// the client's IP in X-Forwarded-For or X-Real-IP.
+	TrustedProxies []string `json:"trustedProxies"`
 }
 
 // Database holds database configuration
@@ -54,10 +65,60 @@ type Features struct {
 
 // JWT holds JWT configuration
 type JWT struct {
-	Secret          string `json:"secret"`
-	ExpirationHours int    `json:"expirationHours"`
+	Secret string `json:"secret"`
+	// ExpirationHours is how long a session (its refresh token) lives
+	// without being used
+	ExpirationHours int `json:"expirationHours"`
+	// AccessTokenMinutes is the lifetime of the access token cookie
+	AccessTokenMinutes int `json:"accessTokenMinutes"`
+	// Audience, when set, is written to and required in every token so a
+	// token issued by one workshop is rejected by the others on a shared
+	// deployment. Each tenant gets its slug.
+	Audience string `json:"audience"`
 }
 
+// Tenancy configures hosting several independent workshops (tenants) on one
+// deployment. Each tenant has its own SQLite file under DataDir and is served
+// on its hostnames, plus <slug>.<RootDomain> when RootDomain is set. The
+// super-admin console that creates and suspends tenants is served on
+// ConsoleHost. When disabled the app runs as a single workshop on
+// Database.Path.
+type Tenancy struct {
+	Enabled     bool   `json:"enabled"`
+	ConsoleHost string `json:"consoleHost"`
+	RootDomain  string `json:"rootDomain"`
+	DataDir     string `json:"dataDir"`
+}
+
+// Storage configures where uploaded files (ticket photos and documents) are
+// kept: on local disk under Dir, or in a bucket of an S3-compatible service.
+// On a shared deployment each tenant's files go under its own prefix.
+type Storage struct {
+	Driver string `json:"driver"` // "local" (default) or "s3"
+	Dir    string `json:"dir"`
+	// Prefix is prepended to every stored file's key
+	Prefix string `json:"prefix"`
+	// MaxUploadMB limits the size of each uploaded file
+	MaxUploadMB int `json:"maxUploadMB"`
+	S3          S3  `json:"s3"`
+}
+
+// S3 holds the bucket and credentials of an S3-compatible storage service
+type S3 struct {
+	// Endpoint is the service's base URL, e.g. https://s3.us-east-1.amazonaws.com
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+// Storage drivers
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
 // Load reads configuration from the specified JSON file and overrides with environment variables
 func Load(configPath string) (*Config, error) {
 	var cfg Config
@@ -88,6 +149,9 @@ func Load(configPath string) (*Config, error) {
 	if cfg.JWT.ExpirationHours == 0 {
 		cfg.JWT.ExpirationHours = 24
 	}
+	if cfg.JWT.AccessTokenMinutes == 0 {
+		cfg.JWT.AccessTokenMinutes = 15
+	}
 
 	// Validate configuration
 	if err := cfg.validate(); err != nil {
@@ -116,6 +180,21 @@ func (c *Config) applyEnvOverrides() {
 		c.Server.Host = host
 	}
 
+	// Public base URL
+	if baseURL := os.Getenv("BASE_URL"); baseURL != "" {
+		c.Server.BaseURL = baseURL
+	}
+
+	// Reverse proxies allowed to forward the client's IP, comma separated
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		c.Server.TrustedProxies = nil
+		for _, proxy := range strings.Split(proxies, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				c.Server.TrustedProxies = append(c.Server.TrustedProxies, proxy)
+			}
+		}
+	}
+
 	// Database path
 	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
 		c.Database.Path = dbPath
@@ -125,6 +204,40 @@ func (c *Config) applyEnvOverrides() {
 	if secret := os.Getenv("JWT_SECRET"); secret != "" {
 		c.JWT.Secret = secret
 	}
+
+	// Multi-tenant hosting
+	if tenancy := os.Getenv("TENANCY_ENABLED"); tenancy != "" {
+		c.Tenancy.Enabled = tenancy == "true" || tenancy == "1"
+	}
+	if host := os.Getenv("CONSOLE_HOST"); host != "" {
+		c.Tenancy.ConsoleHost = host
+	}
+	if dir := os.Getenv("TENANCY_DATA_DIR"); dir != "" {
+		c.Tenancy.DataDir = dir
+	}
+
+	// Uploaded files
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" {
+		c.Storage.Driver = driver
+	}
+	if dir := os.Getenv("STORAGE_DIR"); dir != "" {
+		c.Storage.Dir = dir
+	}
+	if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+		c.Storage.S3.Endpoint = endpoint
+	}
+	if region := os.Getenv("S3_REGION"); region != "" {
+		c.Storage.S3.Region = region
+	}
+	if bucket := os.Getenv("S3_BUCKET"); bucket != "" {
+		c.Storage.S3.Bucket = bucket
+	}
+	if key := os.Getenv("S3_ACCESS_KEY"); key != "" {
+		c.Storage.S3.AccessKey = key
+	}
+	if secret := os.Getenv("S3_SECRET_KEY"); secret != "" {
+		c.Storage.S3.SecretKey = secret
+	}
 }
 
 // validate checks that all required configuration values are present
@@ -133,13 +246,17 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
-	if c.Database.Path == "" {
+	if c.Tenancy.Enabled {
+		if err := c.validateTenancy(); err != nil {
+			return err
+		}
+	} else if c.Database.Path == "" {
 		return fmt.Errorf("database path is required")
 	}
 
 	// Validate database path for security
 	cleanDBPath := filepath.Clean(c.Database.Path)
-	if !filepath.IsLocal(cleanDBPath) && !filepath.IsAbs(cleanDBPath) {
+	if c.Database.Path != "" && !filepath.IsLocal(cleanDBPath) && !filepath.IsAbs(cleanDBPath) {
 		return fmt.Errorf("invalid database path: potential path traversal detected")
 	}
 
@@ -153,15 +270,105 @@ func (c *Config) validate() error {
 		c.JWT.ExpirationHours = 24 // Default to 24 hours
 	}
 
+	if _, err := c.TrustedProxyPrefixes(); err != nil {
+		return err
+	}
+
+	return c.validateStorage()
+}
+
+// validateStorage checks the upload storage settings and fills in defaults
+func (c *Config) validateStorage() error {
+	if c.Storage.MaxUploadMB <= 0 {
+		c.Storage.MaxUploadMB = 10
+	}
+	switch c.Storage.Driver {
+	case "", StorageLocal:
+		c.Storage.Driver = StorageLocal
+		if c.Storage.Dir == "" {
+			c.Storage.Dir = "data/uploads"
+		}
+		cleanDir := filepath.Clean(c.Storage.Dir)
+		if !filepath.IsLocal(cleanDir) && !filepath.IsAbs(cleanDir) {
+			return fmt.Errorf("invalid storage dir: potential path traversal detected")
+		}
+		c.Storage.Dir = cleanDir
+	case StorageS3:
+		s3 := c.Storage.S3
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			return fmt.Errorf("s3 storage needs an endpoint, a bucket and credentials")
+		}
+		if c.Storage.S3.Region == "" {
+			c.Storage.S3.Region = "us-east-1"
+		}
+	default:
+		return fmt.Errorf("unknown storage driver: %s", c.Storage.Driver)
+	}
+	return nil
+}
+
+// validateTenancy checks the multi-tenant settings and fills in defaults
+func (c *Config) validateTenancy() error {
+	c.Tenancy.ConsoleHost = strings.ToLower(strings.TrimSpace(c.Tenancy.ConsoleHost))
+	if c.Tenancy.ConsoleHost == "" {
+		return fmt.Errorf("tenancy console host is required")
+	}
+	c.Tenancy.RootDomain = strings.ToLower(strings.Trim(strings.TrimSpace(c.Tenancy.RootDomain), "."))
+
+	if c.Tenancy.DataDir == "" {
+		c.Tenancy.DataDir = "data"
+	}
+	cleanDir := filepath.Clean(c.Tenancy.DataDir)
+	if !filepath.IsLocal(cleanDir) && !filepath.IsAbs(cleanDir) {
+		return fmt.Errorf("invalid tenancy data dir: potential path traversal detected")
+	}
+	c.Tenancy.DataDir = cleanDir
 	return nil
 }
 
+// TrustedProxyPrefixes parses Server.TrustedProxies; a single IP is a range
+// of one address
+func (c *Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, proxy := range c.Server.TrustedProxies {
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 // Address returns the full server address (host:port)
 func (c *Config) Address() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+// PublicURL returns the public base URL without a trailing slash
+func (c *Config) PublicURL() string {
+	if c.Server.BaseURL != "" {
+		return strings.TrimRight(c.Server.BaseURL, "/")
+	}
+	return fmt.Sprintf("http://localhost:%d", c.Server.Port)
+}
+
 // GetDatabasePath returns the cleaned and validated database path
 func (c *Config) GetDatabasePath() string {
 	return filepath.Clean(c.Database.Path)
 }
+
+// PlatformDatabasePath returns the path of the tenant registry
+func (c *Config) PlatformDatabasePath() string {
+	return filepath.Join(c.Tenancy.DataDir, "platform.db")
+}
+
+// TenantDatabasePath returns the path of a tenant's database. The slug must
+// already be validated (domain.ValidTenantSlug).
+func (c *Config) TenantDatabasePath(slug string) string {
+	return filepath.Join(c.Tenancy.DataDir, "tenants", slug+".db")
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// User token purposes
+const (
+	TokenPurposeReset  = "reset"
+	TokenPurposeVerify = "verify"
+	TokenPurposeInvite = "invite"
+)
+
+// RecoveryCodeCount is how many single-use 2FA recovery codes are issued
+const RecoveryCodeCount = 10
+
+// Settings keys for the two-factor policy
+const (
+	SettingRequire2FAAdmin      = "require_2fa_admin"
+	SettingRequire2FATechnician = "require_2fa_technician"
+)
+
+// Token lifetimes by purpose
+const (
+	ResetTokenTTL  = time.Hour
+	VerifyTokenTTL = 48 * time.Hour
+	InviteTokenTTL = 7 * 24 * time.Hour
+)
+
+// UserToken is a single-use, expiring token sent to a user by email. Only the
+// SHA-256 hash of the token is stored; the plain value lives in the link.
+type UserToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"userId"`
+	Purpose   string     `json:"purpose"` // reset, verify, invite
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Usable reports whether the token has not been used and has not expired
+func (t *UserToken) Usable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// Session is a signed-in device. The browser holds a short-lived access token
+// bound to the session and a refresh token that is rotated on every use; only
+// hashes of refresh tokens are stored.
+type Session struct {
+	ID                  int64      `json:"id"`
+	UserID              int64      `json:"userId"`
+	User                *User      `json:"user,omitempty"`
+	RefreshHash         string     `json:"-"`
+	PreviousRefreshHash string     `json:"-"`
+	RotatedAt           *time.Time `json:"rotatedAt,omitempty"`
+	UserAgent           string     `json:"userAgent,omitempty"`
+	IP                  string     `json:"ip,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	LastUsedAt          time.Time  `json:"lastUsedAt"`
+	ExpiresAt           time.Time  `json:"expiresAt"`
+	RevokedAt           *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Active reports whether the session has not been revoked and has not expired
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
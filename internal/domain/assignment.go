@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AssignmentStrategySetting is the settings key holding how new tickets get
+// a technician
+const AssignmentStrategySetting = "assignment_strategy"
+
+// Ticket assignment strategies. Manual keeps tickets with whoever creates
+// them from a booking, and walk-ins unassigned.
+const (
+	AssignmentManual      = ""
+	AssignmentRoundRobin  = "round_robin"
+	AssignmentLeastLoaded = "least_loaded" // fewest hours of open tickets
+	AssignmentSkillMatch  = "skill_match"  // least loaded among those with the service's skills
+)
+
+// AssignmentStrategyInfo describes a strategy for the settings form
+type AssignmentStrategyInfo struct {
+	Key   string
+	Label string
+}
+
+// AssignmentStrategies lists every strategy in the order the settings form
+// shows them
+var AssignmentStrategies = []AssignmentStrategyInfo{
+	{AssignmentManual, "Manual: quien crea el ticket desde una reserva"},
+	{AssignmentRoundRobin, "Por turnos"},
+	{AssignmentLeastLoaded, "Al técnico con menos trabajo pendiente"},
+	{AssignmentSkillMatch, "Por habilidades, y entre ellos al de menos trabajo"},
+}
+
+// IsAssignmentStrategy reports whether key is a known strategy
+func IsAssignmentStrategy(key string) bool {
+	for _, s := range AssignmentStrategies {
+		if s.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundRobinSetting is the settings key holding the technician a branch
+// assigned last by turns
+func RoundRobinSetting(branchID int64) string {
+	return "assignment_last_" + strconv.FormatInt(branchID, 10)
+}
+
+// DefaultDailyHours is the work a technician without their own capacity
+// does in a day
+const DefaultDailyHours = 8.0
+
+// MaxBacklogDays is the days of open work past which a technician is
+// overloaded: they are skipped by assignment while others are free, and
+// stop counting for booking slots
+const MaxBacklogDays = 2.0
+
+// ParseSkills turns a comma-separated list of skill tags into lowercase tags
+// without blanks or repeats
+func ParseSkills(input string) []string {
+	var skills []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		skills = append(skills, tag)
+	}
+	return skills
+}
+
+// HasSkills reports whether have holds every skill in need
+func HasSkills(have, need []string) bool {
+	for _, n := range need {
+		found := false
+		for _, h := range have {
+			if h == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Workload is the open work of a technician: tickets not ready or delivered
+// yet, and their estimated hours
+type Workload struct {
+	Technician  User
+	OpenTickets int
+	OpenHours   float64
+}
+
+// DailyHours is the technician's capacity per day
+func (w Workload) DailyHours() float64 {
+	if w.Technician.DailyHours > 0 {
+		return w.Technician.DailyHours
+	}
+	return DefaultDailyHours
+}
+
+// BacklogDays is how many days of work the technician has open
+func (w Workload) BacklogDays() float64 {
+	return w.OpenHours / w.DailyHours()
+}
+
+// Overloaded reports whether the technician has more open work than
+// MaxBacklogDays
+func (w Workload) Overloaded() bool {
+	return w.BacklogDays() > MaxBacklogDays
+}
+
+// PickTechnician chooses who gets a new ticket among the candidates by the
+// strategy. Overloaded technicians only get it when everyone is. lastID is
+// the technician the branch assigned last by turns. It returns nil for the
+// manual strategy and when nobody fits.
+func PickTechnician(strategy string, candidates []Workload, required []string, lastID int64) *Workload {
+	if strategy == AssignmentSkillMatch {
+		var skilled []Workload
+		for _, c := range candidates {
+			if HasSkills(c.Technician.Skills, required) {
+				skilled = append(skilled, c)
+			}
+		}
+		candidates = skilled
+	}
+	var free []Workload
+	for _, c := range candidates {
+		if !c.Overloaded() {
+			free = append(free, c)
+		}
+	}
+	if len(free) > 0 {
+		candidates = free
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	pool := make([]Workload, len(candidates))
+	copy(pool, candidates)
+	switch strategy {
+	case AssignmentRoundRobin:
+		sort.Slice(pool, func(i, j int) bool { return pool[i].Technician.ID < pool[j].Technician.ID })
+		for i := range pool {
+			if pool[i].Technician.ID > lastID {
+				return &pool[i]
+			}
+		}
+		return &pool[0]
+	case AssignmentLeastLoaded, AssignmentSkillMatch:
+		sort.SliceStable(pool, func(i, j int) bool {
+			if pool[i].OpenHours != pool[j].OpenHours {
+				return pool[i].OpenHours < pool[j].OpenHours
+			}
+			if pool[i].OpenTickets != pool[j].OpenTickets {
+				return pool[i].OpenTickets < pool[j].OpenTickets
+			}
+			return pool[i].Technician.ID < pool[j].Technician.ID
+		})
+		return &pool[0]
+	}
+	return nil
+}
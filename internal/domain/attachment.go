@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// What a ticket attachment documents
+const (
+	AttachmentIntake    = "intake"    // the bike's condition when it came in
+	AttachmentDiagnosis = "diagnosis" // what the quote is for
+	AttachmentOther     = "other"
+)
+
+// AttachmentKinds lists every kind in the order the upload form shows them
+var AttachmentKinds = []string{AttachmentIntake, AttachmentDiagnosis, AttachmentOther}
+
+// IsAttachmentKind reports whether kind is a known attachment kind
+func IsAttachmentKind(kind string) bool {
+	for _, k := range AttachmentKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachmentTypes are the file types tickets accept, with the extension
+// they are stored under. The type is sniffed from the content, not taken
+// from the upload.
+var AttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// AttachmentThumbSize is the longest side of attachment thumbnails, in pixels
+const AttachmentThumbSize = 320
+
+// Attachment is a photo or document of a ticket. Images keep a thumbnail;
+// public ones are shown to the customer on the tracking page.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	TicketID    int64     `json:"ticketId"`
+	Kind        string    `json:"kind"`
+	FileName    string    `json:"fileName"` // as uploaded
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	ThumbKey    string    `json:"-"`
+	Caption     string    `json:"caption,omitempty"`
+	Public      bool      `json:"public"`
+	UploadedBy  int64     `json:"uploadedBy,omitempty"`
+	Uploader    *User     `json:"uploader,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// IsImage reports whether the attachment is a photo
+func (a *Attachment) IsImage() bool {
+	return a.ContentType == "image/jpeg" || a.ContentType == "image/png"
+}
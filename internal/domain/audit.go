@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Audited entity types
+const (
+	AuditEntityUser          = "user"
+	AuditEntitySession       = "session"
+	AuditEntityAPIKey        = "api_key"
+	AuditEntityWebhook       = "webhook"
+	AuditEntityRole          = "role"
+	AuditEntityBranch        = "branch"
+	AuditEntityBrand         = "brand"
+	AuditEntityModel         = "model"
+	AuditEntityService       = "service"
+	AuditEntityChecklist     = "checklist"
+	AuditEntitySetting       = "setting"
+	AuditEntityTicket        = "ticket"
+	AuditEntityTicketPart    = "ticket_part"
+	AuditEntityTimeEntry     = "time_entry"
+	AuditEntityAttachment    = "attachment"
+	AuditEntityComment       = "comment"
+	AuditEntityBooking       = "booking"
+	AuditEntityQuote         = "quote"
+	AuditEntityBicycle       = "bicycle"
+	AuditEntityTransfer      = "transfer"
+	AuditEntityAd            = "ad"
+	AuditEntityInventoryItem = "inventory_item"
+	AuditEntityStockLocation = "stock_location"
+	AuditEntitySupplier      = "supplier"
+	AuditEntityPurchaseOrder = "purchase_order"
+	AuditEntityStolenBike    = "stolen_bike"
+	AuditEntitySurvey        = "survey"
+)
+
+// AuditEntityTypes lists the entity types in the order the log viewer
+// offers them as filters
+var AuditEntityTypes = []string{
+	AuditEntityUser, AuditEntitySession, AuditEntityAPIKey, AuditEntityWebhook, AuditEntityRole, AuditEntityBranch,
+	AuditEntityTicket, AuditEntityTicketPart, AuditEntityTimeEntry, AuditEntityAttachment, AuditEntityComment,
+	AuditEntityBooking, AuditEntityQuote, AuditEntityBicycle, AuditEntityTransfer,
+	AuditEntityService, AuditEntityChecklist, AuditEntityBrand, AuditEntityModel, AuditEntityInventoryItem,
+	AuditEntityStockLocation, AuditEntitySupplier, AuditEntityPurchaseOrder,
+	AuditEntityStolenBike, AuditEntityAd, AuditEntitySetting, AuditEntitySurvey,
+}
+
+// AuditEntityLabel returns the Spanish label for an entity type
+func AuditEntityLabel(entityType string) string {
+	labels := map[string]string{
+		AuditEntityUser:          "Usuario",
+		AuditEntitySession:       "Sesión",
+		AuditEntityAPIKey:        "Clave de API",
+		AuditEntityWebhook:       "Webhook",
+		AuditEntityRole:          "Rol",
+		AuditEntityBranch:        "Sucursal",
+		AuditEntityBrand:         "Marca",
+		AuditEntityModel:         "Modelo",
+		AuditEntityService:       "Servicio",
+		AuditEntityChecklist:     "Checklist",
+		AuditEntitySetting:       "Configuración",
+		AuditEntityTicket:        "Ticket",
+		AuditEntityTicketPart:    "Repuesto de ticket",
+		AuditEntityTimeEntry:     "Registro de tiempo",
+		AuditEntityAttachment:    "Adjunto de ticket",
+		AuditEntityComment:       "Comentario de ticket",
+		AuditEntityBooking:       "Reserva",
+		AuditEntityQuote:         "Presupuesto",
+		AuditEntityBicycle:       "Bicicleta",
+		AuditEntityTransfer:      "Transferencia",
+		AuditEntityAd:            "Anuncio",
+		AuditEntityInventoryItem: "Repuesto",
+		AuditEntityStockLocation: "Ubicación",
+		AuditEntitySupplier:      "Proveedor",
+		AuditEntityPurchaseOrder: "Orden de compra",
+		AuditEntityStolenBike:    "Bicicleta robada",
+		AuditEntitySurvey:        "Encuesta",
+	}
+	if label, ok := labels[entityType]; ok {
+		return label
+	}
+	return entityType
+}
+
+// AuditEntry is one record of the append-only audit log: who did what to
+// which entity, and which fields changed
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	ActorID    int64     `json:"actorId,omitempty"` // zero for anonymous or system actions
+	Actor      *User     `json:"actor,omitempty"`
+	Action     string    `json:"action"` // <entity>.<verb>, e.g. "user.update"
+	EntityType string    `json:"entityType"`
+	EntityID   int64     `json:"entityId,omitempty"`
+	Changes    string    `json:"changes,omitempty"` // JSON object of field -> {"from", "to"}
+	IP         string    `json:"ip,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AuditChange is a single changed field of an AuditEntry
+type AuditChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// ChangeList decodes Changes into fields sorted by name, with values
+// rendered as text
+func (e *AuditEntry) ChangeList() []AuditChange {
+	if e.Changes == "" {
+		return nil
+	}
+	var raw map[string]struct {
+		From interface{} `json:"from"`
+		To   interface{} `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(e.Changes), &raw); err != nil {
+		return nil
+	}
+
+	changes := make([]AuditChange, 0, len(raw))
+	for field, change := range raw {
+		changes = append(changes, AuditChange{
+			Field: field,
+			From:  auditValueText(change.From),
+			To:    auditValueText(change.To),
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func auditValueText(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64, bool:
+		return fmt.Sprint(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
+
+// AuditFilter narrows an audit log query. Zero values match everything.
+type AuditFilter struct {
+	ActorID    int64
+	Action     string // exact action, or a prefix ending in "." such as "user."
+	EntityType string
+	EntityID   int64
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// BoardDeliveredDays is how long delivered tickets stay on the workshop
+// board before leaving it
+const BoardDeliveredDays = 7
+
+// BoardWIPStatuses are the board columns that take a work-in-progress limit.
+// Delivered is where tickets leave the workshop, so it has none.
+var BoardWIPStatuses = []string{
+	TicketStatusReceived,
+	TicketStatusDiagnosing,
+	TicketStatusInProgress,
+	TicketStatusWaitingParts,
+	TicketStatusReady,
+}
+
+// SupportsWIPLimit reports whether the board column of status takes a limit
+func SupportsWIPLimit(status string) bool {
+	for _, s := range BoardWIPStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// WIPLimitSetting is the settings key holding the limit of a column; an
+// empty or zero value means no limit
+func WIPLimitSetting(status string) string {
+	return "board_wip_" + status
+}
+
+// BoardFilter selects the tickets on the workshop board
+type BoardFilter struct {
+	BranchID       int64 // zero for every branch
+	TechnicianID   int64 // zero for every technician
+	Unassigned     bool  // only tickets nobody took yet; TechnicianID is ignored
+	DeliveredSince time.Time
+}
+
+// BoardTicket is a ticket as its board card shows it. Booking carries the
+// customer and the bicycle with its brand and model.
+type BoardTicket struct {
+	Ticket
+	PendingParts int `json:"pendingParts"`
+}
+
+// BoardColumn is one status column of the workshop board. Count is every
+// ticket in the status, also those hidden by a technician filter, since the
+// limit is for the whole branch.
+type BoardColumn struct {
+	Status string
+	Label  string
+	Limit  int // zero for none
+	Count  int
+	Cards  []BoardTicket
+}
+
+// OverLimit reports whether the column holds more tickets than its limit
+func (c BoardColumn) OverLimit() bool {
+	return c.Limit > 0 && c.Count > c.Limit
+}
+
+// Full reports whether the column takes no more tickets
+func (c BoardColumn) Full() bool {
+	return c.Limit > 0 && c.Count >= c.Limit
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBranchID is the branch seeded on first run; everything created
+// before branches existed belongs to it
+const DefaultBranchID int64 = 1
+
+// Default booking hours of a new branch
+const (
+	DefaultBranchOpenDays = "1,2,3,4,5,6"
+	DefaultBranchSlots    = "09:00,10:00,11:00,12:00,14:00,15:00,16:00,17:00"
+)
+
+// Weekdays lists the days in the order the branch form shows them
+var Weekdays = []struct {
+	Day   time.Weekday
+	Label string
+}{
+	{time.Monday, "Lunes"}, {time.Tuesday, "Martes"}, {time.Wednesday, "Miércoles"},
+	{time.Thursday, "Jueves"}, {time.Friday, "Viernes"}, {time.Saturday, "Sábado"},
+	{time.Sunday, "Domingo"},
+}
+
+// Branch is a shop location. Bookings, tickets, stock locations and staff
+// belong to a branch; tracking codes are unique across all of them.
+type Branch struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Address         string    `json:"address,omitempty"`
+	Phone           string    `json:"phone,omitempty"`
+	OpenDays        string    `json:"openDays"`        // weekdays taking bookings, "1,2,3" (0 = Sunday)
+	Slots           string    `json:"slots"`           // booking start times, "09:00,10:00"
+	StockLocationID int64     `json:"stockLocationId"` // where parts for its tickets are taken from
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// SlotTimes returns the booking start times of the branch
+func (b *Branch) SlotTimes() []string {
+	if b.Slots == "" {
+		return nil
+	}
+	return strings.Split(b.Slots, ",")
+}
+
+// OpenOn reports whether the branch takes bookings on a weekday
+func (b *Branch) OpenOn(day time.Weekday) bool {
+	for _, d := range strings.Split(b.OpenDays, ",") {
+		if d == strconv.Itoa(int(day)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSlot reports whether the branch takes bookings at t
+func (b *Branch) HasSlot(t time.Time) bool {
+	if !b.OpenOn(t.Weekday()) {
+		return false
+	}
+	for _, slot := range b.SlotTimes() {
+		if slot == t.Format("15:04") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddWorkingHours returns when hours of work starting at from are done. The
+// branch works on its open days from its first booking slot until an hour
+// after its last; without booking hours every hour counts.
+func (b *Branch) AddWorkingHours(from time.Time, hours float64) time.Time {
+	remaining := time.Duration(hours * float64(time.Hour))
+	slots := b.SlotTimes()
+	if len(slots) == 0 || b.OpenDays == "" {
+		return from.Add(remaining)
+	}
+	open, errOpen := time.Parse("15:04", slots[0])
+	last, errLast := time.Parse("15:04", slots[len(slots)-1])
+	if errOpen != nil || errLast != nil {
+		return from.Add(remaining)
+	}
+	opensAfter := time.Duration(open.Hour())*time.Hour + time.Duration(open.Minute())*time.Minute
+	closesAfter := time.Duration(last.Hour())*time.Hour + time.Duration(last.Minute())*time.Minute + time.Hour
+
+	t := from
+	// A year of closed days means the hours can't be placed
+	for day := 0; day < 366; day++ {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		start, end := midnight.Add(opensAfter), midnight.Add(closesAfter)
+		if b.OpenOn(midnight.Weekday()) && t.Before(end) {
+			if t.Before(start) {
+				t = start
+			}
+			available := end.Sub(t)
+			if available >= remaining {
+				return t.Add(remaining)
+			}
+			remaining -= available
+		}
+		t = midnight.AddDate(0, 0, 1)
+	}
+	return from.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+// NormalizeSlots parses a list of HH:MM times separated by commas, spaces or
+// newlines and returns it sorted and without duplicates. ok is false if any
+// entry is not a valid time.
+func NormalizeSlots(input string) (slots string, ok bool) {
+	seen := make(map[string]bool)
+	var times []string
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		t, err := time.Parse("15:04", field)
+		if err != nil {
+			return "", false
+		}
+		slot := t.Format("15:04")
+		if !seen[slot] {
+			seen[slot] = true
+			times = append(times, slot)
+		}
+	}
+	sort.Strings(times)
+	return strings.Join(times, ","), true
+}
+
+// JoinWeekdays stores a set of weekdays in the OpenDays format
+func JoinWeekdays(days []time.Weekday) string {
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	parts := make([]string, 0, len(days))
+	for i, d := range days {
+		if i > 0 && d == days[i-1] {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(int(d)))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,76 @@
+package domain
+
+import "time"
+
+// How a checklist item is completed
+const (
+	ChecklistCheck       = "check"       // ticked off
+	ChecklistMeasurement = "measurement" // a value is recorded
+	ChecklistPhoto       = "photo"       // a photo is attached
+)
+
+// ChecklistItemTypes lists the item types in the order the editor shows them
+var ChecklistItemTypes = []string{ChecklistCheck, ChecklistMeasurement, ChecklistPhoto}
+
+// IsChecklistItemType reports whether t is a known item type
+func IsChecklistItemType(t string) bool {
+	for _, known := range ChecklistItemTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Checklist is an inspection template attached to services. Tickets for
+// those services get a copy of its items when they are created.
+type Checklist struct {
+	ID        int64           `json:"id"`
+	Name      string          `json:"name"`
+	Items     []ChecklistItem `json:"items,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ChecklistItem is a point of a checklist template
+type ChecklistItem struct {
+	ID          int64  `json:"id"`
+	ChecklistID int64  `json:"checklistId"`
+	Position    int    `json:"position"`
+	Label       string `json:"label"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit,omitempty"` // for measurements, e.g. "psi"
+	Required    bool   `json:"required"`
+}
+
+// TicketChecklistItem is a checklist point copied onto a ticket, so later
+// edits to the template leave existing tickets alone. Required items must be
+// completed before the ticket can be ready.
+type TicketChecklistItem struct {
+	ID           int64      `json:"id"`
+	TicketID     int64      `json:"ticketId"`
+	Position     int        `json:"position"`
+	Label        string     `json:"label"`
+	Type         string     `json:"type"`
+	Unit         string     `json:"unit,omitempty"`
+	Required     bool       `json:"required"`
+	Value        string     `json:"value,omitempty"` // the measurement
+	AttachmentID int64      `json:"attachmentId,omitempty"`
+	CompletedBy  *User      `json:"completedBy,omitempty"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+}
+
+// Done reports whether the item was completed
+func (i *TicketChecklistItem) Done() bool {
+	return i.CompletedAt != nil
+}
+
+// PendingRequired counts the required items not completed yet
+func PendingRequired(items []TicketChecklistItem) int {
+	pending := 0
+	for _, item := range items {
+		if item.Required && !item.Done() {
+			pending++
+		}
+	}
+	return pending
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Who can read a ticket comment
+const (
+	CommentInternal = "internal" // workshop staff only
+	CommentCustomer = "customer" // also shown on the tracking page
+)
+
+// MaxCommentLength is the longest comment body accepted, in characters
+const MaxCommentLength = 2000
+
+// IsCommentVisibility reports whether visibility is a known comment visibility
+func IsCommentVisibility(visibility string) bool {
+	return visibility == CommentInternal || visibility == CommentCustomer
+}
+
+// TicketComment is an entry in the conversation of a ticket. Staff write
+// internal remarks or messages for the customer; customer replies from the
+// tracking page are always customer-visible.
+type TicketComment struct {
+	ID           int64     `json:"id"`
+	TicketID     int64     `json:"ticketId"`
+	AuthorID     int64     `json:"authorId,omitempty"` // 0 for notes from before comments
+	Author       *User     `json:"author,omitempty"`
+	Visibility   string    `json:"visibility"`
+	FromCustomer bool      `json:"fromCustomer"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// IsInternal reports whether only staff can read the comment
+func (c *TicketComment) IsInternal() bool {
+	return c.Visibility == CommentInternal
+}
@@ -2,18 +2,26 @@
 package domain
 
 import (
+	"strings"
 	"time"
+	"unicode"
 )
 
 // User represents a system user (customer, technician, or admin)
 type User struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name"`
-	Phone        string    `json:"phone,omitempty"`
-	Role         string    `json:"role"` // customer, technician, admin
-	CreatedAt    time.Time `json:"createdAt"`
+	ID            int64     `json:"id"`
+	Email         string    `json:"email"`
+	PasswordHash  string    `json:"-"`
+	Name          string    `json:"name"`
+	Phone         string    `json:"phone,omitempty"`
+	Role          string    `json:"role"` // customer, technician, admin
+	EmailVerified bool      `json:"emailVerified"`
+	TOTPSecret    string    `json:"-"` // base32, set while enrolling and once enabled
+	TOTPEnabled   bool      `json:"totpEnabled"`
+	BranchID      int64     `json:"branchId,omitempty"`   // staff home branch; zero works at every branch
+	Skills        []string  `json:"skills,omitempty"`     // technician skill tags, for assignment by skills
+	DailyHours    float64   `json:"dailyHours,omitempty"` // technician capacity; zero for DefaultDailyHours
+	CreatedAt     time.Time `json:"createdAt"`
 }
 
 // Brand represents a bicycle brand
@@ -33,11 +41,13 @@ type Model struct {
 
 // Service represents a service offered by the workshop
 type Service struct {
-	ID             int64   `json:"id"`
-	Name           string  `json:"name"`
-	Description    string  `json:"description"`
-	BasePrice      float64 `json:"basePrice"`
-	EstimatedHours float64 `json:"estimatedHours"`
+	ID             int64    `json:"id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	BasePrice      float64  `json:"basePrice"`
+	EstimatedHours float64  `json:"estimatedHours"`
+	RequiredSkills []string `json:"requiredSkills,omitempty"` // skill tags the technician needs
+	ChecklistID    int64    `json:"checklistId,omitempty"`    // inspection checklist for its tickets
 }
 
 // Bicycle represents a customer's bicycle
@@ -54,6 +64,18 @@ type Bicycle struct {
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
+// NormalizeSerial returns the canonical form of a frame serial number used for
+// lookups and uniqueness: upper case, without whitespace or the separators
+// people usually type inconsistently (dashes, dots and slashes).
+func NormalizeSerial(serial string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' || r == '.' || r == '/' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, serial)
+}
+
 // Booking represents a customer appointment
 type Booking struct {
 	ID          int64     `json:"id"`
@@ -63,6 +85,7 @@ type Booking struct {
 	Bicycle     *Bicycle  `json:"bicycle,omitempty"`   // New field
 	ServiceID   int64     `json:"serviceId"`
 	Service     *Service  `json:"service,omitempty"`
+	BranchID    int64     `json:"branchId"`
 	ScheduledAt time.Time `json:"scheduledAt"`
 	Status      string    `json:"status"` // pending, confirmed, completed, cancelled
 	Notes       string    `json:"notes,omitempty"`
@@ -92,18 +115,20 @@ type Quote struct {
 
 // Ticket represents a work order
 type Ticket struct {
-	ID           int64     `json:"id"`
-	BookingID    int64     `json:"bookingId"`
-	Booking      *Booking  `json:"booking,omitempty"`
-	TechnicianID int64     `json:"technicianId"`
-	Technician   *User     `json:"technician,omitempty"`
-	TrackingCode string    `json:"trackingCode"`
-	QRCode       []byte    `json:"-"`
-	QRCodeBase64 string    `json:"qrCode,omitempty"`
-	Status       string    `json:"status"` // received, diagnosing, in_progress, waiting_parts, ready, delivered
-	Notes        string    `json:"notes,omitempty"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           int64    `json:"id"`
+	BookingID    int64    `json:"bookingId"`
+	Booking      *Booking `json:"booking,omitempty"`
+	TechnicianID int64    `json:"technicianId"`
+	Technician   *User    `json:"technician,omitempty"`
+	BranchID     int64    `json:"branchId"`
+	TrackingCode string   `json:"trackingCode"`
+	QRCode       []byte   `json:"-"`
+	QRCodeBase64 string   `json:"qrCode,omitempty"`
+	Status       string   `json:"status"` // received, diagnosing, in_progress, waiting_parts, ready, delivered
+	// PromisedAt is when the customer was told the bike would be ready
+	PromisedAt *time.Time `json:"promisedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
 }
 
 // Survey represents a post-service feedback survey
@@ -143,6 +168,26 @@ const (
 	RoleAdmin      = "admin"
 )
 
+// TicketStatuses lists the ticket statuses in workflow order
+var TicketStatuses = []string{
+	TicketStatusReceived,
+	TicketStatusDiagnosing,
+	TicketStatusInProgress,
+	TicketStatusWaitingParts,
+	TicketStatusReady,
+	TicketStatusDelivered,
+}
+
+// ValidTicketStatus reports whether status is a known ticket status
+func ValidTicketStatus(status string) bool {
+	for _, s := range TicketStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // TicketStatusLabel returns a human-readable label for a ticket status
 func TicketStatusLabel(status string) string {
 	labels := map[string]string{
@@ -172,9 +217,13 @@ type TicketStatusHistory struct {
 
 // TicketPart represents a part or checklist item for a ticket
 type TicketPart struct {
-	ID        int64     `json:"id"`
-	TicketID  int64     `json:"ticketId"`
-	Name      string    `json:"name"`
-	Status    string    `json:"status"` // pending, done
-	CreatedAt time.Time `json:"createdAt"`
+	ID              int64     `json:"id"`
+	TicketID        int64     `json:"ticketId"`
+	Name            string    `json:"name"`
+	Status          string    `json:"status"` // pending, done
+	InventoryItemID int64     `json:"inventoryItemId,omitempty"`
+	SKU             string    `json:"sku,omitempty"`
+	Quantity        int       `json:"quantity"`
+	LocationID      int64     `json:"locationId,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
 }
@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+// DefaultStockLocationID is the location seeded on first run
+const DefaultStockLocationID int64 = 1
+
+// Stock movement reason constants
+const (
+	MovementReasonConsume    = "consume"    // part used on a ticket
+	MovementReasonReturn     = "return"     // part put back from a ticket
+	MovementReasonAdjustment = "adjustment" // manual count correction
+	MovementReasonReceive    = "receive"    // goods received from a supplier
+)
+
+// InventoryItem represents a part in the inventory catalog
+type InventoryItem struct {
+	ID         int64     `json:"id"`
+	SKU        string    `json:"sku"`
+	EAN        string    `json:"ean,omitempty"` // manufacturer barcode, scanned at the counter
+	Name       string    `json:"name"`
+	Brand      string    `json:"brand,omitempty"`
+	Categories []string  `json:"categories,omitempty"` // compatible bike categories (MTB, Ruta...)
+	Cost       float64   `json:"cost"`
+	SalePrice  float64   `json:"salePrice"`
+	MinStock   int       `json:"minStock"`
+	Active     bool      `json:"active"`
+	Stock      int       `json:"stock"` // total across locations, computed on read
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// LowStock reports whether the item is at or below its minimum stock
+func (i *InventoryItem) LowStock() bool {
+	return i.Stock <= i.MinStock
+}
+
+// StockLocation represents a place where parts are stored
+type StockLocation struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	BranchID  int64     `json:"branchId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StockLevel is the quantity of an item at one location
+type StockLevel struct {
+	ItemID     int64          `json:"itemId"`
+	LocationID int64          `json:"locationId"`
+	Location   *StockLocation `json:"location,omitempty"`
+	Quantity   int            `json:"quantity"`
+}
+
+// StockMovement records a change in stock; Quantity is negative for outflows
+type StockMovement struct {
+	ID           int64     `json:"id"`
+	ItemID       int64     `json:"itemId"`
+	LocationID   int64     `json:"locationId"`
+	LocationName string    `json:"locationName,omitempty"`
+	Quantity     int       `json:"quantity"`
+	Reason       string    `json:"reason"`
+	TicketID     int64     `json:"ticketId,omitempty"`
+	TicketPartID int64     `json:"ticketPartId,omitempty"`
+	UserID       int64     `json:"userId,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -3,6 +3,7 @@ package notifications
 
 import (
 	"context"
+	"log"
 )
 
 // EmailNotification represents an email to send
@@ -70,11 +71,20 @@ func (n *CompositeNotifier) SendSMS(ctx context.Context, phone, message string)
 	})
 }
 
-// MockEmailProvider is a no-op email provider for development
-type MockEmailProvider struct{}
+// MockEmailProvider is a no-op email provider for development. It logs the
+// recipient and subject of each email; the body, which may hold single-use
+// links (password resets, invitations, email verification), only with
+// LogBody.
+type MockEmailProvider struct {
+	LogBody bool
+}
 
 func (m *MockEmailProvider) Send(ctx context.Context, n EmailNotification) error {
-	// Log email in debug mode
+	if m.LogBody {
+		log.Printf("📧 [mock] email to %s: %s\n%s", n.To, n.Subject, n.Body)
+		return nil
+	}
+	log.Printf("📧 [mock] email to %s: %s", n.To, n.Subject)
 	return nil
 }
 
@@ -82,6 +92,6 @@ func (m *MockEmailProvider) Send(ctx context.Context, n EmailNotification) error
 type MockSMSProvider struct{}
 
 func (m *MockSMSProvider) Send(ctx context.Context, n SMSNotification) error {
-	// Log SMS in debug mode
+	log.Printf("📱 [mock] SMS to %s: %s", n.Phone, n.Message)
 	return nil
 }
@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Permissions. A role is a set of these; handlers and routes check
+// permissions, never role names.
+const (
+	// Workshop
+	PermWorkshopView          = "workshop.view"           // staff area: workshop, ticket list and detail, labels
+	PermTicketsCreate         = "tickets.create"          // walk-in tickets and tickets from bookings
+	PermTicketsEdit           = "tickets.edit"            // status, notes and parts of assigned tickets
+	PermTicketsEditAny        = "tickets.edit_any"        // lifts the assignment restriction of tickets.edit
+	PermTicketsStatusOverride = "tickets.status_override" // any status change, not just the workflow's next step
+	PermTicketsAssign         = "tickets.assign"
+	PermPaymentsTake          = "payments.take" // charge and hand over (ready -> delivered)
+	PermQuotesCreate          = "quotes.create"
+	PermQuotesDiscount        = "quotes.discount" // discount lines (negative prices) in quotes
+	PermBicyclesEdit          = "bicycles.edit"
+	PermBookingsView          = "bookings.view"     // own bookings and bicycles
+	PermBookingsViewAny       = "bookings.view_any" // every customer's bookings and bicycles
+
+	// Administration
+	PermUsersManage      = "users.manage" // users, sessions and 2FA resets
+	PermRolesManage      = "roles.manage"
+	PermBranchesManage   = "branches.manage" // branches and their booking hours
+	PermAPIKeysManage    = "api_keys.manage" // API keys for integrations
+	PermWebhooksManage   = "webhooks.manage"
+	PermAuditView        = "audit.view"
+	PermCatalogManage    = "catalog.manage" // brands, models and services
+	PermReportsView      = "reports.view"
+	PermSettingsManage   = "settings.manage"
+	PermAdsManage        = "ads.manage"
+	PermInventoryManage  = "inventory.manage"
+	PermPurchasingManage = "purchasing.manage" // suppliers and purchase orders
+	PermStolenManage     = "stolen.manage"
+)
+
+// PermissionInfo describes a permission for the role editor
+type PermissionInfo struct {
+	Key   string
+	Label string
+	Group string
+}
+
+// Permissions lists every permission in the order the role editor shows them
+var Permissions = []PermissionInfo{
+	{PermWorkshopView, "Acceder al taller", "Taller"},
+	{PermTicketsCreate, "Crear tickets (walk-in y desde reservas)", "Taller"},
+	{PermTicketsEdit, "Trabajar tickets asignados", "Taller"},
+	{PermTicketsEditAny, "Trabajar cualquier ticket", "Taller"},
+	{PermTicketsStatusOverride, "Cambiar estados libremente", "Taller"},
+	{PermTicketsAssign, "Asignar técnicos", "Taller"},
+	{PermPaymentsTake, "Cobrar y entregar", "Taller"},
+	{PermQuotesCreate, "Crear presupuestos", "Taller"},
+	{PermQuotesDiscount, "Aprobar descuentos en presupuestos", "Taller"},
+	{PermBicyclesEdit, "Editar bicicletas", "Taller"},
+	{PermBookingsView, "Ver sus reservas y bicicletas", "Clientes"},
+	{PermBookingsViewAny, "Ver reservas y bicicletas de todos", "Clientes"},
+	{PermUsersManage, "Gestionar usuarios y sesiones", "Administración"},
+	{PermRolesManage, "Gestionar roles", "Administración"},
+	{PermBranchesManage, "Gestionar sucursales y horarios", "Administración"},
+	{PermAPIKeysManage, "Gestionar claves de API", "Administración"},
+	{PermWebhooksManage, "Gestionar webhooks", "Administración"},
+	{PermAuditView, "Ver registro de auditoría", "Administración"},
+	{PermCatalogManage, "Editar catálogo de servicios", "Administración"},
+	{PermReportsView, "Ver reportes", "Administración"},
+	{PermSettingsManage, "Editar configuración", "Administración"},
+	{PermAdsManage, "Gestionar anuncios", "Administración"},
+	{PermInventoryManage, "Gestionar inventario", "Administración"},
+	{PermPurchasingManage, "Gestionar proveedores y compras", "Administración"},
+	{PermStolenManage, "Gestionar registro de robadas", "Administración"},
+}
+
+// AdminPermissions are the permissions that open the admin panel
+var AdminPermissions = []string{
+	PermUsersManage, PermRolesManage, PermBranchesManage, PermAPIKeysManage, PermWebhooksManage,
+	PermAuditView, PermCatalogManage, PermReportsView, PermSettingsManage, PermAdsManage, PermInventoryManage,
+	PermPurchasingManage, PermStolenManage, PermTicketsAssign,
+}
+
+// StaffPermissions are the permissions that make a role staff: any of them
+// opens the workshop or the admin panel
+var StaffPermissions = append([]string{PermWorkshopView}, AdminPermissions...)
+
+// OwnershipRules declares the permissions that only apply to resources the
+// user owns, and the permission that lifts that limit. What "owns" means is
+// up to the resource: the assigned technician of a ticket, the customer of a
+// booking or bicycle.
+var OwnershipRules = map[string]string{
+	PermTicketsEdit:  PermTicketsEditAny,
+	PermBookingsView: PermBookingsViewAny,
+}
+
+// IsPermission reports whether key is a known permission
+func IsPermission(key string) bool {
+	for _, p := range Permissions {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// roleNamePattern restricts role keys to what fits in URLs and JWT claims
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// ValidRoleName reports whether name can be used as a role key
+func ValidRoleName(name string) bool {
+	return roleNamePattern.MatchString(name)
+}
+
+// Role is a named set of permissions. System roles (customer, technician,
+// admin) cannot be deleted; admin always holds every permission.
+type Role struct {
+	Name        string    `json:"name"`
+	Label       string    `json:"label"`
+	Permissions []string  `json:"permissions"`
+	System      bool      `json:"system"`
+	UserCount   int       `json:"userCount,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Has reports whether the role grants a permission
+func (r *Role) Has(permission string) bool {
+	if r == nil {
+		return false
+	}
+	if r.Name == RoleAdmin {
+		return true
+	}
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAny reports whether the role grants at least one of the permissions
+func (r *Role) HasAny(permissions ...string) bool {
+	for _, p := range permissions {
+		if r.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanOn reports whether the role grants a permission on a resource owned by
+// ownerID when acting as userID, applying OwnershipRules
+func (r *Role) CanOn(permission string, ownerID, userID int64) bool {
+	if !r.Has(permission) {
+		return false
+	}
+	if lift, limited := OwnershipRules[permission]; limited && !r.Has(lift) {
+		return ownerID != 0 && ownerID == userID
+	}
+	return true
+}
+
+// IsStaff reports whether the role reaches the workshop or the admin panel
+func (r *Role) IsStaff() bool {
+	return r.HasAny(StaffPermissions...)
+}
+
+// SetPermissions replaces the permissions with the known ones in keys,
+// sorted and without duplicates
+func (r *Role) SetPermissions(keys []string) {
+	seen := make(map[string]bool)
+	r.Permissions = r.Permissions[:0]
+	for _, key := range keys {
+		if IsPermission(key) && !seen[key] {
+			seen[key] = true
+			r.Permissions = append(r.Permissions, key)
+		}
+	}
+	sort.Strings(r.Permissions)
+}
@@ -0,0 +1,96 @@
+package domain
+
+import "time"
+
+// Purchase order status constants
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusSent              = "sent"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+)
+
+// PurchaseOrderStatusLabel returns a human-readable label for a purchase order status
+func PurchaseOrderStatusLabel(status string) string {
+	labels := map[string]string{
+		PurchaseOrderStatusDraft:             "Borrador",
+		PurchaseOrderStatusSent:              "Enviada",
+		PurchaseOrderStatusPartiallyReceived: "Recibida Parcialmente",
+		PurchaseOrderStatusReceived:          "Recibida",
+	}
+	if label, ok := labels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// Supplier represents a parts vendor
+type Supplier struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	ContactName string    `json:"contactName,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// PurchaseOrder represents an order of parts placed with a supplier
+type PurchaseOrder struct {
+	ID         int64               `json:"id"`
+	SupplierID int64               `json:"supplierId"`
+	Supplier   *Supplier           `json:"supplier,omitempty"`
+	Status     string              `json:"status"`
+	Notes      string              `json:"notes,omitempty"`
+	CreatedBy  int64               `json:"createdBy,omitempty"`
+	Lines      []PurchaseOrderLine `json:"lines,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	SentAt     *time.Time          `json:"sentAt,omitempty"`
+	ReceivedAt *time.Time          `json:"receivedAt,omitempty"`
+}
+
+// Total returns the ordered cost of all lines
+func (o *PurchaseOrder) Total() float64 {
+	var total float64
+	for _, line := range o.Lines {
+		total += line.Total()
+	}
+	return total
+}
+
+// Editable reports whether lines can still be added or removed
+func (o *PurchaseOrder) Editable() bool {
+	return o.Status == PurchaseOrderStatusDraft
+}
+
+// Receivable reports whether goods can be received against the order
+func (o *PurchaseOrder) Receivable() bool {
+	return o.Status == PurchaseOrderStatusSent || o.Status == PurchaseOrderStatusPartiallyReceived
+}
+
+// PurchaseOrderLine is one inventory item on a purchase order. Tickets lists
+// the work orders waiting for this part.
+type PurchaseOrderLine struct {
+	ID       int64          `json:"id"`
+	OrderID  int64          `json:"orderId"`
+	ItemID   int64          `json:"itemId"`
+	Item     *InventoryItem `json:"item,omitempty"`
+	Quantity int            `json:"quantity"`
+	Received int            `json:"received"`
+	UnitCost float64        `json:"unitCost"`
+	Tickets  []Ticket       `json:"tickets,omitempty"`
+}
+
+// Total returns the ordered cost of the line
+func (l *PurchaseOrderLine) Total() float64 {
+	return float64(l.Quantity) * l.UnitCost
+}
+
+// Outstanding returns the quantity still to be received
+func (l *PurchaseOrderLine) Outstanding() int {
+	if l.Received >= l.Quantity {
+		return 0
+	}
+	return l.Quantity - l.Received
+}
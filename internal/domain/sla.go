@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultServiceHours is the work assumed for a service without an estimate
+const DefaultServiceHours = 1.0
+
+// SLAAtRiskShare is the share of a ticket's promised window left when it
+// becomes at risk
+const SLAAtRiskShare = 0.25
+
+// Ticket SLA states
+const (
+	SLAOnTrack  = "on_track"
+	SLAAtRisk   = "at_risk"
+	SLABreached = "breached" // not ready by its due date
+	SLAMet      = "met"      // ready by its due date
+)
+
+// StatusDuration is the time a ticket spent in one status
+type StatusDuration struct {
+	Status   string
+	Duration time.Duration
+}
+
+// TicketSLA is the turnaround of a ticket measured against its promised-ready
+// date. Time waiting for parts is outside the workshop's control, so it
+// pushes the due date back and is left out of WorkshopTime.
+type TicketSLA struct {
+	PromisedAt   *time.Time
+	DueAt        *time.Time // PromisedAt plus the time spent waiting for parts
+	ReadyAt      *time.Time // when the ticket last became ready; nil while in the workshop
+	InStatus     []StatusDuration
+	WaitingParts time.Duration
+	Turnaround   time.Duration // intake to ready, or to now while in the workshop
+	WorkshopTime time.Duration // Turnaround without WaitingParts
+	State        string        // empty for tickets without a promise
+}
+
+// readyStatus reports whether a ticket in status has left the workshop's queue
+func readyStatus(status string) bool {
+	return status == TicketStatusReady || status == TicketStatusDelivered
+}
+
+// ComputeSLA measures a ticket from its status history as of now
+func ComputeSLA(ticket *Ticket, history []TicketStatusHistory, now time.Time) *TicketSLA {
+	entries := make([]TicketStatusHistory, len(history))
+	copy(entries, history)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	sla := &TicketSLA{PromisedAt: ticket.PromisedAt}
+	durations := make(map[string]time.Duration)
+	var order []string
+	var readyAt *time.Time
+	for i, entry := range entries {
+		end := now
+		if i+1 < len(entries) {
+			end = entries[i+1].CreatedAt
+		}
+		if _, seen := durations[entry.Status]; !seen {
+			order = append(order, entry.Status)
+		}
+		durations[entry.Status] += end.Sub(entry.CreatedAt)
+
+		// Status changes that keep the status only add notes
+		if i > 0 && entries[i-1].Status == entry.Status {
+			continue
+		}
+		if readyStatus(entry.Status) {
+			if readyAt == nil {
+				at := entry.CreatedAt
+				readyAt = &at
+			}
+		} else {
+			readyAt = nil
+		}
+	}
+	for _, status := range order {
+		sla.InStatus = append(sla.InStatus, StatusDuration{Status: status, Duration: durations[status]})
+	}
+
+	// The clock stops when the ticket is ready
+	end := now
+	if readyAt != nil {
+		end = *readyAt
+		sla.ReadyAt = readyAt
+	}
+	sla.Turnaround = end.Sub(ticket.CreatedAt)
+	for i, entry := range entries {
+		if entry.Status != TicketStatusWaitingParts || !entry.CreatedAt.Before(end) {
+			continue
+		}
+		until := end
+		if i+1 < len(entries) && entries[i+1].CreatedAt.Before(end) {
+			until = entries[i+1].CreatedAt
+		}
+		sla.WaitingParts += until.Sub(entry.CreatedAt)
+	}
+	sla.WorkshopTime = sla.Turnaround - sla.WaitingParts
+
+	if ticket.PromisedAt == nil {
+		return sla
+	}
+	due := ticket.PromisedAt.Add(sla.WaitingParts)
+	sla.DueAt = &due
+	switch {
+	case readyAt != nil && !readyAt.After(due):
+		sla.State = SLAMet
+	case readyAt != nil || now.After(due):
+		sla.State = SLABreached
+	case due.Sub(now) < time.Duration(float64(due.Sub(ticket.CreatedAt))*SLAAtRiskShare):
+		sla.State = SLAAtRisk
+	default:
+		sla.State = SLAOnTrack
+	}
+	return sla
+}
+
+// TurnaroundStat is the average turnaround of a group of tickets that became
+// ready, for the turnaround report
+type TurnaroundStat struct {
+	Name        string
+	Tickets     int
+	Promised    int // tickets with a promised-ready date
+	Breached    int
+	AvgTotal    time.Duration
+	AvgWorkshop time.Duration
+	totalSum    time.Duration
+	workshopSum time.Duration
+}
+
+// Add counts a ready ticket in the group
+func (s *TurnaroundStat) Add(sla *TicketSLA) {
+	s.Tickets++
+	s.totalSum += sla.Turnaround
+	s.workshopSum += sla.WorkshopTime
+	s.AvgTotal = s.totalSum / time.Duration(s.Tickets)
+	s.AvgWorkshop = s.workshopSum / time.Duration(s.Tickets)
+	if sla.State != "" {
+		s.Promised++
+		if sla.State == SLABreached {
+			s.Breached++
+		}
+	}
+}
+
+// OnTimeRate is the percentage of promised tickets ready by their due date
+func (s *TurnaroundStat) OnTimeRate() float64 {
+	if s.Promised == 0 {
+		return 0
+	}
+	return float64(s.Promised-s.Breached) / float64(s.Promised) * 100
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// StolenBike source constants
+const (
+	StolenSourceManual = "manual"
+	StolenSourceImport = "import"
+)
+
+// StolenBike represents an entry in the local stolen-bike registry
+type StolenBike struct {
+	ID               int64      `json:"id"`
+	SerialNumber     string     `json:"serialNumber"`
+	Brand            string     `json:"brand,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	Color            string     `json:"color,omitempty"`
+	BicycleID        int64      `json:"bicycleId,omitempty"`
+	Source           string     `json:"source"` // manual, import
+	Notes            string     `json:"notes,omitempty"`
+	ReportedAt       time.Time  `json:"reportedAt"`
+	RecoveredAt      *time.Time `json:"recoveredAt,omitempty"`
+	LastSeenAt       *time.Time `json:"lastSeenAt,omitempty"`
+	LastSeenTicketID int64      `json:"lastSeenTicketId,omitempty"`
+	CreatedBy        int64      `json:"createdBy,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+// Active reports whether the bike is still flagged as stolen
+func (s *StolenBike) Active() bool {
+	return s.RecoveredAt == nil
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tenant statuses
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+)
+
+// Tenant is an independent workshop hosted on a shared deployment. Its data
+// lives in a SQLite file of its own; the platform registry only keeps how to
+// reach it, how it is branded and which features it has.
+type Tenant struct {
+	ID     int64    `json:"id"`
+	Slug   string   `json:"slug"`  // names its database file and is the audience of its tokens
+	Hosts  []string `json:"hosts"` // hostnames that serve it
+	Status string   `json:"status"`
+
+	// Branding and contact details shown on every page
+	Name           string `json:"name"`
+	Tagline        string `json:"tagline,omitempty"`
+	Logo           string `json:"logo,omitempty"`
+	PrimaryColor   string `json:"primaryColor,omitempty"`
+	SecondaryColor string `json:"secondaryColor,omitempty"`
+	AccentColor    string `json:"accentColor,omitempty"`
+	ContactEmail   string `json:"contactEmail,omitempty"`
+	ContactPhone   string `json:"contactPhone,omitempty"`
+
+	// Feature toggles
+	Payments           bool `json:"payments"`
+	SMS                bool `json:"sms"`
+	Surveys            bool `json:"surveys"`
+	EmailNotifications bool `json:"emailNotifications"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Active reports whether the tenant is being served
+func (t *Tenant) Active() bool {
+	return t.Status == TenantStatusActive
+}
+
+// SuperAdmin manages tenants from the platform console. Super admins have no
+// account in any tenant.
+type SuperAdmin struct {
+	ID           int64     `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+var (
+	tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,31}$`)
+	hostnamePattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+)
+
+// ValidTenantSlug reports whether slug can name a tenant: 2 to 32 lowercase
+// letters, digits or dashes, not starting with a dash
+func ValidTenantSlug(slug string) bool {
+	return tenantSlugPattern.MatchString(slug)
+}
+
+// NormalizeHosts parses hostnames separated by commas, spaces or newlines and
+// returns them lowercased and without duplicates. ok is false if any entry is
+// not a valid hostname.
+func NormalizeHosts(input string) (hosts []string, ok bool) {
+	seen := make(map[string]bool)
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+	for _, field := range fields {
+		host := strings.TrimSuffix(strings.ToLower(field), ".")
+		if len(host) > 253 || !hostnamePattern.MatchString(host) {
+			return nil, false
+		}
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, true
+}
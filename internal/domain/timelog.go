@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LaborRateSetting is the settings key holding the hourly rate logged labor
+// is billed at; empty means labor is not billed
+const LaborRateSetting = "labor_hourly_rate"
+
+// Why a time entry ended
+const (
+	TimeEntryEndPause        = "pause"
+	TimeEntryEndStop         = "stop"
+	TimeEntryEndWaitingParts = "waiting_parts" // paused when the ticket started waiting for parts
+	TimeEntryEndManual       = "manual"        // logged by hand, never ran
+)
+
+// Timer states of a technician on a ticket
+const (
+	TimerIdle    = "idle"
+	TimerRunning = "running"
+	TimerPaused  = "paused"
+)
+
+// TimeEntry is a stretch of work of a technician on a ticket. A running
+// timer is an entry without an end; pausing or stopping ends it, and
+// resuming starts a new one.
+type TimeEntry struct {
+	ID           int64      `json:"id"`
+	TicketID     int64      `json:"ticketId"`
+	TechnicianID int64      `json:"technicianId"`
+	Technician   *User      `json:"technician,omitempty"`
+	StartedAt    time.Time  `json:"startedAt"`
+	EndedAt      *time.Time `json:"endedAt,omitempty"`
+	EndReason    string     `json:"endReason,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	QuoteID      int64      `json:"quoteId,omitempty"` // the quote it was billed on
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// Running reports whether the entry's timer is still on
+func (e *TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Manual reports whether the entry was logged by hand
+func (e *TimeEntry) Manual() bool {
+	return e.EndReason == TimeEntryEndManual
+}
+
+// Duration is the time worked, up to now for a running timer
+func (e *TimeEntry) Duration() time.Duration {
+	if e.EndedAt == nil {
+		return time.Since(e.StartedAt)
+	}
+	return e.EndedAt.Sub(e.StartedAt)
+}
+
+// TimerState is the state of a technician's timer on a ticket given their
+// entries on it, newest first
+func TimerState(entries []TimeEntry) string {
+	for _, e := range entries {
+		if e.Manual() {
+			continue
+		}
+		switch {
+		case e.Running():
+			return TimerRunning
+		case e.EndReason == TimeEntryEndPause || e.EndReason == TimeEntryEndWaitingParts:
+			return TimerPaused
+		default:
+			return TimerIdle
+		}
+	}
+	return TimerIdle
+}
+
+// LaborHours rounds a duration to hundredths of an hour for billing
+func LaborHours(d time.Duration) float64 {
+	return float64(int64(d.Hours()*100+0.5)) / 100
+}
+
+// LaborItem is the quote line billing hours of work at an hourly rate
+func LaborItem(hours, rate float64) QuoteItem {
+	return QuoteItem{
+		Description: fmt.Sprintf("Mano de obra (%.2f h a $%.2f/h)", hours, rate),
+		Quantity:    1,
+		UnitPrice:   hours * rate,
+		Total:       hours * rate,
+	}
+}
+
+// LaborStat compares the estimated and logged hours of the tickets of a
+// service, for the labor report
+type LaborStat struct {
+	ServiceName    string
+	Tickets        int
+	EstimatedHours float64 // the service's estimate times the tickets
+	ActualHours    float64
+}
+
+// Variance is how far the logged hours are over the estimate, as a
+// percentage; negative when under
+func (s LaborStat) Variance() float64 {
+	if s.EstimatedHours == 0 {
+		return 0
+	}
+	return (s.ActualHours - s.EstimatedHours) / s.EstimatedHours * 100
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Bicycle transfer status constants
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusRejected  = "rejected"
+	TransferStatusCancelled = "cancelled"
+)
+
+// BicycleTransfer represents a request to hand a bicycle over to another customer
+type BicycleTransfer struct {
+	ID          int64      `json:"id"`
+	BicycleID   int64      `json:"bicycleId"`
+	Bicycle     *Bicycle   `json:"bicycle,omitempty"`
+	FromUserID  int64      `json:"fromUserId"`
+	FromUser    *User      `json:"fromUser,omitempty"`
+	ToEmail     string     `json:"toEmail"`
+	ToUserID    int64      `json:"toUserId,omitempty"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+}
+
+// BicycleOwnership is one entry of a bicycle's ownership history
+type BicycleOwnership struct {
+	ID         int64      `json:"id"`
+	BicycleID  int64      `json:"bicycleId"`
+	UserID     int64      `json:"userId"`
+	User       *User      `json:"user,omitempty"`
+	TransferID int64      `json:"transferId,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+}
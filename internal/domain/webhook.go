@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// WebhookEventVersion is the version of the payload format. It changes only
+// when fields are removed or change meaning; new fields keep the version.
+const WebhookEventVersion = 1
+
+// Webhook events
+const (
+	WebhookEventTicketCreated       = "ticket.created"
+	WebhookEventTicketStatusChanged = "ticket.status_changed"
+	WebhookEventTicketReady         = "ticket.ready"
+	WebhookEventBookingCreated      = "booking.created"
+	WebhookEventBookingConfirmed    = "booking.confirmed"
+	WebhookEventBookingCancelled    = "booking.cancelled"
+	WebhookEventQuoteCreated        = "quote.created"
+	WebhookEventQuoteApproved       = "quote.approved"
+	WebhookEventQuoteRejected       = "quote.rejected"
+)
+
+// WebhookEventInfo describes an event for the webhook form
+type WebhookEventInfo struct {
+	Key   string
+	Label string
+}
+
+// WebhookEvents lists every event in the order the webhook form shows them
+var WebhookEvents = []WebhookEventInfo{
+	{WebhookEventTicketCreated, "Ticket creado"},
+	{WebhookEventTicketStatusChanged, "Ticket cambia de estado"},
+	{WebhookEventTicketReady, "Ticket listo para retirar"},
+	{WebhookEventBookingCreated, "Reserva creada"},
+	{WebhookEventBookingConfirmed, "Reserva confirmada"},
+	{WebhookEventBookingCancelled, "Reserva cancelada"},
+	{WebhookEventQuoteCreated, "Presupuesto creado"},
+	{WebhookEventQuoteApproved, "Presupuesto aprobado"},
+	{WebhookEventQuoteRejected, "Presupuesto rechazado"},
+}
+
+// IsWebhookEvent reports whether key is a known event
+func IsWebhookEvent(key string) bool {
+	for _, e := range WebhookEvents {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is an endpoint that receives events. The secret signs every
+// delivery so the receiver can check it came from us.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Subscribed reports whether the webhook receives an event
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEvents replaces the events with the known ones in keys, sorted and
+// without duplicates
+func (w *Webhook) SetEvents(keys []string) {
+	seen := make(map[string]bool)
+	w.Events = w.Events[:0]
+	for _, key := range keys {
+		if IsWebhookEvent(key) && !seen[key] {
+			seen[key] = true
+			w.Events = append(w.Events, key)
+		}
+	}
+	sort.Strings(w.Events)
+}
+
+// WebhookEvent is the JSON body of every delivery
+type WebhookEvent struct {
+	ID        string      `json:"id"` // the same on every delivery and replay of the event
+	Type      string      `json:"type"`
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookTicketData is the data of ticket events. History is the latest
+// status change: the one that triggered status events.
+type WebhookTicketData struct {
+	Ticket  *Ticket              `json:"ticket"`
+	History *TicketStatusHistory `json:"history,omitempty"`
+}
+
+// WebhookBookingData is the data of booking events
+type WebhookBookingData struct {
+	Booking *Booking `json:"booking"`
+}
+
+// WebhookQuoteData is the data of quote events
+type WebhookQuoteData struct {
+	Quote *Quote `json:"quote"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending" // waiting for its first attempt or a retry
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookDelivery is one event sent (or to be sent) to one webhook, with the
+// outcome of its last attempt
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhookId"`
+	EventID        string     `json:"eventId"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	ResponseBody   string     `json:"responseBody,omitempty"` // truncated
+	Error          string     `json:"error,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
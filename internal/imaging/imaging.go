@@ -0,0 +1,299 @@
+// Package imaging prepares uploaded photos without external dependencies:
+// it removes the location a camera wrote into a JPEG or PNG, and renders
+// small JPEG thumbnails.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // decoder for thumbnails of PNG photos
+)
+
+// MaxPixels bounds the size of the images Thumbnail decodes, so a small
+// file that expands to a huge image can't exhaust memory. 24 megapixels
+// covers phone cameras and still decodes in under 100 MB.
+const MaxPixels = 24_000_000
+
+// ErrTooLarge is returned by Thumbnail for images over MaxPixels
+var ErrTooLarge = errors.New("image too large")
+
+var (
+	jpegSOI   = []byte{0xFF, 0xD8}
+	pngHeader = []byte("\x89PNG\r\n\x1a\n")
+	exifID    = []byte("Exif\x00\x00")
+	xmpID     = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// EXIF tags read or cleared here
+const (
+	tagOrientation = 0x0112
+	tagGPSIFD      = 0x8825
+)
+
+// StripGPS returns a copy of a JPEG or PNG photo without the location the
+// camera recorded. In a JPEG the GPS fields of the EXIF data are cleared,
+// keeping the rest (orientation, date, camera), and XMP metadata, which may
+// repeat the location, is dropped. A PNG loses its EXIF chunk. Other data is
+// returned unchanged.
+func StripGPS(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, jpegSOI):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngHeader):
+		return stripPNG(data)
+	}
+	return append([]byte(nil), data...)
+}
+
+// stripJPEG copies the segments before the image data, clearing the GPS of
+// the EXIF segment and skipping XMP ones, then the rest as is
+func stripJPEG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, jpegSOI...)
+	pos := len(jpegSOI)
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		// Start of scan: the compressed image follows
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		segment := data[pos:end]
+		payload := segment[4:]
+		if marker == 0xE1 && bytes.HasPrefix(payload, xmpID) {
+			pos = end
+			continue
+		}
+		if marker == 0xE1 && bytes.HasPrefix(payload, exifID) {
+			segment = append([]byte(nil), segment...)
+			clearGPS(segment[4+len(exifID):])
+		}
+		out = append(out, segment...)
+		pos = end
+	}
+	return append(out, data[pos:]...)
+}
+
+// stripPNG copies every chunk but eXIf
+func stripPNG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngHeader...)
+	pos := len(pngHeader)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length // length, type, data and CRC
+		if length < 0 || end > len(data) {
+			break
+		}
+		if string(data[pos+4:pos+8]) != "eXIf" {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return append(out, data[pos:]...)
+}
+
+// tiff reads the TIFF structure that holds EXIF data
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newTIFF(data []byte) *tiff {
+	if len(data) < 8 {
+		return nil
+	}
+	switch string(data[:2]) {
+	case "II":
+		return &tiff{data, binary.LittleEndian}
+	case "MM":
+		return &tiff{data, binary.BigEndian}
+	}
+	return nil
+}
+
+// entries returns the offset of each 12-byte entry of the IFD at offset
+func (t *tiff) entries(offset uint32) []int {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(t.data) {
+		return nil
+	}
+	entries := make([]int, count)
+	for i := range entries {
+		entries[i] = start + i*12
+	}
+	return entries
+}
+
+// find returns the offset of the entry of the first IFD with tag, or -1
+func (t *tiff) find(tag uint16) int {
+	for _, e := range t.entries(t.order.Uint32(t.data[4:])) {
+		if t.order.Uint16(t.data[e:]) == tag {
+			return e
+		}
+	}
+	return -1
+}
+
+// valueSize is how many bytes the values of an entry take
+func (t *tiff) valueSize(entry int) int64 {
+	sizes := map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+	return sizes[t.order.Uint16(t.data[entry+2:])] * int64(t.order.Uint32(t.data[entry+4:]))
+}
+
+// clearGPS zeroes the values of the GPS IFD and then the IFD itself, so it
+// reads as empty. The data keeps its size, so no offset moves.
+func clearGPS(data []byte) {
+	t := newTIFF(data)
+	if t == nil {
+		return
+	}
+	pointer := t.find(tagGPSIFD)
+	if pointer < 0 {
+		return
+	}
+	offset := t.order.Uint32(data[pointer+8:])
+	entries := t.entries(offset)
+	for _, e := range entries {
+		if size := t.valueSize(e); size > 4 {
+			start := int64(t.order.Uint32(data[e+8:]))
+			if start+size <= int64(len(data)) {
+				clear(data[start : start+size])
+			}
+		}
+	}
+	if len(entries) > 0 {
+		clear(data[offset : entries[len(entries)-1]+12])
+	}
+}
+
+// orientation reads the EXIF orientation of a JPEG: 1 is upright, 3 upside
+// down, 6 and 8 turned right and left
+func orientation(data []byte) int {
+	if !bytes.HasPrefix(data, jpegSOI) {
+		return 1
+	}
+	pos := len(jpegSOI)
+	for pos+4 <= len(data) && data[pos] == 0xFF && data[pos+1] != 0xDA {
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		if payload := data[pos+4 : end]; data[pos+1] == 0xE1 && bytes.HasPrefix(payload, exifID) {
+			t := newTIFF(payload[len(exifID):])
+			if t == nil {
+				return 1
+			}
+			if e := t.find(tagOrientation); e >= 0 {
+				return int(t.order.Uint16(t.data[e+8:]))
+			}
+			return 1
+		}
+		pos = end
+	}
+	return 1
+}
+
+// Thumbnail renders a JPEG or PNG photo as a JPEG no larger than maxSide on
+// either side, upright by its EXIF orientation
+func Thumbnail(data []byte, maxSide int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	thumb := rotate(scale(src, maxSide), orientation(data))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbSamples is how many source pixels per side are averaged into each
+// pixel of a thumbnail
+const thumbSamples = 4
+
+// scale shrinks an image to fit in maxSide, averaging a grid of source
+// pixels for each thumbnail pixel
+func scale(src image.Image, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/w)
+		} else {
+			w, h = max(1, w*maxSide/h), maxSide
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var r, g, bl, n uint32
+			for sy := 0; sy < thumbSamples; sy++ {
+				for sx := 0; sx < thumbSamples; sx++ {
+					px := b.Min.X + (x*thumbSamples+sx)*b.Dx()/(w*thumbSamples)
+					py := b.Min.Y + (y*thumbSamples+sy)*b.Dy()/(h*thumbSamples)
+					pr, pg, pb, _ := src.At(px, py).RGBA()
+					r, g, bl, n = r+pr, g+pg, bl+pb, n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), 0xFF})
+		}
+	}
+	return dst
+}
+
+// rotate turns an image upright given its EXIF orientation; mirrored
+// orientations are left as they are
+func rotate(src *image.RGBA, orientation int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var dst *image.RGBA
+	switch orientation {
+	case 3:
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.SetRGBA(w-1-x, h-1-y, src.RGBAAt(x, y))
+			}
+		}
+	case 6:
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.SetRGBA(h-1-y, x, src.RGBAAt(x, y))
+			}
+		}
+	case 8:
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.SetRGBA(y, w-1-x, src.RGBAAt(x, y))
+			}
+		}
+	default:
+		return src
+	}
+	return dst
+}
@@ -0,0 +1,224 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is a small image whose sides tell rotations apart
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 6), uint8(y * 12), 100, 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifTIFF builds little-endian EXIF data with an orientation and a GPS IFD
+// holding a latitude of 33° 27' 42.42"
+func exifTIFF(orientation uint16) []byte {
+	le := binary.LittleEndian
+	t := new(bytes.Buffer)
+	t.WriteString("II")
+	binary.Write(t, le, uint16(42))
+	binary.Write(t, le, uint32(8))
+	// IFD0 at 8 with two entries ends at 38, where the GPS IFD starts
+	binary.Write(t, le, uint16(2))
+	binary.Write(t, le, []uint16{tagOrientation, 3})
+	binary.Write(t, le, uint32(1))
+	binary.Write(t, le, []uint16{orientation, 0})
+	binary.Write(t, le, []uint16{tagGPSIFD, 4})
+	binary.Write(t, le, uint32(1))
+	binary.Write(t, le, uint32(38))
+	binary.Write(t, le, uint32(0))
+	// GPS IFD at 38 with two entries ends at 68, where the latitude is
+	binary.Write(t, le, uint16(2))
+	binary.Write(t, le, []uint16{1, 2})
+	binary.Write(t, le, uint32(2))
+	t.Write([]byte{'S', 0, 0, 0})
+	binary.Write(t, le, []uint16{2, 5})
+	binary.Write(t, le, uint32(3))
+	binary.Write(t, le, uint32(68))
+	binary.Write(t, le, uint32(0))
+	binary.Write(t, le, []uint32{33, 1, 27, 1, 4242, 100})
+	return t.Bytes()
+}
+
+// withSegment inserts a JPEG segment right after the SOI marker
+func withSegment(data []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	out := append([]byte(nil), jpegSOI...)
+	out = append(out, segment...)
+	out = append(out, payload...)
+	return append(out, data[len(jpegSOI):]...)
+}
+
+func TestStripGPSClearsLocation(t *testing.T) {
+	data := withSegment(encodeJPEG(t), 0xE1, append(append([]byte(nil), exifID...), exifTIFF(6)...))
+	stripped := StripGPS(data)
+
+	if len(stripped) != len(data) {
+		t.Fatalf("length changed from %d to %d", len(data), len(stripped))
+	}
+	if bytes.Contains(stripped, []byte{'S', 0, 0, 0}) {
+		t.Error("GPS latitude reference still present")
+	}
+	if got := orientation(stripped); got != 6 {
+		t.Errorf("orientation = %d after stripping, want 6", got)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped JPEG does not decode: %v", err)
+	}
+}
+
+func TestStripGPSMalformed(t *testing.T) {
+	valid := encodeJPEG(t)
+	pngData := encodePNG(t)
+	cases := map[string][]byte{
+		"empty":               nil,
+		"soi only":            jpegSOI,
+		"truncated segment":   {0xFF, 0xD8, 0xFF, 0xE1, 0x00},
+		"zero length":         append([]byte{0xFF, 0xD8, 0xFF, 0xD0, 0x00, 0x00}, valid[2:]...),
+		"length one":          append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}, valid[2:]...),
+		"length past end":     {0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'},
+		"short exif":          withSegment(valid, 0xE1, append(append([]byte(nil), exifID...), 'I', 'I')),
+		"gps pointer outside": withSegment(valid, 0xE1, append(append([]byte(nil), exifID...), gpsPointerOutside()...)),
+		"png header only":     pngHeader,
+		"png chunk past end":  append(append([]byte(nil), pngHeader...), 0x7F, 0xFF, 0xFF, 0xFF, 'e', 'X', 'I', 'f'),
+		"png truncated":       pngData[:len(pngData)/2],
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			StripGPS(data) // must not panic
+		})
+	}
+}
+
+// gpsPointerOutside is EXIF data whose GPS IFD pointer is past its end
+func gpsPointerOutside() []byte {
+	data := exifTIFF(1)
+	binary.LittleEndian.PutUint32(data[30:], 0xFFFFFFF0)
+	return data[:38]
+}
+
+func TestThumbnail(t *testing.T) {
+	plain := encodeJPEG(t)
+	rotated := withSegment(plain, 0xE1, append(append([]byte(nil), exifID...), exifTIFF(6)...))
+	cases := []struct {
+		name          string
+		data          []byte
+		width, height int
+	}{
+		{"jpeg", plain, 20, 10},
+		{"png", encodePNG(t), 20, 10},
+		{"turned right", rotated, 10, 20},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			thumb, err := Thumbnail(c.data, 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != c.width || config.Height != c.height {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", config.Width, config.Height, c.width, c.height)
+			}
+		})
+	}
+}
+
+func TestThumbnailMalformed(t *testing.T) {
+	valid := encodeJPEG(t)
+	cases := map[string][]byte{
+		"empty":           nil,
+		"not an image":    []byte("hello plain text"),
+		"truncated jpeg":  valid[:len(valid)/2],
+		"zero length":     append([]byte{0xFF, 0xD8, 0xFF, 0xD0, 0x00, 0x00}, valid...),
+		"length one":      append([]byte{0xFF, 0xD8, 0xFF, 0xD0, 0x00, 0x01}, valid...),
+		"truncated exif":  withSegment(valid, 0xE1, append(append([]byte(nil), exifID...), 'M', 'M', 0, 42)),
+		"orientation far": withSegment(valid, 0xE1, append(append([]byte(nil), exifID...), exifTIFF(6)[:20]...)),
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			Thumbnail(data, 20) // must not panic; an error is fine
+		})
+	}
+}
+
+func TestThumbnailTooLarge(t *testing.T) {
+	// A PNG header claiming 10000x10000 pixels, with no image data
+	data := append([]byte(nil), pngHeader...)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 10000)
+	binary.BigEndian.PutUint32(ihdr[4:], 10000)
+	ihdr[8], ihdr[9] = 8, 2 // 8-bit RGB
+	data = binary.BigEndian.AppendUint32(data, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	data = append(data, chunk...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+
+	if _, err := Thumbnail(data, 20); err != ErrTooLarge {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	valid := encodeJPEG(t)
+	exif := func(tiff []byte) []byte {
+		return withSegment(valid, 0xE1, append(append([]byte(nil), exifID...), tiff...))
+	}
+	cases := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", valid, 1},
+		{"turned right", exif(exifTIFF(6)), 6},
+		{"upside down", exif(exifTIFF(3)), 3},
+		{"png", encodePNG(t), 1},
+		{"empty", nil, 1},
+		{"zero length segment", append([]byte{0xFF, 0xD8, 0xFF, 0xD0, 0x00, 0x00}, valid...), 1},
+		{"length one segment", append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}, valid...), 1},
+		{"segment past end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x40, 0x00, 'E', 'x'}, 1},
+		{"short tiff", exif([]byte("II*")), 1},
+		{"ifd past end", exif([]byte{'I', 'I', 42, 0, 0xF0, 0xFF, 0xFF, 0xFF}), 1},
+		{"entries past end", exif(exifTIFF(6)[:20]), 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := orientation(c.data); got != c.want {
+				t.Errorf("orientation = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
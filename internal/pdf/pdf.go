@@ -0,0 +1,125 @@
+// Package pdf writes simple text-only PDF documents (A4, Helvetica) without
+// external dependencies. It covers printable listings such as purchase
+// orders, not general layout.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	pageWidth  = 595.28 // A4 in points
+	pageHeight = 841.89
+	margin     = 50.0
+)
+
+// Document is a multi-page text document. Text is placed on the current
+// line; Ln moves down and starts a new page when the bottom margin is reached.
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// New creates an empty A4 document with one page
+func New() *Document {
+	d := &Document{}
+	d.addPage()
+	return d
+}
+
+// Text writes s at horizontal position x on the current line
+func (d *Document) Text(x, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, margin+x, d.y, escape(s))
+}
+
+// Rule draws a horizontal line across the page at the current line
+func (d *Document) Rule() {
+	fmt.Fprintf(d.page(), "%.2f %.2f m %.2f %.2f l 0.5 w S\n", margin, d.y-4, pageWidth-margin, d.y-4)
+}
+
+// Ln moves down by h points, breaking the page when needed
+func (d *Document) Ln(h float64) {
+	d.y -= h
+	if d.y < margin {
+		d.addPage()
+	}
+}
+
+// Width returns the usable width between the margins
+func (d *Document) Width() float64 {
+	return pageWidth - 2*margin
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object
+	var kids bytes.Buffer
+	for i := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+func (d *Document) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// escape converts s to WinAnsi bytes inside a PDF string literal. Characters
+// outside Latin-1 are replaced with '?'.
+func escape(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteByte(0x80)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
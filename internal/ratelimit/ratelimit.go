@@ -0,0 +1,188 @@
+// Package ratelimit provides in-process token bucket rate limiting and
+// progressive lockouts. State lives in memory and is bounded: once a limiter
+// tracks its maximum number of keys, the least recently seen key is dropped.
+// Keys under an active lockout are never dropped, so flooding a Lockout with
+// new keys can't lift a lock.
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// store is a size-bounded map that evicts the least recently used key. Keys
+// whose value is pinned are skipped; when every key is pinned the store grows
+// past its bound until some are released.
+type store[V any] struct {
+	max     int
+	pinned  func(V) bool
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type entry[V any] struct {
+	key   string
+	value V
+}
+
+func newStore[V any](max int, pinned func(V) bool) *store[V] {
+	return &store[V]{max: max, pinned: pinned, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// get returns the value for key, creating it with init if missing
+func (s *store[V]) get(key string, init func() V) V {
+	if el, ok := s.entries[key]; ok {
+		s.order.MoveToFront(el)
+		return el.Value.(*entry[V]).value
+	}
+	if s.max > 0 && len(s.entries) >= s.max {
+		s.evict()
+	}
+	value := init()
+	s.entries[key] = s.order.PushFront(&entry[V]{key: key, value: value})
+	return value
+}
+
+// evict drops the least recently used key that isn't pinned, if any
+func (s *store[V]) evict() {
+	for el := s.order.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(*entry[V])
+		if s.pinned == nil || !s.pinned(e.value) {
+			s.order.Remove(el)
+			delete(s.entries, e.key)
+			return
+		}
+	}
+}
+
+func (s *store[V]) delete(key string) {
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+}
+
+// Limiter is a token bucket per key: each key may spend Burst requests at
+// once and regains Rate requests per second
+type Limiter struct {
+	mu    sync.Mutex
+	rate  float64
+	burst float64
+	keys  *store[*bucket]
+	now   func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter allows burst requests per key, refilled at perMinute per minute,
+// tracking at most maxKeys keys
+func NewLimiter(perMinute float64, burst, maxKeys int) *Limiter {
+	return &Limiter{
+		rate:  perMinute / 60,
+		burst: float64(burst),
+		keys:  newStore[*bucket](maxKeys, nil),
+		now:   time.Now,
+	}
+}
+
+// Allow spends one token for key. When none is left it returns false and how
+// long until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.keys.get(key, func() *bucket { return &bucket{tokens: l.burst, last: now} })
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Lockout locks a key after Threshold consecutive failures. Each lock lasts
+// twice as long as the previous one, from Base up to Max, until a success
+// resets the key.
+type Lockout struct {
+	mu        sync.Mutex
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	keys      *store[*lockState]
+	now       func() time.Time
+}
+
+type lockState struct {
+	failures int
+	locks    int
+	until    time.Time
+}
+
+// NewLockout creates a Lockout tracking at most maxKeys keys, plus those
+// locked at the time
+func NewLockout(threshold int, base, max time.Duration, maxKeys int) *Lockout {
+	l := &Lockout{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		now:       time.Now,
+	}
+	l.keys = newStore[*lockState](maxKeys, func(state *lockState) bool {
+		return state.until.After(l.now())
+	})
+	return l
+}
+
+// Locked returns how long key remains locked, or zero
+func (l *Lockout) Locked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.keys.entries[key]
+	if !ok {
+		return 0
+	}
+	until := el.Value.(*entry[*lockState]).value.until
+	if remaining := until.Sub(l.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Fail records a failed attempt and returns the lock duration if this
+// failure locked the key
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.keys.get(key, func() *lockState { return &lockState{} })
+	state.failures++
+	if state.failures < l.threshold {
+		return 0
+	}
+
+	duration := l.base << uint(state.locks)
+	if duration > l.max || duration <= 0 {
+		duration = l.max
+	}
+	state.failures = 0
+	state.locks++
+	state.until = l.now().Add(duration)
+	return duration
+}
+
+// Reset forgets the failures of key after a successful attempt
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys.delete(key)
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for limiters under test
+type clock struct{ t time.Time }
+
+func newClock() *clock {
+	return &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func key(i int) string { return "key" + strconv.Itoa(i) }
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	c := newClock()
+	l := NewLimiter(60, 3, 100) // one token per second
+	l.now = c.now
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("request past the burst allowed")
+	}
+	if wait != time.Second {
+		t.Errorf("wait = %s, want 1s", wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("other key limited by a's requests")
+	}
+
+	c.advance(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("request refused after a token refilled")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("second request allowed with a single refilled token")
+	}
+
+	c.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("refill above the burst: request %d refused", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("tokens refilled past the burst")
+	}
+}
+
+func TestLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	l := NewLimiter(1, 1, 2)
+	l.now = newClock().now
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("a") // a is now the most recently used
+	l.Allow("c") // evicts b
+
+	if len(l.keys.entries) != 2 {
+		t.Fatalf("tracking %d keys, want 2", len(l.keys.entries))
+	}
+	if _, ok := l.keys.entries["b"]; ok {
+		t.Error("least recently used key kept")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("evicted key starts without a full bucket")
+	}
+}
+
+func TestLockoutDoublesUpToMax(t *testing.T) {
+	c := newClock()
+	l := NewLockout(3, time.Minute, 5*time.Minute, 100)
+	l.now = c.now
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		for i := 0; i < 2; i++ {
+			if wait := l.Fail("a"); wait != 0 {
+				t.Fatalf("locked for %s before the threshold", wait)
+			}
+			if l.Locked("a") != 0 {
+				t.Fatal("Locked before the threshold")
+			}
+		}
+		if got := l.Fail("a"); got != want {
+			t.Fatalf("lock = %s, want %s", got, want)
+		}
+		if locked := l.Locked("a"); locked != want {
+			t.Fatalf("Locked = %s, want %s", locked, want)
+		}
+		c.advance(want)
+		if locked := l.Locked("a"); locked != 0 {
+			t.Fatalf("still locked for %s after the lock ended", locked)
+		}
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	c := newClock()
+	l := NewLockout(2, time.Minute, time.Hour, 100)
+	l.now = c.now
+
+	l.Fail("a")
+	l.Fail("a")
+	c.advance(time.Minute)
+	l.Reset("a")
+
+	l.Fail("a")
+	if wait := l.Fail("a"); wait != time.Minute {
+		t.Errorf("lock after reset = %s, want the base %s", wait, time.Minute)
+	}
+}
+
+func TestLockoutKeepsLockedKeysWhenFull(t *testing.T) {
+	c := newClock()
+	l := NewLockout(2, time.Minute, time.Hour, 10)
+	l.now = c.now
+
+	l.Fail("victim")
+	if wait := l.Fail("victim"); wait != time.Minute {
+		t.Fatalf("lock = %s, want %s", wait, time.Minute)
+	}
+	// Flood the store with new keys, each failing once
+	for i := 0; i < 1000; i++ {
+		l.Fail(key(i))
+	}
+	if l.Locked("victim") == 0 {
+		t.Fatal("flooding new keys lifted an active lock")
+	}
+	if len(l.keys.entries) > 10 {
+		t.Errorf("tracking %d keys, want at most 10 when only one is locked", len(l.keys.entries))
+	}
+
+	// Once the lock has run out the key may go like any other
+	c.advance(time.Minute)
+	for i := 1000; i < 1020; i++ {
+		l.Fail(key(i))
+	}
+	if _, ok := l.keys.entries["victim"]; ok {
+		t.Error("expired lock kept past the bound")
+	}
+}
+
+func TestLockoutGrowsWhenEveryKeyIsLocked(t *testing.T) {
+	c := newClock()
+	l := NewLockout(1, time.Minute, time.Hour, 3)
+	l.now = c.now
+
+	for i := 0; i < 5; i++ {
+		l.Fail(key(i))
+	}
+	for i := 0; i < 5; i++ {
+		if l.Locked(key(i)) == 0 {
+			t.Errorf("%s lost its lock", key(i))
+		}
+	}
+}
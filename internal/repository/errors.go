@@ -0,0 +1,46 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrDuplicateSerial is returned when a bicycle serial number is already
+	// registered to another bicycle
+	ErrDuplicateSerial = errors.New("serial number already registered")
+
+	// ErrDuplicateSKU is returned when an inventory SKU is already in use
+	ErrDuplicateSKU = errors.New("sku already in use")
+
+	// ErrDuplicateEAN is returned when an inventory barcode is already in use
+	ErrDuplicateEAN = errors.New("ean already in use")
+
+	// ErrInsufficientStock is returned when a stock location cannot cover
+	// the quantity being consumed
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrInvalidToken is returned when a user token is unknown, expired or
+	// already used
+	ErrInvalidToken = errors.New("invalid or expired token")
+
+	// ErrDuplicateRole is returned when a role name is already taken
+	ErrDuplicateRole = errors.New("role already exists")
+
+	// ErrRoleInUse is returned when deleting a role that users still hold
+	ErrRoleInUse = errors.New("role is assigned to users")
+
+	// ErrDuplicateTrackingCode is returned when a new ticket's tracking code
+	// is already taken by a ticket of any branch
+	ErrDuplicateTrackingCode = errors.New("tracking code already in use")
+
+	// ErrDuplicateTenant is returned when a tenant slug is already taken
+	ErrDuplicateTenant = errors.New("tenant already exists")
+
+	// ErrHostTaken is returned when a hostname already serves another tenant
+	ErrHostTaken = errors.New("host already in use")
+
+	// ErrQuoteClosed is returned when adding to a quote that was already
+	// approved or rejected
+	ErrQuoteClosed = errors.New("quote is closed")
+
+	// ErrNothingToBill is returned when a ticket has no ended, unbilled time
+	ErrNothingToBill = errors.New("no unbilled time")
+)
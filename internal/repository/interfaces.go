@@ -14,11 +14,120 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id int64) (*domain.User, error)
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	// UpdateWorkProfile saves a technician's skills and daily capacity
+	UpdateWorkProfile(ctx context.Context, id int64, skills []string, dailyHours float64) error
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id int64) error
+	// UpdateTOTP stores the two-factor secret and whether it is enabled; an
+	// empty secret turns two-factor authentication off
+	UpdateTOTP(ctx context.Context, id int64, secret string, enabled bool) error
+	// RecordTOTPStep remembers the last accepted TOTP time step and reports
+	// false if the step was already used, so a code can't be replayed
+	RecordTOTPStep(ctx context.Context, id int64, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, id int64, codeHashes []string) error
+	// UseRecoveryCode burns an unused recovery code or returns ErrInvalidToken
+	UseRecoveryCode(ctx context.Context, id int64, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, id int64) (int, error)
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, role string, limit, offset int) ([]domain.User, error)
 	Count(ctx context.Context, role string) (int, error)
 }
 
+// RoleRepository handles roles and their permissions
+type RoleRepository interface {
+	// Create fails with ErrDuplicateRole if the name is taken
+	Create(ctx context.Context, role *domain.Role) error
+	GetByName(ctx context.Context, name string) (*domain.Role, error)
+	// Update saves the label and replaces the permissions
+	Update(ctx context.Context, role *domain.Role) error
+	// Delete fails with ErrRoleInUse while users hold the role
+	Delete(ctx context.Context, name string) error
+	// List returns every role with its user count, system roles first
+	List(ctx context.Context) ([]domain.Role, error)
+}
+
+// BranchRepository handles shop locations
+type BranchRepository interface {
+	// Create also creates the branch's stock location unless one is set
+	Create(ctx context.Context, branch *domain.Branch) error
+	GetByID(ctx context.Context, id int64) (*domain.Branch, error)
+	Update(ctx context.Context, branch *domain.Branch) error
+	List(ctx context.Context, includeInactive bool) ([]domain.Branch, error)
+}
+
+// UserTokenRepository handles password reset, verification and invitation tokens
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *domain.UserToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.UserToken, error)
+	// Consume marks the token as used. It fails with ErrInvalidToken if the
+	// token was already used, so a link only works once even under races.
+	Consume(ctx context.Context, id int64) error
+	// InvalidateForUser marks every unused token of the given purpose as used
+	InvalidateForUser(ctx context.Context, userID int64, purpose string) error
+}
+
+// SessionRepository handles signed-in devices and their refresh tokens
+type SessionRepository interface {
+	Create(ctx context.Context, session *domain.Session) error
+	GetByID(ctx context.Context, id int64) (*domain.Session, error)
+	// GetByRefreshHash matches either the current or the previous refresh
+	// token of a session so callers can detect reuse of a rotated token
+	GetByRefreshHash(ctx context.Context, refreshHash string) (*domain.Session, error)
+	// Rotate replaces the current refresh token and extends the session. It
+	// fails with ErrInvalidToken if oldHash is no longer the current token.
+	Rotate(ctx context.Context, id int64, oldHash, newHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id int64) error
+	// RevokeAllForUser revokes every session of the user except exceptID
+	RevokeAllForUser(ctx context.Context, userID, exceptID int64) error
+	// ListActive returns unrevoked, unexpired sessions with their user, most
+	// recently used first. A zero userID lists every user's sessions.
+	ListActive(ctx context.Context, userID int64) ([]domain.Session, error)
+}
+
+// APIKeyRepository handles the keys integrations call the API with
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByID(ctx context.Context, id int64) (*domain.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	// List returns every key with its creator, newest first
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+// WebhookRepository handles webhook endpoints and the log of what was sent
+// to them
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, id int64) (*domain.Webhook, error)
+	List(ctx context.Context) ([]domain.Webhook, error)
+	// ListForEvent returns the active webhooks subscribed to an event
+	ListForEvent(ctx context.Context, event string) ([]domain.Webhook, error)
+	Update(ctx context.Context, webhook *domain.Webhook) error
+	// Delete removes a webhook with its deliveries
+	Delete(ctx context.Context, id int64) error
+
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+	// ListDeliveries returns a webhook's deliveries, newest first
+	ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]domain.WebhookDelivery, error)
+	// ClaimDueDeliveries returns up to limit pending deliveries whose next
+	// attempt is due and moves that attempt lease later, so that concurrent
+	// sweeps don't send them twice and a crash mid-send retries them
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+	// UpdateDelivery saves the outcome of an attempt
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+}
+
+// AuditLogRepository stores the append-only audit log. There is no update
+// or delete: the table rejects both.
+type AuditLogRepository interface {
+	Record(ctx context.Context, entry *domain.AuditEntry) error
+	// List returns matching entries with their actor, newest first
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	Count(ctx context.Context, filter domain.AuditFilter) (int, error)
+}
+
 // BrandRepository defines the interface for brand data operations
 type BrandRepository interface {
 	Create(ctx context.Context, brand *domain.Brand) error
@@ -52,12 +161,15 @@ type BookingRepository interface {
 	Create(ctx context.Context, booking *domain.Booking) error
 	GetByID(ctx context.Context, id int64) (*domain.Booking, error)
 	GetByCustomerID(ctx context.Context, customerID int64, limit, offset int) ([]domain.Booking, error)
-	GetByDateRange(ctx context.Context, start, end time.Time) ([]domain.Booking, error)
+	GetByBicycleID(ctx context.Context, bicycleID int64) ([]domain.Booking, error)
+	// GetByDateRange, List and CountByStatus cover every branch when
+	// branchID is zero
+	GetByDateRange(ctx context.Context, branchID int64, start, end time.Time) ([]domain.Booking, error)
 	Update(ctx context.Context, booking *domain.Booking) error
 	UpdateStatus(ctx context.Context, id int64, status string) error
 	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context, status string, limit, offset int) ([]domain.Booking, error)
-	CountByStatus(ctx context.Context, status string) (int, error)
+	List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Booking, error)
+	CountByStatus(ctx context.Context, branchID int64, status string) (int, error)
 }
 
 // QuoteRepository defines the interface for quote data operations
@@ -68,11 +180,13 @@ type QuoteRepository interface {
 	Update(ctx context.Context, quote *domain.Quote) error
 	Approve(ctx context.Context, id int64) error
 	Reject(ctx context.Context, id int64, reason string) error
-	List(ctx context.Context, status string, limit, offset int) ([]domain.Quote, error)
+	// List covers every branch when branchID is zero
+	List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Quote, error)
 }
 
 // TicketRepository defines the interface for ticket data operations
 type TicketRepository interface {
+	// Create fails with ErrDuplicateTrackingCode if the code is taken
 	Create(ctx context.Context, ticket *domain.Ticket) error
 	GetByID(ctx context.Context, id int64) (*domain.Ticket, error)
 	GetByTrackingCode(ctx context.Context, code string) (*domain.Ticket, error)
@@ -81,14 +195,119 @@ type TicketRepository interface {
 	UpdateStatus(ctx context.Context, id int64, status string, changedBy int64, notes string) error
 	CreateStatusHistory(ctx context.Context, history *domain.TicketStatusHistory) error
 	GetStatusHistory(ctx context.Context, ticketID int64) ([]domain.TicketStatusHistory, error)
+	// GetStatusHistories returns the history of each ticket, oldest first
+	GetStatusHistories(ctx context.Context, ticketIDs []int64) (map[int64][]domain.TicketStatusHistory, error)
 
 	// Ticket Parts
 	CreateTicketPart(ctx context.Context, part *domain.TicketPart) error
 	GetTicketParts(ctx context.Context, ticketID int64) ([]domain.TicketPart, error)
+	GetTicketPart(ctx context.Context, id int64) (*domain.TicketPart, error)
+	// GetOpenPartsByItem returns pending parts of an inventory item on tickets
+	// that are not ready or delivered yet, oldest first
+	GetOpenPartsByItem(ctx context.Context, itemID int64) ([]domain.TicketPart, error)
+	// ToggleTicketPartStatus flips a part between pending and done. Parts linked
+	// to inventory consume or return stock in the same transaction and fail with
+	// ErrInsufficientStock when the location cannot cover the quantity.
 	ToggleTicketPartStatus(ctx context.Context, id int64) error
+	// DeleteTicketPart removes a part, returning its stock if it was consumed
 	DeleteTicketPart(ctx context.Context, id int64) error
-	List(ctx context.Context, status string, limit, offset int) ([]domain.Ticket, error)
-	CountByStatus(ctx context.Context) (map[string]int, error)
+	// List and CountByStatus cover every branch when branchID is zero
+	List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Ticket, error)
+	CountByStatus(ctx context.Context, branchID int64) (map[string]int, error)
+	// ListBoard returns the tickets on the workshop board, oldest first, with
+	// their customer, bicycle, technician and count of pending parts
+	ListBoard(ctx context.Context, filter domain.BoardFilter) ([]domain.BoardTicket, error)
+	// QueueHours sums the estimated hours of the tickets a branch still has
+	// to work on, counting domain.DefaultServiceHours for services without
+	// an estimate
+	QueueHours(ctx context.Context, branchID int64) (float64, error)
+	// OpenWorkByTechnician counts the tickets not ready or delivered yet of
+	// each technician and sums their estimated hours like QueueHours. Only
+	// the technician's ID is set; it covers every branch when branchID is
+	// zero.
+	OpenWorkByTechnician(ctx context.Context, branchID int64) (map[int64]domain.Workload, error)
+	// ListOpen and ListReceivedBetween return tickets oldest first with their
+	// technician and their booking's customer and service; they cover every
+	// branch when branchID is zero. ListOpen skips ready and delivered
+	// tickets.
+	ListOpen(ctx context.Context, branchID int64) ([]domain.Ticket, error)
+	ListReceivedBetween(ctx context.Context, branchID int64, from, to time.Time) ([]domain.Ticket, error)
+}
+
+// AttachmentRepository defines the interface for ticket attachments. It
+// only keeps their records; the files are in the upload storage.
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *domain.Attachment) error
+	GetByID(ctx context.Context, id int64) (*domain.Attachment, error)
+	// ListByTicket returns the attachments of a ticket with their uploader,
+	// oldest first
+	ListByTicket(ctx context.Context, ticketID int64) ([]domain.Attachment, error)
+	SetPublic(ctx context.Context, id int64, public bool) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// ChecklistRepository handles inspection checklist templates and the copies
+// tickets work through
+type ChecklistRepository interface {
+	Create(ctx context.Context, checklist *domain.Checklist) error
+	// GetByID returns a checklist with its items in order
+	GetByID(ctx context.Context, id int64) (*domain.Checklist, error)
+	// List returns every checklist with its items, by name
+	List(ctx context.Context) ([]domain.Checklist, error)
+	Rename(ctx context.Context, id int64, name string) error
+	// Delete removes a checklist and detaches it from its services; tickets
+	// keep their copies
+	Delete(ctx context.Context, id int64) error
+	// AddItem appends an item at the end of its checklist
+	AddItem(ctx context.Context, item *domain.ChecklistItem) error
+	DeleteItem(ctx context.Context, checklistID, itemID int64) error
+
+	// ApplyToTicket copies the items of a checklist onto a ticket
+	ApplyToTicket(ctx context.Context, checklistID, ticketID int64) error
+	// ListByTicket returns the checklist items of a ticket in order
+	ListByTicket(ctx context.Context, ticketID int64) ([]domain.TicketChecklistItem, error)
+	GetTicketItem(ctx context.Context, id int64) (*domain.TicketChecklistItem, error)
+	// CompleteTicketItem records who completed an item, with its
+	// measurement or photo
+	CompleteTicketItem(ctx context.Context, id int64, value string, attachmentID, userID int64) error
+	ReopenTicketItem(ctx context.Context, id int64) error
+	// ReopenByAttachment reopens the items completed with a photo that is
+	// being deleted
+	ReopenByAttachment(ctx context.Context, attachmentID int64) error
+}
+
+// CommentRepository handles the conversation of each ticket
+type CommentRepository interface {
+	Create(ctx context.Context, comment *domain.TicketComment) error
+	// ListByTicket returns the comments of a ticket with their author,
+	// oldest first
+	ListByTicket(ctx context.Context, ticketID int64) ([]domain.TicketComment, error)
+}
+
+// TimeEntryRepository defines the interface for technician time tracking
+type TimeEntryRepository interface {
+	Create(ctx context.Context, entry *domain.TimeEntry) error
+	GetByID(ctx context.Context, id int64) (*domain.TimeEntry, error)
+	// ListByTicket returns the entries of a ticket with their technician,
+	// newest first
+	ListByTicket(ctx context.Context, ticketID int64) ([]domain.TimeEntry, error)
+	// EndRunning ends the running timers of a technician, of a ticket or of
+	// both; a zero ID matches any. It returns how many it ended.
+	EndRunning(ctx context.Context, technicianID, ticketID int64, reason string) (int64, error)
+	// SetEndReason changes why an ended entry ended
+	SetEndReason(ctx context.Context, id int64, reason string) error
+	Delete(ctx context.Context, id int64) error
+	// BillLabor adds the ended, unbilled time of a ticket to the pending
+	// quote of its booking as one labor line at rate, creating the quote
+	// valid until validUntil if there is none. The entries are claimed in
+	// the same transaction, so each is billed once. It returns the quote and
+	// the quote as it was before, nil when created, or fails with
+	// ErrQuoteClosed or ErrNothingToBill.
+	BillLabor(ctx context.Context, ticketID, bookingID int64, rate float64, validUntil time.Time) (*domain.Quote, *domain.Quote, error)
+	// LaborByService sums the logged hours of the tickets received in a
+	// period per service, against the service's estimate; it covers every
+	// branch when branchID is zero
+	LaborByService(ctx context.Context, branchID int64, from, to time.Time) ([]domain.LaborStat, error)
 }
 
 // SurveyRepository defines the interface for survey data operations
@@ -118,10 +337,84 @@ type BicycleRepository interface {
 	Create(ctx context.Context, bicycle *domain.Bicycle) error
 	GetByID(ctx context.Context, id int64) (*domain.Bicycle, error)
 	GetByUserID(ctx context.Context, userID int64) ([]domain.Bicycle, error)
+	GetBySerial(ctx context.Context, serial string) (*domain.Bicycle, error)
 	Update(ctx context.Context, bicycle *domain.Bicycle) error
 	Delete(ctx context.Context, id int64) error
 }
 
+// StolenBikeRepository defines the interface for the stolen-bike registry
+type StolenBikeRepository interface {
+	Create(ctx context.Context, bike *domain.StolenBike) error
+	GetByID(ctx context.Context, id int64) (*domain.StolenBike, error)
+	GetActiveBySerial(ctx context.Context, serial string) (*domain.StolenBike, error)
+	List(ctx context.Context, includeRecovered bool, limit, offset int) ([]domain.StolenBike, error)
+	ListSeen(ctx context.Context, limit int) ([]domain.StolenBike, error)
+	MarkRecovered(ctx context.Context, id int64) error
+	RecordSighting(ctx context.Context, id, ticketID int64) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// InventoryRepository defines the interface for parts, stock locations and movements
+type InventoryRepository interface {
+	CreateItem(ctx context.Context, item *domain.InventoryItem) error
+	GetItem(ctx context.Context, id int64) (*domain.InventoryItem, error)
+	GetItemBySKU(ctx context.Context, sku string) (*domain.InventoryItem, error)
+	GetItemByEAN(ctx context.Context, ean string) (*domain.InventoryItem, error)
+	UpdateItem(ctx context.Context, item *domain.InventoryItem) error
+	ListItems(ctx context.Context, search string, includeInactive bool) ([]domain.InventoryItem, error)
+	ListLowStock(ctx context.Context) ([]domain.InventoryItem, error)
+
+	CreateLocation(ctx context.Context, location *domain.StockLocation) error
+	ListLocations(ctx context.Context) ([]domain.StockLocation, error)
+
+	GetStockLevels(ctx context.Context, itemID int64) ([]domain.StockLevel, error)
+	GetStock(ctx context.Context, itemID, locationID int64) (int, error)
+	// AdjustStock applies a movement and updates the stock level atomically
+	AdjustStock(ctx context.Context, movement *domain.StockMovement) error
+	ListMovements(ctx context.Context, itemID int64, limit int) ([]domain.StockMovement, error)
+}
+
+// BicycleTransferRepository defines the interface for bicycle ownership transfers
+type BicycleTransferRepository interface {
+	Create(ctx context.Context, transfer *domain.BicycleTransfer) error
+	GetByID(ctx context.Context, id int64) (*domain.BicycleTransfer, error)
+	GetPendingByBicycleID(ctx context.Context, bicycleID int64) (*domain.BicycleTransfer, error)
+	ListPendingForEmail(ctx context.Context, email string) ([]domain.BicycleTransfer, error)
+	ListPendingFromUser(ctx context.Context, userID int64) ([]domain.BicycleTransfer, error)
+	// Accept moves the bicycle to the recipient, redacts the previous owner's
+	// private notes and records the ownership change, all in one transaction
+	Accept(ctx context.Context, id, toUserID int64) error
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	GetOwnershipHistory(ctx context.Context, bicycleID int64) ([]domain.BicycleOwnership, error)
+}
+
+// SupplierRepository handles parts vendors
+type SupplierRepository interface {
+	Create(ctx context.Context, supplier *domain.Supplier) error
+	GetByID(ctx context.Context, id int64) (*domain.Supplier, error)
+	Update(ctx context.Context, supplier *domain.Supplier) error
+	List(ctx context.Context, includeInactive bool) ([]domain.Supplier, error)
+}
+
+// PurchaseOrderRepository handles purchase orders and their lines
+type PurchaseOrderRepository interface {
+	Create(ctx context.Context, order *domain.PurchaseOrder) error
+	// GetByID returns the order with its supplier, lines and linked tickets
+	GetByID(ctx context.Context, id int64) (*domain.PurchaseOrder, error)
+	List(ctx context.Context, status string, limit, offset int) ([]domain.PurchaseOrder, error)
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	AddLine(ctx context.Context, line *domain.PurchaseOrderLine) error
+	DeleteLine(ctx context.Context, orderID, lineID int64) error
+	LinkTicket(ctx context.Context, lineID, ticketID int64) error
+	UnlinkTicket(ctx context.Context, lineID, ticketID int64) error
+	// ListOpenLinesByTicket returns undelivered lines a ticket is waiting on
+	ListOpenLinesByTicket(ctx context.Context, ticketID int64) ([]domain.PurchaseOrderLine, error)
+	// Receive books the received quantities (by line ID) into stock at the
+	// given location and moves the order to partially_received or received,
+	// all in one transaction
+	Receive(ctx context.Context, orderID int64, quantities map[int64]int, locationID, userID int64) error
+}
+
 // SettingsRepository handles application configuration
 type SettingsRepository interface {
 	Get(ctx context.Context, key string) (string, error)
@@ -130,15 +423,52 @@ type SettingsRepository interface {
 
 // Repositories bundles all repository interfaces
 type Repositories struct {
-	Users    UserRepository
-	Brands   BrandRepository
-	Models   ModelRepository
-	Services ServiceRepository
-	Bicycles BicycleRepository
-	Bookings BookingRepository
-	Quotes   QuoteRepository
-	Tickets  TicketRepository
-	Surveys  SurveyRepository
-	Ads      AdRepository
-	Settings SettingsRepository
+	Users       UserRepository
+	Roles       RoleRepository
+	Branches    BranchRepository
+	UserTokens  UserTokenRepository
+	Sessions    SessionRepository
+	APIKeys     APIKeyRepository
+	Webhooks    WebhookRepository
+	AuditLog    AuditLogRepository
+	Brands      BrandRepository
+	Models      ModelRepository
+	Services    ServiceRepository
+	Bicycles    BicycleRepository
+	Bookings    BookingRepository
+	Quotes      QuoteRepository
+	Tickets     TicketRepository
+	TimeEntries TimeEntryRepository
+	Attachments AttachmentRepository
+	Comments    CommentRepository
+	Checklists  ChecklistRepository
+	Surveys     SurveyRepository
+	Ads         AdRepository
+	Settings    SettingsRepository
+	StolenBikes StolenBikeRepository
+	Transfers   BicycleTransferRepository
+	Inventory   InventoryRepository
+	Suppliers   SupplierRepository
+	Purchases   PurchaseOrderRepository
+}
+
+// TenantRepository handles the platform registry of hosted workshops. It
+// lives in the platform database, not in any tenant's.
+type TenantRepository interface {
+	// Create fails with ErrDuplicateTenant if the slug is taken and with
+	// ErrHostTaken if another tenant already has one of the hosts
+	Create(ctx context.Context, tenant *domain.Tenant) error
+	GetByID(ctx context.Context, id int64) (*domain.Tenant, error)
+	// Update saves everything but the slug and replaces the hosts; it fails
+	// with ErrHostTaken like Create
+	Update(ctx context.Context, tenant *domain.Tenant) error
+	List(ctx context.Context) ([]domain.Tenant, error)
+}
+
+// SuperAdminRepository handles the accounts of the platform console
+type SuperAdminRepository interface {
+	Create(ctx context.Context, admin *domain.SuperAdmin) error
+	GetByID(ctx context.Context, id int64) (*domain.SuperAdmin, error)
+	GetByEmail(ctx context.Context, email string) (*domain.SuperAdmin, error)
+	Count(ctx context.Context) (int, error)
 }
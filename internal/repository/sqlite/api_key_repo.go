@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// APIKeyRepo implements repository.APIKeyRepository
+type APIKeyRepo struct {
+	db *DB
+}
+
+// NewAPIKeyRepo creates a new APIKeyRepo
+func NewAPIKeyRepo(db *DB) repository.APIKeyRepository {
+	return &APIKeyRepo{db: db}
+}
+
+const apiKeySelect = `
+	SELECT k.id, k.name, k.prefix, k.secret_hash, k.scopes, k.created_by, k.expires_at, k.last_used_at,
+		k.revoked_at, k.created_at, COALESCE(u.name, ''), COALESCE(u.email, '')
+	FROM api_keys k
+	LEFT JOIN users u ON u.id = k.created_by
+`
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var k domain.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	var creator domain.User
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.SecretHash, &scopes, &k.CreatedBy, &expiresAt, &lastUsedAt,
+		&revokedAt, &k.CreatedAt, &creator.Name, &creator.Email)
+	if err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	creator.ID = k.CreatedBy
+	k.Creator = &creator
+	return &k, nil
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (name, prefix, secret_hash, scopes, created_by, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, key.Name, key.Prefix, key.SecretHash, strings.Join(key.Scopes, ","), key.CreatedBy, key.ExpiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get API key ID: %w", err)
+	}
+	key.ID = id
+	key.CreatedAt = now
+	return nil
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id int64) (*domain.APIKey, error) {
+	return r.get(ctx, `k.id = ?`, id)
+}
+
+func (r *APIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	return r.get(ctx, `k.prefix = ?`, prefix)
+}
+
+func (r *APIKeyRepo) get(ctx context.Context, where string, arg interface{}) (*domain.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, apiKeySelect+` WHERE `+where, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepo) List(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, apiKeySelect+` ORDER BY k.created_at DESC, k.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// AttachmentRepo implements repository.AttachmentRepository
+type AttachmentRepo struct {
+	db *DB
+}
+
+// NewAttachmentRepo creates a new AttachmentRepo
+func NewAttachmentRepo(db *DB) repository.AttachmentRepository {
+	return &AttachmentRepo{db: db}
+}
+
+const attachmentSelect = `
+	SELECT a.id, a.ticket_id, a.kind, a.file_name, a.content_type, a.size, a.storage_key, a.thumb_key,
+		a.caption, a.public, COALESCE(a.uploaded_by, 0), COALESCE(u.name, ''), a.created_at
+	FROM attachments a
+	LEFT JOIN users u ON a.uploaded_by = u.id
+`
+
+func scanAttachment(row rowScanner) (*domain.Attachment, error) {
+	var a domain.Attachment
+	var uploaderName string
+	err := row.Scan(&a.ID, &a.TicketID, &a.Kind, &a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.ThumbKey,
+		&a.Caption, &a.Public, &a.UploadedBy, &uploaderName, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if a.UploadedBy != 0 {
+		a.Uploader = &domain.User{ID: a.UploadedBy, Name: uploaderName}
+	}
+	return &a, nil
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, attachment *domain.Attachment) error {
+	now := time.Now()
+	var uploadedBy interface{}
+	if attachment.UploadedBy != 0 {
+		uploadedBy = attachment.UploadedBy
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO attachments (ticket_id, kind, file_name, content_type, size, storage_key, thumb_key, caption,
+			public, uploaded_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, attachment.TicketID, attachment.Kind, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.StorageKey, attachment.ThumbKey, attachment.Caption, attachment.Public, uploadedBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get attachment ID: %w", err)
+	}
+	attachment.ID = id
+	attachment.CreatedAt = now
+	return nil
+}
+
+func (r *AttachmentRepo) GetByID(ctx context.Context, id int64) (*domain.Attachment, error) {
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, attachmentSelect+` WHERE a.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+func (r *AttachmentRepo) ListByTicket(ctx context.Context, ticketID int64) ([]domain.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, attachmentSelect+` WHERE a.ticket_id = ? ORDER BY a.created_at, a.id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []domain.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *AttachmentRepo) SetPublic(ctx context.Context, id int64, public bool) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE attachments SET public = ? WHERE id = ?`, public, id); err != nil {
+		return fmt.Errorf("failed to update attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
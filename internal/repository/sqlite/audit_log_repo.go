@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// AuditLogRepo implements repository.AuditLogRepository
+type AuditLogRepo struct {
+	db *DB
+}
+
+// NewAuditLogRepo creates a new AuditLogRepo
+func NewAuditLogRepo(db *DB) repository.AuditLogRepository {
+	return &AuditLogRepo{db: db}
+}
+
+func (r *AuditLogRepo) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	// Stored in UTC so the date filters can compare the text values
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, changes, ip, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullInt64(entry.ActorID), entry.Action, entry.EntityType, nullInt64(entry.EntityID),
+		entry.Changes, entry.IP, entry.RequestID, entry.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get audit entry ID: %w", err)
+	}
+	entry.ID = id
+	return nil
+}
+
+// auditWhere builds the WHERE clause for a filter
+func auditWhere(filter domain.AuditFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.ActorID > 0 {
+		conditions = append(conditions, "a.actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, ".") {
+			conditions = append(conditions, "a.action LIKE ?")
+			args = append(args, filter.Action+"%")
+		} else {
+			conditions = append(conditions, "a.action = ?")
+			args = append(args, filter.Action)
+		}
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "a.entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID > 0 {
+		conditions = append(conditions, "a.entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "a.created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "a.created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *AuditLogRepo) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	where, args := auditWhere(filter)
+	query := `
+		SELECT a.id, COALESCE(a.actor_id, 0), a.action, a.entity_type, COALESCE(a.entity_id, 0),
+			COALESCE(a.changes, ''), COALESCE(a.ip, ''), COALESCE(a.request_id, ''), a.created_at,
+			COALESCE(u.name, ''), COALESCE(u.email, '')
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.actor_id` + where + `
+		ORDER BY a.id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.AuditEntry
+	for rows.Next() {
+		var e domain.AuditEntry
+		var actorName, actorEmail string
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID,
+			&e.Changes, &e.IP, &e.RequestID, &e.CreatedAt, &actorName, &actorEmail); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.CreatedAt = e.CreatedAt.Local()
+		if e.ActorID > 0 {
+			e.Actor = &domain.User{ID: e.ActorID, Name: actorName, Email: actorEmail}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (r *AuditLogRepo) Count(ctx context.Context, filter domain.AuditFilter) (int, error) {
+	where, args := auditWhere(filter)
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log a`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit log: %w", err)
+	}
+	return count, nil
+}
+
+// nullInt64 stores zero IDs as NULL
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
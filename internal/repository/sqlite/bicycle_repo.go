@@ -22,8 +22,8 @@ func NewBicycleRepo(db *DB) repository.BicycleRepository {
 
 func (r *BicycleRepo) Create(ctx context.Context, bicycle *domain.Bicycle) error {
 	query := `
-		INSERT INTO bicycles (user_id, brand_id, model_id, color, serial_number, notes, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO bicycles (user_id, brand_id, model_id, color, serial_number, serial_normalized, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	now := time.Now()
 	var brandID, modelID interface{}
@@ -36,7 +36,10 @@ func (r *BicycleRepo) Create(ctx context.Context, bicycle *domain.Bicycle) error
 	}
 
 	result, err := r.db.ExecContext(ctx, query,
-		bicycle.UserID, brandID, modelID, bicycle.Color, bicycle.SerialNumber, bicycle.Notes, now)
+		bicycle.UserID, brandID, modelID, bicycle.Color, bicycle.SerialNumber, domain.NormalizeSerial(bicycle.SerialNumber), bicycle.Notes, now)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("failed to create bicycle: %w", repository.ErrDuplicateSerial)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create bicycle: %w", err)
 	}
@@ -143,10 +146,28 @@ func (r *BicycleRepo) GetByUserID(ctx context.Context, userID int64) ([]domain.B
 	return bicycles, nil
 }
 
+// GetBySerial finds a bicycle by its normalized serial number
+func (r *BicycleRepo) GetBySerial(ctx context.Context, serial string) (*domain.Bicycle, error) {
+	normalized := domain.NormalizeSerial(serial)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM bicycles WHERE serial_normalized = ?`, normalized).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bicycle by serial: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
 func (r *BicycleRepo) Update(ctx context.Context, bicycle *domain.Bicycle) error {
 	query := `
 		UPDATE bicycles 
-		SET brand_id = ?, model_id = ?, color = ?, serial_number = ?, notes = ?
+		SET brand_id = ?, model_id = ?, color = ?, serial_number = ?, serial_normalized = ?, notes = ?
 		WHERE id = ?
 	`
 	var brandID, modelID interface{}
@@ -158,7 +179,10 @@ func (r *BicycleRepo) Update(ctx context.Context, bicycle *domain.Bicycle) error
 	}
 
 	_, err := r.db.ExecContext(ctx, query, 
-		brandID, modelID, bicycle.Color, bicycle.SerialNumber, bicycle.Notes, bicycle.ID)
+		brandID, modelID, bicycle.Color, bicycle.SerialNumber, domain.NormalizeSerial(bicycle.SerialNumber), bicycle.Notes, bicycle.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("failed to update bicycle: %w", repository.ErrDuplicateSerial)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update bicycle: %w", err)
 	}
@@ -22,16 +22,19 @@ func NewBookingRepo(db *DB) repository.BookingRepository {
 
 func (r *BookingRepo) Create(ctx context.Context, booking *domain.Booking) error {
 	query := `
-		INSERT INTO bookings (customer_id, bicycle_id, service_id, scheduled_at, status, notes, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO bookings (customer_id, bicycle_id, service_id, branch_id, scheduled_at, status, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	var bicycleID interface{}
 	if booking.BicycleID != 0 {
 		bicycleID = booking.BicycleID
 	}
+	if booking.BranchID == 0 {
+		booking.BranchID = domain.DefaultBranchID
+	}
 
 	result, err := r.db.ExecContext(ctx, query,
-		booking.CustomerID, bicycleID, booking.ServiceID, booking.ScheduledAt, booking.Status, booking.Notes, time.Now())
+		booking.CustomerID, bicycleID, booking.ServiceID, booking.BranchID, booking.ScheduledAt, booking.Status, booking.Notes, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
@@ -46,7 +49,7 @@ func (r *BookingRepo) Create(ctx context.Context, booking *domain.Booking) error
 
 func (r *BookingRepo) GetByID(ctx context.Context, id int64) (*domain.Booking, error) {
 	query := `
-		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, b.scheduled_at, b.status, b.notes, b.created_at,
+		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
 			   u.id, u.email, u.name, u.phone, u.role,
 			   s.id, s.name, s.description, s.base_price, s.estimated_hours
 		FROM bookings b
@@ -65,7 +68,7 @@ func (r *BookingRepo) GetByID(ctx context.Context, id int64) (*domain.Booking, e
 	var servicePrice, serviceHours sql.NullFloat64
 	
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&booking.ID, &booking.CustomerID, &bicycleID, &booking.ServiceID, &booking.ScheduledAt, 
+		&booking.ID, &booking.CustomerID, &bicycleID, &booking.ServiceID, &booking.BranchID, &booking.ScheduledAt, 
 		&booking.Status, &booking.Notes, &booking.CreatedAt,
 		&booking.Customer.ID, &booking.Customer.Email, &booking.Customer.Name, 
 		&booking.Customer.Phone, &booking.Customer.Role,
@@ -95,7 +98,7 @@ func (r *BookingRepo) GetByID(ctx context.Context, id int64) (*domain.Booking, e
 
 func (r *BookingRepo) GetByCustomerID(ctx context.Context, customerID int64, limit, offset int) ([]domain.Booking, error) {
 	query := `
-		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, b.scheduled_at, b.status, b.notes, b.created_at,
+		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
 			   s.name
 		FROM bookings b
 		LEFT JOIN services s ON b.service_id = s.id
@@ -112,16 +115,35 @@ func (r *BookingRepo) GetByCustomerID(ctx context.Context, customerID int64, lim
 	return r.scanBookings(rows)
 }
 
-func (r *BookingRepo) GetByDateRange(ctx context.Context, start, end time.Time) ([]domain.Booking, error) {
+// GetByBicycleID returns the service history of a bicycle across all owners
+func (r *BookingRepo) GetByBicycleID(ctx context.Context, bicycleID int64) ([]domain.Booking, error) {
+	query := `
+		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
+			   s.name
+		FROM bookings b
+		LEFT JOIN services s ON b.service_id = s.id
+		WHERE b.bicycle_id = ?
+		ORDER BY b.scheduled_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, bicycleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookings by bicycle: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanBookings(rows)
+}
+
+func (r *BookingRepo) GetByDateRange(ctx context.Context, branchID int64, start, end time.Time) ([]domain.Booking, error) {
 	query := `
-		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, b.scheduled_at, b.status, b.notes, b.created_at,
+		SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
 			   s.name
 		FROM bookings b
 		LEFT JOIN services s ON b.service_id = s.id
-		WHERE b.scheduled_at BETWEEN ? AND ?
+		WHERE b.scheduled_at BETWEEN ? AND ? AND (? = 0 OR COALESCE(b.branch_id, 1) = ?)
 		ORDER BY b.scheduled_at
 	`
-	rows, err := r.db.QueryContext(ctx, query, start, end)
+	rows, err := r.db.QueryContext(ctx, query, start, end, branchID, branchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bookings by date range: %w", err)
 	}
@@ -167,31 +189,32 @@ func (r *BookingRepo) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *BookingRepo) List(ctx context.Context, status string, limit, offset int) ([]domain.Booking, error) {
+func (r *BookingRepo) List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Booking, error) {
 	var query string
 	var args []interface{}
 
 	if status != "" {
 		query = `
-			SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, b.scheduled_at, b.status, b.notes, b.created_at,
+			SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
 				   s.name
 			FROM bookings b
 			LEFT JOIN services s ON b.service_id = s.id
-			WHERE b.status = ?
+			WHERE b.status = ? AND (? = 0 OR COALESCE(b.branch_id, 1) = ?)
 			ORDER BY b.scheduled_at DESC
 			LIMIT ? OFFSET ?
 		`
-		args = []interface{}{status, limit, offset}
+		args = []interface{}{status, branchID, branchID, limit, offset}
 	} else {
 		query = `
-			SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, b.scheduled_at, b.status, b.notes, b.created_at,
+			SELECT b.id, b.customer_id, b.bicycle_id, b.service_id, COALESCE(b.branch_id, 1), b.scheduled_at, b.status, b.notes, b.created_at,
 				   s.name
 			FROM bookings b
 			LEFT JOIN services s ON b.service_id = s.id
+			WHERE ? = 0 OR COALESCE(b.branch_id, 1) = ?
 			ORDER BY b.scheduled_at DESC
 			LIMIT ? OFFSET ?
 		`
-		args = []interface{}{limit, offset}
+		args = []interface{}{branchID, branchID, limit, offset}
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -203,15 +226,16 @@ func (r *BookingRepo) List(ctx context.Context, status string, limit, offset int
 	return r.scanBookings(rows)
 }
 
-func (r *BookingRepo) CountByStatus(ctx context.Context, status string) (int, error) {
+func (r *BookingRepo) CountByStatus(ctx context.Context, branchID int64, status string) (int, error) {
 	var query string
 	var args []interface{}
 
 	if status != "" {
-		query = `SELECT COUNT(*) FROM bookings WHERE status = ?`
-		args = []interface{}{status}
+		query = `SELECT COUNT(*) FROM bookings WHERE status = ? AND (? = 0 OR COALESCE(branch_id, 1) = ?)`
+		args = []interface{}{status, branchID, branchID}
 	} else {
-		query = `SELECT COUNT(*) FROM bookings`
+		query = `SELECT COUNT(*) FROM bookings WHERE ? = 0 OR COALESCE(branch_id, 1) = ?`
+		args = []interface{}{branchID, branchID}
 	}
 
 	var count int
@@ -229,7 +253,7 @@ func (r *BookingRepo) scanBookings(rows *sql.Rows) ([]domain.Booking, error) {
 		var bicycleID sql.NullInt64
 		var serviceName sql.NullString
 		if err := rows.Scan(
-			&b.ID, &b.CustomerID, &bicycleID, &b.ServiceID, &b.ScheduledAt, 
+			&b.ID, &b.CustomerID, &bicycleID, &b.ServiceID, &b.BranchID, &b.ScheduledAt, 
 			&b.Status, &b.Notes, &b.CreatedAt, &serviceName,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan booking: %w", err)
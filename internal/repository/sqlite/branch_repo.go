@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// BranchRepo implements repository.BranchRepository
+type BranchRepo struct {
+	db *DB
+}
+
+// NewBranchRepo creates a new BranchRepo
+func NewBranchRepo(db *DB) repository.BranchRepository {
+	return &BranchRepo{db: db}
+}
+
+const branchSelect = `SELECT id, name, address, phone, open_days, slots, COALESCE(stock_location_id, 0), active, created_at FROM branches`
+
+func (r *BranchRepo) Create(ctx context.Context, branch *domain.Branch) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO branches (name, address, phone, open_days, slots, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, branch.Name, branch.Address, branch.Phone, branch.OpenDays, branch.Slots, branch.Active, now)
+	if err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get branch ID: %w", err)
+	}
+
+	// Every branch gets a stock location of its own for ticket parts
+	if branch.StockLocationID == 0 {
+		result, err := tx.ExecContext(ctx, `INSERT INTO stock_locations (name, branch_id, created_at) VALUES (?, ?, ?)`,
+			"Taller "+branch.Name, id, now)
+		if err != nil {
+			return fmt.Errorf("failed to create branch stock location: %w", err)
+		}
+		if branch.StockLocationID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get stock location ID: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE branches SET stock_location_id = ? WHERE id = ?`, branch.StockLocationID, id); err != nil {
+		return fmt.Errorf("failed to set branch stock location: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit branch: %w", err)
+	}
+	branch.ID = id
+	branch.CreatedAt = now
+	return nil
+}
+
+func (r *BranchRepo) GetByID(ctx context.Context, id int64) (*domain.Branch, error) {
+	branch, err := scanBranch(r.db.QueryRowContext(ctx, branchSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+	return branch, nil
+}
+
+func (r *BranchRepo) Update(ctx context.Context, branch *domain.Branch) error {
+	query := `
+		UPDATE branches SET name = ?, address = ?, phone = ?, open_days = ?, slots = ?, stock_location_id = ?, active = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		branch.Name, branch.Address, branch.Phone, branch.OpenDays, branch.Slots, branch.StockLocationID, branch.Active, branch.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update branch: %w", err)
+	}
+	return nil
+}
+
+func (r *BranchRepo) List(ctx context.Context, includeInactive bool) ([]domain.Branch, error) {
+	query := branchSelect
+	if !includeInactive {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer rows.Close()
+
+	var branches []domain.Branch
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan branch: %w", err)
+		}
+		branches = append(branches, *branch)
+	}
+	return branches, rows.Err()
+}
+
+func scanBranch(row rowScanner) (*domain.Branch, error) {
+	b := &domain.Branch{}
+	var address, phone sql.NullString
+	if err := row.Scan(&b.ID, &b.Name, &address, &phone, &b.OpenDays, &b.Slots, &b.StockLocationID, &b.Active, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	b.Address = address.String
+	b.Phone = phone.String
+	return b, nil
+}
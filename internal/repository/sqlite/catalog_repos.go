@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
@@ -196,9 +197,10 @@ func NewServiceRepo(db *DB) repository.ServiceRepository {
 }
 
 func (r *ServiceRepo) Create(ctx context.Context, service *domain.Service) error {
-	query := `INSERT INTO services (name, description, base_price, estimated_hours) VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO services (name, description, base_price, estimated_hours, required_skills, checklist_id) VALUES (?, ?, ?, ?, ?, ?)`
 	result, err := r.db.ExecContext(ctx, query,
-		service.Name, service.Description, service.BasePrice, service.EstimatedHours)
+		service.Name, service.Description, service.BasePrice, service.EstimatedHours,
+		strings.Join(service.RequiredSkills, ","), nullInt64(service.ChecklistID))
 	if err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
 	}
@@ -208,23 +210,27 @@ func (r *ServiceRepo) Create(ctx context.Context, service *domain.Service) error
 }
 
 func (r *ServiceRepo) GetByID(ctx context.Context, id int64) (*domain.Service, error) {
-	query := `SELECT id, name, description, base_price, estimated_hours FROM services WHERE id = ?`
+	query := `SELECT id, name, description, base_price, estimated_hours, COALESCE(required_skills, ''), COALESCE(checklist_id, 0) FROM services WHERE id = ?`
 	service := &domain.Service{}
+	var skills string
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&service.ID, &service.Name, &service.Description, &service.BasePrice, &service.EstimatedHours)
+		&service.ID, &service.Name, &service.Description, &service.BasePrice, &service.EstimatedHours, &skills,
+		&service.ChecklistID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service: %w", err)
 	}
+	service.RequiredSkills = domain.ParseSkills(skills)
 	return service, nil
 }
 
 func (r *ServiceRepo) Update(ctx context.Context, service *domain.Service) error {
-	query := `UPDATE services SET name = ?, description = ?, base_price = ?, estimated_hours = ? WHERE id = ?`
+	query := `UPDATE services SET name = ?, description = ?, base_price = ?, estimated_hours = ?, required_skills = ?, checklist_id = ? WHERE id = ?`
 	_, err := r.db.ExecContext(ctx, query,
-		service.Name, service.Description, service.BasePrice, service.EstimatedHours, service.ID)
+		service.Name, service.Description, service.BasePrice, service.EstimatedHours,
+		strings.Join(service.RequiredSkills, ","), nullInt64(service.ChecklistID), service.ID)
 	return err
 }
 
@@ -235,7 +241,7 @@ func (r *ServiceRepo) Delete(ctx context.Context, id int64) error {
 }
 
 func (r *ServiceRepo) List(ctx context.Context) ([]domain.Service, error) {
-	query := `SELECT id, name, description, base_price, estimated_hours FROM services ORDER BY name`
+	query := `SELECT id, name, description, base_price, estimated_hours, COALESCE(required_skills, ''), COALESCE(checklist_id, 0) FROM services ORDER BY name`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list services: %w", err)
@@ -245,9 +251,11 @@ func (r *ServiceRepo) List(ctx context.Context) ([]domain.Service, error) {
 	var services []domain.Service
 	for rows.Next() {
 		var s domain.Service
-		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.BasePrice, &s.EstimatedHours); err != nil {
+		var skills string
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.BasePrice, &s.EstimatedHours, &skills, &s.ChecklistID); err != nil {
 			return nil, err
 		}
+		s.RequiredSkills = domain.ParseSkills(skills)
 		services = append(services, s)
 	}
 	return services, nil
@@ -363,22 +371,24 @@ func (r *QuoteRepo) Reject(ctx context.Context, id int64, reason string) error {
 	return err
 }
 
-func (r *QuoteRepo) List(ctx context.Context, status string, limit, offset int) ([]domain.Quote, error) {
+func (r *QuoteRepo) List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Quote, error) {
 	var query string
 	var args []interface{}
 
+	// Quotes belong to the branch of their booking
+	branchFilter := `(? = 0 OR booking_id IN (SELECT id FROM bookings WHERE COALESCE(branch_id, 1) = ?))`
 	if status != "" {
 		query = `
 			SELECT id, booking_id, items_json, total, status, rejection_reason, valid_until, created_at
-			FROM quotes WHERE status = ? ORDER BY created_at DESC LIMIT ? OFFSET ?
+			FROM quotes WHERE status = ? AND ` + branchFilter + ` ORDER BY created_at DESC LIMIT ? OFFSET ?
 		`
-		args = []interface{}{status, limit, offset}
+		args = []interface{}{status, branchID, branchID, limit, offset}
 	} else {
 		query = `
 			SELECT id, booking_id, items_json, total, status, rejection_reason, valid_until, created_at
-			FROM quotes ORDER BY created_at DESC LIMIT ? OFFSET ?
+			FROM quotes WHERE ` + branchFilter + ` ORDER BY created_at DESC LIMIT ? OFFSET ?
 		`
-		args = []interface{}{limit, offset}
+		args = []interface{}{branchID, branchID, limit, offset}
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
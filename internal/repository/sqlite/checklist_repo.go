@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// ChecklistRepo implements repository.ChecklistRepository
+type ChecklistRepo struct {
+	db *DB
+}
+
+// NewChecklistRepo creates a new ChecklistRepo
+func NewChecklistRepo(db *DB) repository.ChecklistRepository {
+	return &ChecklistRepo{db: db}
+}
+
+const checklistItemSelect = `SELECT id, checklist_id, position, label, type, unit, required FROM checklist_items`
+
+func scanChecklistItem(row rowScanner) (*domain.ChecklistItem, error) {
+	var item domain.ChecklistItem
+	err := row.Scan(&item.ID, &item.ChecklistID, &item.Position, &item.Label, &item.Type, &item.Unit, &item.Required)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Create stores a checklist together with its items, numbered in order
+func (r *ChecklistRepo) Create(ctx context.Context, checklist *domain.Checklist) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `INSERT INTO checklists (name, created_at) VALUES (?, ?)`, checklist.Name, now)
+	if err != nil {
+		return fmt.Errorf("failed to create checklist: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get checklist ID: %w", err)
+	}
+	for i := range checklist.Items {
+		item := &checklist.Items[i]
+		item.ChecklistID, item.Position = id, i+1
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO checklist_items (checklist_id, position, label, type, unit, required)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, id, item.Position, item.Label, item.Type, item.Unit, item.Required)
+		if err != nil {
+			return fmt.Errorf("failed to create checklist item: %w", err)
+		}
+		if item.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get checklist item ID: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit checklist: %w", err)
+	}
+	checklist.ID = id
+	checklist.CreatedAt = now
+	return nil
+}
+
+func (r *ChecklistRepo) GetByID(ctx context.Context, id int64) (*domain.Checklist, error) {
+	checklist := &domain.Checklist{}
+	err := r.db.QueryRowContext(ctx, `SELECT id, name, created_at FROM checklists WHERE id = ?`, id).
+		Scan(&checklist.ID, &checklist.Name, &checklist.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checklist: %w", err)
+	}
+
+	items, err := r.listItems(ctx, ` WHERE checklist_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	checklist.Items = items
+	return checklist, nil
+}
+
+func (r *ChecklistRepo) List(ctx context.Context) ([]domain.Checklist, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, created_at FROM checklists ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checklists: %w", err)
+	}
+	var checklists []domain.Checklist
+	for rows.Next() {
+		var c domain.Checklist
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan checklist: %w", err)
+		}
+		checklists = append(checklists, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Items are read once the checklists are, as the connection is shared
+	items, err := r.listItems(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range checklists {
+		for _, item := range items {
+			if item.ChecklistID == checklists[i].ID {
+				checklists[i].Items = append(checklists[i].Items, item)
+			}
+		}
+	}
+	return checklists, nil
+}
+
+// listItems returns the template items matching where, in order
+func (r *ChecklistRepo) listItems(ctx context.Context, where string, args ...interface{}) ([]domain.ChecklistItem, error) {
+	rows, err := r.db.QueryContext(ctx, checklistItemSelect+where+` ORDER BY checklist_id, position, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checklist items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.ChecklistItem
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan checklist item: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ChecklistRepo) Rename(ctx context.Context, id int64, name string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE checklists SET name = ? WHERE id = ?`, name, id); err != nil {
+		return fmt.Errorf("failed to rename checklist: %w", err)
+	}
+	return nil
+}
+
+func (r *ChecklistRepo) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`UPDATE services SET checklist_id = NULL WHERE checklist_id = ?`,
+		`DELETE FROM checklist_items WHERE checklist_id = ?`,
+		`DELETE FROM checklists WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete checklist: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *ChecklistRepo) AddItem(ctx context.Context, item *domain.ChecklistItem) error {
+	var position int
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE checklist_id = ?`,
+		item.ChecklistID).Scan(&position)
+	if err != nil {
+		return fmt.Errorf("failed to number checklist item: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO checklist_items (checklist_id, position, label, type, unit, required)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, item.ChecklistID, position, item.Label, item.Type, item.Unit, item.Required)
+	if err != nil {
+		return fmt.Errorf("failed to create checklist item: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get checklist item ID: %w", err)
+	}
+	item.ID = id
+	item.Position = position
+	return nil
+}
+
+func (r *ChecklistRepo) DeleteItem(ctx context.Context, checklistID, itemID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM checklist_items WHERE id = ? AND checklist_id = ?`, itemID, checklistID)
+	if err != nil {
+		return fmt.Errorf("failed to delete checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *ChecklistRepo) ApplyToTicket(ctx context.Context, checklistID, ticketID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO ticket_checklist_items (ticket_id, position, label, type, unit, required)
+		SELECT ?, position, label, type, unit, required
+		FROM checklist_items
+		WHERE checklist_id = ?
+		ORDER BY position, id
+	`, ticketID, checklistID)
+	if err != nil {
+		return fmt.Errorf("failed to apply checklist: %w", err)
+	}
+	return nil
+}
+
+const ticketChecklistSelect = `
+	SELECT i.id, i.ticket_id, i.position, i.label, i.type, i.unit, i.required, i.value,
+		COALESCE(i.attachment_id, 0), COALESCE(i.completed_by, 0), COALESCE(u.name, ''), i.completed_at
+	FROM ticket_checklist_items i
+	LEFT JOIN users u ON i.completed_by = u.id
+`
+
+func scanTicketChecklistItem(row rowScanner) (*domain.TicketChecklistItem, error) {
+	var item domain.TicketChecklistItem
+	var completedBy int64
+	var completedByName string
+	var completedAt sql.NullTime
+	err := row.Scan(&item.ID, &item.TicketID, &item.Position, &item.Label, &item.Type, &item.Unit, &item.Required,
+		&item.Value, &item.AttachmentID, &completedBy, &completedByName, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		item.CompletedAt = &completedAt.Time
+	}
+	if completedBy != 0 {
+		item.CompletedBy = &domain.User{ID: completedBy, Name: completedByName}
+	}
+	return &item, nil
+}
+
+func (r *ChecklistRepo) ListByTicket(ctx context.Context, ticketID int64) ([]domain.TicketChecklistItem, error) {
+	rows, err := r.db.QueryContext(ctx, ticketChecklistSelect+` WHERE i.ticket_id = ? ORDER BY i.position, i.id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ticket checklist: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.TicketChecklistItem
+	for rows.Next() {
+		item, err := scanTicketChecklistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ticket checklist item: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ChecklistRepo) GetTicketItem(ctx context.Context, id int64) (*domain.TicketChecklistItem, error) {
+	item, err := scanTicketChecklistItem(r.db.QueryRowContext(ctx, ticketChecklistSelect+` WHERE i.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket checklist item: %w", err)
+	}
+	return item, nil
+}
+
+func (r *ChecklistRepo) CompleteTicketItem(ctx context.Context, id int64, value string, attachmentID, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ticket_checklist_items SET value = ?, attachment_id = ?, completed_by = ?, completed_at = ?
+		WHERE id = ?
+	`, value, nullInt64(attachmentID), nullInt64(userID), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to complete checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *ChecklistRepo) ReopenTicketItem(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ticket_checklist_items SET value = '', attachment_id = NULL, completed_by = NULL, completed_at = NULL
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to reopen checklist item: %w", err)
+	}
+	return nil
+}
+
+func (r *ChecklistRepo) ReopenByAttachment(ctx context.Context, attachmentID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ticket_checklist_items SET attachment_id = NULL, completed_by = NULL, completed_at = NULL
+		WHERE attachment_id = ?
+	`, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to reopen checklist items: %w", err)
+	}
+	return nil
+}
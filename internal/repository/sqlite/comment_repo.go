@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// CommentRepo implements repository.CommentRepository
+type CommentRepo struct {
+	db *DB
+}
+
+// NewCommentRepo creates a new CommentRepo
+func NewCommentRepo(db *DB) repository.CommentRepository {
+	return &CommentRepo{db: db}
+}
+
+func (r *CommentRepo) Create(ctx context.Context, comment *domain.TicketComment) error {
+	now := time.Now()
+	var authorID interface{}
+	if comment.AuthorID != 0 {
+		authorID = comment.AuthorID
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO ticket_comments (ticket_id, author_id, visibility, from_customer, body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, comment.TicketID, authorID, comment.Visibility, comment.FromCustomer, comment.Body, now)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get comment ID: %w", err)
+	}
+	comment.ID = id
+	comment.CreatedAt = now
+	return nil
+}
+
+func (r *CommentRepo) ListByTicket(ctx context.Context, ticketID int64) ([]domain.TicketComment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.ticket_id, COALESCE(c.author_id, 0), COALESCE(u.name, ''), c.visibility, c.from_customer,
+			c.body, c.created_at
+		FROM ticket_comments c
+		LEFT JOIN users u ON c.author_id = u.id
+		WHERE c.ticket_id = ?
+		ORDER BY c.created_at, c.id
+	`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []domain.TicketComment
+	for rows.Next() {
+		var c domain.TicketComment
+		var authorName string
+		if err := rows.Scan(&c.ID, &c.TicketID, &c.AuthorID, &authorName, &c.Visibility, &c.FromCustomer,
+			&c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		if c.AuthorID != 0 {
+			c.Author = &domain.User{ID: c.AuthorID, Name: authorName}
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
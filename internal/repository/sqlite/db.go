@@ -4,10 +4,13 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"bicicletapp/internal/domain"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -199,6 +202,376 @@ func (db *DB) Migrate() error {
 			value TEXT,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+
+		// Normalized serial numbers (unique when present)
+		`ALTER TABLE bicycles ADD COLUMN serial_normalized TEXT`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_bicycles_serial ON bicycles(serial_normalized) WHERE serial_normalized <> ''`,
+
+		// Stolen-bike registry
+		`CREATE TABLE IF NOT EXISTS stolen_bikes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			serial_number TEXT NOT NULL,
+			serial_normalized TEXT NOT NULL,
+			brand TEXT,
+			model TEXT,
+			color TEXT,
+			bicycle_id INTEGER REFERENCES bicycles(id) ON DELETE SET NULL,
+			source TEXT DEFAULT 'manual',
+			notes TEXT,
+			reported_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			recovered_at DATETIME,
+			last_seen_at DATETIME,
+			last_seen_ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_stolen_bikes_serial ON stolen_bikes(serial_normalized)`,
+
+		// Bicycle ownership transfers
+		`CREATE TABLE IF NOT EXISTS bicycle_transfers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bicycle_id INTEGER NOT NULL REFERENCES bicycles(id) ON DELETE CASCADE,
+			from_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			to_email TEXT NOT NULL,
+			to_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			status TEXT DEFAULT 'pending',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			responded_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_bicycle_transfers_bicycle ON bicycle_transfers(bicycle_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bicycle_transfers_email ON bicycle_transfers(to_email, status)`,
+		`CREATE TABLE IF NOT EXISTS bicycle_ownership_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			bicycle_id INTEGER NOT NULL REFERENCES bicycles(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			transfer_id INTEGER REFERENCES bicycle_transfers(id) ON DELETE SET NULL,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_bicycle_ownership_bicycle ON bicycle_ownership_history(bicycle_id)`,
+
+		// Parts inventory
+		`CREATE TABLE IF NOT EXISTS inventory_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sku TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			brand TEXT,
+			categories TEXT,
+			cost REAL DEFAULT 0,
+			sale_price REAL DEFAULT 0,
+			min_stock INTEGER DEFAULT 0,
+			active BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS stock_locations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT OR IGNORE INTO stock_locations (id, name) VALUES (1, 'Taller')`,
+		`CREATE TABLE IF NOT EXISTS stock_levels (
+			item_id INTEGER NOT NULL REFERENCES inventory_items(id) ON DELETE CASCADE,
+			location_id INTEGER NOT NULL REFERENCES stock_locations(id) ON DELETE CASCADE,
+			quantity INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (item_id, location_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS stock_movements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id INTEGER NOT NULL REFERENCES inventory_items(id) ON DELETE CASCADE,
+			location_id INTEGER NOT NULL REFERENCES stock_locations(id),
+			quantity INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL,
+			ticket_part_id INTEGER REFERENCES ticket_parts(id) ON DELETE SET NULL,
+			user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			notes TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_stock_movements_item ON stock_movements(item_id)`,
+		`ALTER TABLE ticket_parts ADD COLUMN inventory_item_id INTEGER REFERENCES inventory_items(id) ON DELETE SET NULL`,
+		`ALTER TABLE ticket_parts ADD COLUMN quantity INTEGER DEFAULT 1`,
+		`ALTER TABLE ticket_parts ADD COLUMN location_id INTEGER REFERENCES stock_locations(id)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_parts_item ON ticket_parts(inventory_item_id)`,
+		`CREATE TABLE IF NOT EXISTS suppliers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			contact_name TEXT,
+			email TEXT,
+			phone TEXT,
+			notes TEXT,
+			active BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS purchase_orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			supplier_id INTEGER NOT NULL REFERENCES suppliers(id),
+			status TEXT NOT NULL DEFAULT 'draft',
+			notes TEXT,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			sent_at DATETIME,
+			received_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_purchase_orders_status ON purchase_orders(status)`,
+		`CREATE TABLE IF NOT EXISTS purchase_order_lines (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+			item_id INTEGER NOT NULL REFERENCES inventory_items(id),
+			quantity INTEGER NOT NULL,
+			received INTEGER NOT NULL DEFAULT 0,
+			unit_cost REAL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_purchase_order_lines_order ON purchase_order_lines(order_id)`,
+		`CREATE TABLE IF NOT EXISTS purchase_order_line_tickets (
+			line_id INTEGER NOT NULL REFERENCES purchase_order_lines(id) ON DELETE CASCADE,
+			ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+			PRIMARY KEY (line_id, ticket_id)
+		)`,
+		`ALTER TABLE inventory_items ADD COLUMN ean TEXT DEFAULT ''`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_items_ean ON inventory_items(ean) WHERE ean <> ''`,
+		// Accounts created before email verification existed count as verified
+		`ALTER TABLE users ADD COLUMN email_verified BOOLEAN DEFAULT 1`,
+		`CREATE TABLE IF NOT EXISTS user_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose)`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			refresh_hash TEXT NOT NULL UNIQUE,
+			previous_refresh_hash TEXT,
+			rotated_at DATETIME,
+			user_agent TEXT,
+			ip TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME,
+			expires_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_previous_refresh ON sessions(previous_refresh_hash)`,
+		`ALTER TABLE users ADD COLUMN totp_secret TEXT DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN totp_last_step INTEGER DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS user_recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash TEXT NOT NULL,
+			used_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes(user_id)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor_id INTEGER,
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id INTEGER,
+			changes TEXT,
+			ip TEXT,
+			request_id TEXT,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at)`,
+		// The audit log is append-only
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log is append-only');
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log is append-only');
+		END`,
+		`CREATE TABLE IF NOT EXISTS roles (
+			name TEXT PRIMARY KEY,
+			label TEXT NOT NULL,
+			system BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS role_permissions (
+			role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+			permission TEXT NOT NULL,
+			PRIMARY KEY (role, permission)
+		)`,
+		// Default permissions are seeded only the first time a system role is
+		// created, so later edits in the admin survive restarts. Admin holds
+		// every permission implicitly.
+		`INSERT OR IGNORE INTO role_permissions (role, permission)
+		SELECT 'customer', column1 FROM (VALUES ('bookings.view'))
+		WHERE NOT EXISTS (SELECT 1 FROM roles WHERE name = 'customer')`,
+		`INSERT OR IGNORE INTO role_permissions (role, permission)
+		SELECT 'technician', column1 FROM (VALUES
+			('workshop.view'), ('tickets.create'), ('tickets.edit'), ('payments.take'),
+			('quotes.create'), ('bicycles.edit'), ('bookings.view'), ('bookings.view_any'))
+		WHERE NOT EXISTS (SELECT 1 FROM roles WHERE name = 'technician')`,
+		`INSERT OR IGNORE INTO roles (name, label, system) VALUES
+			('customer', 'Cliente', 1), ('technician', 'Técnico', 1), ('admin', 'Administrador', 1)`,
+
+		// Branches. Existing bookings, tickets and stock belong to the first
+		// one; staff with no branch work at all of them.
+		`CREATE TABLE IF NOT EXISTS branches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			address TEXT DEFAULT '',
+			phone TEXT DEFAULT '',
+			open_days TEXT NOT NULL,
+			slots TEXT NOT NULL,
+			stock_location_id INTEGER REFERENCES stock_locations(id),
+			active BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT OR IGNORE INTO branches (id, name, open_days, slots, stock_location_id)
+		VALUES (1, 'Casa Matriz', '1,2,3,4,5,6', '09:00,10:00,11:00,12:00,14:00,15:00,16:00,17:00', 1)`,
+		`ALTER TABLE bookings ADD COLUMN branch_id INTEGER DEFAULT 1 REFERENCES branches(id)`,
+		`ALTER TABLE tickets ADD COLUMN branch_id INTEGER DEFAULT 1 REFERENCES branches(id)`,
+		`ALTER TABLE stock_locations ADD COLUMN branch_id INTEGER DEFAULT 1 REFERENCES branches(id)`,
+		`ALTER TABLE users ADD COLUMN branch_id INTEGER DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_branch ON bookings(branch_id, scheduled_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_tickets_branch ON tickets(branch_id, status)`,
+
+		// API keys for integrations; only a hash of the secret is kept
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL UNIQUE,
+			secret_hash TEXT NOT NULL,
+			scopes TEXT NOT NULL DEFAULT '',
+			created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			revoked_at DATETIME,
+			created_at DATETIME NOT NULL
+		)`,
+
+		// Outbound webhooks and their delivery log
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '',
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event_id TEXT NOT NULL,
+			event TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER NOT NULL DEFAULT 0,
+			response_body TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			next_attempt_at DATETIME,
+			delivered_at DATETIME,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)`,
+
+		// Promised-ready date given at intake, for turnaround SLAs
+		`ALTER TABLE tickets ADD COLUMN promised_at DATETIME`,
+
+		// Technician time tracking; a running timer has no ended_at
+		`CREATE TABLE IF NOT EXISTS time_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticket_id INTEGER NOT NULL REFERENCES tickets(id),
+			technician_id INTEGER NOT NULL REFERENCES users(id),
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME,
+			end_reason TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			quote_id INTEGER REFERENCES quotes(id),
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_time_entries_ticket ON time_entries(ticket_id, started_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(technician_id, ended_at)`,
+
+		// Technician skills and capacity, and the skills a service needs, for
+		// automatic assignment
+		`ALTER TABLE users ADD COLUMN skills TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN daily_hours REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE services ADD COLUMN required_skills TEXT NOT NULL DEFAULT ''`,
+
+		// Ticket photos and documents; the files live in the upload storage
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticket_id INTEGER NOT NULL REFERENCES tickets(id),
+			kind TEXT NOT NULL,
+			file_name TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			storage_key TEXT NOT NULL,
+			thumb_key TEXT NOT NULL DEFAULT '',
+			caption TEXT NOT NULL DEFAULT '',
+			public INTEGER NOT NULL DEFAULT 1,
+			uploaded_by INTEGER REFERENCES users(id),
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachments_ticket ON attachments(ticket_id, created_at)`,
+
+		// Ticket conversations. The single notes field each ticket had is
+		// moved into an internal comment, once: a note already copied is not
+		// copied again if the run stopped before clearing it.
+		`CREATE TABLE IF NOT EXISTS ticket_comments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticket_id INTEGER NOT NULL REFERENCES tickets(id),
+			author_id INTEGER REFERENCES users(id),
+			visibility TEXT NOT NULL,
+			from_customer BOOLEAN NOT NULL DEFAULT 0,
+			body TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_comments_ticket ON ticket_comments(ticket_id, created_at)`,
+		`INSERT INTO ticket_comments (ticket_id, visibility, body, created_at)
+		SELECT id, 'internal', notes, COALESCE(updated_at, created_at) FROM tickets
+		WHERE COALESCE(notes, '') != ''
+			AND NOT EXISTS (SELECT 1 FROM ticket_comments c
+				WHERE c.ticket_id = tickets.id AND c.author_id IS NULL AND c.body = tickets.notes)`,
+		`UPDATE tickets SET notes = NULL WHERE COALESCE(notes, '') != ''`,
+
+		// Inspection checklists: templates attached to services, and the copy
+		// of the items each ticket works through
+		`CREATE TABLE IF NOT EXISTS checklists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS checklist_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			checklist_id INTEGER NOT NULL REFERENCES checklists(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			label TEXT NOT NULL,
+			type TEXT NOT NULL,
+			unit TEXT NOT NULL DEFAULT '',
+			required BOOLEAN NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_checklist_items_checklist ON checklist_items(checklist_id, position)`,
+		`ALTER TABLE services ADD COLUMN checklist_id INTEGER REFERENCES checklists(id)`,
+		`CREATE TABLE IF NOT EXISTS ticket_checklist_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ticket_id INTEGER NOT NULL REFERENCES tickets(id),
+			position INTEGER NOT NULL,
+			label TEXT NOT NULL,
+			type TEXT NOT NULL,
+			unit TEXT NOT NULL DEFAULT '',
+			required BOOLEAN NOT NULL DEFAULT 0,
+			value TEXT NOT NULL DEFAULT '',
+			attachment_id INTEGER REFERENCES attachments(id),
+			completed_by INTEGER REFERENCES users(id),
+			completed_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_checklist_items_ticket ON ticket_checklist_items(ticket_id, position)`,
 	}
 
 	for _, migration := range migrations {
@@ -211,9 +584,122 @@ func (db *DB) Migrate() error {
 		}
 	}
 
+	return db.backfillSerials()
+}
+
+// backfillSerials fills in the normalized serial of every bicycle with
+// domain.NormalizeSerial, the same rule the repository applies on writes.
+// When legacy serials collide the oldest bicycle keeps it; the others are
+// left without a normalized serial and logged, so that staff can correct
+// them before saving those bicycles again.
+func (db *DB) backfillSerials() error {
+	rows, err := db.Query(`SELECT id, COALESCE(serial_number, ''), serial_normalized FROM bicycles ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to read bicycle serials: %w", err)
+	}
+	type change struct {
+		id         int64
+		normalized string
+	}
+	var changes []change
+	owner := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var serial string
+		var current sql.NullString
+		if err := rows.Scan(&id, &serial, &current); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read bicycle serials: %w", err)
+		}
+		normalized := domain.NormalizeSerial(serial)
+		if first, taken := owner[normalized]; taken && normalized != "" {
+			log.Printf("⚠️ Bicycle %d has the same serial as bicycle %d (%s); correct it before editing the bicycle", id, first, normalized)
+			normalized = ""
+		} else if normalized != "" {
+			owner[normalized] = id
+		}
+		if !current.Valid || current.String != normalized {
+			changes = append(changes, change{id, normalized})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read bicycle serials: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Clear the changed rows first so two bicycles can swap serials without
+	// tripping the unique index
+	for _, c := range changes {
+		if _, err := tx.Exec(`UPDATE bicycles SET serial_normalized = NULL WHERE id = ?`, c.id); err != nil {
+			return fmt.Errorf("failed to backfill bicycle serials: %w", err)
+		}
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(`UPDATE bicycles SET serial_normalized = ? WHERE id = ?`, c.normalized, c.id); err != nil {
+			return fmt.Errorf("failed to backfill bicycle serials: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// MigratePlatform creates the tenant registry used when several workshops
+// share a deployment. It runs on the platform database, which holds no
+// workshop data; each tenant's database runs Migrate.
+func (db *DB) MigratePlatform() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS tenants (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT UNIQUE NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			name TEXT NOT NULL,
+			tagline TEXT DEFAULT '',
+			logo TEXT DEFAULT '',
+			primary_color TEXT DEFAULT '',
+			secondary_color TEXT DEFAULT '',
+			accent_color TEXT DEFAULT '',
+			contact_email TEXT DEFAULT '',
+			contact_phone TEXT DEFAULT '',
+			payments BOOLEAN DEFAULT 0,
+			sms BOOLEAN DEFAULT 0,
+			surveys BOOLEAN DEFAULT 1,
+			email_notifications BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS tenant_hosts (
+			host TEXT PRIMARY KEY,
+			tenant_id INTEGER NOT NULL REFERENCES tenants(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_tenant_hosts_tenant ON tenant_hosts(tenant_id)`,
+		`CREATE TABLE IF NOT EXISTS super_admins (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			email TEXT UNIQUE NOT NULL,
+			password_hash TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}
+
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			return fmt.Errorf("platform migration failed: %w\nSQL: %s", err, migration)
+		}
+	}
 	return nil
 }
 
+// isUniqueViolation reports whether err was caused by a UNIQUE constraint
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	return db.DB.Close()
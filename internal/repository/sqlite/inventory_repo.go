@@ -0,0 +1,392 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// InventoryRepo implements repository.InventoryRepository
+type InventoryRepo struct {
+	db *DB
+}
+
+// NewInventoryRepo creates a new InventoryRepo
+func NewInventoryRepo(db *DB) repository.InventoryRepository {
+	return &InventoryRepo{db: db}
+}
+
+// inventorySelect includes the total stock across all locations
+const inventorySelect = `
+	SELECT i.id, i.sku, i.ean, i.name, i.brand, i.categories, i.cost, i.sale_price, i.min_stock, i.active,
+		   i.created_at, i.updated_at,
+		   COALESCE((SELECT SUM(quantity) FROM stock_levels WHERE item_id = i.id), 0)
+	FROM inventory_items i
+`
+
+func (r *InventoryRepo) CreateItem(ctx context.Context, item *domain.InventoryItem) error {
+	query := `
+		INSERT INTO inventory_items (sku, ean, name, brand, categories, cost, sale_price, min_stock, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	item.SKU = strings.ToUpper(strings.TrimSpace(item.SKU))
+	item.EAN = strings.TrimSpace(item.EAN)
+
+	result, err := r.db.ExecContext(ctx, query,
+		item.SKU, item.EAN, item.Name, item.Brand, joinCategories(item.Categories),
+		item.Cost, item.SalePrice, item.MinStock, item.Active, now, now)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("failed to create inventory item: %w", inventoryUniqueError(err))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create inventory item: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get inventory item ID: %w", err)
+	}
+	item.ID = id
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	return nil
+}
+
+func (r *InventoryRepo) GetItem(ctx context.Context, id int64) (*domain.InventoryItem, error) {
+	item, err := scanInventoryItem(r.db.QueryRowContext(ctx, inventorySelect+` WHERE i.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory item: %w", err)
+	}
+	return item, nil
+}
+
+func (r *InventoryRepo) GetItemBySKU(ctx context.Context, sku string) (*domain.InventoryItem, error) {
+	sku = strings.ToUpper(strings.TrimSpace(sku))
+	item, err := scanInventoryItem(r.db.QueryRowContext(ctx, inventorySelect+` WHERE i.sku = ?`, sku))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory item by sku: %w", err)
+	}
+	return item, nil
+}
+
+func (r *InventoryRepo) GetItemByEAN(ctx context.Context, ean string) (*domain.InventoryItem, error) {
+	ean = strings.TrimSpace(ean)
+	if ean == "" {
+		return nil, nil
+	}
+	item, err := scanInventoryItem(r.db.QueryRowContext(ctx, inventorySelect+` WHERE i.ean = ?`, ean))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory item by ean: %w", err)
+	}
+	return item, nil
+}
+
+func (r *InventoryRepo) UpdateItem(ctx context.Context, item *domain.InventoryItem) error {
+	query := `
+		UPDATE inventory_items
+		SET sku = ?, ean = ?, name = ?, brand = ?, categories = ?, cost = ?, sale_price = ?, min_stock = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`
+	item.SKU = strings.ToUpper(strings.TrimSpace(item.SKU))
+	item.EAN = strings.TrimSpace(item.EAN)
+	item.UpdatedAt = time.Now()
+
+	_, err := r.db.ExecContext(ctx, query,
+		item.SKU, item.EAN, item.Name, item.Brand, joinCategories(item.Categories),
+		item.Cost, item.SalePrice, item.MinStock, item.Active, item.UpdatedAt, item.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("failed to update inventory item: %w", inventoryUniqueError(err))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update inventory item: %w", err)
+	}
+	return nil
+}
+
+func (r *InventoryRepo) ListItems(ctx context.Context, search string, includeInactive bool) ([]domain.InventoryItem, error) {
+	var conditions []string
+	var args []interface{}
+
+	if !includeInactive {
+		conditions = append(conditions, "i.active = 1")
+	}
+	if search = strings.TrimSpace(search); search != "" {
+		conditions = append(conditions, "(i.sku LIKE ? OR i.name LIKE ? OR i.brand LIKE ?)")
+		like := "%" + search + "%"
+		args = append(args, like, like, like)
+	}
+
+	query := inventorySelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY i.name"
+
+	return r.queryItems(ctx, query, args...)
+}
+
+func (r *InventoryRepo) ListLowStock(ctx context.Context) ([]domain.InventoryItem, error) {
+	query := `
+		SELECT id, sku, ean, name, brand, categories, cost, sale_price, min_stock, active, created_at, updated_at, stock
+		FROM (
+			SELECT i.*, COALESCE((SELECT SUM(quantity) FROM stock_levels WHERE item_id = i.id), 0) AS stock
+			FROM inventory_items i
+			WHERE i.active = 1
+		)
+		WHERE stock <= min_stock
+		ORDER BY name
+	`
+	return r.queryItems(ctx, query)
+}
+
+func (r *InventoryRepo) CreateLocation(ctx context.Context, location *domain.StockLocation) error {
+	if location.BranchID == 0 {
+		location.BranchID = domain.DefaultBranchID
+	}
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO stock_locations (name, branch_id, created_at) VALUES (?, ?, ?)`, location.Name, location.BranchID, now)
+	if err != nil {
+		return fmt.Errorf("failed to create stock location: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get stock location ID: %w", err)
+	}
+	location.ID = id
+	location.CreatedAt = now
+	return nil
+}
+
+func (r *InventoryRepo) ListLocations(ctx context.Context) ([]domain.StockLocation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, COALESCE(branch_id, 1), created_at FROM stock_locations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []domain.StockLocation
+	for rows.Next() {
+		var l domain.StockLocation
+		if err := rows.Scan(&l.ID, &l.Name, &l.BranchID, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock location: %w", err)
+		}
+		locations = append(locations, l)
+	}
+	return locations, rows.Err()
+}
+
+// GetStockLevels returns the quantity of an item at every location
+func (r *InventoryRepo) GetStockLevels(ctx context.Context, itemID int64) ([]domain.StockLevel, error) {
+	query := `
+		SELECT l.id, l.name, COALESCE(s.quantity, 0)
+		FROM stock_locations l
+		LEFT JOIN stock_levels s ON s.location_id = l.id AND s.item_id = ?
+		ORDER BY l.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []domain.StockLevel
+	for rows.Next() {
+		level := domain.StockLevel{ItemID: itemID, Location: &domain.StockLocation{}}
+		if err := rows.Scan(&level.LocationID, &level.Location.Name, &level.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan stock level: %w", err)
+		}
+		level.Location.ID = level.LocationID
+		levels = append(levels, level)
+	}
+	return levels, rows.Err()
+}
+
+func (r *InventoryRepo) GetStock(ctx context.Context, itemID, locationID int64) (int, error) {
+	var quantity int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT quantity FROM stock_levels WHERE item_id = ? AND location_id = ?`, itemID, locationID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return quantity, nil
+}
+
+func (r *InventoryRepo) AdjustStock(ctx context.Context, movement *domain.StockMovement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := applyStockMovement(ctx, tx, movement); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock movement: %w", err)
+	}
+	return nil
+}
+
+func (r *InventoryRepo) ListMovements(ctx context.Context, itemID int64, limit int) ([]domain.StockMovement, error) {
+	query := `
+		SELECT m.id, m.item_id, m.location_id, l.name, m.quantity, m.reason, m.ticket_id, m.ticket_part_id,
+			   m.user_id, m.notes, m.created_at
+		FROM stock_movements m
+		LEFT JOIN stock_locations l ON m.location_id = l.id
+		WHERE m.item_id = ?
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, itemID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []domain.StockMovement
+	for rows.Next() {
+		var m domain.StockMovement
+		var locationName, notes sql.NullString
+		var ticketID, partID, userID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.ItemID, &m.LocationID, &locationName, &m.Quantity, &m.Reason,
+			&ticketID, &partID, &userID, &notes, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		m.LocationName = locationName.String
+		m.TicketID = ticketID.Int64
+		m.TicketPartID = partID.Int64
+		m.UserID = userID.Int64
+		m.Notes = notes.String
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+func (r *InventoryRepo) queryItems(ctx context.Context, query string, args ...interface{}) ([]domain.InventoryItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inventory items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.InventoryItem
+	for rows.Next() {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inventory item: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+func scanInventoryItem(row rowScanner) (*domain.InventoryItem, error) {
+	item := &domain.InventoryItem{}
+	var ean, brand, categories sql.NullString
+	err := row.Scan(&item.ID, &item.SKU, &ean, &item.Name, &brand, &categories, &item.Cost, &item.SalePrice,
+		&item.MinStock, &item.Active, &item.CreatedAt, &item.UpdatedAt, &item.Stock)
+	if err != nil {
+		return nil, err
+	}
+	item.EAN = ean.String
+	item.Brand = brand.String
+	item.Categories = splitCategories(categories.String)
+	return item, nil
+}
+
+// applyStockMovement records a movement and updates the stock level inside tx.
+// Outflows that would leave the location below zero fail with
+// repository.ErrInsufficientStock.
+func applyStockMovement(ctx context.Context, tx *sql.Tx, m *domain.StockMovement) error {
+	if m.LocationID == 0 {
+		m.LocationID = domain.DefaultStockLocationID
+	}
+
+	var current int
+	err := tx.QueryRowContext(ctx,
+		`SELECT quantity FROM stock_levels WHERE item_id = ? AND location_id = ?`, m.ItemID, m.LocationID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get stock level: %w", err)
+	}
+	if current+m.Quantity < 0 {
+		return fmt.Errorf("item %d at location %d has %d: %w", m.ItemID, m.LocationID, current, repository.ErrInsufficientStock)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_levels (item_id, location_id, quantity) VALUES (?, ?, ?)
+		ON CONFLICT(item_id, location_id) DO UPDATE SET quantity = quantity + excluded.quantity`,
+		m.ItemID, m.LocationID, m.Quantity); err != nil {
+		return fmt.Errorf("failed to update stock level: %w", err)
+	}
+
+	var ticketID, partID, userID interface{}
+	if m.TicketID != 0 {
+		ticketID = m.TicketID
+	}
+	if m.TicketPartID != 0 {
+		partID = m.TicketPartID
+	}
+	if m.UserID != 0 {
+		userID = m.UserID
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_movements (item_id, location_id, quantity, reason, ticket_id, ticket_part_id, user_id, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ItemID, m.LocationID, m.Quantity, m.Reason, ticketID, partID, userID, m.Notes, now)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	m.ID, _ = result.LastInsertId()
+	m.CreatedAt = now
+	return nil
+}
+
+// inventoryUniqueError tells which unique column a violation came from
+func inventoryUniqueError(err error) error {
+	if strings.Contains(err.Error(), "inventory_items.ean") {
+		return repository.ErrDuplicateEAN
+	}
+	return repository.ErrDuplicateSKU
+}
+
+func joinCategories(categories []string) string {
+	var cleaned []string
+	for _, c := range categories {
+		if c = strings.TrimSpace(c); c != "" {
+			cleaned = append(cleaned, c)
+		}
+	}
+	return strings.Join(cleaned, ",")
+}
+
+func splitCategories(value string) []string {
+	var categories []string
+	for _, c := range strings.Split(value, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
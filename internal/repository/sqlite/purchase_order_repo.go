@@ -0,0 +1,345 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// PurchaseOrderRepo implements repository.PurchaseOrderRepository
+type PurchaseOrderRepo struct {
+	db *DB
+}
+
+// NewPurchaseOrderRepo creates a new PurchaseOrderRepo
+func NewPurchaseOrderRepo(db *DB) repository.PurchaseOrderRepository {
+	return &PurchaseOrderRepo{db: db}
+}
+
+const purchaseOrderSelect = `
+	SELECT o.id, o.supplier_id, o.status, o.notes, o.created_by, o.created_at, o.sent_at, o.received_at,
+		   s.name, s.email, s.phone, s.contact_name
+	FROM purchase_orders o
+	LEFT JOIN suppliers s ON o.supplier_id = s.id
+`
+
+const purchaseLineSelect = `
+	SELECT l.id, l.order_id, l.item_id, l.quantity, l.received, l.unit_cost, i.sku, i.name
+	FROM purchase_order_lines l
+	LEFT JOIN inventory_items i ON l.item_id = i.id
+`
+
+func (r *PurchaseOrderRepo) Create(ctx context.Context, order *domain.PurchaseOrder) error {
+	query := `
+		INSERT INTO purchase_orders (supplier_id, status, notes, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	order.Status = domain.PurchaseOrderStatusDraft
+
+	var createdBy interface{}
+	if order.CreatedBy != 0 {
+		createdBy = order.CreatedBy
+	}
+
+	result, err := r.db.ExecContext(ctx, query, order.SupplierID, order.Status, order.Notes, createdBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase order: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get purchase order ID: %w", err)
+	}
+	order.ID = id
+	order.CreatedAt = now
+	return nil
+}
+
+func (r *PurchaseOrderRepo) GetByID(ctx context.Context, id int64) (*domain.PurchaseOrder, error) {
+	orders, err := r.query(ctx, purchaseOrderSelect+` WHERE o.id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	order := &orders[0]
+
+	order.Lines, err = r.queryLines(ctx, purchaseLineSelect+` WHERE l.order_id = ? ORDER BY l.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order lines: %w", err)
+	}
+
+	// Attach the tickets waiting on each line
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT lt.line_id, t.id, t.tracking_code, t.status
+		FROM purchase_order_line_tickets lt
+		JOIN purchase_order_lines l ON lt.line_id = l.id
+		JOIN tickets t ON lt.ticket_id = t.id
+		WHERE l.order_id = ?
+		ORDER BY t.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase order tickets: %w", err)
+	}
+	defer rows.Close()
+
+	byLine := make(map[int64][]domain.Ticket)
+	for rows.Next() {
+		var lineID int64
+		var t domain.Ticket
+		if err := rows.Scan(&lineID, &t.ID, &t.TrackingCode, &t.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase order ticket: %w", err)
+		}
+		byLine[lineID] = append(byLine[lineID], t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range order.Lines {
+		order.Lines[i].Tickets = byLine[order.Lines[i].ID]
+	}
+	return order, nil
+}
+
+func (r *PurchaseOrderRepo) List(ctx context.Context, status string, limit, offset int) ([]domain.PurchaseOrder, error) {
+	query := purchaseOrderSelect
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE o.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY o.created_at DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	orders, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase orders: %w", err)
+	}
+
+	// Lines are needed for the totals shown in listings
+	for i := range orders {
+		orders[i].Lines, err = r.queryLines(ctx, purchaseLineSelect+` WHERE l.order_id = ? ORDER BY l.id`, orders[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list purchase order lines: %w", err)
+		}
+	}
+	return orders, nil
+}
+
+func (r *PurchaseOrderRepo) UpdateStatus(ctx context.Context, id int64, status string) error {
+	query := `UPDATE purchase_orders SET status = ? WHERE id = ?`
+	args := []interface{}{status, id}
+	if status == domain.PurchaseOrderStatusSent {
+		query = `UPDATE purchase_orders SET status = ?, sent_at = ? WHERE id = ?`
+		args = []interface{}{status, time.Now(), id}
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update purchase order status: %w", err)
+	}
+	return nil
+}
+
+func (r *PurchaseOrderRepo) AddLine(ctx context.Context, line *domain.PurchaseOrderLine) error {
+	query := `INSERT INTO purchase_order_lines (order_id, item_id, quantity, unit_cost) VALUES (?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query, line.OrderID, line.ItemID, line.Quantity, line.UnitCost)
+	if err != nil {
+		return fmt.Errorf("failed to add purchase order line: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get purchase order line ID: %w", err)
+	}
+	line.ID = id
+	return nil
+}
+
+func (r *PurchaseOrderRepo) DeleteLine(ctx context.Context, orderID, lineID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM purchase_order_lines WHERE id = ? AND order_id = ?`, lineID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete purchase order line: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		// Foreign keys are not enforced, so the cascade is done by hand
+		if _, err := tx.ExecContext(ctx, `DELETE FROM purchase_order_line_tickets WHERE line_id = ?`, lineID); err != nil {
+			return fmt.Errorf("failed to unlink purchase order line tickets: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit purchase order line deletion: %w", err)
+	}
+	return nil
+}
+
+func (r *PurchaseOrderRepo) LinkTicket(ctx context.Context, lineID, ticketID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO purchase_order_line_tickets (line_id, ticket_id) VALUES (?, ?)`, lineID, ticketID)
+	if err != nil {
+		return fmt.Errorf("failed to link ticket to purchase order line: %w", err)
+	}
+	return nil
+}
+
+func (r *PurchaseOrderRepo) UnlinkTicket(ctx context.Context, lineID, ticketID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM purchase_order_line_tickets WHERE line_id = ? AND ticket_id = ?`, lineID, ticketID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink ticket from purchase order line: %w", err)
+	}
+	return nil
+}
+
+func (r *PurchaseOrderRepo) ListOpenLinesByTicket(ctx context.Context, ticketID int64) ([]domain.PurchaseOrderLine, error) {
+	query := purchaseLineSelect + `
+		JOIN purchase_order_line_tickets lt ON lt.line_id = l.id
+		JOIN purchase_orders o ON l.order_id = o.id
+		WHERE lt.ticket_id = ? AND l.received < l.quantity AND o.status <> ?
+		ORDER BY l.id
+	`
+	lines, err := r.queryLines(ctx, query, ticketID, domain.PurchaseOrderStatusReceived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchase order lines by ticket: %w", err)
+	}
+	return lines, nil
+}
+
+func (r *PurchaseOrderRepo) Receive(ctx context.Context, orderID int64, quantities map[int64]int, locationID, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, item_id, quantity, received FROM purchase_order_lines WHERE order_id = ?`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get purchase order lines: %w", err)
+	}
+	var lines []domain.PurchaseOrderLine
+	for rows.Next() {
+		var l domain.PurchaseOrderLine
+		if err := rows.Scan(&l.ID, &l.ItemID, &l.Quantity, &l.Received); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan purchase order line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	complete := true
+	for _, line := range lines {
+		quantity := quantities[line.ID]
+		if quantity > line.Outstanding() {
+			quantity = line.Outstanding()
+		}
+		if quantity > 0 {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE purchase_order_lines SET received = received + ? WHERE id = ?`, quantity, line.ID); err != nil {
+				return fmt.Errorf("failed to update purchase order line: %w", err)
+			}
+			if err := applyStockMovement(ctx, tx, &domain.StockMovement{
+				ItemID:     line.ItemID,
+				LocationID: locationID,
+				Quantity:   quantity,
+				Reason:     domain.MovementReasonReceive,
+				UserID:     userID,
+				Notes:      fmt.Sprintf("OC #%d", orderID),
+			}); err != nil {
+				return err
+			}
+			line.Received += quantity
+		}
+		if line.Outstanding() > 0 {
+			complete = false
+		}
+	}
+
+	status := domain.PurchaseOrderStatusPartiallyReceived
+	var receivedAt interface{}
+	if complete {
+		status = domain.PurchaseOrderStatusReceived
+		receivedAt = time.Now()
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE purchase_orders SET status = ?, received_at = ? WHERE id = ?`, status, receivedAt, orderID); err != nil {
+		return fmt.Errorf("failed to update purchase order status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit purchase order receipt: %w", err)
+	}
+	return nil
+}
+
+func (r *PurchaseOrderRepo) query(ctx context.Context, query string, args ...interface{}) ([]domain.PurchaseOrder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []domain.PurchaseOrder
+	for rows.Next() {
+		o := domain.PurchaseOrder{Supplier: &domain.Supplier{}}
+		var notes, supplierName, supplierEmail, supplierPhone, contactName sql.NullString
+		var createdBy sql.NullInt64
+		var sentAt, receivedAt sql.NullTime
+		if err := rows.Scan(&o.ID, &o.SupplierID, &o.Status, &notes, &createdBy, &o.CreatedAt, &sentAt, &receivedAt,
+			&supplierName, &supplierEmail, &supplierPhone, &contactName); err != nil {
+			return nil, err
+		}
+		o.Notes = notes.String
+		o.CreatedBy = createdBy.Int64
+		if sentAt.Valid {
+			o.SentAt = &sentAt.Time
+		}
+		if receivedAt.Valid {
+			o.ReceivedAt = &receivedAt.Time
+		}
+		o.Supplier.ID = o.SupplierID
+		o.Supplier.Name = supplierName.String
+		o.Supplier.Email = supplierEmail.String
+		o.Supplier.Phone = supplierPhone.String
+		o.Supplier.ContactName = contactName.String
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (r *PurchaseOrderRepo) queryLines(ctx context.Context, query string, args ...interface{}) ([]domain.PurchaseOrderLine, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []domain.PurchaseOrderLine
+	for rows.Next() {
+		l := domain.PurchaseOrderLine{Item: &domain.InventoryItem{}}
+		var sku, name sql.NullString
+		if err := rows.Scan(&l.ID, &l.OrderID, &l.ItemID, &l.Quantity, &l.Received, &l.UnitCost, &sku, &name); err != nil {
+			return nil, err
+		}
+		l.Item.ID = l.ItemID
+		l.Item.SKU = sku.String
+		l.Item.Name = name.String
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import "bicicletapp/internal/repository"
+
+// NewRepositories creates every repository of a workshop database
+func NewRepositories(db *DB) *repository.Repositories {
+	return &repository.Repositories{
+		Users:       NewUserRepo(db),
+		Roles:       NewRoleRepo(db),
+		Branches:    NewBranchRepo(db),
+		UserTokens:  NewUserTokenRepo(db),
+		Sessions:    NewSessionRepo(db),
+		APIKeys:     NewAPIKeyRepo(db),
+		Webhooks:    NewWebhookRepo(db),
+		AuditLog:    NewAuditLogRepo(db),
+		Brands:      NewBrandRepo(db),
+		Models:      NewModelRepo(db),
+		Services:    NewServiceRepo(db),
+		Bicycles:    NewBicycleRepo(db),
+		Bookings:    NewBookingRepo(db),
+		Quotes:      NewQuoteRepo(db),
+		Tickets:     NewTicketRepo(db),
+		TimeEntries: NewTimeEntryRepo(db),
+		Attachments: NewAttachmentRepo(db),
+		Comments:    NewCommentRepo(db),
+		Checklists:  NewChecklistRepo(db),
+		Surveys:     NewSurveyRepo(db),
+		Ads:         NewAdRepo(db),
+		Settings:    NewSettingsRepo(db),
+		StolenBikes: NewStolenBikeRepo(db),
+		Transfers:   NewTransferRepo(db),
+		Inventory:   NewInventoryRepo(db),
+		Suppliers:   NewSupplierRepo(db),
+		Purchases:   NewPurchaseOrderRepo(db),
+	}
+}
@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// RoleRepo implements repository.RoleRepository
+type RoleRepo struct {
+	db *DB
+}
+
+// NewRoleRepo creates a new RoleRepo
+func NewRoleRepo(db *DB) repository.RoleRepository {
+	return &RoleRepo{db: db}
+}
+
+func (r *RoleRepo) Create(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `INSERT INTO roles (name, label, system, created_at) VALUES (?, ?, ?, ?)`,
+		role.Name, role.Label, role.System, now)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateRole
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	if err := replaceRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+	role.CreatedAt = now
+	return nil
+}
+
+func (r *RoleRepo) GetByName(ctx context.Context, name string) (*domain.Role, error) {
+	role := &domain.Role{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, label, system, created_at FROM roles WHERE name = ?
+	`, name).Scan(&role.Name, &role.Label, &role.System, &role.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	permissions, err := r.permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions[role.Name]
+	return role, nil
+}
+
+func (r *RoleRepo) Update(ctx context.Context, role *domain.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE roles SET label = ? WHERE name = ?`, role.Label, role.Name); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if err := replaceRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role: %w", err)
+	}
+	return nil
+}
+
+// replaceRolePermissions stores the role's permission set
+func replaceRolePermissions(ctx context.Context, tx *sql.Tx, role *domain.Role) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role = ?`, role.Name); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	for _, permission := range role.Permissions {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO role_permissions (role, permission) VALUES (?, ?)`,
+			role.Name, permission); err != nil {
+			return fmt.Errorf("failed to add role permission: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *RoleRepo) Delete(ctx context.Context, name string) error {
+	var users int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE role = ?`, name).Scan(&users); err != nil {
+		return fmt.Errorf("failed to count role users: %w", err)
+	}
+	if users > 0 {
+		return repository.ErrRoleInUse
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Foreign keys are not enforced, so the permissions go explicitly
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete role permissions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM roles WHERE name = ? AND system = 0`, name); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role deletion: %w", err)
+	}
+	return nil
+}
+
+func (r *RoleRepo) List(ctx context.Context) ([]domain.Role, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ro.name, ro.label, ro.system, ro.created_at,
+			(SELECT COUNT(*) FROM users u WHERE u.role = ro.name)
+		FROM roles ro
+		ORDER BY ro.system DESC, ro.label
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		if err := rows.Scan(&role.Name, &role.Label, &role.System, &role.CreatedAt, &role.UserCount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+
+	permissions, err := r.permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		roles[i].Permissions = permissions[roles[i].Name]
+	}
+	return roles, nil
+}
+
+// permissions loads every role's permissions keyed by role name
+func (r *RoleRepo) permissions(ctx context.Context) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make(map[string][]string)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("failed to scan role permission: %w", err)
+		}
+		permissions[role] = append(permissions[role], permission)
+	}
+	return permissions, nil
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// SessionRepo implements repository.SessionRepository
+type SessionRepo struct {
+	db *DB
+}
+
+// NewSessionRepo creates a new SessionRepo
+func NewSessionRepo(db *DB) repository.SessionRepository {
+	return &SessionRepo{db: db}
+}
+
+const sessionSelect = `
+	SELECT s.id, s.user_id, s.refresh_hash, COALESCE(s.previous_refresh_hash, ''), s.rotated_at,
+		COALESCE(s.user_agent, ''), COALESCE(s.ip, ''), s.created_at, s.last_used_at, s.expires_at, s.revoked_at,
+		COALESCE(u.name, ''), COALESCE(u.email, ''), COALESCE(u.role, '')
+	FROM sessions s
+	LEFT JOIN users u ON u.id = s.user_id
+`
+
+func scanSession(row rowScanner) (*domain.Session, error) {
+	var s domain.Session
+	var rotatedAt, lastUsedAt, revokedAt sql.NullTime
+	var user domain.User
+	err := row.Scan(&s.ID, &s.UserID, &s.RefreshHash, &s.PreviousRefreshHash, &rotatedAt,
+		&s.UserAgent, &s.IP, &s.CreatedAt, &lastUsedAt, &s.ExpiresAt, &revokedAt,
+		&user.Name, &user.Email, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if rotatedAt.Valid {
+		s.RotatedAt = &rotatedAt.Time
+	}
+	if lastUsedAt.Valid {
+		s.LastUsedAt = lastUsedAt.Time
+	} else {
+		s.LastUsedAt = s.CreatedAt
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	user.ID = s.UserID
+	s.User = &user
+	return &s, nil
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (user_id, refresh_hash, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, session.UserID, session.RefreshHash, session.UserAgent, session.IP, now, now, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get session ID: %w", err)
+	}
+	session.ID = id
+	return nil
+}
+
+func (r *SessionRepo) GetByID(ctx context.Context, id int64) (*domain.Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx, sessionSelect+` WHERE s.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SessionRepo) GetByRefreshHash(ctx context.Context, refreshHash string) (*domain.Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx,
+		sessionSelect+` WHERE s.refresh_hash = ? OR s.previous_refresh_hash = ? LIMIT 1`, refreshHash, refreshHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session by refresh token: %w", err)
+	}
+	return s, nil
+}
+
+func (r *SessionRepo) Rotate(ctx context.Context, id int64, oldHash, newHash string, expiresAt time.Time) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET previous_refresh_hash = refresh_hash, refresh_hash = ?, rotated_at = ?, last_used_at = ?, expires_at = ?
+		WHERE id = ? AND refresh_hash = ? AND revoked_at IS NULL
+	`, newHash, now, now, expiresAt, id, oldHash)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepo) RevokeAllForUser(ctx context.Context, userID, exceptID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = ?
+		WHERE user_id = ? AND id <> ? AND revoked_at IS NULL
+	`, time.Now(), userID, exceptID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepo) ListActive(ctx context.Context, userID int64) ([]domain.Session, error) {
+	query := sessionSelect + ` WHERE s.revoked_at IS NULL`
+	var args []interface{}
+	if userID > 0 {
+		query += ` AND s.user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY s.last_used_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		// Expiry is checked here rather than in SQL so it uses the same clock
+		// as the middleware
+		if s.Active() {
+			sessions = append(sessions, *s)
+		}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// StolenBikeRepo implements repository.StolenBikeRepository
+type StolenBikeRepo struct {
+	db *DB
+}
+
+// NewStolenBikeRepo creates a new StolenBikeRepo
+func NewStolenBikeRepo(db *DB) repository.StolenBikeRepository {
+	return &StolenBikeRepo{db: db}
+}
+
+const stolenBikeColumns = `id, serial_number, brand, model, color, bicycle_id, source, notes,
+	reported_at, recovered_at, last_seen_at, last_seen_ticket_id, created_by, created_at`
+
+func (r *StolenBikeRepo) Create(ctx context.Context, bike *domain.StolenBike) error {
+	query := `
+		INSERT INTO stolen_bikes (serial_number, serial_normalized, brand, model, color, bicycle_id, source, notes, reported_at, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	if bike.ReportedAt.IsZero() {
+		bike.ReportedAt = now
+	}
+	if bike.Source == "" {
+		bike.Source = domain.StolenSourceManual
+	}
+
+	var bicycleID, createdBy interface{}
+	if bike.BicycleID != 0 {
+		bicycleID = bike.BicycleID
+	}
+	if bike.CreatedBy != 0 {
+		createdBy = bike.CreatedBy
+	}
+
+	result, err := r.db.ExecContext(ctx, query,
+		bike.SerialNumber, domain.NormalizeSerial(bike.SerialNumber), bike.Brand, bike.Model, bike.Color,
+		bicycleID, bike.Source, bike.Notes, bike.ReportedAt, createdBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to create stolen bike: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get stolen bike ID: %w", err)
+	}
+	bike.ID = id
+	bike.CreatedAt = now
+	return nil
+}
+
+func (r *StolenBikeRepo) GetByID(ctx context.Context, id int64) (*domain.StolenBike, error) {
+	query := `SELECT ` + stolenBikeColumns + ` FROM stolen_bikes WHERE id = ?`
+	bike, err := scanStolenBike(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stolen bike: %w", err)
+	}
+	return bike, nil
+}
+
+// GetActiveBySerial returns the most recent unrecovered report for a serial
+func (r *StolenBikeRepo) GetActiveBySerial(ctx context.Context, serial string) (*domain.StolenBike, error) {
+	normalized := domain.NormalizeSerial(serial)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	query := `SELECT ` + stolenBikeColumns + ` FROM stolen_bikes
+		WHERE serial_normalized = ? AND recovered_at IS NULL
+		ORDER BY reported_at DESC LIMIT 1`
+	bike, err := scanStolenBike(r.db.QueryRowContext(ctx, query, normalized))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stolen bike by serial: %w", err)
+	}
+	return bike, nil
+}
+
+func (r *StolenBikeRepo) List(ctx context.Context, includeRecovered bool, limit, offset int) ([]domain.StolenBike, error) {
+	query := `SELECT ` + stolenBikeColumns + ` FROM stolen_bikes`
+	if !includeRecovered {
+		query += ` WHERE recovered_at IS NULL`
+	}
+	query += ` ORDER BY reported_at DESC LIMIT ? OFFSET ?`
+
+	return r.query(ctx, query, limit, offset)
+}
+
+// ListSeen returns active reports whose serial has shown up at reception
+func (r *StolenBikeRepo) ListSeen(ctx context.Context, limit int) ([]domain.StolenBike, error) {
+	query := `SELECT ` + stolenBikeColumns + ` FROM stolen_bikes
+		WHERE recovered_at IS NULL AND last_seen_at IS NOT NULL
+		ORDER BY last_seen_at DESC LIMIT ?`
+
+	return r.query(ctx, query, limit)
+}
+
+func (r *StolenBikeRepo) MarkRecovered(ctx context.Context, id int64) error {
+	query := `UPDATE stolen_bikes SET recovered_at = ? WHERE id = ? AND recovered_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to mark stolen bike recovered: %w", err)
+	}
+	return nil
+}
+
+// RecordSighting stores when and on which ticket a flagged serial showed up
+func (r *StolenBikeRepo) RecordSighting(ctx context.Context, id, ticketID int64) error {
+	query := `UPDATE stolen_bikes SET last_seen_at = ?, last_seen_ticket_id = ? WHERE id = ?`
+	var tID interface{}
+	if ticketID != 0 {
+		tID = ticketID
+	}
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), tID, id); err != nil {
+		return fmt.Errorf("failed to record stolen bike sighting: %w", err)
+	}
+	return nil
+}
+
+func (r *StolenBikeRepo) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM stolen_bikes WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete stolen bike: %w", err)
+	}
+	return nil
+}
+
+func (r *StolenBikeRepo) query(ctx context.Context, query string, args ...interface{}) ([]domain.StolenBike, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stolen bikes: %w", err)
+	}
+	defer rows.Close()
+
+	var bikes []domain.StolenBike
+	for rows.Next() {
+		bike, err := scanStolenBike(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stolen bike: %w", err)
+		}
+		bikes = append(bikes, *bike)
+	}
+	return bikes, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStolenBike(row rowScanner) (*domain.StolenBike, error) {
+	bike := &domain.StolenBike{}
+	var brand, model, color, source, notes sql.NullString
+	var bicycleID, seenTicketID, createdBy sql.NullInt64
+	var recoveredAt, seenAt sql.NullTime
+
+	err := row.Scan(
+		&bike.ID, &bike.SerialNumber, &brand, &model, &color, &bicycleID, &source, &notes,
+		&bike.ReportedAt, &recoveredAt, &seenAt, &seenTicketID, &createdBy, &bike.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bike.Brand = brand.String
+	bike.Model = model.String
+	bike.Color = color.String
+	bike.Source = source.String
+	bike.Notes = notes.String
+	bike.BicycleID = bicycleID.Int64
+	bike.LastSeenTicketID = seenTicketID.Int64
+	bike.CreatedBy = createdBy.Int64
+	if recoveredAt.Valid {
+		bike.RecoveredAt = &recoveredAt.Time
+	}
+	if seenAt.Valid {
+		bike.LastSeenAt = &seenAt.Time
+	}
+	return bike, nil
+}
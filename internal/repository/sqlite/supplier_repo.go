@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// SupplierRepo implements repository.SupplierRepository
+type SupplierRepo struct {
+	db *DB
+}
+
+// NewSupplierRepo creates a new SupplierRepo
+func NewSupplierRepo(db *DB) repository.SupplierRepository {
+	return &SupplierRepo{db: db}
+}
+
+const supplierSelect = `SELECT id, name, contact_name, email, phone, notes, active, created_at FROM suppliers`
+
+func (r *SupplierRepo) Create(ctx context.Context, supplier *domain.Supplier) error {
+	query := `
+		INSERT INTO suppliers (name, contact_name, email, phone, notes, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone, supplier.Notes, supplier.Active, now)
+	if err != nil {
+		return fmt.Errorf("failed to create supplier: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get supplier ID: %w", err)
+	}
+	supplier.ID = id
+	supplier.CreatedAt = now
+	return nil
+}
+
+func (r *SupplierRepo) GetByID(ctx context.Context, id int64) (*domain.Supplier, error) {
+	supplier, err := scanSupplier(r.db.QueryRowContext(ctx, supplierSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+	return supplier, nil
+}
+
+func (r *SupplierRepo) Update(ctx context.Context, supplier *domain.Supplier) error {
+	query := `
+		UPDATE suppliers SET name = ?, contact_name = ?, email = ?, phone = ?, notes = ?, active = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone, supplier.Notes, supplier.Active, supplier.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return nil
+}
+
+func (r *SupplierRepo) List(ctx context.Context, includeInactive bool) ([]domain.Supplier, error) {
+	query := supplierSelect
+	if !includeInactive {
+		query += ` WHERE active = 1`
+	}
+	query += ` ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	var suppliers []domain.Supplier
+	for rows.Next() {
+		supplier, err := scanSupplier(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		suppliers = append(suppliers, *supplier)
+	}
+	return suppliers, rows.Err()
+}
+
+func scanSupplier(row rowScanner) (*domain.Supplier, error) {
+	s := &domain.Supplier{}
+	var contactName, email, phone, notes sql.NullString
+	if err := row.Scan(&s.ID, &s.Name, &contactName, &email, &phone, &notes, &s.Active, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	s.ContactName = contactName.String
+	s.Email = email.String
+	s.Phone = phone.String
+	s.Notes = notes.String
+	return s, nil
+}
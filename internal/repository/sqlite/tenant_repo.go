@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// TenantRepo implements repository.TenantRepository on the platform database
+type TenantRepo struct {
+	db *DB
+}
+
+// NewTenantRepo creates a new TenantRepo
+func NewTenantRepo(db *DB) repository.TenantRepository {
+	return &TenantRepo{db: db}
+}
+
+const tenantSelect = `SELECT id, slug, status, name, tagline, logo, primary_color, secondary_color, accent_color,
+	contact_email, contact_phone, payments, sms, surveys, email_notifications, created_at FROM tenants`
+
+func (r *TenantRepo) Create(ctx context.Context, tenant *domain.Tenant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO tenants (slug, status, name, tagline, logo, primary_color, secondary_color, accent_color,
+			contact_email, contact_phone, payments, sms, surveys, email_notifications, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tenant.Slug, tenant.Status, tenant.Name, tenant.Tagline, tenant.Logo, tenant.PrimaryColor, tenant.SecondaryColor,
+		tenant.AccentColor, tenant.ContactEmail, tenant.ContactPhone, tenant.Payments, tenant.SMS, tenant.Surveys,
+		tenant.EmailNotifications, now)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateTenant
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get tenant ID: %w", err)
+	}
+
+	if err := insertTenantHosts(ctx, tx, id, tenant.Hosts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tenant: %w", err)
+	}
+	tenant.ID = id
+	tenant.CreatedAt = now
+	return nil
+}
+
+func (r *TenantRepo) GetByID(ctx context.Context, id int64) (*domain.Tenant, error) {
+	tenant, err := scanTenant(r.db.QueryRowContext(ctx, tenantSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	hosts, err := r.hostsByTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenant.Hosts = hosts[tenant.ID]
+	return tenant, nil
+}
+
+func (r *TenantRepo) Update(ctx context.Context, tenant *domain.Tenant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE tenants SET status = ?, name = ?, tagline = ?, logo = ?, primary_color = ?, secondary_color = ?,
+			accent_color = ?, contact_email = ?, contact_phone = ?, payments = ?, sms = ?, surveys = ?,
+			email_notifications = ?
+		WHERE id = ?
+	`, tenant.Status, tenant.Name, tenant.Tagline, tenant.Logo, tenant.PrimaryColor, tenant.SecondaryColor,
+		tenant.AccentColor, tenant.ContactEmail, tenant.ContactPhone, tenant.Payments, tenant.SMS, tenant.Surveys,
+		tenant.EmailNotifications, tenant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tenant_hosts WHERE tenant_id = ?`, tenant.ID); err != nil {
+		return fmt.Errorf("failed to clear tenant hosts: %w", err)
+	}
+	if err := insertTenantHosts(ctx, tx, tenant.ID, tenant.Hosts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tenant: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantRepo) List(ctx context.Context) ([]domain.Tenant, error) {
+	rows, err := r.db.QueryContext(ctx, tenantSelect+` ORDER BY slug`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	var tenants []domain.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, *tenant)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	// Hosts are read once the tenant rows are closed (single connection)
+	hosts, err := r.hostsByTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tenants {
+		tenants[i].Hosts = hosts[tenants[i].ID]
+	}
+	return tenants, nil
+}
+
+// hostsByTenant returns every tenant's hostnames, sorted
+func (r *TenantRepo) hostsByTenant(ctx context.Context) (map[int64][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT tenant_id, host FROM tenant_hosts ORDER BY host`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant hosts: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := make(map[int64][]string)
+	for rows.Next() {
+		var tenantID int64
+		var host string
+		if err := rows.Scan(&tenantID, &host); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant host: %w", err)
+		}
+		hosts[tenantID] = append(hosts[tenantID], host)
+	}
+	return hosts, rows.Err()
+}
+
+func insertTenantHosts(ctx context.Context, tx *sql.Tx, tenantID int64, hosts []string) error {
+	for _, host := range hosts {
+		_, err := tx.ExecContext(ctx, `INSERT INTO tenant_hosts (host, tenant_id) VALUES (?, ?)`,
+			strings.ToLower(host), tenantID)
+		if isUniqueViolation(err) {
+			return repository.ErrHostTaken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add tenant host: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanTenant(row rowScanner) (*domain.Tenant, error) {
+	t := &domain.Tenant{}
+	var tagline, logo, primary, secondary, accent, email, phone sql.NullString
+	err := row.Scan(&t.ID, &t.Slug, &t.Status, &t.Name, &tagline, &logo, &primary, &secondary, &accent,
+		&email, &phone, &t.Payments, &t.SMS, &t.Surveys, &t.EmailNotifications, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Tagline = tagline.String
+	t.Logo = logo.String
+	t.PrimaryColor = primary.String
+	t.SecondaryColor = secondary.String
+	t.AccentColor = accent.String
+	t.ContactEmail = email.String
+	t.ContactPhone = phone.String
+	return t, nil
+}
+
+// SuperAdminRepo implements repository.SuperAdminRepository on the platform
+// database
+type SuperAdminRepo struct {
+	db *DB
+}
+
+// NewSuperAdminRepo creates a new SuperAdminRepo
+func NewSuperAdminRepo(db *DB) repository.SuperAdminRepository {
+	return &SuperAdminRepo{db: db}
+}
+
+func (r *SuperAdminRepo) Create(ctx context.Context, admin *domain.SuperAdmin) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `INSERT INTO super_admins (email, password_hash, created_at) VALUES (?, ?, ?)`,
+		strings.ToLower(admin.Email), admin.PasswordHash, now)
+	if err != nil {
+		return fmt.Errorf("failed to create super admin: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get super admin ID: %w", err)
+	}
+	admin.ID = id
+	admin.CreatedAt = now
+	return nil
+}
+
+func (r *SuperAdminRepo) GetByID(ctx context.Context, id int64) (*domain.SuperAdmin, error) {
+	return r.get(ctx, `id = ?`, id)
+}
+
+func (r *SuperAdminRepo) GetByEmail(ctx context.Context, email string) (*domain.SuperAdmin, error) {
+	return r.get(ctx, `email = ?`, strings.ToLower(strings.TrimSpace(email)))
+}
+
+func (r *SuperAdminRepo) get(ctx context.Context, where string, arg interface{}) (*domain.SuperAdmin, error) {
+	admin := &domain.SuperAdmin{}
+	err := r.db.QueryRowContext(ctx, `SELECT id, email, password_hash, created_at FROM super_admins WHERE `+where, arg).
+		Scan(&admin.ID, &admin.Email, &admin.PasswordHash, &admin.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get super admin: %w", err)
+	}
+	return admin, nil
+}
+
+func (r *SuperAdminRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM super_admins`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count super admins: %w", err)
+	}
+	return count, nil
+}
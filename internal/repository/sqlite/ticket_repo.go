@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
@@ -23,13 +24,20 @@ func NewTicketRepo(db *DB) repository.TicketRepository {
 
 func (r *TicketRepo) Create(ctx context.Context, ticket *domain.Ticket) error {
 	query := `
-		INSERT INTO tickets (booking_id, technician_id, tracking_code, qr_code, status, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO tickets (booking_id, technician_id, branch_id, tracking_code, qr_code, status, promised_at,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	if ticket.BranchID == 0 {
+		ticket.BranchID = domain.DefaultBranchID
+	}
 	now := time.Now()
 	result, err := r.db.ExecContext(ctx, query,
-		ticket.BookingID, ticket.TechnicianID, ticket.TrackingCode, ticket.QRCode,
-		ticket.Status, ticket.Notes, now, now)
+		ticket.BookingID, ticket.TechnicianID, ticket.BranchID, ticket.TrackingCode, ticket.QRCode,
+		ticket.Status, ticket.PromisedAt, now, now)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateTrackingCode
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create ticket: %w", err)
 	}
@@ -61,7 +69,7 @@ func (r *TicketRepo) Create(ctx context.Context, ticket *domain.Ticket) error {
 func (r *TicketRepo) GetByID(ctx context.Context, id int64) (*domain.Ticket, error) {
 	query := `
 		SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, t.qr_code, 
-			   t.status, t.notes, t.created_at, t.updated_at,
+			   t.status, t.promised_at, t.created_at, t.updated_at, COALESCE(t.branch_id, 1),
 			   u.id, u.name, u.email
 		FROM tickets t
 		LEFT JOIN users u ON t.technician_id = u.id
@@ -72,12 +80,13 @@ func (r *TicketRepo) GetByID(ctx context.Context, id int64) (*domain.Ticket, err
 	}
 
 	var qrCode []byte
+	var promisedAt sql.NullTime
 	var techID sql.NullInt64
 	var techName, techEmail sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&ticket.ID, &ticket.BookingID, &ticket.TechnicianID, &ticket.TrackingCode, &qrCode,
-		&ticket.Status, &ticket.Notes, &ticket.CreatedAt, &ticket.UpdatedAt,
+		&ticket.Status, &promisedAt, &ticket.CreatedAt, &ticket.UpdatedAt, &ticket.BranchID,
 		&techID, &techName, &techEmail,
 	)
 	if err == sql.ErrNoRows {
@@ -87,6 +96,9 @@ func (r *TicketRepo) GetByID(ctx context.Context, id int64) (*domain.Ticket, err
 		return nil, fmt.Errorf("failed to get ticket: %w", err)
 	}
 
+	if promisedAt.Valid {
+		ticket.PromisedAt = &promisedAt.Time
+	}
 	ticket.QRCode = qrCode
 	if len(qrCode) > 0 {
 		ticket.QRCodeBase64 = base64.StdEncoding.EncodeToString(qrCode)
@@ -104,16 +116,17 @@ func (r *TicketRepo) GetByID(ctx context.Context, id int64) (*domain.Ticket, err
 func (r *TicketRepo) GetByTrackingCode(ctx context.Context, code string) (*domain.Ticket, error) {
 	query := `
 		SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, t.qr_code, 
-			   t.status, t.notes, t.created_at, t.updated_at
+			   t.status, t.promised_at, t.created_at, t.updated_at, COALESCE(t.branch_id, 1)
 		FROM tickets t
 		WHERE t.tracking_code = ?
 	`
 	ticket := &domain.Ticket{}
 	var qrCode []byte
+	var promisedAt sql.NullTime
 
 	err := r.db.QueryRowContext(ctx, query, code).Scan(
 		&ticket.ID, &ticket.BookingID, &ticket.TechnicianID, &ticket.TrackingCode, &qrCode,
-		&ticket.Status, &ticket.Notes, &ticket.CreatedAt, &ticket.UpdatedAt,
+		&ticket.Status, &promisedAt, &ticket.CreatedAt, &ticket.UpdatedAt, &ticket.BranchID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -122,6 +135,9 @@ func (r *TicketRepo) GetByTrackingCode(ctx context.Context, code string) (*domai
 		return nil, fmt.Errorf("failed to get ticket by tracking code: %w", err)
 	}
 
+	if promisedAt.Valid {
+		ticket.PromisedAt = &promisedAt.Time
+	}
 	ticket.QRCode = qrCode
 	if len(qrCode) > 0 {
 		ticket.QRCodeBase64 = base64.StdEncoding.EncodeToString(qrCode)
@@ -137,7 +153,7 @@ func (r *TicketRepo) GetByTechnicianID(ctx context.Context, technicianID int64,
 	if status != "" {
 		query = `
 			SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, 
-				   t.status, t.notes, t.created_at, t.updated_at
+				   t.status, t.created_at, t.updated_at, COALESCE(t.branch_id, 1)
 			FROM tickets t
 			WHERE t.technician_id = ? AND t.status = ?
 			ORDER BY t.updated_at DESC
@@ -147,7 +163,7 @@ func (r *TicketRepo) GetByTechnicianID(ctx context.Context, technicianID int64,
 	} else {
 		query = `
 			SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, 
-				   t.status, t.notes, t.created_at, t.updated_at
+				   t.status, t.created_at, t.updated_at, COALESCE(t.branch_id, 1)
 			FROM tickets t
 			WHERE t.technician_id = ?
 			ORDER BY t.updated_at DESC
@@ -168,12 +184,12 @@ func (r *TicketRepo) GetByTechnicianID(ctx context.Context, technicianID int64,
 func (r *TicketRepo) Update(ctx context.Context, ticket *domain.Ticket) error {
 	query := `
 		UPDATE tickets 
-		SET technician_id = ?, status = ?, notes = ?, updated_at = ?
+		SET technician_id = ?, status = ?, updated_at = ?
 		WHERE id = ?
 	`
 	ticket.UpdatedAt = time.Now()
 	_, err := r.db.ExecContext(ctx, query,
-		ticket.TechnicianID, ticket.Status, ticket.Notes, ticket.UpdatedAt, ticket.ID)
+		ticket.TechnicianID, ticket.Status, ticket.UpdatedAt, ticket.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update ticket: %w", err)
 	}
@@ -209,29 +225,30 @@ func (r *TicketRepo) UpdateStatus(ctx context.Context, id int64, status string,
 	return nil
 }
 
-func (r *TicketRepo) List(ctx context.Context, status string, limit, offset int) ([]domain.Ticket, error) {
+func (r *TicketRepo) List(ctx context.Context, branchID int64, status string, limit, offset int) ([]domain.Ticket, error) {
 	var query string
 	var args []interface{}
 
 	if status != "" {
 		query = `
 			SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, 
-				   t.status, t.notes, t.created_at, t.updated_at
+				   t.status, t.created_at, t.updated_at, COALESCE(t.branch_id, 1)
 			FROM tickets t
-			WHERE t.status = ?
+			WHERE t.status = ? AND (? = 0 OR COALESCE(t.branch_id, 1) = ?)
 			ORDER BY t.updated_at DESC
 			LIMIT ? OFFSET ?
 		`
-		args = []interface{}{status, limit, offset}
+		args = []interface{}{status, branchID, branchID, limit, offset}
 	} else {
 		query = `
 			SELECT t.id, t.booking_id, t.technician_id, t.tracking_code, 
-				   t.status, t.notes, t.created_at, t.updated_at
+				   t.status, t.created_at, t.updated_at, COALESCE(t.branch_id, 1)
 			FROM tickets t
+			WHERE ? = 0 OR COALESCE(t.branch_id, 1) = ?
 			ORDER BY t.updated_at DESC
 			LIMIT ? OFFSET ?
 		`
-		args = []interface{}{limit, offset}
+		args = []interface{}{branchID, branchID, limit, offset}
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -243,9 +260,9 @@ func (r *TicketRepo) List(ctx context.Context, status string, limit, offset int)
 	return r.scanTicketsSimple(rows)
 }
 
-func (r *TicketRepo) CountByStatus(ctx context.Context) (map[string]int, error) {
-	query := `SELECT status, COUNT(*) FROM tickets GROUP BY status`
-	rows, err := r.db.QueryContext(ctx, query)
+func (r *TicketRepo) CountByStatus(ctx context.Context, branchID int64) (map[string]int, error) {
+	query := `SELECT status, COUNT(*) FROM tickets WHERE ? = 0 OR COALESCE(branch_id, 1) = ? GROUP BY status`
+	rows, err := r.db.QueryContext(ctx, query, branchID, branchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count tickets by status: %w", err)
 	}
@@ -263,16 +280,191 @@ func (r *TicketRepo) CountByStatus(ctx context.Context) (map[string]int, error)
 	return counts, nil
 }
 
+func (r *TicketRepo) ListBoard(ctx context.Context, filter domain.BoardFilter) ([]domain.BoardTicket, error) {
+	conditions := []string{"(t.status != ? OR t.updated_at >= ?)"}
+	args := []interface{}{domain.TicketStatusDelivered, filter.DeliveredSince}
+	if filter.BranchID != 0 {
+		conditions = append(conditions, "COALESCE(t.branch_id, 1) = ?")
+		args = append(args, filter.BranchID)
+	}
+	if filter.Unassigned {
+		conditions = append(conditions, "COALESCE(t.technician_id, 0) = 0")
+	} else if filter.TechnicianID != 0 {
+		conditions = append(conditions, "t.technician_id = ?")
+		args = append(args, filter.TechnicianID)
+	}
+
+	query := `
+		SELECT t.id, t.booking_id, COALESCE(t.technician_id, 0), t.tracking_code, t.status,
+			   t.created_at, t.updated_at, COALESCE(t.branch_id, 1),
+			   COALESCE(u.name, ''), COALESCE(c.id, 0), COALESCE(c.name, ''), COALESCE(c.phone, ''),
+			   COALESCE(bi.id, 0), COALESCE(bi.color, ''), COALESCE(br.name, ''), COALESCE(m.name, ''),
+			   (SELECT COUNT(*) FROM ticket_parts p WHERE p.ticket_id = t.id AND p.status = 'pending')
+		FROM tickets t
+		LEFT JOIN users u ON t.technician_id = u.id
+		LEFT JOIN bookings b ON t.booking_id = b.id
+		LEFT JOIN users c ON b.customer_id = c.id
+		LEFT JOIN bicycles bi ON b.bicycle_id = bi.id
+		LEFT JOIN brands br ON bi.brand_id = br.id
+		LEFT JOIN models m ON bi.model_id = m.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY t.created_at ASC, t.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list board tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var tickets []domain.BoardTicket
+	for rows.Next() {
+		var t domain.BoardTicket
+		var techName string
+		customer := &domain.User{}
+		bicycle := &domain.Bicycle{Brand: &domain.Brand{}, Model: &domain.Model{}}
+		if err := rows.Scan(
+			&t.ID, &t.BookingID, &t.TechnicianID, &t.TrackingCode, &t.Status,
+			&t.CreatedAt, &t.UpdatedAt, &t.BranchID,
+			&techName, &customer.ID, &customer.Name, &customer.Phone,
+			&bicycle.ID, &bicycle.Color, &bicycle.Brand.Name, &bicycle.Model.Name,
+			&t.PendingParts,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan board ticket: %w", err)
+		}
+
+		if t.TechnicianID != 0 {
+			t.Technician = &domain.User{ID: t.TechnicianID, Name: techName}
+		}
+		t.Booking = &domain.Booking{ID: t.BookingID, CustomerID: customer.ID, BicycleID: bicycle.ID}
+		if customer.ID != 0 {
+			t.Booking.Customer = customer
+		}
+		if bicycle.ID != 0 {
+			t.Booking.Bicycle = bicycle
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+func (r *TicketRepo) QueueHours(ctx context.Context, branchID int64) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(COALESCE(NULLIF(s.estimated_hours, 0), ?)), 0)
+		FROM tickets t
+		LEFT JOIN bookings b ON t.booking_id = b.id
+		LEFT JOIN services s ON b.service_id = s.id
+		WHERE t.status NOT IN (?, ?) AND COALESCE(t.branch_id, 1) = ?
+	`
+	var hours float64
+	err := r.db.QueryRowContext(ctx, query, domain.DefaultServiceHours,
+		domain.TicketStatusReady, domain.TicketStatusDelivered, branchID).Scan(&hours)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum queued hours: %w", err)
+	}
+	return hours, nil
+}
+
+func (r *TicketRepo) OpenWorkByTechnician(ctx context.Context, branchID int64) (map[int64]domain.Workload, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.technician_id, COUNT(*), COALESCE(SUM(COALESCE(NULLIF(s.estimated_hours, 0), ?)), 0)
+		FROM tickets t
+		LEFT JOIN bookings b ON t.booking_id = b.id
+		LEFT JOIN services s ON b.service_id = s.id
+		WHERE t.status NOT IN (?, ?) AND COALESCE(t.technician_id, 0) != 0
+			AND (? = 0 OR COALESCE(t.branch_id, 1) = ?)
+		GROUP BY t.technician_id
+	`, domain.DefaultServiceHours, domain.TicketStatusReady, domain.TicketStatusDelivered, branchID, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum open work: %w", err)
+	}
+	defer rows.Close()
+
+	work := make(map[int64]domain.Workload)
+	for rows.Next() {
+		var w domain.Workload
+		if err := rows.Scan(&w.Technician.ID, &w.OpenTickets, &w.OpenHours); err != nil {
+			return nil, fmt.Errorf("failed to scan open work: %w", err)
+		}
+		work[w.Technician.ID] = w
+	}
+	return work, rows.Err()
+}
+
+func (r *TicketRepo) ListOpen(ctx context.Context, branchID int64) ([]domain.Ticket, error) {
+	return r.listDetailed(ctx, `t.status NOT IN (?, ?) AND (? = 0 OR COALESCE(t.branch_id, 1) = ?)`,
+		domain.TicketStatusReady, domain.TicketStatusDelivered, branchID, branchID)
+}
+
+func (r *TicketRepo) ListReceivedBetween(ctx context.Context, branchID int64, from, to time.Time) ([]domain.Ticket, error) {
+	return r.listDetailed(ctx, `t.created_at >= ? AND t.created_at < ? AND (? = 0 OR COALESCE(t.branch_id, 1) = ?)`,
+		from, to, branchID, branchID)
+}
+
+// listDetailed returns the tickets matching where, oldest first, with their
+// technician and the customer and service of their booking
+func (r *TicketRepo) listDetailed(ctx context.Context, where string, args ...interface{}) ([]domain.Ticket, error) {
+	query := `
+		SELECT t.id, t.booking_id, COALESCE(t.technician_id, 0), t.tracking_code, t.status,
+			   t.promised_at, t.created_at, t.updated_at, COALESCE(t.branch_id, 1),
+			   COALESCE(u.name, ''), COALESCE(c.id, 0), COALESCE(c.name, ''),
+			   COALESCE(s.id, 0), COALESCE(s.name, ''), COALESCE(s.estimated_hours, 0)
+		FROM tickets t
+		LEFT JOIN users u ON t.technician_id = u.id
+		LEFT JOIN bookings b ON t.booking_id = b.id
+		LEFT JOIN users c ON b.customer_id = c.id
+		LEFT JOIN services s ON b.service_id = s.id
+		WHERE ` + where + `
+		ORDER BY t.created_at ASC, t.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var tickets []domain.Ticket
+	for rows.Next() {
+		var t domain.Ticket
+		var promisedAt sql.NullTime
+		var techName string
+		customer := &domain.User{}
+		service := &domain.Service{}
+		if err := rows.Scan(
+			&t.ID, &t.BookingID, &t.TechnicianID, &t.TrackingCode, &t.Status,
+			&promisedAt, &t.CreatedAt, &t.UpdatedAt, &t.BranchID,
+			&techName, &customer.ID, &customer.Name,
+			&service.ID, &service.Name, &service.EstimatedHours,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+
+		if promisedAt.Valid {
+			t.PromisedAt = &promisedAt.Time
+		}
+		if t.TechnicianID != 0 {
+			t.Technician = &domain.User{ID: t.TechnicianID, Name: techName}
+		}
+		t.Booking = &domain.Booking{ID: t.BookingID, CustomerID: customer.ID, ServiceID: service.ID}
+		if customer.ID != 0 {
+			t.Booking.Customer = customer
+		}
+		if service.ID != 0 {
+			t.Booking.Service = service
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
 func (r *TicketRepo) scanTicketsSimple(rows *sql.Rows) ([]domain.Ticket, error) {
 	var tickets []domain.Ticket
 	for rows.Next() {
 		var t domain.Ticket
 		var techID sql.NullInt64
-		var notes sql.NullString
 
 		if err := rows.Scan(
 			&t.ID, &t.BookingID, &techID, &t.TrackingCode,
-			&t.Status, &notes, &t.CreatedAt, &t.UpdatedAt,
+			&t.Status, &t.CreatedAt, &t.UpdatedAt, &t.BranchID,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan ticket: %w", err)
 		}
@@ -280,9 +472,6 @@ func (r *TicketRepo) scanTicketsSimple(rows *sql.Rows) ([]domain.Ticket, error)
 		if techID.Valid {
 			t.TechnicianID = techID.Int64
 		}
-		if notes.Valid {
-			t.Notes = notes.String
-		}
 
 		tickets = append(tickets, t)
 	}
@@ -309,6 +498,39 @@ func (r *TicketRepo) CreateStatusHistory(ctx context.Context, history *domain.Ti
 	return nil
 }
 
+func (r *TicketRepo) GetStatusHistories(ctx context.Context, ticketIDs []int64) (map[int64][]domain.TicketStatusHistory, error) {
+	histories := make(map[int64][]domain.TicketStatusHistory)
+	if len(ticketIDs) == 0 {
+		return histories, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ticketIDs)), ",")
+	args := make([]interface{}, len(ticketIDs))
+	for i, id := range ticketIDs {
+		args[i] = id
+	}
+	query := `
+		SELECT id, ticket_id, status, COALESCE(changed_by, 0), COALESCE(notes, ''), created_at
+		FROM ticket_status_history
+		WHERE ticket_id IN (` + placeholders + `)
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket status histories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h domain.TicketStatusHistory
+		if err := rows.Scan(&h.ID, &h.TicketID, &h.Status, &h.ChangedBy, &h.Notes, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket status history: %w", err)
+		}
+		histories[h.TicketID] = append(histories[h.TicketID], h)
+	}
+	return histories, rows.Err()
+}
+
 func (r *TicketRepo) GetStatusHistory(ctx context.Context, ticketID int64) ([]domain.TicketStatusHistory, error) {
 	query := `
 		SELECT h.id, h.ticket_id, h.status, h.changed_by, h.notes, h.created_at,
@@ -356,10 +578,25 @@ func (r *TicketRepo) GetStatusHistory(ctx context.Context, ticketID int64) ([]do
 
 // CreateTicketPart creates a new ticket part
 func (r *TicketRepo) CreateTicketPart(ctx context.Context, part *domain.TicketPart) error {
-	query := `INSERT INTO ticket_parts (ticket_id, name, status, created_at) VALUES (?, ?, ?, ?)`
+	query := `
+		INSERT INTO ticket_parts (ticket_id, name, status, inventory_item_id, quantity, location_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
 	now := time.Now()
 
-	result, err := r.db.ExecContext(ctx, query, part.TicketID, part.Name, "pending", now)
+	if part.Quantity < 1 {
+		part.Quantity = 1
+	}
+	var itemID, locationID interface{}
+	if part.InventoryItemID != 0 {
+		itemID = part.InventoryItemID
+		if part.LocationID == 0 {
+			part.LocationID = domain.DefaultStockLocationID
+		}
+		locationID = part.LocationID
+	}
+
+	result, err := r.db.ExecContext(ctx, query, part.TicketID, part.Name, "pending", itemID, part.Quantity, locationID, now)
 	if err != nil {
 		return fmt.Errorf("failed to create ticket part: %w", err)
 	}
@@ -375,53 +612,166 @@ func (r *TicketRepo) CreateTicketPart(ctx context.Context, part *domain.TicketPa
 	return nil
 }
 
+const ticketPartSelect = `
+	SELECT p.id, p.ticket_id, p.name, p.status, p.inventory_item_id, i.sku, COALESCE(p.quantity, 1), p.location_id, p.created_at
+	FROM ticket_parts p
+	LEFT JOIN inventory_items i ON p.inventory_item_id = i.id
+`
+
 // GetTicketParts returns all parts for a ticket
 func (r *TicketRepo) GetTicketParts(ctx context.Context, ticketID int64) ([]domain.TicketPart, error) {
-	query := `SELECT id, ticket_id, name, status, created_at FROM ticket_parts WHERE ticket_id = ? ORDER BY created_at ASC`
-
-	rows, err := r.db.QueryContext(ctx, query, ticketID)
+	parts, err := r.queryParts(ctx, ticketPartSelect+` WHERE p.ticket_id = ? ORDER BY p.created_at ASC`, ticketID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ticket parts: %w", err)
 	}
+	return parts, nil
+}
+
+// GetTicketPart returns a single ticket part
+func (r *TicketRepo) GetTicketPart(ctx context.Context, id int64) (*domain.TicketPart, error) {
+	parts, err := r.queryParts(ctx, ticketPartSelect+` WHERE p.id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket part: %w", err)
+	}
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	return &parts[0], nil
+}
+
+// GetOpenPartsByItem returns pending parts of an item on active tickets, oldest first
+func (r *TicketRepo) GetOpenPartsByItem(ctx context.Context, itemID int64) ([]domain.TicketPart, error) {
+	query := ticketPartSelect + `
+		JOIN tickets t ON p.ticket_id = t.id
+		WHERE p.inventory_item_id = ? AND p.status = 'pending' AND t.status NOT IN (?, ?)
+		ORDER BY p.created_at ASC, p.id ASC
+	`
+	parts, err := r.queryParts(ctx, query, itemID, domain.TicketStatusReady, domain.TicketStatusDelivered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open parts by item: %w", err)
+	}
+	return parts, nil
+}
+
+func (r *TicketRepo) queryParts(ctx context.Context, query string, args ...interface{}) ([]domain.TicketPart, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var parts []domain.TicketPart
 	for rows.Next() {
 		var p domain.TicketPart
-		if err := rows.Scan(&p.ID, &p.TicketID, &p.Name, &p.Status, &p.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan ticket part: %w", err)
+		var itemID, locationID sql.NullInt64
+		var sku sql.NullString
+		if err := rows.Scan(&p.ID, &p.TicketID, &p.Name, &p.Status, &itemID, &sku, &p.Quantity, &locationID, &p.CreatedAt); err != nil {
+			return nil, err
 		}
+		p.InventoryItemID = itemID.Int64
+		p.SKU = sku.String
+		p.LocationID = locationID.Int64
 		parts = append(parts, p)
 	}
-	return parts, nil
+	return parts, rows.Err()
 }
 
-// ToggleTicketPartStatus toggles the status of a ticket part
+// ToggleTicketPartStatus toggles the status of a ticket part. Inventory parts
+// consume stock when marked done and return it when reopened.
 func (r *TicketRepo) ToggleTicketPartStatus(ctx context.Context, id int64) error {
-	// First get current status
-	var status string
-	err := r.db.QueryRowContext(ctx, "SELECT status FROM ticket_parts WHERE id = ?", id).Scan(&status)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	part, err := getPartTx(ctx, tx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get ticket part status: %w", err)
 	}
 
 	newStatus := "done"
-	if status == "done" {
+	if part.Status == "done" {
 		newStatus = "pending"
 	}
 
-	_, err = r.db.ExecContext(ctx, "UPDATE ticket_parts SET status = ? WHERE id = ?", newStatus, id)
-	if err != nil {
+	if part.InventoryItemID != 0 {
+		movement := &domain.StockMovement{
+			ItemID:       part.InventoryItemID,
+			LocationID:   part.LocationID,
+			Quantity:     -part.Quantity,
+			Reason:       domain.MovementReasonConsume,
+			TicketID:     part.TicketID,
+			TicketPartID: part.ID,
+		}
+		if newStatus == "pending" {
+			movement.Quantity = part.Quantity
+			movement.Reason = domain.MovementReasonReturn
+		}
+		if err := applyStockMovement(ctx, tx, movement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE ticket_parts SET status = ? WHERE id = ?", newStatus, id); err != nil {
 		return fmt.Errorf("failed to update ticket part status: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ticket part status: %w", err)
+	}
 	return nil
 }
 
-// DeleteTicketPart deletes a ticket part
+// DeleteTicketPart deletes a ticket part, returning consumed stock
 func (r *TicketRepo) DeleteTicketPart(ctx context.Context, id int64) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM ticket_parts WHERE id = ?", id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	part, err := getPartTx(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
+		return fmt.Errorf("failed to get ticket part: %w", err)
+	}
+
+	if part.InventoryItemID != 0 && part.Status == "done" {
+		if err := applyStockMovement(ctx, tx, &domain.StockMovement{
+			ItemID:     part.InventoryItemID,
+			LocationID: part.LocationID,
+			Quantity:   part.Quantity,
+			Reason:     domain.MovementReasonReturn,
+			TicketID:   part.TicketID,
+			Notes:      "Repuesto eliminado del ticket: " + part.Name,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ticket_parts WHERE id = ?", id); err != nil {
 		return fmt.Errorf("failed to delete ticket part: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ticket part deletion: %w", err)
+	}
 	return nil
 }
+
+func getPartTx(ctx context.Context, tx *sql.Tx, id int64) (*domain.TicketPart, error) {
+	var p domain.TicketPart
+	var itemID, locationID sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, ticket_id, name, status, inventory_item_id, COALESCE(quantity, 1), location_id FROM ticket_parts WHERE id = ?`,
+		id).Scan(&p.ID, &p.TicketID, &p.Name, &p.Status, &itemID, &p.Quantity, &locationID)
+	if err != nil {
+		return nil, err
+	}
+	p.InventoryItemID = itemID.Int64
+	p.LocationID = locationID.Int64
+	return &p, nil
+}
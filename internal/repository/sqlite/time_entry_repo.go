@@ -0,0 +1,286 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// TimeEntryRepo implements repository.TimeEntryRepository
+type TimeEntryRepo struct {
+	db *DB
+}
+
+// NewTimeEntryRepo creates a new TimeEntryRepo
+func NewTimeEntryRepo(db *DB) repository.TimeEntryRepository {
+	return &TimeEntryRepo{db: db}
+}
+
+const timeEntrySelect = `
+	SELECT e.id, e.ticket_id, e.technician_id, COALESCE(u.name, ''), e.started_at, e.ended_at,
+		e.end_reason, e.notes, COALESCE(e.quote_id, 0), e.created_at
+	FROM time_entries e
+	LEFT JOIN users u ON e.technician_id = u.id
+`
+
+func scanTimeEntry(row rowScanner) (*domain.TimeEntry, error) {
+	var e domain.TimeEntry
+	var technicianName string
+	var endedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.TicketID, &e.TechnicianID, &technicianName, &e.StartedAt, &endedAt,
+		&e.EndReason, &e.Notes, &e.QuoteID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		e.EndedAt = &endedAt.Time
+	}
+	e.Technician = &domain.User{ID: e.TechnicianID, Name: technicianName}
+	return &e, nil
+}
+
+func (r *TimeEntryRepo) Create(ctx context.Context, entry *domain.TimeEntry) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO time_entries (ticket_id, technician_id, started_at, ended_at, end_reason, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.TicketID, entry.TechnicianID, entry.StartedAt, entry.EndedAt, entry.EndReason, entry.Notes, now)
+	if err != nil {
+		return fmt.Errorf("failed to create time entry: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get time entry ID: %w", err)
+	}
+	entry.ID = id
+	entry.CreatedAt = now
+	return nil
+}
+
+func (r *TimeEntryRepo) GetByID(ctx context.Context, id int64) (*domain.TimeEntry, error) {
+	entry, err := scanTimeEntry(r.db.QueryRowContext(ctx, timeEntrySelect+` WHERE e.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *TimeEntryRepo) ListByTicket(ctx context.Context, ticketID int64) ([]domain.TimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx, timeEntrySelect+` WHERE e.ticket_id = ? ORDER BY e.started_at DESC, e.id DESC`,
+		ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list time entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.TimeEntry
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *TimeEntryRepo) EndRunning(ctx context.Context, technicianID, ticketID int64, reason string) (int64, error) {
+	conditions := []string{"ended_at IS NULL"}
+	args := []interface{}{time.Now(), reason}
+	if technicianID != 0 {
+		conditions = append(conditions, "technician_id = ?")
+		args = append(args, technicianID)
+	}
+	if ticketID != 0 {
+		conditions = append(conditions, "ticket_id = ?")
+		args = append(args, ticketID)
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE time_entries SET ended_at = ?, end_reason = ? WHERE `+
+		strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to end running timers: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *TimeEntryRepo) SetEndReason(ctx context.Context, id int64, reason string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE time_entries SET end_reason = ? WHERE id = ? AND ended_at IS NOT NULL`,
+		reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to update time entry: %w", err)
+	}
+	return nil
+}
+
+func (r *TimeEntryRepo) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM time_entries WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete time entry: %w", err)
+	}
+	return nil
+}
+
+func (r *TimeEntryRepo) BillLabor(ctx context.Context, ticketID, bookingID int64, rate float64, validUntil time.Time) (*domain.Quote, *domain.Quote, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	quote := &domain.Quote{}
+	var before *domain.Quote
+	var itemsJSON string
+	var rejectionReason sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, booking_id, items_json, total, status, rejection_reason, valid_until, created_at
+		FROM quotes WHERE booking_id = ? ORDER BY created_at DESC LIMIT 1
+	`, bookingID).Scan(&quote.ID, &quote.BookingID, &itemsJSON, &quote.Total,
+		&quote.Status, &rejectionReason, &quote.ValidUntil, &quote.CreatedAt)
+	switch {
+	case err == sql.ErrNoRows:
+		quote = &domain.Quote{
+			BookingID:  bookingID,
+			Status:     domain.QuoteStatusPending,
+			ValidUntil: validUntil,
+			CreatedAt:  time.Now(),
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO quotes (booking_id, items_json, total, status, valid_until, created_at)
+			VALUES (?, '[]', 0, ?, ?, ?)
+		`, quote.BookingID, quote.Status, quote.ValidUntil, quote.CreatedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create quote: %w", err)
+		}
+		quote.ID, _ = result.LastInsertId()
+	case err != nil:
+		return nil, nil, fmt.Errorf("failed to get quote by booking: %w", err)
+	default:
+		if err := json.Unmarshal([]byte(itemsJSON), &quote.Items); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal quote items: %w", err)
+		}
+		quote.RejectionReason = rejectionReason.String
+		if quote.Status != domain.QuoteStatusPending {
+			return nil, nil, repository.ErrQuoteClosed
+		}
+		previous := *quote
+		previous.Items = append([]domain.QuoteItem(nil), quote.Items...)
+		before = &previous
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, started_at, ended_at FROM time_entries
+		WHERE ticket_id = ? AND ended_at IS NOT NULL AND quote_id IS NULL
+	`, ticketID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list unbilled time: %w", err)
+	}
+	var entries []domain.TimeEntry
+	for rows.Next() {
+		var e domain.TimeEntry
+		var endedAt time.Time
+		if err := rows.Scan(&e.ID, &e.StartedAt, &endedAt); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		e.EndedAt = &endedAt
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list unbilled time: %w", err)
+	}
+
+	// Only the entries this update claims are billed, so time already on a
+	// quote is never added again
+	var billed time.Duration
+	for i := range entries {
+		result, err := tx.ExecContext(ctx, `UPDATE time_entries SET quote_id = ? WHERE id = ? AND quote_id IS NULL`,
+			quote.ID, entries[i].ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to mark time entries billed: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			billed += entries[i].Duration()
+		}
+	}
+	hours := domain.LaborHours(billed)
+	if hours == 0 {
+		return nil, nil, repository.ErrNothingToBill
+	}
+
+	item := domain.LaborItem(hours, rate)
+	quote.Items = append(quote.Items, item)
+	quote.Total += item.Total
+	itemsData, err := json.Marshal(quote.Items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal quote items: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE quotes SET items_json = ?, total = ? WHERE id = ?`,
+		itemsData, quote.Total, quote.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to update quote: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit labor billing: %w", err)
+	}
+	return quote, before, nil
+}
+
+func (r *TimeEntryRepo) LaborByService(ctx context.Context, branchID int64, from, to time.Time) ([]domain.LaborStat, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, COALESCE(s.name, ''), COALESCE(s.estimated_hours, 0), e.started_at, e.ended_at
+		FROM time_entries e
+		JOIN tickets t ON e.ticket_id = t.id
+		LEFT JOIN bookings b ON t.booking_id = b.id
+		LEFT JOIN services s ON b.service_id = s.id
+		WHERE e.ended_at IS NOT NULL AND t.created_at >= ? AND t.created_at < ?
+			AND (? = 0 OR COALESCE(t.branch_id, 1) = ?)
+	`, from, to, branchID, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logged labor: %w", err)
+	}
+	defer rows.Close()
+
+	// Durations are summed here since the stored times are not in a format
+	// SQLite's date functions read
+	stats := make(map[string]*domain.LaborStat)
+	counted := make(map[int64]bool)
+	for rows.Next() {
+		var ticketID int64
+		var service string
+		var estimated float64
+		var startedAt, endedAt time.Time
+		if err := rows.Scan(&ticketID, &service, &estimated, &startedAt, &endedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan logged labor: %w", err)
+		}
+		stat := stats[service]
+		if stat == nil {
+			stat = &domain.LaborStat{ServiceName: service}
+			stats[service] = stat
+		}
+		if !counted[ticketID] {
+			counted[ticketID] = true
+			stat.Tickets++
+			stat.EstimatedHours += estimated
+		}
+		stat.ActualHours += endedAt.Sub(startedAt).Hours()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.LaborStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ServiceName < result[j].ServiceName })
+	return result, nil
+}
@@ -0,0 +1,255 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// TransferRepo implements repository.BicycleTransferRepository
+type TransferRepo struct {
+	db *DB
+}
+
+// NewTransferRepo creates a new TransferRepo
+func NewTransferRepo(db *DB) repository.BicycleTransferRepository {
+	return &TransferRepo{db: db}
+}
+
+const transferSelect = `
+	SELECT t.id, t.bicycle_id, t.from_user_id, t.to_email, t.to_user_id, t.status, t.created_at, t.responded_at,
+		   u.name, u.email, b.color, b.serial_number, br.name, m.name
+	FROM bicycle_transfers t
+	LEFT JOIN users u ON t.from_user_id = u.id
+	LEFT JOIN bicycles b ON t.bicycle_id = b.id
+	LEFT JOIN brands br ON b.brand_id = br.id
+	LEFT JOIN models m ON b.model_id = m.id
+`
+
+func (r *TransferRepo) Create(ctx context.Context, transfer *domain.BicycleTransfer) error {
+	query := `
+		INSERT INTO bicycle_transfers (bicycle_id, from_user_id, to_email, status, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	transfer.ToEmail = strings.ToLower(strings.TrimSpace(transfer.ToEmail))
+	transfer.Status = domain.TransferStatusPending
+
+	result, err := r.db.ExecContext(ctx, query,
+		transfer.BicycleID, transfer.FromUserID, transfer.ToEmail, transfer.Status, now)
+	if err != nil {
+		return fmt.Errorf("failed to create bicycle transfer: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get bicycle transfer ID: %w", err)
+	}
+	transfer.ID = id
+	transfer.CreatedAt = now
+	return nil
+}
+
+func (r *TransferRepo) GetByID(ctx context.Context, id int64) (*domain.BicycleTransfer, error) {
+	transfers, err := r.query(ctx, transferSelect+` WHERE t.id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bicycle transfer: %w", err)
+	}
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+	return &transfers[0], nil
+}
+
+func (r *TransferRepo) GetPendingByBicycleID(ctx context.Context, bicycleID int64) (*domain.BicycleTransfer, error) {
+	transfers, err := r.query(ctx, transferSelect+` WHERE t.bicycle_id = ? AND t.status = ? ORDER BY t.created_at DESC LIMIT 1`,
+		bicycleID, domain.TransferStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending bicycle transfer: %w", err)
+	}
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+	return &transfers[0], nil
+}
+
+func (r *TransferRepo) ListPendingForEmail(ctx context.Context, email string) ([]domain.BicycleTransfer, error) {
+	transfers, err := r.query(ctx, transferSelect+` WHERE t.to_email = ? AND t.status = ? ORDER BY t.created_at DESC`,
+		strings.ToLower(strings.TrimSpace(email)), domain.TransferStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incoming bicycle transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+func (r *TransferRepo) ListPendingFromUser(ctx context.Context, userID int64) ([]domain.BicycleTransfer, error) {
+	transfers, err := r.query(ctx, transferSelect+` WHERE t.from_user_id = ? AND t.status = ? ORDER BY t.created_at DESC`,
+		userID, domain.TransferStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outgoing bicycle transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+func (r *TransferRepo) Accept(ctx context.Context, id, toUserID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var bicycleID, fromUserID int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT bicycle_id, from_user_id FROM bicycle_transfers WHERE id = ? AND status = ?`,
+		id, domain.TransferStatusPending).Scan(&bicycleID, &fromUserID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("bicycle transfer %d is not pending", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get bicycle transfer: %w", err)
+	}
+
+	now := time.Now()
+
+	// Guard against the bike having changed hands since the transfer started
+	result, err := tx.ExecContext(ctx,
+		`UPDATE bicycles SET user_id = ?, notes = '' WHERE id = ? AND user_id = ?`,
+		toUserID, bicycleID, fromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to move bicycle: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("bicycle %d no longer belongs to user %d", bicycleID, fromUserID)
+	}
+
+	// Bicycles registered before ownership tracking have no open entry yet
+	var open int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM bicycle_ownership_history WHERE bicycle_id = ? AND ended_at IS NULL`,
+		bicycleID).Scan(&open); err != nil {
+		return fmt.Errorf("failed to check ownership history: %w", err)
+	}
+	if open == 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO bicycle_ownership_history (bicycle_id, user_id, started_at, ended_at)
+			SELECT id, ?, created_at, ? FROM bicycles WHERE id = ?`,
+			fromUserID, now, bicycleID); err != nil {
+			return fmt.Errorf("failed to record previous owner: %w", err)
+		}
+	} else if _, err := tx.ExecContext(ctx,
+		`UPDATE bicycle_ownership_history SET ended_at = ? WHERE bicycle_id = ? AND ended_at IS NULL`,
+		now, bicycleID); err != nil {
+		return fmt.Errorf("failed to close ownership entry: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO bicycle_ownership_history (bicycle_id, user_id, transfer_id, started_at) VALUES (?, ?, ?, ?)`,
+		bicycleID, toUserID, id, now); err != nil {
+		return fmt.Errorf("failed to record new owner: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE bicycle_transfers SET status = ?, to_user_id = ?, responded_at = ? WHERE id = ?`,
+		domain.TransferStatusAccepted, toUserID, now, id); err != nil {
+		return fmt.Errorf("failed to update bicycle transfer: %w", err)
+	}
+
+	// Any other pending transfer of this bike is now stale
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE bicycle_transfers SET status = ?, responded_at = ? WHERE bicycle_id = ? AND status = ? AND id <> ?`,
+		domain.TransferStatusCancelled, now, bicycleID, domain.TransferStatusPending, id); err != nil {
+		return fmt.Errorf("failed to cancel stale transfers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit bicycle transfer: %w", err)
+	}
+	return nil
+}
+
+func (r *TransferRepo) UpdateStatus(ctx context.Context, id int64, status string) error {
+	query := `UPDATE bicycle_transfers SET status = ?, responded_at = ? WHERE id = ? AND status = ?`
+	_, err := r.db.ExecContext(ctx, query, status, time.Now(), id, domain.TransferStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update bicycle transfer status: %w", err)
+	}
+	return nil
+}
+
+func (r *TransferRepo) GetOwnershipHistory(ctx context.Context, bicycleID int64) ([]domain.BicycleOwnership, error) {
+	query := `
+		SELECT o.id, o.bicycle_id, o.user_id, o.transfer_id, o.started_at, o.ended_at, u.name
+		FROM bicycle_ownership_history o
+		LEFT JOIN users u ON o.user_id = u.id
+		WHERE o.bicycle_id = ?
+		ORDER BY o.started_at ASC, o.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, bicycleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []domain.BicycleOwnership
+	for rows.Next() {
+		var o domain.BicycleOwnership
+		var userID, transferID sql.NullInt64
+		var endedAt sql.NullTime
+		var userName sql.NullString
+		if err := rows.Scan(&o.ID, &o.BicycleID, &userID, &transferID, &o.StartedAt, &endedAt, &userName); err != nil {
+			return nil, fmt.Errorf("failed to scan ownership entry: %w", err)
+		}
+		o.UserID = userID.Int64
+		o.TransferID = transferID.Int64
+		if endedAt.Valid {
+			o.EndedAt = &endedAt.Time
+		}
+		o.User = &domain.User{ID: o.UserID, Name: userName.String}
+		history = append(history, o)
+	}
+	return history, rows.Err()
+}
+
+func (r *TransferRepo) query(ctx context.Context, query string, args ...interface{}) ([]domain.BicycleTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []domain.BicycleTransfer
+	for rows.Next() {
+		t := domain.BicycleTransfer{
+			FromUser: &domain.User{},
+			Bicycle:  &domain.Bicycle{Brand: &domain.Brand{}, Model: &domain.Model{}},
+		}
+		var toUserID sql.NullInt64
+		var respondedAt sql.NullTime
+		var fromName, fromEmail, color, serial, brandName, modelName sql.NullString
+		if err := rows.Scan(
+			&t.ID, &t.BicycleID, &t.FromUserID, &t.ToEmail, &toUserID, &t.Status, &t.CreatedAt, &respondedAt,
+			&fromName, &fromEmail, &color, &serial, &brandName, &modelName,
+		); err != nil {
+			return nil, err
+		}
+		t.ToUserID = toUserID.Int64
+		if respondedAt.Valid {
+			t.RespondedAt = &respondedAt.Time
+		}
+		t.FromUser.ID = t.FromUserID
+		t.FromUser.Name = fromName.String
+		t.FromUser.Email = fromEmail.String
+		t.Bicycle.ID = t.BicycleID
+		t.Bicycle.Color = color.String
+		t.Bicycle.SerialNumber = serial.String
+		t.Bicycle.Brand.Name = brandName.String
+		t.Bicycle.Model.Name = modelName.String
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
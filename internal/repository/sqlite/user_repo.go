@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
@@ -24,11 +25,11 @@ func NewUserRepo(db *DB) repository.UserRepository {
 
 func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, phone, role, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO users (email, password_hash, name, phone, role, email_verified, branch_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := r.db.ExecContext(ctx, query,
-		user.Email, user.PasswordHash, user.Name, user.Phone, user.Role, time.Now())
+		user.Email, user.PasswordHash, user.Name, user.Phone, user.Role, user.EmailVerified, user.BranchID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -42,42 +43,139 @@ func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, id int64) (*domain.User, error) {
-	query := `SELECT id, email, password_hash, name, phone, role, created_at FROM users WHERE id = ?`
+	query := `SELECT id, email, password_hash, name, phone, role, email_verified, COALESCE(totp_secret, ''), totp_enabled, created_at, COALESCE(branch_id, 0), COALESCE(skills, ''), COALESCE(daily_hours, 0) FROM users WHERE id = ?`
 	user := &domain.User{}
+	var skills string
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Phone, &user.Role, &user.CreatedAt)
+		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Phone, &user.Role, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.CreatedAt, &user.BranchID, &skills, &user.DailyHours)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.Skills = domain.ParseSkills(skills)
 	return user, nil
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `SELECT id, email, password_hash, name, phone, role, created_at FROM users WHERE email = ?`
+	query := `SELECT id, email, password_hash, name, phone, role, email_verified, COALESCE(totp_secret, ''), totp_enabled, created_at, COALESCE(branch_id, 0), COALESCE(skills, ''), COALESCE(daily_hours, 0) FROM users WHERE email = ?`
 	user := &domain.User{}
+	var skills string
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Phone, &user.Role, &user.CreatedAt)
+		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Phone, &user.Role, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.CreatedAt, &user.BranchID, &skills, &user.DailyHours)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
+	user.Skills = domain.ParseSkills(skills)
 	return user, nil
 }
 
+func (r *UserRepo) UpdateWorkProfile(ctx context.Context, id int64, skills []string, dailyHours float64) error {
+	query := `UPDATE users SET skills = ?, daily_hours = ? WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, strings.Join(skills, ","), dailyHours, id); err != nil {
+		return fmt.Errorf("failed to update work profile: %w", err)
+	}
+	return nil
+}
+
 func (r *UserRepo) Update(ctx context.Context, user *domain.User) error {
-	query := `UPDATE users SET email = ?, name = ?, phone = ?, role = ? WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, user.Email, user.Name, user.Phone, user.Role, user.ID)
+	query := `UPDATE users SET email = ?, name = ?, phone = ?, role = ?, branch_id = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, user.Email, user.Name, user.Phone, user.Role, user.BranchID, user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil
 }
 
+func (r *UserRepo) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id int64) error {
+	query := `UPDATE users SET email_verified = 1 WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdateTOTP(ctx context.Context, id int64, secret string, enabled bool) error {
+	query := `UPDATE users SET totp_secret = ?, totp_enabled = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, secret, enabled, id)
+	if err != nil {
+		return fmt.Errorf("failed to update two-factor settings: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepo) RecordTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = ? WHERE id = ? AND COALESCE(totp_last_step, 0) < ?`
+	result, err := r.db.ExecContext(ctx, query, step, id, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor step: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor step: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *UserRepo) ReplaceRecoveryCodes(ctx context.Context, id int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)`, id, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *UserRepo) UseRecoveryCode(ctx context.Context, id int64, codeHash string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes SET used_at = ?
+		WHERE id = (SELECT id FROM user_recovery_codes WHERE user_id = ? AND code_hash = ? AND used_at IS NULL LIMIT 1)
+	`, time.Now(), id, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *UserRepo) CountRecoveryCodes(ctx context.Context, id int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL`, id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
 func (r *UserRepo) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM users WHERE id = ?`
 	_, err := r.db.ExecContext(ctx, query, id)
@@ -92,10 +190,10 @@ func (r *UserRepo) List(ctx context.Context, role string, limit, offset int) ([]
 	var args []interface{}
 
 	if role != "" {
-		query = `SELECT id, email, password_hash, name, phone, role, created_at FROM users WHERE role = ? ORDER BY name LIMIT ? OFFSET ?`
+		query = `SELECT id, email, password_hash, name, phone, role, email_verified, COALESCE(totp_secret, ''), totp_enabled, created_at, COALESCE(branch_id, 0), COALESCE(skills, ''), COALESCE(daily_hours, 0) FROM users WHERE role = ? ORDER BY name LIMIT ? OFFSET ?`
 		args = []interface{}{role, limit, offset}
 	} else {
-		query = `SELECT id, email, password_hash, name, phone, role, created_at FROM users ORDER BY name LIMIT ? OFFSET ?`
+		query = `SELECT id, email, password_hash, name, phone, role, email_verified, COALESCE(totp_secret, ''), totp_enabled, created_at, COALESCE(branch_id, 0), COALESCE(skills, ''), COALESCE(daily_hours, 0) FROM users ORDER BY name LIMIT ? OFFSET ?`
 		args = []interface{}{limit, offset}
 	}
 
@@ -108,9 +206,11 @@ func (r *UserRepo) List(ctx context.Context, role string, limit, offset int) ([]
 	var users []domain.User
 	for rows.Next() {
 		var u domain.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Role, &u.CreatedAt); err != nil {
+		var skills string
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.Role, &u.EmailVerified, &u.TOTPSecret, &u.TOTPEnabled, &u.CreatedAt, &u.BranchID, &skills, &u.DailyHours); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		u.Skills = domain.ParseSkills(skills)
 		users = append(users, u)
 	}
 	return users, nil
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// UserTokenRepo implements repository.UserTokenRepository
+type UserTokenRepo struct {
+	db *DB
+}
+
+// NewUserTokenRepo creates a new UserTokenRepo
+func NewUserTokenRepo(db *DB) repository.UserTokenRepository {
+	return &UserTokenRepo{db: db}
+}
+
+func (r *UserTokenRepo) Create(ctx context.Context, token *domain.UserToken) error {
+	token.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get user token ID: %w", err)
+	}
+	token.ID = id
+	return nil
+}
+
+func (r *UserTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*domain.UserToken, error) {
+	var t domain.UserToken
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, purpose, token_hash, expires_at, used_at, created_at
+		FROM user_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt, &usedAt, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user token: %w", err)
+	}
+	if usedAt.Valid {
+		t.UsedAt = &usedAt.Time
+	}
+	return &t, nil
+}
+
+func (r *UserTokenRepo) Consume(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL
+	`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to consume user token: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to consume user token: %w", err)
+	}
+	if affected == 0 {
+		return repository.ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *UserTokenRepo) InvalidateForUser(ctx context.Context, userID int64, purpose string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_tokens SET used_at = ?
+		WHERE user_id = ? AND purpose = ? AND used_at IS NULL
+	`, time.Now(), userID, purpose)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// WebhookRepo implements repository.WebhookRepository
+type WebhookRepo struct {
+	db *DB
+}
+
+// NewWebhookRepo creates a new WebhookRepo
+func NewWebhookRepo(db *DB) repository.WebhookRepository {
+	return &WebhookRepo{db: db}
+}
+
+const webhookSelect = `SELECT id, name, url, secret, events, active, created_at FROM webhooks`
+
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var w domain.Webhook
+	var events string
+	if err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &w.Active, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	if events != "" {
+		w.Events = strings.Split(events, ",")
+	}
+	return &w, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook *domain.Webhook) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhooks (name, url, secret, events, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, webhook.Name, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Active, now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get webhook ID: %w", err)
+	}
+	webhook.ID = id
+	webhook.CreatedAt = now
+	return nil
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, webhookSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepo) List(ctx context.Context) ([]domain.Webhook, error) {
+	return r.list(ctx, webhookSelect+` ORDER BY name, id`)
+}
+
+func (r *WebhookRepo) ListForEvent(ctx context.Context, event string) ([]domain.Webhook, error) {
+	// events is a comma-separated list; the commas around it let LIKE match
+	// whole entries only
+	return r.list(ctx, webhookSelect+` WHERE active = 1 AND ',' || events || ',' LIKE ? ORDER BY id`,
+		"%,"+event+",%")
+}
+
+func (r *WebhookRepo) list(ctx context.Context, query string, args ...interface{}) ([]domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, webhook *domain.Webhook) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhooks SET name = ?, url = ?, secret = ?, events = ?, active = ? WHERE id = ?
+	`, webhook.Name, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Active, webhook.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook deletion: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliverySelect = `
+	SELECT id, webhook_id, event_id, event, payload, status, attempts, response_status, response_body, error,
+		next_attempt_at, delivered_at, created_at
+	FROM webhook_deliveries
+`
+
+func scanWebhookDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var nextAttemptAt, deliveredAt sql.NullTime
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.ResponseBody, &d.Error, &nextAttemptAt, &deliveredAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, delivery.WebhookID, delivery.EventID, delivery.Event, delivery.Payload, delivery.Status,
+		delivery.NextAttemptAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get webhook delivery ID: %w", err)
+	}
+	delivery.ID = id
+	delivery.CreatedAt = now
+	return nil
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, webhookDeliverySelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]domain.WebhookDelivery, error) {
+	return r.listDeliveries(ctx, r.db.DB, webhookDeliverySelect+` WHERE webhook_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`,
+		webhookID, limit)
+}
+
+// queryer is what listDeliveries needs from a database or a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (r *WebhookRepo) listDeliveries(ctx context.Context, q queryer, query string, args ...interface{}) ([]domain.WebhookDelivery, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deliveries, err := r.listDeliveries(ctx, tx, webhookDeliverySelect+`
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		domain.WebhookDeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	leased := now.Add(lease)
+	for i := range deliveries {
+		if _, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?`,
+			leased, deliveries[i].ID); err != nil {
+			return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook delivery claim: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_status = ?, response_body = ?, error = ?, next_attempt_at = ?,
+			delivered_at = ?
+		WHERE id = ?
+	`, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.ResponseBody, delivery.Error,
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/ratelimit"
+)
+
+// JSON API plumbing shared by every versioned API: error bodies, bearer
+// authentication, cursor pagination and ETags.
+
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+	apiMaxBodyBytes = 1 << 20
+
+	// apiScanLimit bounds the rows read when a list has to be filtered in
+	// memory (e.g. a customer's bookings) before paginating
+	apiScanLimit = 1000
+)
+
+// apiErrorBody is the body of every error response
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+// apiErrorDetail describes an error: code is stable and meant for programs,
+// message is English text meant for developers
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErrorBody{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// apiInternalError logs err and writes a 500 without details
+func apiInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("⚠️ API %s %s failed: %v", r.Method, r.URL.Path, err)
+	writeAPIError(w, http.StatusInternalServerError, "internal", "Internal server error")
+}
+
+// writeJSON writes v as JSON. Successful GETs carry a weak ETag of the body
+// and answer 304 when the client already has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet && status == http.StatusOK {
+		etag := etagOfBody(body)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// etagOf returns the ETag writeJSON sends for v
+func etagOf(v interface{}) string {
+	body, _ := json.Marshal(v)
+	return etagOfBody(body)
+}
+
+func etagOfBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match / If-Match header lists etag.
+// Comparison is weak: W/ prefixes are ignored.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// apiPreconditionFailed enforces If-Match on a change to current, the
+// resource as GET returns it. Without the header the change goes ahead.
+func apiPreconditionFailed(w http.ResponseWriter, r *http.Request, current interface{}) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, etagOf(current)) {
+		return false
+	}
+	writeAPIError(w, http.StatusPreconditionFailed, "precondition_failed", "The resource changed since it was read")
+	return true
+}
+
+// decodeJSON reads a JSON request body into v, rejecting unknown fields
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiPage is the window of a list requested with ?limit= and ?cursor=
+type apiPage struct {
+	Limit  int
+	Offset int
+}
+
+// apiList is the body of paginated responses. NextCursor is absent on the
+// last page.
+type apiList struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// errInvalidCursor is returned for cursors this server didn't issue
+var errInvalidCursor = errors.New("invalid cursor")
+
+// parsePage reads the limit and cursor query parameters
+func parsePage(r *http.Request) (apiPage, error) {
+	page := apiPage{Limit: apiDefaultLimit}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return page, errors.New("limit must be a positive integer")
+		}
+		page.Limit = min(limit, apiMaxLimit)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return page, errInvalidCursor
+		}
+		offset, ok := strings.CutPrefix(string(raw), "o:")
+		if !ok {
+			return page, errInvalidCursor
+		}
+		page.Offset, err = strconv.Atoi(offset)
+		if err != nil || page.Offset < 0 {
+			return page, errInvalidCursor
+		}
+	}
+	return page, nil
+}
+
+// apiPageParam parses the page of a list request, writing a 400 when the
+// parameters are invalid
+func apiPageParam(w http.ResponseWriter, r *http.Request) (apiPage, bool) {
+	page, err := parsePage(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
+		return page, false
+	}
+	return page, true
+}
+
+// cursorAt returns the opaque cursor of the page starting at offset
+func cursorAt(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+// pageOf builds the response for rows read with LIMIT page.Limit+1 OFFSET
+// page.Offset: the extra row only tells whether another page follows
+func pageOf[T any](rows []T, page apiPage) apiList {
+	list := apiList{Data: rows}
+	if len(rows) > page.Limit {
+		list.Data = rows[:page.Limit]
+		list.NextCursor = cursorAt(page.Offset + page.Limit)
+	}
+	if rows == nil {
+		list.Data = []T{}
+	}
+	return list
+}
+
+// sliceOf paginates a list that is already complete in memory
+func sliceOf[T any](all []T, page apiPage) apiList {
+	if page.Offset >= len(all) {
+		return apiList{Data: []T{}}
+	}
+	end := min(page.Offset+page.Limit+1, len(all))
+	return pageOf(all[page.Offset:end], page)
+}
+
+// apiAuthMiddleware authenticates API requests with a bearer access token.
+// Same-origin browser code may rely on the session cookies instead (state
+// changes then need the X-CSRF-Token header). Failures answer 401 instead of
+// redirecting to the login page.
+func (s *Server) apiAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims *Claims
+		if key := r.Header.Get(domain.APIKeyHeader); key != "" {
+			if claims = s.authenticateAPIKey(r.Context(), key); claims == nil {
+				writeAPIError(w, http.StatusUnauthorized, "invalid_api_key", "Unknown, expired or revoked API key")
+				return
+			}
+		} else if header := r.Header.Get("Authorization"); header != "" {
+			scheme, token, _ := strings.Cut(header, " ")
+			if strings.EqualFold(scheme, "Bearer") {
+				claims = s.validateAccessToken(r.Context(), strings.TrimSpace(token))
+			}
+		} else {
+			if cookie, err := r.Cookie("auth_token"); err == nil {
+				claims = s.validateAccessToken(r.Context(), cookie.Value)
+			}
+			if claims == nil {
+				claims = s.refreshSession(w, r)
+			}
+		}
+		if claims == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Missing, invalid or expired access token")
+			return
+		}
+
+		// Same 2FA policy as the web: staff without it are held back. API
+		// keys can't answer a second factor and are issued by admins instead.
+		if claims.APIKeyID == 0 && s.twoFactorRequired(r.Context(), claims.Role) {
+			user, err := s.repos.Users.GetByID(r.Context(), claims.UserID)
+			if err != nil || user == nil || !user.TOTPEnabled {
+				writeAPIError(w, http.StatusForbidden, "two_factor_required", "Two-factor authentication must be enabled for this account")
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiRequirePermission lets the request through when the user holds at
+// least one of the permissions
+func (s *Server) apiRequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.currentRole(r).HasAny(permissions...) {
+				writeAPIError(w, http.StatusForbidden, "forbidden", "Missing permission: "+strings.Join(permissions, " or "))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiRateLimit limits each client IP to requestsPerMinute requests on the
+// routes it wraps, answering with a JSON 429
+func apiRateLimit(requestsPerMinute int) func(http.Handler) http.Handler {
+	limiter := ratelimit.NewLimiter(float64(requestsPerMinute), requestsPerMinute, maxRateLimitKeys)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := limiter.Allow(clientIP(r)); !ok {
+				setRetryAfter(w, wait)
+				writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiNotFound and apiMethodNotAllowed keep unknown API routes in JSON
+func apiNotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "No such endpoint")
+}
+
+func apiMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed on this endpoint")
+}
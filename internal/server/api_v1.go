@@ -0,0 +1,965 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiV1Prefix is where version 1 of the JSON API is mounted
+const apiV1Prefix = "/api/v1"
+
+// apiV1Operations lists every endpoint of version 1 of the API. The table
+// registers the routes and generates the OpenAPI document, so the two can't
+// drift apart.
+func (s *Server) apiV1Operations() []apiOperation {
+	statusFilter := apiParam{Name: "status", Description: "Only items in this status"}
+	branchFilter := apiParam{Name: "branch_id", Integer: true,
+		Description: "Only items of this branch. Staff pinned to a branch always get theirs."}
+
+	return []apiOperation{
+		// Documentation and authentication
+		{Method: http.MethodGet, Path: "/openapi.json", ID: "getOpenAPI", Tag: "meta", Public: true,
+			Summary: "This OpenAPI document", Response: map[string]interface{}{}, Handler: s.apiGetOpenAPI},
+		{Method: http.MethodPost, Path: "/auth/token", ID: "createToken", Tag: "auth", Public: true, RateLimit: 20,
+			Summary: "Sign in with email and password (and a 2FA code when enabled)",
+			Request: apiTokenRequest{}, Response: apiTokenResponse{}, Handler: s.apiCreateToken},
+		{Method: http.MethodPost, Path: "/auth/refresh", ID: "refreshToken", Tag: "auth", Public: true, RateLimit: 60,
+			Summary: "Exchange a refresh token for a new access token and a rotated refresh token",
+			Request: apiRefreshRequest{}, Response: apiTokenResponse{}, Handler: s.apiRefreshToken},
+		{Method: http.MethodPost, Path: "/auth/revoke", ID: "revokeToken", Tag: "auth", Public: true, RateLimit: 60,
+			Summary: "Sign out the session of a refresh token", Status: http.StatusNoContent,
+			Request: apiRefreshRequest{}, Handler: s.apiRevokeToken},
+
+		// Users
+		{Method: http.MethodGet, Path: "/users/me", ID: "getCurrentUser", Tag: "users", Scope: domain.ScopeReadUsers,
+			Summary: "The signed-in user", Response: domain.User{}, Handler: s.apiGetCurrentUser},
+		{Method: http.MethodGet, Path: "/users", ID: "listUsers", Tag: "users", Scope: domain.ScopeReadUsers, List: true,
+			Permissions: []string{domain.PermUsersManage},
+			Params:      []apiParam{{Name: "role", Description: "Only users with this role"}},
+			Summary:     "List users", Response: domain.User{}, Handler: s.apiListUsers},
+		{Method: http.MethodGet, Path: "/users/{id}", ID: "getUser", Tag: "users", Scope: domain.ScopeReadUsers,
+			Permissions: []string{domain.PermUsersManage},
+			Summary:     "Get a user", Response: domain.User{}, Handler: s.apiGetUser},
+
+		// Bicycles
+		{Method: http.MethodGet, Path: "/bicycles", ID: "listBicycles", Tag: "bicycles", Scope: domain.ScopeReadBicycles, List: true,
+			Permissions: []string{domain.PermBookingsView},
+			Params: []apiParam{{Name: "user_id", Integer: true,
+				Description: "Owner of the bicycles; defaults to the signed-in user"}},
+			Summary: "List a user's bicycles", Response: domain.Bicycle{}, Handler: s.apiListBicycles},
+		{Method: http.MethodGet, Path: "/bicycles/{id}", ID: "getBicycle", Tag: "bicycles", Scope: domain.ScopeReadBicycles,
+			Permissions: []string{domain.PermBookingsView},
+			Summary:     "Get a bicycle", Response: domain.Bicycle{}, Handler: s.apiGetBicycle},
+
+		// Bookings
+		{Method: http.MethodGet, Path: "/bookings", ID: "listBookings", Tag: "bookings", Scope: domain.ScopeReadBookings, List: true,
+			Permissions: []string{domain.PermBookingsView},
+			Params: []apiParam{statusFilter, branchFilter, {Name: "customer_id", Integer: true,
+				Description: "Only bookings of this customer. Customers only ever see their own."}},
+			Summary: "List bookings, newest first", Response: domain.Booking{}, Handler: s.apiListBookings},
+		{Method: http.MethodPost, Path: "/bookings", ID: "createBooking", Tag: "bookings", Scope: domain.ScopeWriteBookings,
+			Permissions: []string{domain.PermBookingsView}, Status: http.StatusCreated,
+			Summary: "Book a slot for the signed-in customer",
+			Request: apiBookingRequest{}, Response: domain.Booking{}, Handler: s.apiCreateBooking},
+		{Method: http.MethodGet, Path: "/bookings/{id}", ID: "getBooking", Tag: "bookings", Scope: domain.ScopeReadBookings,
+			Permissions: []string{domain.PermBookingsView},
+			Summary:     "Get a booking", Response: domain.Booking{}, Handler: s.apiGetBooking},
+		{Method: http.MethodPost, Path: "/bookings/{id}/cancel", ID: "cancelBooking", Tag: "bookings", Scope: domain.ScopeWriteBookings,
+			Permissions: []string{domain.PermBookingsView}, IfMatch: true,
+			Summary: "Cancel a booking", Response: domain.Booking{}, Handler: s.apiCancelBooking},
+
+		// Quotes
+		{Method: http.MethodGet, Path: "/quotes", ID: "listQuotes", Tag: "quotes", Scope: domain.ScopeReadQuotes, List: true,
+			Permissions: []string{domain.PermBookingsView}, Params: []apiParam{statusFilter, branchFilter},
+			Summary: "List quotes, newest first", Response: domain.Quote{}, Handler: s.apiListQuotes},
+		{Method: http.MethodGet, Path: "/quotes/{id}", ID: "getQuote", Tag: "quotes", Scope: domain.ScopeReadQuotes,
+			Permissions: []string{domain.PermBookingsView},
+			Summary:     "Get a quote", Response: domain.Quote{}, Handler: s.apiGetQuote},
+		{Method: http.MethodPost, Path: "/quotes/{id}/approve", ID: "approveQuote", Tag: "quotes", Scope: domain.ScopeWriteQuotes,
+			Permissions: []string{domain.PermBookingsView}, IfMatch: true,
+			Summary: "Approve a pending quote", Response: domain.Quote{}, Handler: s.apiApproveQuote},
+		{Method: http.MethodPost, Path: "/quotes/{id}/reject", ID: "rejectQuote", Tag: "quotes", Scope: domain.ScopeWriteQuotes,
+			Permissions: []string{domain.PermBookingsView}, IfMatch: true,
+			Summary: "Reject a pending quote",
+			Request: apiRejectQuoteRequest{}, Response: domain.Quote{}, Handler: s.apiRejectQuote},
+
+		// Tickets and their parts
+		{Method: http.MethodGet, Path: "/tickets", ID: "listTickets", Tag: "tickets", Scope: domain.ScopeReadTickets, List: true,
+			Permissions: []string{domain.PermWorkshopView}, Params: []apiParam{statusFilter, branchFilter},
+			Summary: "List tickets, newest first", Response: domain.Ticket{}, Handler: s.apiListTickets},
+		{Method: http.MethodGet, Path: "/tickets/{id}", ID: "getTicket", Tag: "tickets", Scope: domain.ScopeReadTickets,
+			Permissions: []string{domain.PermWorkshopView, domain.PermBookingsView},
+			Summary:     "Get a ticket", Response: domain.Ticket{}, Handler: s.apiGetTicket},
+		{Method: http.MethodPost, Path: "/tickets/{id}/status", ID: "changeTicketStatus", Tag: "tickets", Scope: domain.ScopeWriteTickets,
+			Permissions: []string{domain.PermTicketsEdit}, IfMatch: true,
+			Summary: "Move a ticket to another status, following the workflow",
+			Request: apiTicketStatusRequest{}, Response: domain.Ticket{}, Handler: s.apiChangeTicketStatus},
+		{Method: http.MethodGet, Path: "/tickets/{id}/parts", ID: "listTicketParts", Tag: "parts", Scope: domain.ScopeReadTickets, List: true,
+			Permissions: []string{domain.PermWorkshopView},
+			Summary:     "List the parts of a ticket", Response: domain.TicketPart{}, Handler: s.apiListTicketParts},
+		{Method: http.MethodPost, Path: "/tickets/{id}/parts", ID: "addTicketPart", Tag: "parts", Scope: domain.ScopeWriteTickets,
+			Permissions: []string{domain.PermTicketsEdit}, Status: http.StatusCreated,
+			Summary: "Add a part to a ticket",
+			Request: apiTicketPartRequest{}, Response: domain.TicketPart{}, Handler: s.apiAddTicketPart},
+
+		// Surveys
+		{Method: http.MethodGet, Path: "/surveys", ID: "listSurveys", Tag: "surveys", Scope: domain.ScopeReadSurveys, List: true,
+			Permissions: []string{domain.PermReportsView},
+			Summary:     "List survey answers, newest first", Response: domain.Survey{}, Handler: s.apiListSurveys},
+		{Method: http.MethodPost, Path: "/tickets/{id}/survey", ID: "submitSurvey", Tag: "surveys", Scope: domain.ScopeWriteSurveys,
+			Permissions: []string{domain.PermBookingsView}, Status: http.StatusCreated,
+			Summary: "Rate the service of a ready or delivered ticket",
+			Request: apiSurveyRequest{}, Response: domain.Survey{}, Handler: s.apiSubmitSurvey},
+
+		// Catalog
+		{Method: http.MethodGet, Path: "/brands", ID: "listBrands", Tag: "catalog", Scope: domain.ScopeReadCatalog, List: true,
+			Summary: "List bicycle brands", Response: domain.Brand{}, Handler: s.apiListBrands},
+		{Method: http.MethodGet, Path: "/brands/{id}/models", ID: "listBrandModels", Tag: "catalog", Scope: domain.ScopeReadCatalog, List: true,
+			Summary: "List the models of a brand", Response: domain.Model{}, Handler: s.apiListBrandModels},
+		{Method: http.MethodGet, Path: "/services", ID: "listServices", Tag: "catalog", Scope: domain.ScopeReadCatalog, List: true,
+			Summary: "List the services offered", Response: domain.Service{}, Handler: s.apiListServices},
+	}
+}
+
+// setupAPIv1Routes mounts version 1 of the JSON API
+func (s *Server) setupAPIv1Routes(r chi.Router) {
+	r.Route(apiV1Prefix, func(r chi.Router) {
+		r.NotFound(apiNotFound)
+		r.MethodNotAllowed(apiMethodNotAllowed)
+
+		for _, op := range s.apiV1Operations() {
+			handler := http.Handler(op.Handler)
+			if len(op.Permissions) > 0 {
+				handler = s.apiRequirePermission(op.Permissions...)(handler)
+			}
+			if op.Scope != "" {
+				handler = s.requireScope(op.Scope)(handler)
+			}
+			if !op.Public {
+				handler = s.apiAuthMiddleware(handler)
+			}
+			if op.RateLimit > 0 {
+				handler = apiRateLimit(op.RateLimit)(handler)
+			}
+			r.Method(op.Method, op.Path, handler)
+		}
+	})
+}
+
+// Request and response bodies
+
+type apiTokenRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Code     string `json:"code,omitempty"` // TOTP or recovery code, for accounts with 2FA
+}
+
+type apiRefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type apiTokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken,omitempty"` // absent when the current one stays valid
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"` // seconds until the access token expires
+}
+
+type apiBookingRequest struct {
+	ServiceID int64  `json:"serviceId"`
+	BicycleID int64  `json:"bicycleId,omitempty"`
+	BranchID  int64  `json:"branchId,omitempty"` // defaults to the main branch
+	Date      string `json:"date"`               // YYYY-MM-DD
+	Time      string `json:"time"`               // HH:MM, one of the branch's slots
+	Notes     string `json:"notes,omitempty"`
+}
+
+type apiRejectQuoteRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type apiTicketStatusRequest struct {
+	Status string `json:"status"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+type apiTicketPartRequest struct {
+	Name            string `json:"name,omitempty"` // defaults to the inventory item's name
+	InventoryItemID int64  `json:"inventoryItemId,omitempty"`
+	Quantity        int    `json:"quantity,omitempty"` // defaults to 1
+}
+
+type apiSurveyRequest struct {
+	Rating   int    `json:"rating"` // 1-5
+	Feedback string `json:"feedback,omitempty"`
+}
+
+// apiID parses a numeric URL parameter
+func apiID(r *http.Request, key string) int64 {
+	id, _ := strconv.ParseInt(getURLParam(r, key), 10, 64)
+	return id
+}
+
+// apiQueryID parses a numeric query parameter, zero when absent
+func apiQueryID(w http.ResponseWriter, r *http.Request, key string) (int64, bool) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", key+" must be a positive integer")
+		return 0, false
+	}
+	return id, true
+}
+
+// apiBranchFilter returns the branch_id filter of a list; staff pinned to a
+// branch always get theirs
+func apiBranchFilter(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if claims := getUserClaims(r); claims.BranchID != 0 {
+		return claims.BranchID, true
+	}
+	return apiQueryID(w, r, "branch_id")
+}
+
+// Authentication
+
+func (s *Server) apiCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req apiTokenRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	accountKey := loginKey(req.Email)
+	if wait := s.loginLockout.Locked(accountKey); wait > 0 {
+		setRetryAfter(w, wait)
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many attempts")
+		return
+	}
+	if ok, wait := s.loginLimiter.Allow(accountKey); !ok {
+		setRetryAfter(w, wait)
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many attempts")
+		return
+	}
+
+	ctx := r.Context()
+	user, err := s.repos.Users.GetByEmail(ctx, req.Email)
+	valid := err == nil && user != nil && checkPasswordHash(req.Password, user.PasswordHash)
+	if valid && user.TOTPEnabled && req.Code == "" {
+		writeAPIError(w, http.StatusUnauthorized, "two_factor_required", "This account needs a 2FA code")
+		return
+	}
+	if valid && user.TOTPEnabled {
+		valid = s.checkSecondFactor(ctx, user, req.Code)
+	}
+	if !valid {
+		if wait := s.loginLockout.Fail(accountKey); wait > 0 {
+			log.Printf("🔒 Login for %s locked for %s after repeated failures (from %s)", accountKey, wait, clientIP(r))
+			setRetryAfter(w, wait)
+			writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many attempts")
+			return
+		}
+		writeAPIError(w, http.StatusUnauthorized, "invalid_credentials", "Invalid email, password or code")
+		return
+	}
+	if !user.EmailVerified {
+		writeAPIError(w, http.StatusForbidden, "email_unverified", "The email address is not verified yet")
+		return
+	}
+
+	token, refresh, err := s.createSession(r, user)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.loginLockout.Reset(accountKey)
+	writeJSON(w, r, http.StatusOK, s.tokenResponse(token, refresh))
+}
+
+func (s *Server) apiRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req apiRefreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	token, refresh, claims := s.renewSession(r.Context(), req.RefreshToken)
+	if req.RefreshToken == "" || claims == nil {
+		writeAPIError(w, http.StatusUnauthorized, "invalid_token", "Invalid, expired or revoked refresh token")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.tokenResponse(token, refresh))
+}
+
+func (s *Server) apiRevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req apiRefreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.RefreshToken != "" {
+		s.revokeSession(r, req.RefreshToken)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) tokenResponse(token, refresh string) apiTokenResponse {
+	return apiTokenResponse{
+		AccessToken:  token,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTokenTTL().Seconds()),
+	}
+}
+
+// Users
+
+func (s *Server) apiGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.repos.Users.GetByID(r.Context(), getUserClaims(r).UserID)
+	if err != nil || user == nil {
+		apiInternalError(w, r, errors.Join(errors.New("signed-in user not found"), err))
+		return
+	}
+	writeJSON(w, r, http.StatusOK, user)
+}
+
+func (s *Server) apiListUsers(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	users, err := s.repos.Users.List(r.Context(), r.URL.Query().Get("role"), page.Limit+1, page.Offset)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, pageOf(users, page))
+}
+
+func (s *Server) apiGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.repos.Users.GetByID(r.Context(), apiID(r, "id"))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if user == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, user)
+}
+
+// Bicycles
+
+func (s *Server) apiListBicycles(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := apiQueryID(w, r, "user_id")
+	if !ok {
+		return
+	}
+	if userID == 0 {
+		userID = getUserClaims(r).UserID
+	}
+	if !s.canOn(r, domain.PermBookingsView, userID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "You can only list your own bicycles")
+		return
+	}
+
+	bicycles, err := s.repos.Bicycles.GetByUserID(r.Context(), userID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(bicycles, page))
+}
+
+func (s *Server) apiGetBicycle(w http.ResponseWriter, r *http.Request) {
+	bicycle, err := s.repos.Bicycles.GetByID(r.Context(), apiID(r, "id"))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if bicycle == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Bicycle not found")
+		return
+	}
+	if !s.canOn(r, domain.PermBookingsView, bicycle.UserID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Not your bicycle")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, bicycle)
+}
+
+// Bookings
+
+func (s *Server) apiListBookings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	branchID, ok := apiBranchFilter(w, r)
+	if !ok {
+		return
+	}
+	customerID, ok := apiQueryID(w, r, "customer_id")
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	// Customers only see their own bookings
+	if !s.can(r, domain.PermBookingsViewAny) {
+		if customerID != 0 && customerID != getUserClaims(r).UserID {
+			writeAPIError(w, http.StatusForbidden, "forbidden", "You can only list your own bookings")
+			return
+		}
+		customerID = getUserClaims(r).UserID
+	}
+
+	if customerID == 0 {
+		bookings, err := s.repos.Bookings.List(ctx, branchID, status, page.Limit+1, page.Offset)
+		if err != nil {
+			apiInternalError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, pageOf(bookings, page))
+		return
+	}
+
+	all, err := s.repos.Bookings.GetByCustomerID(ctx, customerID, apiScanLimit, 0)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	var bookings []domain.Booking
+	for _, b := range all {
+		if (status == "" || b.Status == status) && (branchID == 0 || b.BranchID == branchID) {
+			bookings = append(bookings, b)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(bookings, page))
+}
+
+// apiBooking loads the booking of the request, writing the error response
+// when it doesn't exist or belongs to someone else
+func (s *Server) apiBooking(w http.ResponseWriter, r *http.Request, id int64) *domain.Booking {
+	booking, err := s.repos.Bookings.GetByID(r.Context(), id)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil
+	}
+	if booking == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Booking not found")
+		return nil
+	}
+	if !s.canOn(r, domain.PermBookingsView, booking.CustomerID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Not your booking")
+		return nil
+	}
+	return booking
+}
+
+func (s *Server) apiGetBooking(w http.ResponseWriter, r *http.Request) {
+	if booking := s.apiBooking(w, r, apiID(r, "id")); booking != nil {
+		writeJSON(w, r, http.StatusOK, booking)
+	}
+}
+
+func (s *Server) apiCreateBooking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := getUserClaims(r)
+
+	var req apiBookingRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Only verified accounts can book
+	customer, err := s.repos.Users.GetByID(ctx, claims.UserID)
+	if err != nil || customer == nil {
+		apiInternalError(w, r, errors.Join(errors.New("signed-in user not found"), err))
+		return
+	}
+	if !customer.EmailVerified {
+		writeAPIError(w, http.StatusForbidden, "email_unverified", "The email address is not verified yet")
+		return
+	}
+
+	if service, _ := s.repos.Services.GetByID(ctx, req.ServiceID); service == nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_service", "Unknown service")
+		return
+	}
+	if req.BicycleID != 0 {
+		bicycle, _ := s.repos.Bicycles.GetByID(ctx, req.BicycleID)
+		if bicycle == nil || bicycle.UserID != claims.UserID {
+			writeAPIError(w, http.StatusUnprocessableEntity, "invalid_bicycle", "Unknown bicycle")
+			return
+		}
+	}
+
+	scheduledAt, err := time.Parse("2006-01-02 15:04", req.Date+" "+req.Time)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_slot", "date must be YYYY-MM-DD and time HH:MM")
+		return
+	}
+	if req.BranchID == 0 {
+		req.BranchID = domain.DefaultBranchID
+	}
+	branch, err := s.checkBookingSlot(ctx, req.BranchID, scheduledAt)
+	switch {
+	case errors.Is(err, errSlotTaken):
+		writeAPIError(w, http.StatusConflict, "slot_taken", "The slot is already booked")
+		return
+	case errors.Is(err, errInvalidSlot):
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_slot", "The branch doesn't take bookings at that time")
+		return
+	case err != nil:
+		apiInternalError(w, r, err)
+		return
+	}
+
+	booking := &domain.Booking{
+		CustomerID:  claims.UserID,
+		BicycleID:   req.BicycleID,
+		ServiceID:   req.ServiceID,
+		BranchID:    branch.ID,
+		ScheduledAt: scheduledAt,
+		Status:      domain.BookingStatusPending,
+		Notes:       req.Notes,
+	}
+	if err := s.repos.Bookings.Create(ctx, booking); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.audit(r, "booking.create", domain.AuditEntityBooking, booking.ID, nil, booking)
+	s.emitBookingEvent(ctx, booking.ID, domain.WebhookEventBookingCreated)
+
+	if created, _ := s.repos.Bookings.GetByID(ctx, booking.ID); created != nil {
+		booking = created
+	}
+	w.Header().Set("Location", apiV1Prefix+"/bookings/"+strconv.FormatInt(booking.ID, 10))
+	writeJSON(w, r, http.StatusCreated, booking)
+}
+
+func (s *Server) apiCancelBooking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	booking := s.apiBooking(w, r, apiID(r, "id"))
+	if booking == nil || apiPreconditionFailed(w, r, booking) {
+		return
+	}
+	if booking.Status == domain.BookingStatusCancelled || booking.Status == domain.BookingStatusCompleted {
+		writeAPIError(w, http.StatusConflict, "invalid_state", "The booking is already "+booking.Status)
+		return
+	}
+
+	if err := s.repos.Bookings.UpdateStatus(ctx, booking.ID, domain.BookingStatusCancelled); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.audit(r, "booking.cancel", domain.AuditEntityBooking, booking.ID,
+		map[string]string{"status": booking.Status}, map[string]string{"status": domain.BookingStatusCancelled})
+	s.emitBookingEvent(ctx, booking.ID, domain.WebhookEventBookingCancelled)
+
+	if updated, _ := s.repos.Bookings.GetByID(ctx, booking.ID); updated != nil {
+		booking = updated
+	}
+	writeJSON(w, r, http.StatusOK, booking)
+}
+
+// Quotes
+
+func (s *Server) apiListQuotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	branchID, ok := apiBranchFilter(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	if s.can(r, domain.PermBookingsViewAny) {
+		quotes, err := s.repos.Quotes.List(ctx, branchID, status, page.Limit+1, page.Offset)
+		if err != nil {
+			apiInternalError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, pageOf(quotes, page))
+		return
+	}
+
+	// Customers see the quotes of their own bookings
+	bookings, err := s.repos.Bookings.GetByCustomerID(ctx, getUserClaims(r).UserID, apiScanLimit, 0)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	var quotes []domain.Quote
+	for _, b := range bookings {
+		if branchID != 0 && b.BranchID != branchID {
+			continue
+		}
+		quote, err := s.repos.Quotes.GetByBookingID(ctx, b.ID)
+		if err != nil {
+			apiInternalError(w, r, err)
+			return
+		}
+		if quote != nil && (status == "" || quote.Status == status) {
+			quotes = append(quotes, *quote)
+		}
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(quotes, page))
+}
+
+// apiQuote loads the quote of the request, writing the error response when
+// it doesn't exist or belongs to someone else's booking
+func (s *Server) apiQuote(w http.ResponseWriter, r *http.Request) *domain.Quote {
+	quote, err := s.repos.Quotes.GetByID(r.Context(), apiID(r, "id"))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil
+	}
+	if quote == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Quote not found")
+		return nil
+	}
+	booking, err := s.repos.Bookings.GetByID(r.Context(), quote.BookingID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil
+	}
+	if booking == nil || !s.canOn(r, domain.PermBookingsView, booking.CustomerID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Not your quote")
+		return nil
+	}
+	return quote
+}
+
+func (s *Server) apiGetQuote(w http.ResponseWriter, r *http.Request) {
+	if quote := s.apiQuote(w, r); quote != nil {
+		writeJSON(w, r, http.StatusOK, quote)
+	}
+}
+
+func (s *Server) apiApproveQuote(w http.ResponseWriter, r *http.Request) {
+	quote := s.apiQuote(w, r)
+	if quote == nil || apiPreconditionFailed(w, r, quote) {
+		return
+	}
+	if quote.Status != domain.QuoteStatusPending {
+		writeAPIError(w, http.StatusConflict, "invalid_state", "The quote is already "+quote.Status)
+		return
+	}
+
+	if err := s.repos.Quotes.Approve(r.Context(), quote.ID); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.audit(r, "quote.approve", domain.AuditEntityQuote, quote.ID,
+		map[string]string{"status": quote.Status}, map[string]string{"status": domain.QuoteStatusApproved})
+	s.emitQuoteEvent(r.Context(), quote.ID, domain.WebhookEventQuoteApproved)
+	s.writeQuote(w, r, quote)
+}
+
+func (s *Server) apiRejectQuote(w http.ResponseWriter, r *http.Request) {
+	var req apiRejectQuoteRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+	quote := s.apiQuote(w, r)
+	if quote == nil || apiPreconditionFailed(w, r, quote) {
+		return
+	}
+	if quote.Status != domain.QuoteStatusPending {
+		writeAPIError(w, http.StatusConflict, "invalid_state", "The quote is already "+quote.Status)
+		return
+	}
+
+	if err := s.repos.Quotes.Reject(r.Context(), quote.ID, req.Reason); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.audit(r, "quote.reject", domain.AuditEntityQuote, quote.ID,
+		map[string]string{"status": quote.Status}, map[string]string{"status": domain.QuoteStatusRejected, "reason": req.Reason})
+	s.emitQuoteEvent(r.Context(), quote.ID, domain.WebhookEventQuoteRejected)
+	s.writeQuote(w, r, quote)
+}
+
+// writeQuote responds with the current version of a quote
+func (s *Server) writeQuote(w http.ResponseWriter, r *http.Request, quote *domain.Quote) {
+	if updated, _ := s.repos.Quotes.GetByID(r.Context(), quote.ID); updated != nil {
+		quote = updated
+	}
+	writeJSON(w, r, http.StatusOK, quote)
+}
+
+// Tickets
+
+func (s *Server) apiListTickets(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	branchID, ok := apiBranchFilter(w, r)
+	if !ok {
+		return
+	}
+	tickets, err := s.repos.Tickets.List(r.Context(), branchID, r.URL.Query().Get("status"), page.Limit+1, page.Offset)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, pageOf(tickets, page))
+}
+
+// apiTicket loads the ticket of the request, writing the error response when
+// it doesn't exist or the user can't see it: staff see the tickets of their
+// branches, customers the tickets of their bookings
+func (s *Server) apiTicket(w http.ResponseWriter, r *http.Request) *domain.Ticket {
+	ctx := r.Context()
+	ticket, err := s.repos.Tickets.GetByID(ctx, apiID(r, "id"))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil
+	}
+	if ticket == nil || (s.can(r, domain.PermWorkshopView) && !s.inBranch(r, ticket.BranchID)) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Ticket not found")
+		return nil
+	}
+	if s.can(r, domain.PermWorkshopView) {
+		return ticket
+	}
+
+	booking, err := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return nil
+	}
+	if booking == nil || !s.canOn(r, domain.PermBookingsView, booking.CustomerID) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Not your ticket")
+		return nil
+	}
+	return ticket
+}
+
+func (s *Server) apiGetTicket(w http.ResponseWriter, r *http.Request) {
+	if ticket := s.apiTicket(w, r); ticket != nil {
+		writeJSON(w, r, http.StatusOK, ticket)
+	}
+}
+
+func (s *Server) apiChangeTicketStatus(w http.ResponseWriter, r *http.Request) {
+	var req apiTicketStatusRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	ticket := s.apiTicket(w, r)
+	if ticket == nil || apiPreconditionFailed(w, r, ticket) {
+		return
+	}
+	if !s.canEditTicket(r, ticket) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "You are not assigned to this ticket")
+		return
+	}
+
+	switch err := s.changeTicketStatus(r, ticket, req.Status, req.Notes); {
+	case errors.Is(err, errForbiddenTransition):
+		writeAPIError(w, http.StatusForbidden, "forbidden_transition", "Delivering a ticket needs "+domain.PermPaymentsTake)
+		return
+	case errors.Is(err, errInvalidTransition):
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_transition",
+			"Can't move a "+ticket.Status+" ticket to "+strconv.Quote(req.Status))
+		return
+	case errors.Is(err, errChecklistIncomplete):
+		writeAPIError(w, http.StatusUnprocessableEntity, "checklist_incomplete",
+			"Required checklist items must be completed first")
+		return
+	case errors.Is(err, errWIPLimit):
+		writeAPIError(w, http.StatusConflict, "wip_limit", "The "+strconv.Quote(req.Status)+" column is at its limit")
+		return
+	case err != nil:
+		apiInternalError(w, r, err)
+		return
+	}
+
+	if updated, _ := s.repos.Tickets.GetByID(r.Context(), ticket.ID); updated != nil {
+		ticket = updated
+	}
+	writeJSON(w, r, http.StatusOK, ticket)
+}
+
+func (s *Server) apiListTicketParts(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	ticket := s.apiTicket(w, r)
+	if ticket == nil {
+		return
+	}
+	parts, err := s.repos.Tickets.GetTicketParts(r.Context(), ticket.ID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(parts, page))
+}
+
+func (s *Server) apiAddTicketPart(w http.ResponseWriter, r *http.Request) {
+	var req apiTicketPartRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	ticket := s.apiTicket(w, r)
+	if ticket == nil {
+		return
+	}
+	if !s.canEditTicket(r, ticket) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "You are not assigned to this ticket")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_quantity", "quantity must be positive")
+		return
+	}
+
+	part, err := s.addTicketPart(r, ticket, strings.TrimSpace(req.Name), req.InventoryItemID, req.Quantity)
+	if errors.Is(err, errInvalidPart) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_part", "A part needs a name or a known inventory item")
+		return
+	}
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if created, _ := s.repos.Tickets.GetTicketPart(r.Context(), part.ID); created != nil {
+		part = created
+	}
+	writeJSON(w, r, http.StatusCreated, part)
+}
+
+// Surveys
+
+func (s *Server) apiListSurveys(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	surveys, err := s.repos.Surveys.List(r.Context(), page.Limit+1, page.Offset)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, pageOf(surveys, page))
+}
+
+func (s *Server) apiSubmitSurvey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req apiSurveyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	ticket := s.apiTicket(w, r)
+	if ticket == nil {
+		return
+	}
+
+	// Only the customer answers, once the work is done
+	booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
+	if booking == nil || booking.CustomerID != getUserClaims(r).UserID {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "Only the customer can answer the survey")
+		return
+	}
+	if ticket.Status != domain.TicketStatusReady && ticket.Status != domain.TicketStatusDelivered {
+		writeAPIError(w, http.StatusConflict, "invalid_state", "The ticket isn't finished yet")
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_rating", "rating must be between 1 and 5")
+		return
+	}
+	if existing, _ := s.repos.Surveys.GetByTicketID(ctx, ticket.ID); existing != nil {
+		writeAPIError(w, http.StatusConflict, "already_answered", "The survey was already answered")
+		return
+	}
+
+	survey := &domain.Survey{
+		TicketID: ticket.ID,
+		Rating:   req.Rating,
+		Feedback: req.Feedback,
+	}
+	if err := s.repos.Surveys.Create(ctx, survey); err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	s.audit(r, "survey.create", domain.AuditEntitySurvey, survey.ID, nil, survey)
+	if created, _ := s.repos.Surveys.GetByTicketID(ctx, ticket.ID); created != nil {
+		survey = created
+	}
+	writeJSON(w, r, http.StatusCreated, survey)
+}
+
+// Catalog
+
+func (s *Server) apiListBrands(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	brands, err := s.repos.Brands.List(r.Context())
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(brands, page))
+}
+
+func (s *Server) apiListBrandModels(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	brand, err := s.repos.Brands.GetByID(r.Context(), apiID(r, "id"))
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	if brand == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "Brand not found")
+		return
+	}
+	models, err := s.repos.Models.GetByBrandID(r.Context(), brand.ID)
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(models, page))
+}
+
+func (s *Server) apiListServices(w http.ResponseWriter, r *http.Request) {
+	page, ok := apiPageParam(w, r)
+	if !ok {
+		return
+	}
+	services, err := s.repos.Services.List(r.Context())
+	if err != nil {
+		apiInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, sliceOf(services, page))
+}
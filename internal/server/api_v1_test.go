@@ -0,0 +1,106 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestOpenAPIMatchesRoutes checks that the OpenAPI document describes
+// exactly the routes mounted under /api/v1, and that API keys can't reach an
+// authenticated operation that has no scope
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET openapi.json: status %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string
+			Security    *[]interface{}
+			Scope       string `json:"x-api-key-scope"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi version = %q, want 3.x", doc.OpenAPI)
+	}
+
+	documented := map[string]bool{}
+	operationIDs := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+			if op.OperationID == "" || operationIDs[op.OperationID] {
+				t.Errorf("%s %s: missing or duplicate operationId %q", method, path, op.OperationID)
+			}
+			operationIDs[op.OperationID] = true
+			public := op.Security != nil && len(*op.Security) == 0
+			if !public && op.Scope == "" {
+				t.Errorf("%s %s: authenticated operation without an API key scope", method, path)
+			}
+		}
+	}
+
+	routed := map[string]bool{}
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if path, ok := strings.CutPrefix(route, "/api/v1/"); ok {
+			routed[method+" /"+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	for _, route := range sortedKeys(routed) {
+		if !documented[route] {
+			t.Errorf("route %s is not in the OpenAPI document", route)
+		}
+	}
+	for _, route := range sortedKeys(documented) {
+		if !routed[route] {
+			t.Errorf("OpenAPI documents %s, which is not routed", route)
+		}
+	}
+}
+
+// TestAPIErrorsAreJSON checks that the API answers 401 instead of
+// redirecting to the login page, with the JSON error body
+func TestAPIErrorsAreJSON(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, path := range []string{"/api/v1/bookings", "/api/v1/no-such-endpoint"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 401 or 404", path, rec.Code)
+		}
+		var body struct {
+			Error struct{ Code, Message string }
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code == "" {
+			t.Errorf("GET %s: body %q is not a JSON error", path, rec.Body.String())
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+)
+
+const (
+	// apiKeyPrefix starts every key so it is easy to spot in config files
+	// and secret scanners
+	apiKeyPrefix = "bk_"
+
+	// apiKeyTouchInterval limits how often a key's last use is written
+	apiKeyTouchInterval = time.Minute
+)
+
+// newAPIKey returns a new plain key and the prefix that identifies it. The
+// key looks like bk_<prefix>_<secret>; only its hash is persisted.
+func newAPIKey() (key, prefix string, err error) {
+	raw := make([]byte, 4)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate key prefix: %w", err)
+	}
+	secret, err := newRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(raw)
+	return apiKeyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+// isAPIRequest reports whether the request targets the JSON API, which
+// answers with status codes instead of redirects
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// authenticateAPIKey returns claims acting as the key's creator, limited to
+// the key's scopes, or nil when the key is unknown, revoked or expired
+func (s *Server) authenticateAPIKey(ctx context.Context, plain string) *Claims {
+	rest, ok := strings.CutPrefix(plain, apiKeyPrefix)
+	if !ok {
+		return nil
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil
+	}
+
+	key, err := s.repos.APIKeys.GetByPrefix(ctx, prefix)
+	if err != nil {
+		log.Printf("⚠️ Failed to load API key %s: %v", prefix, err)
+		return nil
+	}
+	if key == nil || !key.Active() || subtle.ConstantTimeCompare([]byte(hashToken(plain)), []byte(key.SecretHash)) != 1 {
+		return nil
+	}
+	user, err := s.repos.Users.GetByID(ctx, key.CreatedBy)
+	if err != nil || user == nil {
+		return nil
+	}
+
+	if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repos.APIKeys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			log.Printf("⚠️ Failed to record use of API key %s: %v", prefix, err)
+		}
+	}
+
+	return &Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		BranchID: user.BranchID,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}
+}
+
+// hasScope reports whether the request may reach an endpoint needing scope.
+// Only API keys are limited by scopes; people are limited by their role.
+func (c *Claims) hasScope(scope string) bool {
+	if c.APIKeyID == 0 {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// requireScope lets API key requests through only when the key holds scope
+func (s *Server) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := getUserClaims(r); claims != nil && !claims.hasScope(scope) {
+				writeAPIError(w, http.StatusForbidden, "insufficient_scope", "The API key lacks the scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleAPIKeysList shows the API keys and the form to issue one
+func (s *Server) handleAPIKeysList(w http.ResponseWriter, r *http.Request) {
+	s.renderAPIKeys(w, r, "", nil)
+}
+
+// renderAPIKeys renders the API keys page. A new key is shown in full this
+// one time, since only its hash is stored.
+func (s *Server) renderAPIKeys(w http.ResponseWriter, r *http.Request, newKey string, flash *FlashMessage) {
+	keys, err := s.repos.APIKeys.List(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading API keys", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Claves de API")
+	data.Flash = flash
+	switch r.URL.Query().Get("error") {
+	case "name_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre de la clave es obligatorio"}
+	case "scopes_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "Elige al menos un permiso para la clave"}
+	case "invalid_expiry":
+		data.Flash = &FlashMessage{Type: "error", Message: "La fecha de vencimiento debe ser posterior a hoy"}
+	}
+	data.Data = map[string]interface{}{
+		"Keys":   keys,
+		"Scopes": domain.APIScopes,
+		"NewKey": newKey,
+		"Header": domain.APIKeyHeader,
+	}
+	s.render(w, r, "pages/admin/api_keys.html", data)
+}
+
+// handleCreateAPIKey issues a key and shows it once
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	key := &domain.APIKey{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		CreatedBy: getUserClaims(r).UserID,
+	}
+	key.SetScopes(r.Form["scopes"])
+	if key.Name == "" {
+		http.Redirect(w, r, "/admin/api-keys?error=name_required", http.StatusSeeOther)
+		return
+	}
+	if len(key.Scopes) == 0 {
+		http.Redirect(w, r, "/admin/api-keys?error=scopes_required", http.StatusSeeOther)
+		return
+	}
+	if value := r.FormValue("expires_at"); value != "" {
+		expiresAt, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil || !expiresAt.After(time.Now()) {
+			http.Redirect(w, r, "/admin/api-keys?error=invalid_expiry", http.StatusSeeOther)
+			return
+		}
+		key.ExpiresAt = &expiresAt
+	}
+
+	plain, prefix, err := newAPIKey()
+	if err != nil {
+		http.Error(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	key.Prefix = prefix
+	key.SecretHash = hashToken(plain)
+	if err := s.repos.APIKeys.Create(r.Context(), key); err != nil {
+		http.Error(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "api_key.create", domain.AuditEntityAPIKey, key.ID, nil, key)
+
+	s.renderAPIKeys(w, r, plain, &FlashMessage{Type: "success", Message: "Clave creada. Cópiala ahora: no se volverá a mostrar."})
+}
+
+// handleRevokeAPIKey stops a key from working immediately
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	key, err := s.repos.APIKeys.GetByID(ctx, id)
+	if err != nil || key == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.repos.APIKeys.Revoke(ctx, id); err != nil {
+		http.Error(w, "Error revoking API key", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "api_key.revoke", domain.AuditEntityAPIKey, id, nil, nil)
+	http.Redirect(w, r, "/admin/api-keys", http.StatusSeeOther)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"bicicletapp/internal/domain"
+)
+
+// formWorkProfile reads the skills and daily capacity of a staff user from
+// the user form
+func formWorkProfile(r *http.Request) ([]string, float64) {
+	hours, _ := strconv.ParseFloat(r.FormValue("daily_hours"), 64)
+	if hours < 0 || hours > 24 {
+		hours = 0
+	}
+	return domain.ParseSkills(r.FormValue("skills")), hours
+}
+
+// assignmentStrategy is how new tickets get a technician
+func (s *Server) assignmentStrategy(ctx context.Context) string {
+	strategy, _ := s.repos.Settings.Get(ctx, domain.AssignmentStrategySetting)
+	if !domain.IsAssignmentStrategy(strategy) {
+		return domain.AssignmentManual
+	}
+	return strategy
+}
+
+// workloads returns the open work of the staff of a branch. Work at every
+// branch counts, since a technician may serve several.
+func (s *Server) workloads(ctx context.Context, branchID int64) []domain.Workload {
+	open, err := s.repos.Tickets.OpenWorkByTechnician(ctx, 0)
+	if err != nil {
+		log.Printf("⚠️ Failed to load technician workloads: %v", err)
+	}
+	staff := s.staffUsers(ctx, branchID)
+	loads := make([]domain.Workload, len(staff))
+	for i := range staff {
+		loads[i] = open[staff[i].ID]
+		loads[i].Technician = staff[i]
+	}
+	return loads
+}
+
+// overloaded picks the overloaded technicians among loads
+func overloaded(loads []domain.Workload) []domain.Workload {
+	var busy []domain.Workload
+	for _, load := range loads {
+		if load.Overloaded() {
+			busy = append(busy, load)
+		}
+	}
+	return busy
+}
+
+// workloadOf returns the open work of a technician among loads, or nil
+func workloadOf(loads []domain.Workload, technicianID int64) *domain.Workload {
+	for i := range loads {
+		if loads[i].Technician.ID == technicianID {
+			return &loads[i]
+		}
+	}
+	return nil
+}
+
+// assignTechnician gives a new ticket a technician by the assignment
+// strategy. When nobody fits, the ticket keeps whoever it had.
+func (s *Server) assignTechnician(ctx context.Context, ticket *domain.Ticket) {
+	strategy := s.assignmentStrategy(ctx)
+	if strategy == domain.AssignmentManual {
+		return
+	}
+	branchID := ticket.BranchID
+	if branchID == 0 {
+		branchID = domain.DefaultBranchID
+	}
+
+	var required []string
+	if booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID); booking != nil {
+		if service, _ := s.repos.Services.GetByID(ctx, booking.ServiceID); service != nil {
+			required = service.RequiredSkills
+		}
+	}
+	lastSetting := domain.RoundRobinSetting(branchID)
+	last, _ := s.repos.Settings.Get(ctx, lastSetting)
+	lastID, _ := strconv.ParseInt(last, 10, 64)
+
+	picked := domain.PickTechnician(strategy, s.workloads(ctx, branchID), required, lastID)
+	if picked == nil {
+		return
+	}
+	ticket.TechnicianID = picked.Technician.ID
+	if strategy == domain.AssignmentRoundRobin {
+		if err := s.repos.Settings.Set(ctx, lastSetting, strconv.FormatInt(picked.Technician.ID, 10)); err != nil {
+			log.Printf("⚠️ Failed to save the round robin turn: %v", err)
+		}
+	}
+}
+
+// slotCapacity is how many bookings a branch takes per slot: one per
+// technician that is not overloaded, and at least one
+func (s *Server) slotCapacity(ctx context.Context, branchID int64) int {
+	loads := s.workloads(ctx, branchID)
+	capacity := len(loads) - len(overloaded(loads))
+	if capacity == 0 {
+		return 1
+	}
+	return capacity
+}
+
+// handleCapacity shows the open work and capacity of each technician of the
+// current branch, the most loaded first
+func (s *Server) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	branchID := s.currentBranch(r)
+
+	loads := s.workloads(ctx, branchID)
+	sort.SliceStable(loads, func(i, j int) bool { return loads[i].BacklogDays() > loads[j].BacklogDays() })
+	slots := 0
+	if branchID != 0 {
+		slots = s.slotCapacity(ctx, branchID)
+	}
+
+	data := s.newPageData(r, "Capacidad del Taller")
+	data.Data = map[string]interface{}{
+		"Workloads":      loads,
+		"Overloaded":     len(overloaded(loads)),
+		"SlotCapacity":   slots,
+		"MaxBacklogDays": domain.MaxBacklogDays,
+		"Strategy":       s.assignmentStrategy(ctx),
+		"Strategies":     domain.AssignmentStrategies,
+	}
+	s.render(w, r, "pages/technician/capacity.html", data)
+}
+
+// handleUpdateAssignmentSettings saves how new tickets get a technician
+func (s *Server) handleUpdateAssignmentSettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	value := r.FormValue(domain.AssignmentStrategySetting)
+	if !domain.IsAssignmentStrategy(value) {
+		http.Error(w, "Unknown assignment strategy", http.StatusBadRequest)
+		return
+	}
+	before, _ := s.repos.Settings.Get(ctx, domain.AssignmentStrategySetting)
+	if err := s.repos.Settings.Set(ctx, domain.AssignmentStrategySetting, value); err != nil {
+		http.Error(w, "Error saving settings", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "setting.update", domain.AuditEntitySetting, 0,
+		map[string]string{domain.AssignmentStrategySetting: before},
+		map[string]string{domain.AssignmentStrategySetting: value})
+	http.Redirect(w, r, "/admin/settings?msg=assignment", http.StatusSeeOther)
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"bicicletapp/internal/config"
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/imaging"
+	"bicicletapp/internal/storage"
+)
+
+// maxAttachmentFiles is how many files one upload may carry
+const maxAttachmentFiles = 10
+
+// uploadFormSlack is the room left in a request for the form fields and
+// multipart framing around its files
+const uploadFormSlack = 1 << 20
+
+// Reasons an uploaded file is refused
+var (
+	errAttachmentTooLarge = errors.New("file over the upload size limit")
+	errAttachmentType     = errors.New("file type not accepted")
+)
+
+// newStorage opens the upload storage the configuration names
+func newStorage(cfg *config.Config) storage.Storage {
+	if cfg.Storage.Driver == config.StorageS3 {
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  cfg.Storage.S3.Endpoint,
+			Region:    cfg.Storage.S3.Region,
+			Bucket:    cfg.Storage.S3.Bucket,
+			AccessKey: cfg.Storage.S3.AccessKey,
+			SecretKey: cfg.Storage.S3.SecretKey,
+		})
+	}
+	return storage.NewLocal(cfg.Storage.Dir)
+}
+
+// maxUploadBytes is the size limit of each uploaded file
+func (s *Server) maxUploadBytes() int64 {
+	return int64(s.config.Storage.MaxUploadMB) << 20
+}
+
+// maxRequestBytes is the size limit of a request body: an upload of the most
+// files allowed, each at the size limit. A checklist item takes one photo at
+// most.
+func (s *Server) maxRequestBytes(r *http.Request) int64 {
+	if isChecklistItemPath(r.URL.Path) {
+		return s.maxUploadBytes() + uploadFormSlack
+	}
+	return maxAttachmentFiles*s.maxUploadBytes() + uploadFormSlack
+}
+
+// isChecklistItemPath tells whether path is /tickets/{id}/checklist/{itemId}.
+// Body limits apply before routing, so the route is matched by hand.
+func isChecklistItemPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) == 4 && parts[0] == "tickets" && parts[2] == "checklist"
+}
+
+// attachmentKey returns a new random storage key for a file of a ticket
+func (s *Server) attachmentKey(ticketID int64, suffix string) string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return storage.JoinKey(s.config.Storage.Prefix, "tickets", strconv.FormatInt(ticketID, 10),
+		hex.EncodeToString(raw)+suffix)
+}
+
+// storeAttachment checks an uploaded file and keeps it, with a thumbnail
+// when it is a photo. Photos lose their GPS location before being stored.
+func (s *Server) storeAttachment(ctx context.Context, attachment *domain.Attachment, header *multipart.FileHeader) error {
+	if header.Size > s.maxUploadBytes() {
+		return errAttachmentTooLarge
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, s.maxUploadBytes()+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > s.maxUploadBytes() {
+		return errAttachmentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := domain.AttachmentTypes[contentType]
+	if !ok {
+		return errAttachmentType
+	}
+	attachment.ContentType = contentType
+	attachment.FileName = filepath.Base(header.Filename)
+
+	var thumb []byte
+	if attachment.IsImage() {
+		data = imaging.StripGPS(data)
+		thumb, err = imaging.Thumbnail(data, domain.AttachmentThumbSize)
+		if errors.Is(err, imaging.ErrTooLarge) {
+			return errAttachmentTooLarge
+		}
+		if err != nil {
+			return errAttachmentType // not a photo we can read
+		}
+	}
+	attachment.Size = int64(len(data))
+
+	attachment.StorageKey = s.attachmentKey(attachment.TicketID, ext)
+	if err := s.storage.Put(ctx, attachment.StorageKey, data, contentType); err != nil {
+		return err
+	}
+	if thumb != nil {
+		attachment.ThumbKey = strings.TrimSuffix(attachment.StorageKey, ext) + "-thumb.jpg"
+		if err := s.storage.Put(ctx, attachment.ThumbKey, thumb, "image/jpeg"); err != nil {
+			s.removeAttachmentFiles(ctx, attachment)
+			return err
+		}
+	}
+	if err := s.repos.Attachments.Create(ctx, attachment); err != nil {
+		s.removeAttachmentFiles(ctx, attachment)
+		return err
+	}
+	return nil
+}
+
+// removeAttachmentFiles deletes the stored files of an attachment. A failure
+// only leaves an orphan file behind, so it is logged.
+func (s *Server) removeAttachmentFiles(ctx context.Context, attachment *domain.Attachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("⚠️ Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+// storeUploads stores the files of a request's "files" field as attachments
+// of a ticket, all of one kind and with the same caption. Files are stored
+// one by one and a refused file stops the rest. It returns the error code to
+// report, or "" when every file was stored.
+func (s *Server) storeUploads(r *http.Request, ticket *domain.Ticket, kind, caption string, public bool) string {
+	if err := r.ParseMultipartForm(32 << 20); err != nil || r.MultipartForm == nil {
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return "attachment_size"
+		}
+		return "attachment_failed"
+	}
+	files := r.MultipartForm.File["files"]
+	switch {
+	case len(files) == 0:
+		return "attachment_missing"
+	case len(files) > maxAttachmentFiles:
+		return "attachment_count"
+	}
+
+	for _, header := range files {
+		attachment := &domain.Attachment{
+			TicketID:   ticket.ID,
+			Kind:       kind,
+			Caption:    caption,
+			Public:     public,
+			UploadedBy: getUserClaims(r).UserID,
+		}
+		err := s.storeAttachment(r.Context(), attachment, header)
+		switch {
+		case errors.Is(err, errAttachmentTooLarge):
+			return "attachment_size"
+		case errors.Is(err, errAttachmentType):
+			return "attachment_type"
+		case err != nil:
+			log.Printf("❌ Failed to store attachment of ticket %d: %v", ticket.ID, err)
+			return "attachment_failed"
+		}
+		s.audit(r, "attachment.create", domain.AuditEntityAttachment, attachment.ID, nil, attachment)
+	}
+	return ""
+}
+
+// handleUploadAttachments stores the photos and documents uploaded to a
+// ticket
+func (s *Server) handleUploadAttachments(w http.ResponseWriter, r *http.Request) {
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+
+	kind := r.FormValue("kind")
+	if !domain.IsAttachmentKind(kind) {
+		kind = domain.AttachmentOther
+	}
+	caption := strings.TrimSpace(r.FormValue("caption"))
+	if code := s.storeUploads(r, ticket, kind, caption, r.FormValue("public") == "on"); code != "" {
+		http.Redirect(w, r, redirect+"?error="+code, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, redirect+"?msg=attachments#attachments", http.StatusSeeOther)
+}
+
+// ticketAttachment loads the attachment of a ticket a request names, writing
+// the error response when the user cannot work on the ticket
+func (s *Server) ticketAttachment(w http.ResponseWriter, r *http.Request) (*domain.Ticket, *domain.Attachment) {
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return nil, nil
+	}
+	id, _ := strconv.ParseInt(getURLParam(r, "attachmentId"), 10, 64)
+	attachment, err := s.repos.Attachments.GetByID(r.Context(), id)
+	if err != nil || attachment == nil || attachment.TicketID != ticket.ID {
+		http.NotFound(w, r)
+		return nil, nil
+	}
+	return ticket, attachment
+}
+
+// handleToggleAttachment shows an attachment to the customer or hides it
+func (s *Server) handleToggleAttachment(w http.ResponseWriter, r *http.Request) {
+	ticket, attachment := s.ticketAttachment(w, r)
+	if attachment == nil {
+		return
+	}
+	if err := s.repos.Attachments.SetPublic(r.Context(), attachment.ID, !attachment.Public); err != nil {
+		http.Error(w, "Error updating attachment", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "attachment.update", domain.AuditEntityAttachment, attachment.ID,
+		map[string]bool{"public": attachment.Public}, map[string]bool{"public": !attachment.Public})
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d#attachments", ticket.ID), http.StatusSeeOther)
+}
+
+// handleDeleteAttachment removes an attachment and its files
+func (s *Server) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket, attachment := s.ticketAttachment(w, r)
+	if attachment == nil {
+		return
+	}
+	if err := s.repos.Attachments.Delete(ctx, attachment.ID); err != nil {
+		http.Error(w, "Error deleting attachment", http.StatusInternalServerError)
+		return
+	}
+	s.removeAttachmentFiles(ctx, attachment)
+	// A checklist point proven by this photo needs another one
+	if err := s.repos.Checklists.ReopenByAttachment(ctx, attachment.ID); err != nil {
+		log.Printf("⚠️ Failed to reopen checklist items of attachment %d: %v", attachment.ID, err)
+	}
+	s.audit(r, "attachment.delete", domain.AuditEntityAttachment, attachment.ID, attachment, nil)
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d#attachments", ticket.ID), http.StatusSeeOther)
+}
+
+// handleAttachmentFile sends an attachment of a ticket, or its thumbnail
+// with ?thumb=1, to the workshop
+func (s *Server) handleAttachmentFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ticket, err := s.repos.Tickets.GetByID(ctx, id)
+	if err != nil || ticket == nil || !s.inBranch(r, ticket.BranchID) {
+		http.NotFound(w, r)
+		return
+	}
+	attachmentID, _ := strconv.ParseInt(getURLParam(r, "attachmentId"), 10, 64)
+	attachment, err := s.repos.Attachments.GetByID(ctx, attachmentID)
+	if err != nil || attachment == nil || attachment.TicketID != ticket.ID {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveAttachment(w, r, attachment)
+}
+
+// handleTrackingAttachment sends a photo the workshop shared with the
+// customer, or its thumbnail with ?thumb=1, to whoever has the tracking code
+func (s *Server) handleTrackingAttachment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket, err := s.repos.Tickets.GetByTrackingCode(ctx, getURLParam(r, "code"))
+	if err != nil || ticket == nil {
+		http.NotFound(w, r)
+		return
+	}
+	id, _ := strconv.ParseInt(getURLParam(r, "attachmentId"), 10, 64)
+	attachment, err := s.repos.Attachments.GetByID(ctx, id)
+	if err != nil || attachment == nil || attachment.TicketID != ticket.ID || !attachment.Public {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveAttachment(w, r, attachment)
+}
+
+// serveAttachment copies an attachment's file, or its thumbnail, from the
+// storage. Files never change once stored, so browsers may keep them.
+func (s *Server) serveAttachment(w http.ResponseWriter, r *http.Request, attachment *domain.Attachment) {
+	key, contentType := attachment.StorageKey, attachment.ContentType
+	if r.URL.Query().Get("thumb") == "1" && attachment.ThumbKey != "" {
+		key, contentType = attachment.ThumbKey, "image/jpeg"
+	}
+	file, err := s.storage.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to read stored file %s: %v", key, err)
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	disposition := "inline"
+	if !attachment.IsImage() {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, attachment.FileName))
+	io.Copy(w, file)
+}
+
+// handleConditionReport shows a printable report of the bike's condition at
+// intake: its details and the intake photos with their notes
+func (s *Server) handleConditionReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ticket, err := s.repos.Tickets.GetByID(ctx, id)
+	if err != nil || ticket == nil || !s.inBranch(r, ticket.BranchID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
+	if booking != nil {
+		if booking.BicycleID != 0 {
+			booking.Bicycle, _ = s.repos.Bicycles.GetByID(ctx, booking.BicycleID)
+		}
+		if booking.CustomerID != 0 {
+			booking.Customer, _ = s.repos.Users.GetByID(ctx, booking.CustomerID)
+		}
+	}
+	attachments, err := s.repos.Attachments.ListByTicket(ctx, ticket.ID)
+	if err != nil {
+		http.Error(w, "Error loading attachments", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Estado al Ingreso #"+ticket.TrackingCode)
+	data.Data = map[string]interface{}{
+		"Ticket":  ticket,
+		"Booking": booking,
+		"Photos":  attachmentsOf(attachments, domain.AttachmentIntake, false),
+	}
+	s.render(w, r, "pages/technician/ticket_condition.html", data)
+}
+
+// attachmentsOf picks the photos of a kind among attachments, and only the
+// ones shared with the customer when public is set
+func attachmentsOf(attachments []domain.Attachment, kind string, public bool) []domain.Attachment {
+	var photos []domain.Attachment
+	for _, a := range attachments {
+		if a.IsImage() && (kind == "" || a.Kind == kind) && (!public || a.Public) {
+			photos = append(photos, a)
+		}
+	}
+	return photos
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bicicletapp/internal/domain"
+)
+
+// boardTechnicianUnassigned is the technician filter value for tickets
+// nobody took yet
+const boardTechnicianUnassigned = "unassigned"
+
+// wipLimits returns the work-in-progress limit of every board column that
+// has one
+func (s *Server) wipLimits(ctx context.Context) map[string]int {
+	limits := make(map[string]int)
+	for _, status := range domain.BoardWIPStatuses {
+		value, _ := s.repos.Settings.Get(ctx, domain.WIPLimitSetting(status))
+		if limit, err := strconv.Atoi(value); err == nil && limit > 0 {
+			limits[status] = limit
+		}
+	}
+	return limits
+}
+
+// checkWIPLimit returns errWIPLimit when a ticket moving to status would go
+// over the work-in-progress limit of that column in its branch
+func (s *Server) checkWIPLimit(ctx context.Context, ticket *domain.Ticket, status string) error {
+	if status == ticket.Status || !domain.SupportsWIPLimit(status) {
+		return nil
+	}
+	limit := s.wipLimits(ctx)[status]
+	if limit == 0 {
+		return nil
+	}
+	counts, err := s.repos.Tickets.CountByStatus(ctx, ticket.BranchID)
+	if err != nil {
+		return err
+	}
+	if counts[status] >= limit {
+		return errWIPLimit
+	}
+	return nil
+}
+
+// handleWorkshopBoard shows every ticket of the current branch as a card in
+// the column of its status. Limits only apply to a single branch, so the
+// consolidated view shows none.
+func (s *Server) handleWorkshopBoard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	branchID := s.currentBranch(r)
+
+	filter := domain.BoardFilter{
+		BranchID:       branchID,
+		DeliveredSince: time.Now().AddDate(0, 0, -domain.BoardDeliveredDays),
+	}
+	technician := r.URL.Query().Get("technician")
+	if technician == boardTechnicianUnassigned {
+		filter.Unassigned = true
+	} else {
+		filter.TechnicianID, _ = strconv.ParseInt(technician, 10, 64)
+	}
+
+	tickets, err := s.repos.Tickets.ListBoard(ctx, filter)
+	if err != nil {
+		http.Error(w, "Error loading tickets", http.StatusInternalServerError)
+		return
+	}
+	counts, _ := s.repos.Tickets.CountByStatus(ctx, branchID)
+	limits := map[string]int{}
+	if branchID != 0 {
+		limits = s.wipLimits(ctx)
+	}
+
+	columns := make([]domain.BoardColumn, len(domain.TicketStatuses))
+	index := make(map[string]int)
+	for i, status := range domain.TicketStatuses {
+		columns[i] = domain.BoardColumn{
+			Status: status,
+			Label:  domain.TicketStatusLabel(status),
+			Limit:  limits[status],
+			Count:  counts[status],
+		}
+		index[status] = i
+	}
+	for _, ticket := range tickets {
+		if i, ok := index[ticket.Status]; ok {
+			columns[i].Cards = append(columns[i].Cards, ticket)
+		}
+	}
+	// Delivered tickets only stay on the board for a few days
+	delivered := &columns[index[domain.TicketStatusDelivered]]
+	delivered.Count = len(delivered.Cards)
+
+	data := s.newPageData(r, "Tablero del Taller")
+	data.Data = map[string]interface{}{
+		"Columns":       columns,
+		"Technicians":   s.staffUsers(ctx, branchID),
+		"Technician":    technician,
+		"Unassigned":    boardTechnicianUnassigned,
+		"DeliveredDays": domain.BoardDeliveredDays,
+		"Consolidated":  branchID == 0,
+	}
+	s.render(w, r, "pages/technician/board.html", data)
+}
+
+// boardMoveRequest is the body of a card move
+type boardMoveRequest struct {
+	Status string `json:"status"`
+	Notes  string `json:"notes"`
+}
+
+// handleBoardMove moves a card to another column. It follows the same rules
+// as handleUpdateTicketStatus, column limits included. The response carries
+// the new counts of every column of the ticket's branch.
+func (s *Server) handleBoardMove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req boardMoveRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ticket, err := s.repos.Tickets.GetByID(ctx, id)
+	if err != nil || ticket == nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "El ticket no existe")
+		return
+	}
+	if !s.canEditTicket(r, ticket) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "No tienes asignado este ticket")
+		return
+	}
+
+	switch err := s.changeTicketStatus(r, ticket, req.Status, req.Notes); {
+	case errors.Is(err, errForbiddenTransition):
+		writeAPIError(w, http.StatusForbidden, "forbidden_transition", "Tu rol no puede cobrar ni entregar bicicletas")
+		return
+	case errors.Is(err, errInvalidTransition):
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_transition",
+			fmt.Sprintf("Un ticket %s no puede pasar a %s", domain.TicketStatusLabel(ticket.Status), domain.TicketStatusLabel(req.Status)))
+		return
+	case errors.Is(err, errChecklistIncomplete):
+		writeAPIError(w, http.StatusUnprocessableEntity, "checklist_incomplete",
+			"Completa los puntos obligatorios del checklist antes de marcar el ticket como listo")
+		return
+	case errors.Is(err, errWIPLimit):
+		writeAPIError(w, http.StatusConflict, "wip_limit",
+			fmt.Sprintf("La columna %s ya tiene %d tickets, su límite", domain.TicketStatusLabel(req.Status), s.wipLimits(ctx)[req.Status]))
+		return
+	case err != nil:
+		apiInternalError(w, r, err)
+		return
+	}
+
+	counts, _ := s.repos.Tickets.CountByStatus(ctx, s.currentBranch(r))
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"ticketId":    ticket.ID,
+		"status":      req.Status,
+		"statusLabel": domain.TicketStatusLabel(req.Status),
+		"counts":      counts,
+	})
+}
+
+// handleUpdateBoardSettings saves the work-in-progress limits of the board
+func (s *Server) handleUpdateBoardSettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	before := map[string]string{}
+	after := map[string]string{}
+	for _, status := range domain.BoardWIPStatuses {
+		key := domain.WIPLimitSetting(status)
+		value := ""
+		if limit, err := strconv.Atoi(r.FormValue(key)); err == nil && limit > 0 {
+			value = strconv.Itoa(limit)
+		}
+		before[key], _ = s.repos.Settings.Get(ctx, key)
+		if err := s.repos.Settings.Set(ctx, key, value); err != nil {
+			http.Error(w, "Error saving settings", http.StatusInternalServerError)
+			return
+		}
+		after[key] = value
+	}
+	s.audit(r, "setting.update", domain.AuditEntitySetting, 0, before, after)
+	http.Redirect(w, r, "/admin/settings?msg=board", http.StatusSeeOther)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bicicletapp/internal/domain"
+)
+
+// branchCookieName holds the branch a staff member is working in. Staff
+// with a home branch are pinned to it and the cookie is ignored.
+const branchCookieName = "branch"
+
+// currentBranch returns the branch the signed-in staff member is working in,
+// or zero for every branch (consolidated view)
+func (s *Server) currentBranch(r *http.Request) int64 {
+	claims := getUserClaims(r)
+	if claims == nil {
+		return 0
+	}
+	if claims.BranchID != 0 {
+		return claims.BranchID
+	}
+	cookie, err := r.Cookie(branchCookieName)
+	if err != nil {
+		return 0
+	}
+	id, _ := strconv.ParseInt(cookie.Value, 10, 64)
+	return id
+}
+
+// inBranch reports whether the signed-in user may work on records of a
+// branch: staff pinned to a home branch only reach that one
+func (s *Server) inBranch(r *http.Request, branchID int64) bool {
+	claims := getUserClaims(r)
+	return claims != nil && (claims.BranchID == 0 || claims.BranchID == branchID)
+}
+
+// canEditTicket reports whether the signed-in user may work a ticket: it must
+// be in reach (see inBranch) and pass the tickets.edit ownership rule
+func (s *Server) canEditTicket(r *http.Request, ticket *domain.Ticket) bool {
+	return s.inBranch(r, ticket.BranchID) && s.canOn(r, domain.PermTicketsEdit, ticket.TechnicianID)
+}
+
+// worksAt reports whether a staff user can be assigned work at a branch
+func worksAt(user *domain.User, branchID int64) bool {
+	return user.BranchID == 0 || user.BranchID == branchID
+}
+
+// handleSwitchBranch changes the branch the workshop and admin views show.
+// Zero switches to the consolidated view of every branch.
+func (s *Server) handleSwitchBranch(w http.ResponseWriter, r *http.Request) {
+	if getUserClaims(r).BranchID != 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.FormValue("branch_id"), 10, 64)
+	if id != 0 {
+		branch, err := s.repos.Branches.GetByID(r.Context(), id)
+		if err != nil || branch == nil || !branch.Active {
+			http.Error(w, "Unknown branch", http.StatusBadRequest)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     branchCookieName,
+		Value:    strconv.FormatInt(id, 10),
+		Path:     "/",
+		MaxAge:   365 * 24 * 3600,
+		HttpOnly: true,
+		Secure:   !s.config.Debug,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Back to the page the switcher was used on
+	target := "/workshop"
+	if ref, err := url.Parse(r.Referer()); err == nil && strings.HasPrefix(ref.Path, "/") && !strings.HasPrefix(ref.Path, "//") {
+		target = ref.Path
+		if ref.RawQuery != "" {
+			target += "?" + ref.RawQuery
+		}
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
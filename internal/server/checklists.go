@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bicicletapp/internal/domain"
+)
+
+// handleChecklistsList shows the checklist templates and the services that
+// use each
+func (s *Server) handleChecklistsList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	checklists, err := s.repos.Checklists.List(ctx)
+	if err != nil {
+		http.Error(w, "Error loading checklists", http.StatusInternalServerError)
+		return
+	}
+	services, _ := s.repos.Services.List(ctx)
+	servicesOf := make(map[int64][]string)
+	for _, service := range services {
+		if service.ChecklistID != 0 {
+			servicesOf[service.ChecklistID] = append(servicesOf[service.ChecklistID], service.Name)
+		}
+	}
+
+	data := s.newPageData(r, "Checklists de Ingreso")
+	if r.URL.Query().Get("error") == "name_required" {
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre del checklist es obligatorio"}
+	}
+	data.Data = map[string]interface{}{
+		"Checklists": checklists,
+		"ServicesOf": servicesOf,
+	}
+	s.render(w, r, "pages/admin/checklists.html", data)
+}
+
+// handleCreateChecklist creates an empty checklist and opens it to add items
+func (s *Server) handleCreateChecklist(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Redirect(w, r, "/admin/checklists?error=name_required", http.StatusSeeOther)
+		return
+	}
+	checklist := &domain.Checklist{Name: name}
+	if err := s.repos.Checklists.Create(r.Context(), checklist); err != nil {
+		http.Error(w, "Error creating checklist", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "checklist.create", domain.AuditEntityChecklist, checklist.ID, nil, checklist)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/checklists/%d", checklist.ID), http.StatusSeeOther)
+}
+
+// checklistOf loads the checklist a request names, writing a not found
+// response when there is none
+func (s *Server) checklistOf(w http.ResponseWriter, r *http.Request) *domain.Checklist {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	checklist, err := s.repos.Checklists.GetByID(r.Context(), id)
+	if err != nil || checklist == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+	return checklist
+}
+
+// handleChecklistPage shows a checklist's items with the forms to edit them
+func (s *Server) handleChecklistPage(w http.ResponseWriter, r *http.Request) {
+	checklist := s.checklistOf(w, r)
+	if checklist == nil {
+		return
+	}
+
+	data := s.newPageData(r, "Checklist "+checklist.Name)
+	switch r.URL.Query().Get("error") {
+	case "name_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre del checklist es obligatorio"}
+	case "label_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "Describe el punto a revisar"}
+	}
+	data.Data = map[string]interface{}{
+		"Checklist": checklist,
+		"ItemTypes": domain.ChecklistItemTypes,
+	}
+	s.render(w, r, "pages/admin/checklist_form.html", data)
+}
+
+// handleRenameChecklist saves a checklist's name
+func (s *Server) handleRenameChecklist(w http.ResponseWriter, r *http.Request) {
+	checklist := s.checklistOf(w, r)
+	if checklist == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/admin/checklists/%d", checklist.ID)
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Redirect(w, r, redirect+"?error=name_required", http.StatusSeeOther)
+		return
+	}
+	if err := s.repos.Checklists.Rename(r.Context(), checklist.ID, name); err != nil {
+		http.Error(w, "Error updating checklist", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "checklist.update", domain.AuditEntityChecklist, checklist.ID,
+		map[string]string{"name": checklist.Name}, map[string]string{"name": name})
+
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handleDeleteChecklist removes a checklist; tickets keep the items they got
+func (s *Server) handleDeleteChecklist(w http.ResponseWriter, r *http.Request) {
+	checklist := s.checklistOf(w, r)
+	if checklist == nil {
+		return
+	}
+	if err := s.repos.Checklists.Delete(r.Context(), checklist.ID); err != nil {
+		http.Error(w, "Error deleting checklist", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "checklist.delete", domain.AuditEntityChecklist, checklist.ID, checklist, nil)
+
+	http.Redirect(w, r, "/admin/checklists", http.StatusSeeOther)
+}
+
+// handleAddChecklistItem appends an item to a checklist
+func (s *Server) handleAddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	checklist := s.checklistOf(w, r)
+	if checklist == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/admin/checklists/%d", checklist.ID)
+
+	item := &domain.ChecklistItem{
+		ChecklistID: checklist.ID,
+		Label:       strings.TrimSpace(r.FormValue("label")),
+		Type:        r.FormValue("type"),
+		Required:    r.FormValue("required") == "on",
+	}
+	if item.Label == "" {
+		http.Redirect(w, r, redirect+"?error=label_required", http.StatusSeeOther)
+		return
+	}
+	if !domain.IsChecklistItemType(item.Type) {
+		item.Type = domain.ChecklistCheck
+	}
+	if item.Type == domain.ChecklistMeasurement {
+		item.Unit = strings.TrimSpace(r.FormValue("unit"))
+	}
+
+	if err := s.repos.Checklists.AddItem(r.Context(), item); err != nil {
+		http.Error(w, "Error adding checklist item", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "checklist.update", domain.AuditEntityChecklist, checklist.ID, nil, item)
+
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handleDeleteChecklistItem removes an item from a checklist
+func (s *Server) handleDeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	checklist := s.checklistOf(w, r)
+	if checklist == nil {
+		return
+	}
+	itemID, _ := strconv.ParseInt(getURLParam(r, "itemId"), 10, 64)
+	var item *domain.ChecklistItem
+	for i := range checklist.Items {
+		if checklist.Items[i].ID == itemID {
+			item = &checklist.Items[i]
+		}
+	}
+	if item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := s.repos.Checklists.DeleteItem(r.Context(), checklist.ID, item.ID); err != nil {
+		http.Error(w, "Error deleting checklist item", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "checklist.update", domain.AuditEntityChecklist, checklist.ID, item, nil)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/checklists/%d", checklist.ID), http.StatusSeeOther)
+}
+
+// applyChecklist copies the checklist of a new ticket's service onto it. A
+// failure leaves the ticket without one, so it is only logged.
+func (s *Server) applyChecklist(ctx context.Context, ticket *domain.Ticket) {
+	booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
+	if booking == nil {
+		return
+	}
+	service, _ := s.repos.Services.GetByID(ctx, booking.ServiceID)
+	if service == nil || service.ChecklistID == 0 {
+		return
+	}
+	if err := s.repos.Checklists.ApplyToTicket(ctx, service.ChecklistID, ticket.ID); err != nil {
+		log.Printf("⚠️ Failed to apply checklist %d to ticket %s: %v", service.ChecklistID, ticket.TrackingCode, err)
+	}
+}
+
+// checkChecklist returns errChecklistIncomplete when a ticket moving to
+// status would be ready with required checklist items open
+func (s *Server) checkChecklist(ctx context.Context, ticket *domain.Ticket, status string) error {
+	finished := func(status string) bool {
+		return status == domain.TicketStatusReady || status == domain.TicketStatusDelivered
+	}
+	if !finished(status) || finished(ticket.Status) {
+		return nil
+	}
+	items, err := s.repos.Checklists.ListByTicket(ctx, ticket.ID)
+	if err != nil {
+		return err
+	}
+	if domain.PendingRequired(items) > 0 {
+		return errChecklistIncomplete
+	}
+	return nil
+}
+
+// ticketChecklistItem loads the checklist item of a ticket a request names,
+// writing the error response when the user cannot work on the ticket
+func (s *Server) ticketChecklistItem(w http.ResponseWriter, r *http.Request) (*domain.Ticket, *domain.TicketChecklistItem) {
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return nil, nil
+	}
+	id, _ := strconv.ParseInt(getURLParam(r, "itemId"), 10, 64)
+	item, err := s.repos.Checklists.GetTicketItem(r.Context(), id)
+	if err != nil || item == nil || item.TicketID != ticket.ID {
+		http.NotFound(w, r)
+		return nil, nil
+	}
+	return ticket, item
+}
+
+// handleCompleteChecklistItem ticks off a checklist item of a ticket,
+// recording its measurement or storing its photo as an intake attachment
+func (s *Server) handleCompleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket, item := s.ticketChecklistItem(w, r)
+	if item == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+	userID := getUserClaims(r).UserID
+
+	var value string
+	var attachmentID int64
+	switch item.Type {
+	case domain.ChecklistMeasurement:
+		value = strings.TrimSpace(r.FormValue("value"))
+		if _, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64); err != nil {
+			http.Redirect(w, r, redirect+"?error=checklist_value#checklist", http.StatusSeeOther)
+			return
+		}
+	case domain.ChecklistPhoto:
+		// limitRequestBody holds the body to a single file at the size limit
+		_, header, err := r.FormFile("photo")
+		if errors.As(err, new(*http.MaxBytesError)) {
+			http.Redirect(w, r, redirect+"?error=attachment_size#checklist", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			http.Redirect(w, r, redirect+"?error=checklist_photo#checklist", http.StatusSeeOther)
+			return
+		}
+		attachment := &domain.Attachment{
+			TicketID:   ticket.ID,
+			Kind:       domain.AttachmentIntake,
+			Caption:    item.Label,
+			Public:     true,
+			UploadedBy: userID,
+		}
+		switch err := s.storeAttachment(ctx, attachment, header); {
+		case errors.Is(err, errAttachmentTooLarge):
+			http.Redirect(w, r, redirect+"?error=attachment_size#checklist", http.StatusSeeOther)
+			return
+		case errors.Is(err, errAttachmentType), err == nil && !attachment.IsImage():
+			if err == nil {
+				s.repos.Attachments.Delete(ctx, attachment.ID)
+				s.removeAttachmentFiles(ctx, attachment)
+			}
+			http.Redirect(w, r, redirect+"?error=checklist_photo#checklist", http.StatusSeeOther)
+			return
+		case err != nil:
+			log.Printf("❌ Failed to store checklist photo of ticket %d: %v", ticket.ID, err)
+			http.Redirect(w, r, redirect+"?error=attachment_failed#checklist", http.StatusSeeOther)
+			return
+		}
+		s.audit(r, "attachment.create", domain.AuditEntityAttachment, attachment.ID, nil, attachment)
+		attachmentID = attachment.ID
+	}
+
+	if err := s.repos.Checklists.CompleteTicketItem(ctx, item.ID, value, attachmentID, userID); err != nil {
+		http.Error(w, "Error updating checklist", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "ticket.checklist", domain.AuditEntityTicket, ticket.ID, nil,
+		map[string]string{"item": item.Label, "value": value})
+
+	http.Redirect(w, r, redirect+"#checklist", http.StatusSeeOther)
+}
+
+// handleReopenChecklistItem marks a checklist item of a ticket as not done.
+// A photo it had stays among the ticket's attachments.
+func (s *Server) handleReopenChecklistItem(w http.ResponseWriter, r *http.Request) {
+	ticket, item := s.ticketChecklistItem(w, r)
+	if item == nil {
+		return
+	}
+	if err := s.repos.Checklists.ReopenTicketItem(r.Context(), item.ID); err != nil {
+		http.Error(w, "Error updating checklist", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "ticket.checklist", domain.AuditEntityTicket, ticket.ID,
+		map[string]string{"item": item.Label, "value": item.Value}, nil)
+
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d#checklist", ticket.ID), http.StatusSeeOther)
+}
+
+// checklistDone counts the completed items of a ticket's checklist
+func checklistDone(items []domain.TicketChecklistItem) int {
+	done := 0
+	for _, item := range items {
+		if item.Done() {
+			done++
+		}
+	}
+	return done
+}
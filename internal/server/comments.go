@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"bicicletapp/internal/domain"
+)
+
+// commentBody reads the comment text of a form, returning the error code to
+// report when it is empty or too long
+func commentBody(r *http.Request) (string, string) {
+	body := strings.TrimSpace(r.FormValue("body"))
+	switch {
+	case body == "":
+		return "", "comment_empty"
+	case utf8.RuneCountInString(body) > domain.MaxCommentLength:
+		return "", "comment_long"
+	}
+	return body, ""
+}
+
+// handleAddTicketComment adds a staff comment to the conversation of a
+// ticket. Customer-visible ones are sent to the customer.
+func (s *Server) handleAddTicketComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+
+	body, code := commentBody(r)
+	if code != "" {
+		http.Redirect(w, r, redirect+"?error="+code+"#comments", http.StatusSeeOther)
+		return
+	}
+	visibility := r.FormValue("visibility")
+	if !domain.IsCommentVisibility(visibility) {
+		visibility = domain.CommentInternal
+	}
+
+	comment := &domain.TicketComment{
+		TicketID:   ticket.ID,
+		AuthorID:   getUserClaims(r).UserID,
+		Visibility: visibility,
+		Body:       body,
+	}
+	if err := s.repos.Comments.Create(ctx, comment); err != nil {
+		http.Error(w, "Error saving comment", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "comment.create", domain.AuditEntityComment, comment.ID, nil, comment)
+
+	if !comment.IsInternal() {
+		s.notifyTicketCustomer(ctx, ticket,
+			fmt.Sprintf("Mensaje del taller sobre tu bicicleta (%s)", ticket.TrackingCode),
+			fmt.Sprintf("%s\n\nPuedes responder en %s/tracking/%s", body, s.config.PublicURL(), ticket.TrackingCode))
+	}
+	http.Redirect(w, r, redirect+"#comments", http.StatusSeeOther)
+}
+
+// handlePublicReplyComment adds a customer reply from the tracking page to
+// the conversation of a ticket. The tracking code is the customer's proof
+// of ownership, as for quote approvals.
+func (s *Server) handlePublicReplyComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	code := getURLParam(r, "code")
+	ticket, err := s.repos.Tickets.GetByTrackingCode(ctx, code)
+	if err != nil || ticket == nil {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	redirect := "/tracking/" + code
+
+	body, errCode := commentBody(r)
+	if errCode != "" {
+		http.Redirect(w, r, redirect+"?error="+errCode+"#comments", http.StatusSeeOther)
+		return
+	}
+
+	comment := &domain.TicketComment{
+		TicketID:     ticket.ID,
+		Visibility:   domain.CommentCustomer,
+		FromCustomer: true,
+		Body:         body,
+	}
+	if booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID); booking != nil {
+		comment.AuthorID = booking.CustomerID
+	}
+	if err := s.repos.Comments.Create(ctx, comment); err != nil {
+		http.Error(w, "Error saving comment", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "comment.create", domain.AuditEntityComment, comment.ID, nil, comment)
+	s.notifyTicketTechnician(ctx, ticket, comment)
+
+	http.Redirect(w, r, redirect+"?msg=comment_sent#comments", http.StatusSeeOther)
+}
+
+// notifyTicketTechnician emails a customer reply to the technician assigned
+// to the ticket, when email notifications are on
+func (s *Server) notifyTicketTechnician(ctx context.Context, ticket *domain.Ticket, comment *domain.TicketComment) {
+	if !s.config.Features.EmailNotifications || ticket.TechnicianID == 0 {
+		return
+	}
+	technician, err := s.repos.Users.GetByID(ctx, ticket.TechnicianID)
+	if err != nil || technician == nil || technician.Email == "" {
+		return
+	}
+	subject := fmt.Sprintf("El cliente respondió en el ticket %s", ticket.TrackingCode)
+	body := fmt.Sprintf("%s\n\n%s/tickets/%d#comments", comment.Body, s.config.PublicURL(), ticket.ID)
+	if err := s.notifier.SendEmail(ctx, technician.Email, subject, body); err != nil {
+		log.Printf("⚠️ Failed to email %s about ticket %s: %v", technician.Email, ticket.TrackingCode, err)
+	}
+}
+
+// customerComments keeps the comments of a thread the customer can read
+func customerComments(comments []domain.TicketComment) []domain.TicketComment {
+	var visible []domain.TicketComment
+	for _, c := range comments {
+		if !c.IsInternal() {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,181 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"bicicletapp/internal/config"
+	"bicicletapp/internal/domain/notifications"
+	"bicicletapp/internal/repository/sqlite"
+	"bicicletapp/internal/server"
+	"bicicletapp/internal/templates"
+)
+
+// newTestRouter builds the real router on a fresh database
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repos := sqlite.NewRepositories(db)
+
+	tmpl, err := templates.NewManager("../../templates", false)
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+
+	cfg := &config.Config{
+		Server:   config.Server{Port: 8080},
+		Business: config.Business{Name: "Test"},
+		JWT:      config.JWT{Secret: "test-secret", ExpirationHours: 24, AccessTokenMinutes: 15},
+	}
+	notifier := notifications.NewCompositeNotifier(&notifications.MockEmailProvider{}, &notifications.MockSMSProvider{})
+	return server.New(cfg, repos, tmpl, notifier).GetRouter()
+}
+
+var csrfFieldPattern = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// fetchCSRF loads the login page and returns the CSRF cookie and the token
+// embedded in its form
+func fetchCSRF(t *testing.T, router http.Handler) (*http.Cookie, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /login: status %d", rec.Code)
+	}
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "csrf_token" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("GET /login did not set a csrf_token cookie")
+	}
+
+	match := csrfFieldPattern.FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatal("login form has no csrf_token field")
+	}
+	return cookie, match[1]
+}
+
+func postLogin(router http.Handler, cookie *http.Cookie, form url.Values, header http.Header) *httptest.ResponseRecorder {
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("email", "nobody@example.com")
+	form.Set("password", "wrong-password")
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for name, values := range header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCSRFTokenIssuedAndRendered(t *testing.T) {
+	router := newTestRouter(t)
+	cookie, token := fetchCSRF(t, router)
+
+	if token != cookie.Value {
+		t.Errorf("form token %q does not match cookie %q", token, cookie.Value)
+	}
+	if !cookie.HttpOnly {
+		t.Error("csrf_token cookie should be HttpOnly")
+	}
+}
+
+func TestCSRFRejectsMissingToken(t *testing.T) {
+	router := newTestRouter(t)
+
+	if rec := postLogin(router, nil, nil, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("POST without cookie or token: got %d, want 403", rec.Code)
+	}
+
+	cookie, _ := fetchCSRF(t, router)
+	if rec := postLogin(router, cookie, nil, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("POST with cookie but no token: got %d, want 403", rec.Code)
+	}
+}
+
+func TestCSRFAcceptsMatchingToken(t *testing.T) {
+	router := newTestRouter(t)
+	cookie, token := fetchCSRF(t, router)
+
+	rec := postLogin(router, cookie, url.Values{"csrf_token": {token}}, nil)
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("POST with form token was rejected")
+	}
+
+	rec = postLogin(router, cookie, nil, http.Header{"X-Csrf-Token": {token}})
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("POST with X-CSRF-Token header was rejected")
+	}
+}
+
+func TestCSRFRejectsMismatchedToken(t *testing.T) {
+	router := newTestRouter(t)
+	cookie, _ := fetchCSRF(t, router)
+	_, other := fetchCSRF(t, router)
+
+	rec := postLogin(router, cookie, url.Values{"csrf_token": {other}}, nil)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST with another browser's token: got %d, want 403", rec.Code)
+	}
+}
+
+func TestCSRFRejectsForgedCookie(t *testing.T) {
+	router := newTestRouter(t)
+
+	// An attacker able to plant a cookie still can't mint a signed token
+	forged := "0123456789abcdef0123456789abcdef.deadbeef"
+	cookie := &http.Cookie{Name: "csrf_token", Value: forged}
+	rec := postLogin(router, cookie, url.Values{"csrf_token": {forged}}, nil)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST with forged cookie: got %d, want 403", rec.Code)
+	}
+}
+
+func TestCSRFExemptsTokenAuthenticatedRequests(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := postLogin(router, nil, nil, http.Header{"Authorization": {"Bearer invalid"}})
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("POST with Authorization header was rejected by CSRF check")
+	}
+}
+
+func TestCSRFProtectsLogout(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST /logout without token: got %d, want 403", rec.Code)
+	}
+}
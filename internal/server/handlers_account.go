@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// minPasswordLength is enforced when users choose a password through a link
+const minPasswordLength = 8
+
+// newRandomToken returns a random hex token suitable for links and cookies
+func newRandomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// hashToken returns the value stored for a plain token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueUserToken invalidates the user's previous tokens of the same purpose
+// and returns a new plain token. Only its hash is persisted.
+func (s *Server) issueUserToken(ctx context.Context, user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repos.UserTokens.InvalidateForUser(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+	err = s.repos.UserTokens.Create(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// lookupUserToken returns the usable token matching the plain value if its
+// purpose is one of the given ones
+func (s *Server) lookupUserToken(ctx context.Context, token string, purposes ...string) (*domain.UserToken, error) {
+	t, err := s.repos.UserTokens.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || !t.Usable() {
+		return nil, repository.ErrInvalidToken
+	}
+	for _, p := range purposes {
+		if t.Purpose == p {
+			return t, nil
+		}
+	}
+	return nil, repository.ErrInvalidToken
+}
+
+// sendAccountEmail delivers an account email with a single-use link. These
+// messages are sent regardless of the notification feature toggles since
+// users cannot access their account without them.
+func (s *Server) sendAccountEmail(ctx context.Context, user *domain.User, purpose string, ttl time.Duration, path, subject, intro string) {
+	if user.Email == "" {
+		return
+	}
+	token, err := s.issueUserToken(ctx, user, purpose, ttl)
+	if err != nil {
+		log.Printf("⚠️ Failed to issue %s token for %s: %v", purpose, user.Email, err)
+		return
+	}
+
+	link := s.config.PublicURL() + path + token
+	body := fmt.Sprintf("Hola %s,\n\n%s\n\n%s\n\nEl enlace vence en %s y solo puede usarse una vez. Si no fuiste tú, ignora este mensaje.\n\n%s",
+		user.Name, intro, link, formatTokenTTL(ttl), s.config.Business.Name)
+	if err := s.notifier.SendEmail(ctx, user.Email, subject, body); err != nil {
+		log.Printf("⚠️ Failed to send %s email to %s: %v", purpose, user.Email, err)
+	}
+}
+
+// formatTokenTTL renders a token lifetime in Spanish
+func formatTokenTTL(ttl time.Duration) string {
+	if ttl < 24*time.Hour {
+		hours := int(ttl.Hours())
+		if hours == 1 {
+			return "1 hora"
+		}
+		return fmt.Sprintf("%d horas", hours)
+	}
+	days := int(ttl.Hours() / 24)
+	if days == 1 {
+		return "1 día"
+	}
+	return fmt.Sprintf("%d días", days)
+}
+
+func (s *Server) sendVerificationEmail(ctx context.Context, user *domain.User) {
+	s.sendAccountEmail(ctx, user, domain.TokenPurposeVerify, domain.VerifyTokenTTL, "/verify-email/",
+		"Verifica tu email", "Confirma tu dirección de correo para activar tu cuenta:")
+}
+
+func (s *Server) sendPasswordResetEmail(ctx context.Context, user *domain.User) {
+	s.sendAccountEmail(ctx, user, domain.TokenPurposeReset, domain.ResetTokenTTL, "/reset-password/",
+		"Restablecer contraseña", "Recibimos un pedido para restablecer tu contraseña. Elige una nueva desde este enlace:")
+}
+
+func (s *Server) sendInvitationEmail(ctx context.Context, user *domain.User) {
+	s.sendAccountEmail(ctx, user, domain.TokenPurposeInvite, domain.InviteTokenTTL, "/invite/",
+		"Tu cuenta en "+s.config.Business.Name, "Registramos tu bicicleta en el taller. Crea tu contraseña para seguir el estado de tus reparaciones y reservar turnos:")
+}
+
+// handleForgotPasswordPage renders the forgot password form
+func (s *Server) handleForgotPasswordPage(w http.ResponseWriter, r *http.Request) {
+	data := s.newPageData(r, "Recuperar Contraseña")
+	s.render(w, r, "pages/public/forgot_password.html", data)
+}
+
+// handleForgotPassword emails a reset link. The response is the same whether
+// or not the email exists so accounts can't be enumerated.
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	email := strings.TrimSpace(r.FormValue("email"))
+	if user, _ := s.repos.Users.GetByEmail(ctx, email); user != nil {
+		s.sendPasswordResetEmail(ctx, user)
+	}
+
+	data := s.newPageData(r, "Recuperar Contraseña")
+	data.Flash = &FlashMessage{Type: "success", Message: "Si el email está registrado, te enviamos un enlace para restablecer tu contraseña."}
+	s.render(w, r, "pages/public/forgot_password.html", data)
+}
+
+// passwordTokenPurpose returns the token purpose served by a set-password route
+func passwordTokenPurpose(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/invite/") {
+		return domain.TokenPurposeInvite
+	}
+	return domain.TokenPurposeReset
+}
+
+// renderSetPassword renders the set password form for reset and invitation links
+func (s *Server) renderSetPassword(w http.ResponseWriter, r *http.Request, purpose string, flash *FlashMessage) {
+	title := "Nueva Contraseña"
+	if purpose == domain.TokenPurposeInvite {
+		title = "Crea tu Contraseña"
+	}
+	data := s.newPageData(r, title)
+	data.Flash = flash
+	data.Data = map[string]interface{}{
+		"Action": r.URL.Path,
+		"Invite": purpose == domain.TokenPurposeInvite,
+	}
+	s.render(w, r, "pages/public/reset_password.html", data)
+}
+
+// handleSetPasswordPage shows the form behind a reset or invitation link
+func (s *Server) handleSetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	purpose := passwordTokenPurpose(r)
+	if _, err := s.lookupUserToken(r.Context(), getURLParam(r, "token"), purpose); err != nil {
+		http.Redirect(w, r, "/login?error=invalid_token", http.StatusSeeOther)
+		return
+	}
+	s.renderSetPassword(w, r, purpose, nil)
+}
+
+// handleSetPassword consumes a reset or invitation token and stores the new
+// password. Following either link proves ownership of the email address.
+func (s *Server) handleSetPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	purpose := passwordTokenPurpose(r)
+	token, err := s.lookupUserToken(ctx, getURLParam(r, "token"), purpose)
+	if err != nil {
+		http.Redirect(w, r, "/login?error=invalid_token", http.StatusSeeOther)
+		return
+	}
+
+	password := r.FormValue("password")
+	if len(password) < minPasswordLength {
+		s.renderSetPassword(w, r, purpose, &FlashMessage{Type: "error", Message: fmt.Sprintf("La contraseña debe tener al menos %d caracteres", minPasswordLength)})
+		return
+	}
+	if password != r.FormValue("confirm_password") {
+		s.renderSetPassword(w, r, purpose, &FlashMessage{Type: "error", Message: "Las contraseñas no coinciden"})
+		return
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		http.Error(w, "Error processing password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.repos.UserTokens.Consume(ctx, token.ID); err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			http.Redirect(w, r, "/login?error=invalid_token", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Error updating password", http.StatusInternalServerError)
+		return
+	}
+	if err := s.repos.Users.UpdatePassword(ctx, token.UserID, hashedPassword); err != nil {
+		http.Error(w, "Error updating password", http.StatusInternalServerError)
+		return
+	}
+	if err := s.repos.Users.MarkEmailVerified(ctx, token.UserID); err != nil {
+		log.Printf("⚠️ Failed to mark user %d as verified: %v", token.UserID, err)
+	}
+	s.revokeUserSessions(ctx, token.UserID, 0)
+	s.auditAs(r, token.UserID, "user.password_reset", domain.AuditEntityUser, token.UserID,
+		nil, map[string]string{"via": purpose})
+
+	http.Redirect(w, r, "/login?password=1", http.StatusSeeOther)
+}
+
+// handleVerifyEmail consumes a verification link
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token, err := s.lookupUserToken(ctx, getURLParam(r, "token"), domain.TokenPurposeVerify)
+	if err == nil {
+		err = s.repos.UserTokens.Consume(ctx, token.ID)
+	}
+	if err != nil {
+		http.Redirect(w, r, "/login?error=invalid_token", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Users.MarkEmailVerified(ctx, token.UserID); err != nil {
+		http.Error(w, "Error verifying email", http.StatusInternalServerError)
+		return
+	}
+	s.auditAs(r, token.UserID, "user.verify_email", domain.AuditEntityUser, token.UserID,
+		map[string]bool{"emailVerified": false}, map[string]bool{"emailVerified": true})
+	http.Redirect(w, r, "/login?verified=1", http.StatusSeeOther)
+}
+
+// handleResendVerification sends a fresh verification link to an unverified account
+func (s *Server) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	email := strings.TrimSpace(r.FormValue("email"))
+	if user, _ := s.repos.Users.GetByEmail(ctx, email); user != nil && !user.EmailVerified {
+		s.sendVerificationEmail(ctx, user)
+	}
+	http.Redirect(w, r, "/login?resent=1", http.StatusSeeOther)
+}
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"bicicletapp/internal/barcode"
 	"bicicletapp/internal/domain"
+	"bicicletapp/internal/pdf"
+	"bicicletapp/internal/repository"
 )
 
 // Admin handlers
@@ -14,21 +23,28 @@ import (
 // handleAdminDashboard shows admin dashboard with metrics
 func (s *Server) handleAdminDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	branchID := s.currentBranch(r)
 
 	// Get counts
 	userCount, _ := s.repos.Users.Count(ctx, "")
 	customerCount, _ := s.repos.Users.Count(ctx, domain.RoleCustomer)
 	techCount, _ := s.repos.Users.Count(ctx, domain.RoleTechnician)
 
-	bookingCount, _ := s.repos.Bookings.CountByStatus(ctx, "")
-	pendingBookings, _ := s.repos.Bookings.CountByStatus(ctx, domain.BookingStatusPending)
+	bookingCount, _ := s.repos.Bookings.CountByStatus(ctx, branchID, "")
+	pendingBookings, _ := s.repos.Bookings.CountByStatus(ctx, branchID, domain.BookingStatusPending)
 
-	ticketCounts, _ := s.repos.Tickets.CountByStatus(ctx)
+	ticketCounts, _ := s.repos.Tickets.CountByStatus(ctx, branchID)
 
 	// Get average rating
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	avgRating, _ := s.repos.Surveys.GetAverageRating(ctx, thirtyDaysAgo)
 
+	// Flagged serials that showed up at reception
+	stolenSightings, _ := s.repos.StolenBikes.ListSeen(ctx, 10)
+
+	// Parts at or below their minimum stock
+	lowStock, _ := s.repos.Inventory.ListLowStock(ctx)
+
 	data := s.newPageData(r, "Panel de Administración")
 	data.Data = map[string]interface{}{
 		"UserCount":       userCount,
@@ -38,6 +54,8 @@ func (s *Server) handleAdminDashboard(w http.ResponseWriter, r *http.Request) {
 		"PendingBookings": pendingBookings,
 		"TicketCounts":    ticketCounts,
 		"AvgRating":       avgRating,
+		"StolenSightings": stolenSightings,
+		"LowStock":        lowStock,
 	}
 	s.render(w, r, "pages/admin/dashboard.html", data)
 }
@@ -54,20 +72,62 @@ func (s *Server) handleUsersList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	roles, _ := s.repos.Roles.List(ctx)
+	roleLabels := make(map[string]string, len(roles))
+	for _, ro := range roles {
+		roleLabels[ro.Name] = ro.Label
+	}
+
 	data := s.newPageData(r, "Gestión de Usuarios")
 	data.Data = map[string]interface{}{
 		"Users":       users,
 		"CurrentRole": role,
+		"RoleLabels":  roleLabels,
 	}
 	s.render(w, r, "pages/admin/users.html", data)
 }
 
 func (s *Server) handleNewUserPage(w http.ResponseWriter, r *http.Request) {
-	data := s.newPageData(r, "Nuevo Usuario")
-	data.Data = map[string]interface{}{"User": nil}
+	s.renderUserForm(w, r, "Nuevo Usuario", nil, nil)
+}
+
+// renderUserForm renders the user form with the roles the signed-in user
+// may assign
+func (s *Server) renderUserForm(w http.ResponseWriter, r *http.Request, title string, user *domain.User, flash *FlashMessage) {
+	roles, _ := s.repos.Roles.List(r.Context())
+	assignable := make([]domain.Role, 0, len(roles))
+	for _, role := range roles {
+		if s.canGrantRole(r, role.Name) {
+			assignable = append(assignable, role)
+		}
+	}
+
+	var homeBranch int64
+	if user != nil {
+		homeBranch = user.BranchID
+	}
+
+	data := s.newPageData(r, title)
+	data.Flash = flash
+	data.Data = map[string]interface{}{
+		"User":              user,
+		"Roles":             assignable,
+		"HomeBranch":        homeBranch,
+		"DefaultDailyHours": domain.DefaultDailyHours,
+	}
 	s.render(w, r, "pages/admin/user_form.html", data)
 }
 
+// formHomeBranch reads the home branch of the user form. Admins pinned to a
+// branch can only place users in it.
+func (s *Server) formHomeBranch(r *http.Request) int64 {
+	branchID, _ := strconv.ParseInt(r.FormValue("branch_id"), 10, 64)
+	if !s.inBranch(r, branchID) {
+		return getUserClaims(r).BranchID
+	}
+	return branchID
+}
+
 func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -77,22 +137,25 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	email := r.FormValue("email")
+	// Pass back the input data so user doesn't lose it
+	input := &domain.User{
+		Name:     r.FormValue("name"),
+		Email:    email,
+		Phone:    r.FormValue("phone"),
+		Role:     r.FormValue("role"),
+		BranchID: s.formHomeBranch(r),
+	}
+	input.Skills, input.DailyHours = formWorkProfile(r)
+
+	if !s.canGrantRole(r, input.Role) {
+		s.renderUserForm(w, r, "Nuevo Usuario", input, &FlashMessage{Type: "error", Message: "No puedes asignar ese rol"})
+		return
+	}
 
 	// Check if email already exists
 	existingUser, _ := s.repos.Users.GetByEmail(ctx, email)
 	if existingUser != nil {
-		data := s.newPageData(r, "Nuevo Usuario")
-		// Pass back the input data so user doesn't lose it
-		data.Data = map[string]interface{}{
-			"User": &domain.User{
-				Name:  r.FormValue("name"),
-				Email: email,
-				Phone: r.FormValue("phone"),
-				Role:  r.FormValue("role"),
-			},
-		}
-		data.Flash = &FlashMessage{Type: "error", Message: "El email ya está registrado"}
-		s.render(w, r, "pages/admin/user_form.html", data)
+		s.renderUserForm(w, r, "Nuevo Usuario", input, &FlashMessage{Type: "error", Message: "El email ya está registrado"})
 		return
 	}
 
@@ -108,13 +171,23 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		Email:        email,
 		Phone:        r.FormValue("phone"),
 		Role:         r.FormValue("role"),
+		BranchID:     input.BranchID,
 		PasswordHash: hashedPassword,
+		// Accounts created by an admin don't need to confirm their email
+		EmailVerified: true,
+		Skills:        input.Skills,
+		DailyHours:    input.DailyHours,
 	}
 
 	if err := s.repos.Users.Create(ctx, user); err != nil {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
+	if err := s.repos.Users.UpdateWorkProfile(ctx, user.ID, user.Skills, user.DailyHours); err != nil {
+		http.Error(w, "Error creating user", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "user.create", domain.AuditEntityUser, user.ID, nil, user)
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
@@ -129,9 +202,11 @@ func (s *Server) handleEditUserPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := s.newPageData(r, "Editar Usuario")
-	data.Data = map[string]interface{}{"User": user}
-	s.render(w, r, "pages/admin/user_form.html", data)
+	var flash *FlashMessage
+	if r.URL.Query().Get("msg") == "2fa_reset" {
+		flash = &FlashMessage{Type: "success", Message: "Verificación en dos pasos restablecida. El usuario deberá configurarla de nuevo."}
+	}
+	s.renderUserForm(w, r, "Editar Usuario", user, flash)
 }
 
 func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -149,43 +224,74 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users who hold more than the editor are out of reach
+	if !s.canGrantRole(r, user.Role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if !s.canGrantRole(r, r.FormValue("role")) {
+		s.renderUserForm(w, r, "Editar Usuario", user, &FlashMessage{Type: "error", Message: "No puedes asignar ese rol"})
+		return
+	}
+
 	// Check for duplicate email if changed
 	newEmail := r.FormValue("email")
 	if newEmail != user.Email {
 		existing, _ := s.repos.Users.GetByEmail(ctx, newEmail)
 		if existing != nil {
-			data := s.newPageData(r, "Editar Usuario")
 			// Update user object with form values for re-rendering
 			user.Name = r.FormValue("name")
 			user.Email = newEmail
 			user.Phone = r.FormValue("phone")
 			user.Role = r.FormValue("role")
+			user.BranchID = s.formHomeBranch(r)
+			user.Skills, user.DailyHours = formWorkProfile(r)
 
-			data.Data = map[string]interface{}{"User": user}
-			data.Flash = &FlashMessage{Type: "error", Message: "El email ya está registrado"}
-			s.render(w, r, "pages/admin/user_form.html", data)
+			s.renderUserForm(w, r, "Editar Usuario", user, &FlashMessage{Type: "error", Message: "El email ya está registrado"})
 			return
 		}
 	}
 
+	branchID := s.formHomeBranch(r)
+	roleChanged := r.FormValue("role") != user.Role || branchID != user.BranchID
+	before := *user
+
 	user.Name = r.FormValue("name")
 	user.Email = newEmail
 	user.Phone = r.FormValue("phone")
 	user.Role = r.FormValue("role")
+	user.BranchID = branchID
+	user.Skills, user.DailyHours = formWorkProfile(r)
+
+	if err := s.repos.Users.Update(ctx, user); err != nil {
+		http.Error(w, "Error updating user", http.StatusInternalServerError)
+		return
+	}
+	if err := s.repos.Users.UpdateWorkProfile(ctx, user.ID, user.Skills, user.DailyHours); err != nil {
+		http.Error(w, "Error updating user", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "user.update", domain.AuditEntityUser, user.ID, &before, user)
 
 	// Update password if provided
-	if newPassword := r.FormValue("password"); newPassword != "" {
+	newPassword := r.FormValue("password")
+	if newPassword != "" {
 		hashedPassword, err := hashPassword(newPassword)
 		if err != nil {
 			http.Error(w, "Error processing password", http.StatusInternalServerError)
 			return
 		}
-		user.PasswordHash = hashedPassword
+		if err := s.repos.Users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			http.Error(w, "Error updating password", http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, "user.password_change", domain.AuditEntityUser, user.ID, nil, nil)
 	}
 
-	if err := s.repos.Users.Update(ctx, user); err != nil {
-		http.Error(w, "Error updating user", http.StatusInternalServerError)
-		return
+	// Existing sessions carry the old role and home branch (or were opened
+	// with the old password), so sign the user out everywhere
+	if roleChanged || newPassword != "" {
+		s.revokeUserSessions(ctx, user.ID, 0)
 	}
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
@@ -195,14 +301,55 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	user, _ := s.repos.Users.GetByID(ctx, id)
+	if user != nil && !s.canGrantRole(r, user.Role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 	if err := s.repos.Users.Delete(ctx, id); err != nil {
 		http.Error(w, "Error deleting user", http.StatusInternalServerError)
 		return
 	}
+	s.revokeUserSessions(ctx, id, 0)
+	s.audit(r, "user.delete", domain.AuditEntityUser, id, user, nil)
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
 
+// Sessions
+
+func (s *Server) handleSessionsList(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.repos.Sessions.ListActive(r.Context(), 0)
+	if err != nil {
+		http.Error(w, "Error loading sessions", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Sesiones Activas")
+	data.Data = map[string]interface{}{
+		"Sessions":       sessions,
+		"CurrentSession": getUserClaims(r).SessionID,
+	}
+	s.render(w, r, "pages/admin/sessions.html", data)
+}
+
+func (s *Server) handleAdminRevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	if err := s.repos.Sessions.Revoke(r.Context(), id); err != nil {
+		http.Error(w, "Error revoking session", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "session.revoke", domain.AuditEntitySession, id, nil, nil)
+	http.Redirect(w, r, "/admin/sessions", http.StatusSeeOther)
+}
+
+func (s *Server) handleAdminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	s.revokeUserSessions(r.Context(), id, 0)
+	s.audit(r, "user.sessions_revoke", domain.AuditEntityUser, id, nil, nil)
+	http.Redirect(w, r, "/admin/sessions", http.StatusSeeOther)
+}
+
 // Catalog management - Brands
 
 func (s *Server) handleBrandsList(w http.ResponseWriter, r *http.Request) {
@@ -257,6 +404,7 @@ func (s *Server) handleCreateBrand(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating brand", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "brand.create", domain.AuditEntityBrand, brand.ID, nil, brand)
 
 	http.Redirect(w, r, "/admin/brands", http.StatusSeeOther)
 }
@@ -276,6 +424,7 @@ func (s *Server) handleUpdateBrand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *brand
 	brand.Name = r.FormValue("name")
 	brand.LogoURL = r.FormValue("logo_url")
 
@@ -283,6 +432,7 @@ func (s *Server) handleUpdateBrand(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error updating brand", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "brand.update", domain.AuditEntityBrand, brand.ID, &before, brand)
 
 	http.Redirect(w, r, "/admin/brands", http.StatusSeeOther)
 }
@@ -291,10 +441,12 @@ func (s *Server) handleDeleteBrand(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	brand, _ := s.repos.Brands.GetByID(ctx, id)
 	if err := s.repos.Brands.Delete(ctx, id); err != nil {
 		http.Error(w, "Error deleting brand", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "brand.delete", domain.AuditEntityBrand, id, brand, nil)
 
 	http.Redirect(w, r, "/admin/brands", http.StatusSeeOther)
 }
@@ -372,6 +524,7 @@ func (s *Server) handleCreateModel(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating model", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "model.create", domain.AuditEntityModel, model.ID, nil, model)
 
 	http.Redirect(w, r, "/admin/models", http.StatusSeeOther)
 }
@@ -391,6 +544,7 @@ func (s *Server) handleUpdateModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *model
 	brandID, _ := strconv.ParseInt(r.FormValue("brand_id"), 10, 64)
 	model.BrandID = brandID
 	model.Name = r.FormValue("name")
@@ -399,6 +553,7 @@ func (s *Server) handleUpdateModel(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error updating model", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "model.update", domain.AuditEntityModel, model.ID, &before, model)
 
 	http.Redirect(w, r, "/admin/models", http.StatusSeeOther)
 }
@@ -407,10 +562,12 @@ func (s *Server) handleDeleteModel(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	model, _ := s.repos.Models.GetByID(ctx, id)
 	if err := s.repos.Models.Delete(ctx, id); err != nil {
 		http.Error(w, "Error deleting model", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "model.delete", domain.AuditEntityModel, id, model, nil)
 
 	http.Redirect(w, r, "/admin/models", http.StatusSeeOther)
 }
@@ -432,8 +589,10 @@ func (s *Server) handleServicesList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleNewServicePage(w http.ResponseWriter, r *http.Request) {
+	checklists, _ := s.repos.Checklists.List(r.Context())
+
 	data := s.newPageData(r, "Nuevo Servicio")
-	data.Data = map[string]interface{}{"Service": nil}
+	data.Data = map[string]interface{}{"Service": nil, "Checklists": checklists}
 	s.render(w, r, "pages/admin/service_form.html", data)
 }
 
@@ -447,18 +606,22 @@ func (s *Server) handleCreateService(w http.ResponseWriter, r *http.Request) {
 
 	basePrice, _ := strconv.ParseFloat(r.FormValue("base_price"), 64)
 	estimatedHours, _ := strconv.ParseFloat(r.FormValue("estimated_hours"), 64)
+	checklistID, _ := strconv.ParseInt(r.FormValue("checklist_id"), 10, 64)
 
 	service := &domain.Service{
 		Name:           r.FormValue("name"),
 		Description:    r.FormValue("description"),
 		BasePrice:      basePrice,
 		EstimatedHours: estimatedHours,
+		RequiredSkills: domain.ParseSkills(r.FormValue("required_skills")),
+		ChecklistID:    checklistID,
 	}
 
 	if err := s.repos.Services.Create(ctx, service); err != nil {
 		http.Error(w, "Error creating service", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "service.create", domain.AuditEntityService, service.ID, nil, service)
 
 	http.Redirect(w, r, "/admin/services", http.StatusSeeOther)
 }
@@ -473,8 +636,10 @@ func (s *Server) handleEditServicePage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	checklists, _ := s.repos.Checklists.List(ctx)
+
 	data := s.newPageData(r, "Editar Servicio")
-	data.Data = map[string]interface{}{"Service": service}
+	data.Data = map[string]interface{}{"Service": service, "Checklists": checklists}
 	s.render(w, r, "pages/admin/service_form.html", data)
 }
 
@@ -493,15 +658,19 @@ func (s *Server) handleUpdateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *service
 	service.Name = r.FormValue("name")
 	service.Description = r.FormValue("description")
 	service.BasePrice, _ = strconv.ParseFloat(r.FormValue("base_price"), 64)
 	service.EstimatedHours, _ = strconv.ParseFloat(r.FormValue("estimated_hours"), 64)
+	service.RequiredSkills = domain.ParseSkills(r.FormValue("required_skills"))
+	service.ChecklistID, _ = strconv.ParseInt(r.FormValue("checklist_id"), 10, 64)
 
 	if err := s.repos.Services.Update(ctx, service); err != nil {
 		http.Error(w, "Error updating service", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "service.update", domain.AuditEntityService, service.ID, &before, service)
 
 	http.Redirect(w, r, "/admin/services", http.StatusSeeOther)
 }
@@ -510,10 +679,12 @@ func (s *Server) handleDeleteService(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	service, _ := s.repos.Services.GetByID(ctx, id)
 	if err := s.repos.Services.Delete(ctx, id); err != nil {
 		http.Error(w, "Error deleting service", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "service.delete", domain.AuditEntityService, id, service, nil)
 
 	http.Redirect(w, r, "/admin/services", http.StatusSeeOther)
 }
@@ -522,21 +693,28 @@ func (s *Server) handleDeleteService(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleReportsDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	branchID := s.currentBranch(r)
 
 	// Get this month's bookings count
 	now := time.Now()
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	monthlyBookings, _ := s.repos.Bookings.GetByDateRange(ctx, startOfMonth, now)
+	monthlyBookings, _ := s.repos.Bookings.GetByDateRange(ctx, branchID, startOfMonth, now)
 
 	// Get ticket counts
-	ticketCounts, _ := s.repos.Tickets.CountByStatus(ctx)
+	ticketCounts, _ := s.repos.Tickets.CountByStatus(ctx, branchID)
+
+	// The consolidated view compares the branches side by side
+	var branchStats []branchReport
+	if branchID == 0 {
+		branchStats = s.branchReports(ctx, startOfMonth, now)
+	}
 
 	// Get average rating
 	thirtyDaysAgo := now.AddDate(0, 0, -30)
 	avgRating, _ := s.repos.Surveys.GetAverageRating(ctx, thirtyDaysAgo)
 
 	// Get approved quotes for revenue
-	quotes, _ := s.repos.Quotes.List(ctx, domain.QuoteStatusApproved, 100, 0)
+	quotes, _ := s.repos.Quotes.List(ctx, s.currentBranch(r), domain.QuoteStatusApproved, 100, 0)
 	var totalRevenue float64
 	for _, q := range quotes {
 		totalRevenue += q.Total
@@ -549,10 +727,38 @@ func (s *Server) handleReportsDashboard(w http.ResponseWriter, r *http.Request)
 		"AvgRating":       avgRating,
 		"TotalRevenue":    totalRevenue,
 		"CurrentMonth":    now.Month().String(),
+		"BranchStats":     branchStats,
+		"AtRisk":          len(s.atRiskTickets(ctx, branchID)),
 	}
 	s.render(w, r, "pages/admin/reports.html", data)
 }
 
+// branchReport is one row of the branch comparison in the reports dashboard
+type branchReport struct {
+	Branch          domain.Branch
+	MonthlyBookings int
+	OpenTickets     int
+	Delivered       int
+}
+
+// branchReports compares the bookings and tickets of every branch
+func (s *Server) branchReports(ctx context.Context, from, to time.Time) []branchReport {
+	branches, _ := s.repos.Branches.List(ctx, true)
+	reports := make([]branchReport, 0, len(branches))
+	for _, branch := range branches {
+		bookings, _ := s.repos.Bookings.GetByDateRange(ctx, branch.ID, from, to)
+		counts, _ := s.repos.Tickets.CountByStatus(ctx, branch.ID)
+		report := branchReport{Branch: branch, MonthlyBookings: len(bookings), Delivered: counts[domain.TicketStatusDelivered]}
+		for status, n := range counts {
+			if status != domain.TicketStatusDelivered {
+				report.OpenTickets += n
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
 func (s *Server) handleBookingsReport(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -560,7 +766,7 @@ func (s *Server) handleBookingsReport(w http.ResponseWriter, r *http.Request) {
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, 0, -30)
 
-	bookings, _ := s.repos.Bookings.GetByDateRange(ctx, startDate, endDate)
+	bookings, _ := s.repos.Bookings.GetByDateRange(ctx, s.currentBranch(r), startDate, endDate)
 
 	data := s.newPageData(r, "Reporte de Reservas")
 	data.Data = map[string]interface{}{
@@ -575,7 +781,7 @@ func (s *Server) handleRevenueReport(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get approved quotes for revenue calculation
-	quotes, _ := s.repos.Quotes.List(ctx, domain.QuoteStatusApproved, 100, 0)
+	quotes, _ := s.repos.Quotes.List(ctx, s.currentBranch(r), domain.QuoteStatusApproved, 100, 0)
 
 	var totalRevenue float64
 	for _, q := range quotes {
@@ -601,7 +807,8 @@ func (s *Server) handleSurveysReport(w http.ResponseWriter, r *http.Request) {
 
 	// Calculate response rate
 	// Total tickets that could have a survey (delivered or ready)
-	counts, _ := s.repos.Tickets.CountByStatus(ctx)
+	// Surveys aren't split by branch, so neither is the response rate
+	counts, _ := s.repos.Tickets.CountByStatus(ctx, 0)
 	eligibleTickets := counts[domain.TicketStatusDelivered] + counts[domain.TicketStatusReady]
 
 	var responseRate float64
@@ -655,9 +862,26 @@ func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := s.newPageData(r, "Configuración")
+	switch r.URL.Query().Get("msg") {
+	case "security":
+		data.Flash = &FlashMessage{Type: "success", Message: "Política de seguridad actualizada"}
+	case "board":
+		data.Flash = &FlashMessage{Type: "success", Message: "Límites del tablero actualizados"}
+	case "labor":
+		data.Flash = &FlashMessage{Type: "success", Message: "Tarifa de mano de obra actualizada"}
+	case "assignment":
+		data.Flash = &FlashMessage{Type: "success", Message: "Asignación de técnicos actualizada"}
+	}
 	data.Data = map[string]interface{}{
-		"Config":      s.config,
-		"HeroConcept": heroConcept,
+		"Config":               s.config,
+		"HeroConcept":          heroConcept,
+		"Require2FAAdmin":      s.twoFactorRequired(ctx, domain.RoleAdmin),
+		"Require2FATechnician": s.twoFactorRequired(ctx, domain.RoleTechnician),
+		"WIPStatuses":          domain.BoardWIPStatuses,
+		"WIPLimits":            s.wipLimits(ctx),
+		"LaborRate":            s.laborRate(ctx),
+		"AssignmentStrategies": domain.AssignmentStrategies,
+		"AssignmentStrategy":   s.assignmentStrategy(ctx),
 	}
 	s.render(w, r, "pages/admin/settings.html", data)
 }
@@ -672,12 +896,15 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 
 	heroConcept := r.FormValue("hero_concept")
 	if heroConcept != "" {
+		previous, _ := s.repos.Settings.Get(ctx, "hero_concept")
 		if err := s.repos.Settings.Set(ctx, "hero_concept", heroConcept); err != nil {
 			data := s.newPageData(r, "Configuración")
 			data.Flash = &FlashMessage{Type: "error", Message: "Error al guardar la configuración"}
 			s.render(w, r, "pages/admin/settings.html", data)
 			return
 		}
+		s.audit(r, "setting.update", domain.AuditEntitySetting, 0,
+			map[string]string{"hero_concept": previous}, map[string]string{"hero_concept": heroConcept})
 	}
 
 	data := s.newPageData(r, "Configuración")
@@ -685,8 +912,15 @@ func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
 
 	// Re-fetch to show updated state
 	data.Data = map[string]interface{}{
-		"Config":      s.config,
-		"HeroConcept": heroConcept,
+		"Config":               s.config,
+		"HeroConcept":          heroConcept,
+		"Require2FAAdmin":      s.twoFactorRequired(ctx, domain.RoleAdmin),
+		"Require2FATechnician": s.twoFactorRequired(ctx, domain.RoleTechnician),
+		"WIPStatuses":          domain.BoardWIPStatuses,
+		"WIPLimits":            s.wipLimits(ctx),
+		"LaborRate":            s.laborRate(ctx),
+		"AssignmentStrategies": domain.AssignmentStrategies,
+		"AssignmentStrategy":   s.assignmentStrategy(ctx),
 	}
 	s.render(w, r, "pages/admin/settings.html", data)
 }
@@ -717,24 +951,38 @@ func (s *Server) apiGetAvailableSlots(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get existing bookings for the date
+	branchID, _ := strconv.ParseInt(r.URL.Query().Get("branch_id"), 10, 64)
+	if branchID == 0 {
+		branchID = domain.DefaultBranchID
+	}
+	branch, err := s.repos.Branches.GetByID(ctx, branchID)
+	if err != nil || branch == nil || !branch.Active {
+		http.Error(w, "Unknown branch", http.StatusBadRequest)
+		return
+	}
+
+	// Get existing bookings for the date at the branch
 	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	existingBookings, _ := s.repos.Bookings.GetByDateRange(ctx, startOfDay, endOfDay)
+	existingBookings, _ := s.repos.Bookings.GetByDateRange(ctx, branch.ID, startOfDay, endOfDay)
 
-	// Define available slots (9am to 6pm, 1 hour each)
-	allSlots := []string{"09:00", "10:00", "11:00", "12:00", "14:00", "15:00", "16:00", "17:00"}
+	// The branch's booking hours, if it opens that day
+	allSlots := []string{}
+	if branch.OpenOn(date.Weekday()) {
+		allSlots = branch.SlotTimes()
+	}
 
-	// Filter out booked slots
-	bookedSlots := make(map[string]bool)
+	// Filter out full slots: each takes a booking per available technician
+	capacity := s.slotCapacity(ctx, branch.ID)
+	bookedSlots := make(map[string]int)
 	for _, b := range existingBookings {
-		bookedSlots[b.ScheduledAt.Format("15:04")] = true
+		bookedSlots[b.ScheduledAt.Format("15:04")]++
 	}
 
-	var availableSlots []string
+	availableSlots := []string{}
 	for _, slot := range allSlots {
-		if !bookedSlots[slot] {
+		if bookedSlots[slot] < capacity {
 			availableSlots = append(availableSlots, slot)
 		}
 	}
@@ -771,7 +1019,8 @@ func (s *Server) handleAdminTicketsList(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 
 	status := r.URL.Query().Get("status")
-	tickets, err := s.repos.Tickets.List(ctx, status, 100, 0)
+	branchID := s.currentBranch(r)
+	tickets, err := s.repos.Tickets.List(ctx, branchID, status, 100, 0)
 	if err != nil {
 		http.Error(w, "Error loading tickets", http.StatusInternalServerError)
 		return
@@ -782,7 +1031,7 @@ func (s *Server) handleAdminTicketsList(w http.ResponseWriter, r *http.Request)
 	// We might need to fetch technicians.
 	// Actually List implementation does fetch basic fields.
 	// Let's get all technicians for the dropdown
-	technicians, _ := s.repos.Users.List(ctx, domain.RoleTechnician, 100, 0)
+	technicians := s.staffUsers(ctx, branchID)
 
 	// We need to fetch customer info for each ticket... this is N+1 but ok for now or we update repo.
 	// For now, let's just show the ticket and technician.
@@ -841,13 +1090,25 @@ func (s *Server) handleAdminUpdateTicketTechnician(w http.ResponseWriter, r *htt
 
 	techID, _ := strconv.ParseInt(r.FormValue("technician_id"), 10, 64)
 
+	// Only staff working at the ticket's branch can take it
+	if techID != 0 {
+		tech, _ := s.repos.Users.GetByID(ctx, techID)
+		if tech == nil || !worksAt(tech, ticket.BranchID) {
+			http.Error(w, "Technician does not work at this branch", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Update technician
+	previousTechID := ticket.TechnicianID
 	ticket.TechnicianID = techID
 
 	if err := s.repos.Tickets.Update(ctx, ticket); err != nil {
 		http.Error(w, "Error updating ticket", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ticket.assign", domain.AuditEntityTicket, ticket.ID,
+		map[string]int64{"technicianId": previousTechID}, map[string]int64{"technicianId": techID})
 
 	// Add history record for reassignment
 	// We can use the UpdateStatus logic or just insert history manually
@@ -902,6 +1163,7 @@ func (s *Server) handleCreateAd(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating ad", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ad.create", domain.AuditEntityAd, ad.ID, nil, ad)
 
 	http.Redirect(w, r, "/admin/ads", http.StatusSeeOther)
 }
@@ -926,6 +1188,7 @@ func (s *Server) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
 	// For simplicity, let's assume it's a full update or just active toggle.
 	// If "action" param is "toggle", just flip active.
 
+	before := *ad
 	if r.FormValue("action") == "toggle" {
 		ad.Active = !ad.Active
 	} else {
@@ -941,6 +1204,7 @@ func (s *Server) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error updating ad", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ad.update", domain.AuditEntityAd, ad.ID, &before, ad)
 
 	http.Redirect(w, r, "/admin/ads", http.StatusSeeOther)
 }
@@ -948,11 +1212,1212 @@ func (s *Server) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleDeleteAd(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ad, _ := s.repos.Ads.GetByID(ctx, id)
 
 	if err := s.repos.Ads.Delete(ctx, id); err != nil {
 		http.Error(w, "Error deleting ad", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ad.delete", domain.AuditEntityAd, id, ad, nil)
 
 	http.Redirect(w, r, "/admin/ads", http.StatusSeeOther)
 }
+
+// Parts inventory
+
+func (s *Server) handleInventoryList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	search := r.URL.Query().Get("q")
+	showAll := r.URL.Query().Get("all") == "1"
+	items, err := s.repos.Inventory.ListItems(ctx, search, showAll)
+	if err != nil {
+		http.Error(w, "Error loading inventory", http.StatusInternalServerError)
+		return
+	}
+	locations, _ := s.repos.Inventory.ListLocations(ctx)
+
+	data := s.newPageData(r, "Inventario de Repuestos")
+	switch r.URL.Query().Get("error") {
+	case "duplicate_sku":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ya existe un repuesto con ese SKU"}
+	case "duplicate_ean":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese código de barras (EAN) ya está asignado a otro repuesto"}
+	case "missing_fields":
+		data.Flash = &FlashMessage{Type: "error", Message: "El SKU y el nombre son obligatorios"}
+	case "location_failed":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se pudo crear la ubicación (¿nombre repetido?)"}
+	}
+
+	data.Data = map[string]interface{}{
+		"Items":     items,
+		"Locations": locations,
+		"Search":    search,
+		"ShowAll":   showAll,
+	}
+	s.render(w, r, "pages/admin/inventory.html", data)
+}
+
+func (s *Server) handleCreateInventoryItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	item := &domain.InventoryItem{Active: true}
+	parseInventoryForm(r, item)
+	if item.SKU == "" || item.Name == "" {
+		http.Redirect(w, r, "/admin/inventory?error=missing_fields", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Inventory.CreateItem(ctx, item); err != nil {
+		if errors.Is(err, repository.ErrDuplicateSKU) {
+			http.Redirect(w, r, "/admin/inventory?error=duplicate_sku", http.StatusSeeOther)
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateEAN) {
+			http.Redirect(w, r, "/admin/inventory?error=duplicate_ean", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Error creating inventory item", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "inventory_item.create", domain.AuditEntityInventoryItem, item.ID, nil, item)
+
+	// Opening stock goes to the default location
+	if initial, _ := strconv.Atoi(r.FormValue("initial_stock")); initial > 0 {
+		claims := getUserClaims(r)
+		if err := s.repos.Inventory.AdjustStock(ctx, &domain.StockMovement{
+			ItemID:   item.ID,
+			Quantity: initial,
+			Reason:   domain.MovementReasonAdjustment,
+			UserID:   claims.UserID,
+			Notes:    "Stock inicial",
+		}); err != nil {
+			log.Printf("⚠️ Failed to set initial stock for %s: %v", item.SKU, err)
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d", item.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleInventoryItemPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	item, err := s.repos.Inventory.GetItem(ctx, id)
+	if err != nil || item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	levels, _ := s.repos.Inventory.GetStockLevels(ctx, id)
+	movements, _ := s.repos.Inventory.ListMovements(ctx, id, 50)
+	openParts, _ := s.repos.Tickets.GetOpenPartsByItem(ctx, id)
+
+	data := s.newPageData(r, "Repuesto "+item.SKU)
+	switch r.URL.Query().Get("error") {
+	case "duplicate_sku":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ya existe un repuesto con ese SKU"}
+	case "duplicate_ean":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese código de barras (EAN) ya está asignado a otro repuesto"}
+	case "insufficient_stock":
+		data.Flash = &FlashMessage{Type: "error", Message: "El ajuste dejaría la ubicación con stock negativo"}
+	case "invalid_quantity":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ingresa una cantidad distinta de cero"}
+	}
+	if r.URL.Query().Get("msg") == "saved" {
+		data.Flash = &FlashMessage{Type: "success", Message: "Cambios guardados"}
+	}
+
+	data.Data = map[string]interface{}{
+		"Item":      item,
+		"Levels":    levels,
+		"Movements": movements,
+		"OpenParts": openParts,
+	}
+	s.render(w, r, "pages/admin/inventory_item.html", data)
+}
+
+func (s *Server) handleUpdateInventoryItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	item, err := s.repos.Inventory.GetItem(ctx, id)
+	if err != nil || item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	before := *item
+	parseInventoryForm(r, item)
+	item.Active = r.FormValue("active") == "on"
+	if item.SKU == "" || item.Name == "" {
+		http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Inventory.UpdateItem(ctx, item); err != nil {
+		if errors.Is(err, repository.ErrDuplicateSKU) {
+			http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?error=duplicate_sku", id), http.StatusSeeOther)
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateEAN) {
+			http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?error=duplicate_ean", id), http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Error updating inventory item", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "inventory_item.update", domain.AuditEntityInventoryItem, item.ID, &before, item)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?msg=saved", id), http.StatusSeeOther)
+}
+
+// handleAdjustInventoryStock records a manual stock movement at a location
+func (s *Server) handleAdjustInventoryStock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	item, err := s.repos.Inventory.GetItem(ctx, id)
+	if err != nil || item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	quantity, _ := strconv.Atoi(r.FormValue("quantity"))
+	if quantity == 0 {
+		http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?error=invalid_quantity", id), http.StatusSeeOther)
+		return
+	}
+	locationID, _ := strconv.ParseInt(r.FormValue("location_id"), 10, 64)
+
+	reason := domain.MovementReasonAdjustment
+	if r.FormValue("reason") == domain.MovementReasonReceive && quantity > 0 {
+		reason = domain.MovementReasonReceive
+	}
+
+	claims := getUserClaims(r)
+	err = s.repos.Inventory.AdjustStock(ctx, &domain.StockMovement{
+		ItemID:     id,
+		LocationID: locationID,
+		Quantity:   quantity,
+		Reason:     reason,
+		UserID:     claims.UserID,
+		Notes:      strings.TrimSpace(r.FormValue("notes")),
+	})
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?error=insufficient_stock", id), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error adjusting stock", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "inventory_item.adjust_stock", domain.AuditEntityInventoryItem, id, nil, map[string]interface{}{
+		"quantity": quantity, "locationId": locationID, "reason": reason,
+	})
+
+	// A correction downwards can leave open tickets without their parts
+	if quantity < 0 {
+		s.checkPartShortages(r, id)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/inventory/%d?msg=saved", id), http.StatusSeeOther)
+}
+
+// handleDeleteInventoryItem deactivates an item; its movement history is kept
+func (s *Server) handleDeleteInventoryItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	item, err := s.repos.Inventory.GetItem(ctx, id)
+	if err != nil || item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	before := *item
+	item.Active = false
+	if err := s.repos.Inventory.UpdateItem(ctx, item); err != nil {
+		http.Error(w, "Error deactivating inventory item", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "inventory_item.deactivate", domain.AuditEntityInventoryItem, id, &before, item)
+
+	http.Redirect(w, r, "/admin/inventory", http.StatusSeeOther)
+}
+
+func (s *Server) handleCreateStockLocation(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Redirect(w, r, "/admin/inventory", http.StatusSeeOther)
+		return
+	}
+
+	location := &domain.StockLocation{Name: name, BranchID: s.currentBranch(r)}
+	if location.BranchID == 0 {
+		location.BranchID, _ = strconv.ParseInt(r.FormValue("branch_id"), 10, 64)
+	}
+	if err := s.repos.Inventory.CreateLocation(r.Context(), location); err != nil {
+		log.Printf("⚠️ Failed to create stock location %q: %v", name, err)
+		http.Redirect(w, r, "/admin/inventory?error=location_failed", http.StatusSeeOther)
+		return
+	}
+	s.audit(r, "stock_location.create", domain.AuditEntityStockLocation, location.ID, nil, location)
+
+	http.Redirect(w, r, "/admin/inventory", http.StatusSeeOther)
+}
+
+// handleInventoryBarcode renders the bin barcode of an item as PNG. It
+// encodes the EAN when there is one, otherwise the SKU; the scan lookup
+// accepts either.
+func (s *Server) handleInventoryBarcode(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	item, err := s.repos.Inventory.GetItem(r.Context(), id)
+	if err != nil || item == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	code := item.EAN
+	if code == "" {
+		code = item.SKU
+	}
+	png, err := barcode.PNG(code, 2, 80)
+	if err != nil {
+		http.Error(w, "Error generating barcode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(png)
+}
+
+// handleInventoryLabels shows a printable sheet of bin labels. Without
+// explicit ?id= values it includes every active item.
+func (s *Server) handleInventoryLabels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var items []domain.InventoryItem
+	if ids := r.URL.Query()["id"]; len(ids) > 0 {
+		for _, raw := range ids {
+			id, _ := strconv.ParseInt(raw, 10, 64)
+			if item, _ := s.repos.Inventory.GetItem(ctx, id); item != nil {
+				items = append(items, *item)
+			}
+		}
+	} else {
+		items, _ = s.repos.Inventory.ListItems(ctx, "", false)
+	}
+
+	data := s.newPageData(r, "Etiquetas de Inventario")
+	data.Data = map[string]interface{}{
+		"Items": items,
+	}
+	s.render(w, r, "pages/admin/inventory_labels.html", data)
+}
+
+// parseInventoryForm copies the catalog fields of the item form into item
+func parseInventoryForm(r *http.Request, item *domain.InventoryItem) {
+	item.SKU = strings.ToUpper(strings.TrimSpace(r.FormValue("sku")))
+	item.EAN = strings.TrimSpace(r.FormValue("ean"))
+	item.Name = strings.TrimSpace(r.FormValue("name"))
+	item.Brand = strings.TrimSpace(r.FormValue("brand"))
+	item.Categories = strings.Split(r.FormValue("categories"), ",")
+	item.Cost, _ = strconv.ParseFloat(r.FormValue("cost"), 64)
+	item.SalePrice, _ = strconv.ParseFloat(r.FormValue("sale_price"), 64)
+	item.MinStock, _ = strconv.Atoi(r.FormValue("min_stock"))
+}
+
+// Suppliers and purchase orders
+
+func (s *Server) handleSuppliersList(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := s.repos.Suppliers.List(r.Context(), true)
+	if err != nil {
+		http.Error(w, "Error loading suppliers", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Proveedores")
+	if r.URL.Query().Get("error") == "name_required" {
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre del proveedor es obligatorio"}
+	}
+	data.Data = map[string]interface{}{
+		"Suppliers": suppliers,
+	}
+	s.render(w, r, "pages/admin/suppliers.html", data)
+}
+
+func (s *Server) handleCreateSupplier(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	supplier := &domain.Supplier{Active: true}
+	parseSupplierForm(r, supplier)
+	if supplier.Name == "" {
+		http.Redirect(w, r, "/admin/suppliers?error=name_required", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Suppliers.Create(r.Context(), supplier); err != nil {
+		http.Error(w, "Error creating supplier", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "supplier.create", domain.AuditEntitySupplier, supplier.ID, nil, supplier)
+
+	http.Redirect(w, r, "/admin/suppliers", http.StatusSeeOther)
+}
+
+func (s *Server) handleUpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	supplier, err := s.repos.Suppliers.GetByID(ctx, id)
+	if err != nil || supplier == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	before := *supplier
+	parseSupplierForm(r, supplier)
+	supplier.Active = r.FormValue("active") == "on"
+	if supplier.Name == "" {
+		http.Redirect(w, r, "/admin/suppliers?error=name_required", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Suppliers.Update(ctx, supplier); err != nil {
+		http.Error(w, "Error updating supplier", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "supplier.update", domain.AuditEntitySupplier, supplier.ID, &before, supplier)
+
+	http.Redirect(w, r, "/admin/suppliers", http.StatusSeeOther)
+}
+
+func parseSupplierForm(r *http.Request, supplier *domain.Supplier) {
+	supplier.Name = strings.TrimSpace(r.FormValue("name"))
+	supplier.ContactName = strings.TrimSpace(r.FormValue("contact_name"))
+	supplier.Email = strings.TrimSpace(r.FormValue("email"))
+	supplier.Phone = strings.TrimSpace(r.FormValue("phone"))
+	supplier.Notes = r.FormValue("notes")
+}
+
+func (s *Server) handlePurchaseOrdersList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := r.URL.Query().Get("status")
+	orders, err := s.repos.Purchases.List(ctx, status, 100, 0)
+	if err != nil {
+		http.Error(w, "Error loading purchase orders", http.StatusInternalServerError)
+		return
+	}
+	suppliers, _ := s.repos.Suppliers.List(ctx, false)
+
+	data := s.newPageData(r, "Órdenes de Compra")
+	data.Data = map[string]interface{}{
+		"Orders":        orders,
+		"Suppliers":     suppliers,
+		"CurrentStatus": status,
+	}
+	s.render(w, r, "pages/admin/purchase_orders.html", data)
+}
+
+func (s *Server) handleCreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	supplierID, _ := strconv.ParseInt(r.FormValue("supplier_id"), 10, 64)
+	supplier, _ := s.repos.Suppliers.GetByID(ctx, supplierID)
+	if supplier == nil {
+		http.Redirect(w, r, "/admin/purchase-orders", http.StatusSeeOther)
+		return
+	}
+
+	claims := getUserClaims(r)
+	order := &domain.PurchaseOrder{
+		SupplierID: supplier.ID,
+		Notes:      r.FormValue("notes"),
+		CreatedBy:  claims.UserID,
+	}
+	if err := s.repos.Purchases.Create(ctx, order); err != nil {
+		http.Error(w, "Error creating purchase order", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.create", domain.AuditEntityPurchaseOrder, order.ID, nil, order)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", order.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handlePurchaseOrderDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(ctx, id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	items, _ := s.repos.Inventory.ListItems(ctx, "", false)
+	locations, _ := s.repos.Inventory.ListLocations(ctx)
+	waiting, _ := s.repos.Tickets.List(ctx, 0, domain.TicketStatusWaitingParts, 100, 0)
+
+	data := s.newPageData(r, fmt.Sprintf("Orden de Compra #%d", order.ID))
+	switch r.URL.Query().Get("error") {
+	case "invalid_line":
+		data.Flash = &FlashMessage{Type: "error", Message: "Selecciona un repuesto y una cantidad mayor a cero"}
+	case "ticket_not_found":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se encontró ese ticket"}
+	case "empty_order":
+		data.Flash = &FlashMessage{Type: "error", Message: "Agrega al menos una línea antes de enviar la orden"}
+	}
+	if r.URL.Query().Get("msg") == "received" {
+		data.Flash = &FlashMessage{Type: "success", Message: "Mercadería recibida e ingresada al inventario"}
+	}
+
+	data.Data = map[string]interface{}{
+		"Order":          order,
+		"Items":          items,
+		"Locations":      locations,
+		"WaitingTickets": waiting,
+	}
+	s.render(w, r, "pages/admin/purchase_order_detail.html", data)
+}
+
+// handleAddPurchaseOrderLine adds an item to a draft order and links the
+// tickets already waiting for that part
+func (s *Server) handleAddPurchaseOrderLine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(ctx, id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !order.Editable() {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	itemID, _ := strconv.ParseInt(r.FormValue("item_id"), 10, 64)
+	quantity, _ := strconv.Atoi(r.FormValue("quantity"))
+	item, _ := s.repos.Inventory.GetItem(ctx, itemID)
+	if item == nil || quantity <= 0 {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d?error=invalid_line", id), http.StatusSeeOther)
+		return
+	}
+
+	line := &domain.PurchaseOrderLine{
+		OrderID:  id,
+		ItemID:   item.ID,
+		Quantity: quantity,
+		UnitCost: item.Cost,
+	}
+	if cost, err := strconv.ParseFloat(r.FormValue("unit_cost"), 64); err == nil && cost >= 0 {
+		line.UnitCost = cost
+	}
+	if err := s.repos.Purchases.AddLine(ctx, line); err != nil {
+		http.Error(w, "Error adding purchase order line", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.add_line", domain.AuditEntityPurchaseOrder, id, nil, line)
+
+	parts, _ := s.repos.Tickets.GetOpenPartsByItem(ctx, item.ID)
+	for _, part := range parts {
+		ticket, _ := s.repos.Tickets.GetByID(ctx, part.TicketID)
+		if ticket != nil && ticket.Status == domain.TicketStatusWaitingParts {
+			if err := s.repos.Purchases.LinkTicket(ctx, line.ID, ticket.ID); err != nil {
+				log.Printf("⚠️ Failed to link ticket %d to PO line %d: %v", ticket.ID, line.ID, err)
+			}
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+}
+
+func (s *Server) handleDeletePurchaseOrderLine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	lineID, _ := strconv.ParseInt(getURLParam(r, "lineId"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(ctx, id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if order.Editable() {
+		var removed *domain.PurchaseOrderLine
+		for i := range order.Lines {
+			if order.Lines[i].ID == lineID {
+				removed = &order.Lines[i]
+			}
+		}
+		if err := s.repos.Purchases.DeleteLine(ctx, id, lineID); err != nil {
+			http.Error(w, "Error deleting purchase order line", http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, "purchase_order.delete_line", domain.AuditEntityPurchaseOrder, id, removed, nil)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+}
+
+// handleLinkPurchaseOrderTicket links a ticket, by tracking code or ID, to a line
+func (s *Server) handleLinkPurchaseOrderTicket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	lineID, _ := strconv.ParseInt(getURLParam(r, "lineId"), 10, 64)
+	if !s.purchaseOrderHasLine(ctx, id, lineID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ref := strings.TrimSpace(r.FormValue("ticket"))
+	ticket, _ := s.repos.Tickets.GetByTrackingCode(ctx, strings.ToLower(ref))
+	if ticket == nil {
+		if ticketID, err := strconv.ParseInt(ref, 10, 64); err == nil {
+			ticket, _ = s.repos.Tickets.GetByID(ctx, ticketID)
+		}
+	}
+	if ticket == nil {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d?error=ticket_not_found", id), http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Purchases.LinkTicket(ctx, lineID, ticket.ID); err != nil {
+		http.Error(w, "Error linking ticket", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.link_ticket", domain.AuditEntityPurchaseOrder, id, nil,
+		map[string]int64{"lineId": lineID, "ticketId": ticket.ID})
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+}
+
+func (s *Server) handleUnlinkPurchaseOrderTicket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	lineID, _ := strconv.ParseInt(getURLParam(r, "lineId"), 10, 64)
+	ticketID, _ := strconv.ParseInt(getURLParam(r, "ticketId"), 10, 64)
+	if !s.purchaseOrderHasLine(ctx, id, lineID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := s.repos.Purchases.UnlinkTicket(ctx, lineID, ticketID); err != nil {
+		http.Error(w, "Error unlinking ticket", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.unlink_ticket", domain.AuditEntityPurchaseOrder, id,
+		map[string]int64{"lineId": lineID, "ticketId": ticketID}, nil)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+}
+
+func (s *Server) purchaseOrderHasLine(ctx context.Context, orderID, lineID int64) bool {
+	order, _ := s.repos.Purchases.GetByID(ctx, orderID)
+	if order == nil {
+		return false
+	}
+	for _, line := range order.Lines {
+		if line.ID == lineID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleSendPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(ctx, id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !order.Editable() {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+		return
+	}
+	if len(order.Lines) == 0 {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d?error=empty_order", id), http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Purchases.UpdateStatus(ctx, id, domain.PurchaseOrderStatusSent); err != nil {
+		http.Error(w, "Error updating purchase order", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.send", domain.AuditEntityPurchaseOrder, id,
+		map[string]string{"status": order.Status}, map[string]string{"status": domain.PurchaseOrderStatusSent})
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+}
+
+// handleReceivePurchaseOrder books received goods into stock, notifies the
+// customers of linked tickets and optionally resumes those tickets
+func (s *Server) handleReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(ctx, id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !order.Receivable() {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	quantities := make(map[int64]int)
+	for _, line := range order.Lines {
+		if qty, _ := strconv.Atoi(r.FormValue(fmt.Sprintf("received_%d", line.ID))); qty > 0 {
+			quantities[line.ID] = qty
+		}
+	}
+	if len(quantities) == 0 {
+		http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	claims := getUserClaims(r)
+	locationID, _ := strconv.ParseInt(r.FormValue("location_id"), 10, 64)
+	if err := s.repos.Purchases.Receive(ctx, id, quantities, locationID, claims.UserID); err != nil {
+		log.Printf("⚠️ Failed to receive purchase order %d: %v", id, err)
+		http.Error(w, "Error receiving purchase order", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "purchase_order.receive", domain.AuditEntityPurchaseOrder, id, nil,
+		map[string]interface{}{"receivedByLine": quantities, "locationId": locationID})
+
+	resume := r.FormValue("resume_tickets") == "on"
+	notified := make(map[int64]bool)
+	for _, line := range order.Lines {
+		if quantities[line.ID] == 0 {
+			continue
+		}
+		for _, linked := range line.Tickets {
+			if notified[linked.ID] {
+				continue
+			}
+			ticket, _ := s.repos.Tickets.GetByID(ctx, linked.ID)
+			if ticket == nil || ticket.Status == domain.TicketStatusReady || ticket.Status == domain.TicketStatusDelivered {
+				continue
+			}
+			notified[ticket.ID] = true
+
+			if resume && ticket.Status == domain.TicketStatusWaitingParts {
+				note := fmt.Sprintf("Llegó repuesto: %s", line.Item.Name)
+				if err := s.systemTicketStatus(r, ticket, domain.TicketStatusInProgress, note); err != nil {
+					log.Printf("⚠️ Failed to resume ticket %d: %v", ticket.ID, err)
+				}
+			}
+			s.notifyTicketCustomer(ctx, ticket,
+				fmt.Sprintf("Llegó el repuesto para tu bicicleta (%s)", ticket.TrackingCode),
+				fmt.Sprintf("¡Buenas noticias! Recibimos %s para tu orden %s y seguimos con el trabajo.", line.Item.Name, ticket.TrackingCode))
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/purchase-orders/%d?msg=received", id), http.StatusSeeOther)
+}
+
+// handleExportPurchaseOrderCSV downloads the order lines for the supplier
+func (s *Server) handleExportPurchaseOrderCSV(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(r.Context(), id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=orden-compra-%d.csv", order.ID))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"sku", "descripcion", "cantidad", "costo_unitario", "total"})
+	for _, line := range order.Lines {
+		writer.Write([]string{
+			line.Item.SKU,
+			line.Item.Name,
+			strconv.Itoa(line.Quantity),
+			strconv.FormatFloat(line.UnitCost, 'f', 2, 64),
+			strconv.FormatFloat(line.Total(), 'f', 2, 64),
+		})
+	}
+	writer.Flush()
+}
+
+// handleExportPurchaseOrderPDF renders a printable order for the supplier
+func (s *Server) handleExportPurchaseOrderPDF(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	order, err := s.repos.Purchases.GetByID(r.Context(), id)
+	if err != nil || order == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	doc := pdf.New()
+	doc.Text(0, 18, true, s.config.Business.Name)
+	doc.Ln(20)
+	doc.Text(0, 10, false, strings.TrimSpace(s.config.Business.ContactEmail+"  "+s.config.Business.ContactPhone))
+	doc.Ln(30)
+
+	doc.Text(0, 14, true, fmt.Sprintf("Orden de Compra #%d", order.ID))
+	doc.Text(330, 10, false, "Fecha: "+order.CreatedAt.Format("02/01/2006"))
+	doc.Ln(18)
+	doc.Text(0, 10, false, "Proveedor: "+order.Supplier.Name)
+	doc.Ln(14)
+	if order.Supplier.ContactName != "" {
+		doc.Text(0, 10, false, "Contacto: "+order.Supplier.ContactName)
+		doc.Ln(14)
+	}
+	doc.Text(0, 10, false, "Estado: "+domain.PurchaseOrderStatusLabel(order.Status))
+	doc.Ln(26)
+
+	columns := []float64{0, 90, 330, 385, 445}
+	doc.Text(columns[0], 10, true, "SKU")
+	doc.Text(columns[1], 10, true, "Descripción")
+	doc.Text(columns[2], 10, true, "Cant.")
+	doc.Text(columns[3], 10, true, "Unitario")
+	doc.Text(columns[4], 10, true, "Total")
+	doc.Rule()
+	doc.Ln(18)
+	for _, line := range order.Lines {
+		doc.Text(columns[0], 10, false, line.Item.SKU)
+		doc.Text(columns[1], 10, false, line.Item.Name)
+		doc.Text(columns[2], 10, false, strconv.Itoa(line.Quantity))
+		doc.Text(columns[3], 10, false, fmt.Sprintf("$%.2f", line.UnitCost))
+		doc.Text(columns[4], 10, false, fmt.Sprintf("$%.2f", line.Total()))
+		doc.Ln(14)
+	}
+	doc.Rule()
+	doc.Ln(18)
+	doc.Text(columns[3], 11, true, "Total")
+	doc.Text(columns[4], 11, true, fmt.Sprintf("$%.2f", order.Total()))
+
+	if order.Notes != "" {
+		doc.Ln(30)
+		doc.Text(0, 10, false, "Notas: "+order.Notes)
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=orden-compra-%d.pdf", order.ID))
+	w.Write(doc.Bytes())
+}
+
+// Stolen bike registry
+
+func (s *Server) handleStolenBikesList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	showAll := r.URL.Query().Get("all") == "1"
+	bikes, err := s.repos.StolenBikes.List(ctx, showAll, 500, 0)
+	if err != nil {
+		http.Error(w, "Error listing stolen bikes", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Registro de Bicicletas Robadas")
+	q := r.URL.Query()
+	switch {
+	case q.Get("imported") != "":
+		data.Flash = &FlashMessage{Type: "success", Message: fmt.Sprintf("Importación completa: %s registros nuevos, %s omitidos", q.Get("imported"), q.Get("skipped"))}
+	case q.Get("error") == "serial_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "El N° de serie es obligatorio"}
+	case q.Get("error") == "already_reported":
+		data.Flash = &FlashMessage{Type: "info", Message: "Ese N° de serie ya figura como robado"}
+	case q.Get("error") == "import_failed":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se pudo leer el archivo CSV"}
+	}
+
+	data.Data = map[string]interface{}{
+		"Bikes":   bikes,
+		"ShowAll": showAll,
+	}
+	s.render(w, r, "pages/admin/stolen_bikes.html", data)
+}
+
+func (s *Server) handleCreateStolenBike(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	serial := strings.TrimSpace(r.FormValue("serial_number"))
+	if domain.NormalizeSerial(serial) == "" {
+		http.Redirect(w, r, "/admin/stolen?error=serial_required", http.StatusSeeOther)
+		return
+	}
+
+	existing, _ := s.repos.StolenBikes.GetActiveBySerial(ctx, serial)
+	if existing != nil {
+		http.Redirect(w, r, "/admin/stolen?error=already_reported", http.StatusSeeOther)
+		return
+	}
+
+	claims := getUserClaims(r)
+	bike := &domain.StolenBike{
+		SerialNumber: serial,
+		Brand:        strings.TrimSpace(r.FormValue("brand")),
+		Model:        strings.TrimSpace(r.FormValue("model")),
+		Color:        strings.TrimSpace(r.FormValue("color")),
+		Notes:        r.FormValue("notes"),
+		Source:       domain.StolenSourceManual,
+		ReportedAt:   parseReportDate(r.FormValue("reported_at")),
+		CreatedBy:    claims.UserID,
+	}
+
+	// Link to our own bicycle record when we know this serial
+	if bicycle, _ := s.repos.Bicycles.GetBySerial(ctx, serial); bicycle != nil {
+		bike.BicycleID = bicycle.ID
+	}
+
+	if err := s.repos.StolenBikes.Create(ctx, bike); err != nil {
+		http.Error(w, "Error creating stolen bike report", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "stolen_bike.create", domain.AuditEntityStolenBike, bike.ID, nil, bike)
+
+	http.Redirect(w, r, "/admin/stolen", http.StatusSeeOther)
+}
+
+// handleImportStolenBikes imports a CSV file into the stolen-bike registry.
+// The first row may be a header naming the columns (serial, brand, model,
+// color, reported_at, notes); without one, that column order is assumed.
+func (s *Server) handleImportStolenBikes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		http.Redirect(w, r, "/admin/stolen?error=import_failed", http.StatusSeeOther)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Redirect(w, r, "/admin/stolen?error=import_failed", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		http.Redirect(w, r, "/admin/stolen?error=import_failed", http.StatusSeeOther)
+		return
+	}
+
+	columns := map[string]int{"serial": 0, "brand": 1, "model": 2, "color": 3, "reported_at": 4, "notes": 5}
+	if isSerialHeader(records[0]) {
+		columns = map[string]int{}
+		for i, name := range records[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch {
+			case isSerialColumn(name):
+				columns["serial"] = i
+			case name == "brand" || name == "marca":
+				columns["brand"] = i
+			case name == "model" || name == "modelo":
+				columns["model"] = i
+			case name == "color":
+				columns["color"] = i
+			case strings.Contains(name, "date") || strings.Contains(name, "fecha") || name == "reported_at":
+				columns["reported_at"] = i
+			case name == "notes" || name == "notas":
+				columns["notes"] = i
+			}
+		}
+		records = records[1:]
+	}
+	if _, ok := columns["serial"]; !ok {
+		http.Redirect(w, r, "/admin/stolen?error=import_failed", http.StatusSeeOther)
+		return
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	claims := getUserClaims(r)
+	imported, skipped := 0, 0
+	for _, record := range records {
+		serial := field(record, "serial")
+		if domain.NormalizeSerial(serial) == "" {
+			skipped++
+			continue
+		}
+		if existing, _ := s.repos.StolenBikes.GetActiveBySerial(ctx, serial); existing != nil {
+			skipped++
+			continue
+		}
+
+		bike := &domain.StolenBike{
+			SerialNumber: serial,
+			Brand:        field(record, "brand"),
+			Model:        field(record, "model"),
+			Color:        field(record, "color"),
+			Notes:        field(record, "notes"),
+			Source:       domain.StolenSourceImport,
+			ReportedAt:   parseReportDate(field(record, "reported_at")),
+			CreatedBy:    claims.UserID,
+		}
+		if bicycle, _ := s.repos.Bicycles.GetBySerial(ctx, serial); bicycle != nil {
+			bike.BicycleID = bicycle.ID
+		}
+		if err := s.repos.StolenBikes.Create(ctx, bike); err != nil {
+			skipped++
+			continue
+		}
+		imported++
+	}
+	s.audit(r, "stolen_bike.import", domain.AuditEntityStolenBike, 0, nil,
+		map[string]int{"imported": imported, "skipped": skipped})
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/stolen?imported=%d&skipped=%d", imported, skipped), http.StatusSeeOther)
+}
+
+func (s *Server) handleRecoverStolenBike(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	before, _ := s.repos.StolenBikes.GetByID(r.Context(), id)
+	if err := s.repos.StolenBikes.MarkRecovered(r.Context(), id); err != nil {
+		http.Error(w, "Error updating stolen bike report", http.StatusInternalServerError)
+		return
+	}
+	after, _ := s.repos.StolenBikes.GetByID(r.Context(), id)
+	s.audit(r, "stolen_bike.recover", domain.AuditEntityStolenBike, id, before, after)
+	http.Redirect(w, r, "/admin/stolen", http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteStolenBike(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	bike, _ := s.repos.StolenBikes.GetByID(r.Context(), id)
+	if err := s.repos.StolenBikes.Delete(r.Context(), id); err != nil {
+		http.Error(w, "Error deleting stolen bike report", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "stolen_bike.delete", domain.AuditEntityStolenBike, id, bike, nil)
+	http.Redirect(w, r, "/admin/stolen", http.StatusSeeOther)
+}
+
+// handleReportBicycleStolen flags one of our registered bicycles as stolen
+func (s *Server) handleReportBicycleStolen(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	bicycle, err := s.repos.Bicycles.GetByID(ctx, id)
+	if err != nil || bicycle == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	redirectTo := r.FormValue("redirect_to")
+	if redirectTo == "" {
+		redirectTo = "/admin/stolen"
+	}
+
+	if domain.NormalizeSerial(bicycle.SerialNumber) == "" {
+		http.Redirect(w, r, "/admin/stolen?error=serial_required", http.StatusSeeOther)
+		return
+	}
+
+	if existing, _ := s.repos.StolenBikes.GetActiveBySerial(ctx, bicycle.SerialNumber); existing == nil {
+		claims := getUserClaims(r)
+		bike := &domain.StolenBike{
+			SerialNumber: bicycle.SerialNumber,
+			Color:        bicycle.Color,
+			BicycleID:    bicycle.ID,
+			Source:       domain.StolenSourceManual,
+			Notes:        r.FormValue("notes"),
+			CreatedBy:    claims.UserID,
+		}
+		if bicycle.Brand != nil {
+			bike.Brand = bicycle.Brand.Name
+		}
+		if bicycle.Model != nil {
+			bike.Model = bicycle.Model.Name
+		}
+		if err := s.repos.StolenBikes.Create(ctx, bike); err != nil {
+			http.Error(w, "Error creating stolen bike report", http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, "stolen_bike.create", domain.AuditEntityStolenBike, bike.ID, nil, bike)
+	}
+
+	http.Redirect(w, r, redirectTo, http.StatusSeeOther)
+}
+
+// isSerialHeader reports whether a CSV row is a header naming a serial column
+func isSerialHeader(row []string) bool {
+	for _, cell := range row {
+		if isSerialColumn(strings.ToLower(strings.TrimSpace(cell))) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSerialColumn(name string) bool {
+	if strings.ContainsAny(name, "0123456789") {
+		return false
+	}
+	return strings.Contains(name, "serial") || strings.Contains(name, "serie")
+}
+
+// parseReportDate accepts ISO and dd/mm/yyyy dates, defaulting to now
+func parseReportDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// apiLookupBicycleBySerial lets reception check a serial before intake
+func (s *Server) apiLookupBicycleBySerial(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serial := r.URL.Query().Get("serial")
+
+	response := map[string]interface{}{
+		"serial":     domain.NormalizeSerial(serial),
+		"found":      false,
+		"stolen":     false,
+		"bicycle":    nil,
+		"ownerEmail": "",
+		"ownerName":  "",
+	}
+
+	bicycle, err := s.repos.Bicycles.GetBySerial(ctx, serial)
+	if err != nil {
+		http.Error(w, "Error looking up bicycle", http.StatusInternalServerError)
+		return
+	}
+	if bicycle != nil {
+		response["found"] = true
+		response["bicycle"] = bicycle
+		if owner, _ := s.repos.Users.GetByID(ctx, bicycle.UserID); owner != nil {
+			response["ownerEmail"] = owner.Email
+			response["ownerName"] = owner.Name
+		}
+	}
+
+	if stolen, _ := s.repos.StolenBikes.GetActiveBySerial(ctx, serial); stolen != nil {
+		response["stolen"] = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// apiLookupInventoryByEAN resolves a scanned product barcode. Bin labels
+// carry the SKU when an item has no EAN, so that is tried as a fallback.
+func (s *Server) apiLookupInventoryByEAN(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	code := strings.TrimSpace(getURLParam(r, "code"))
+
+	item, err := s.repos.Inventory.GetItemByEAN(ctx, code)
+	if err == nil && item == nil {
+		item, err = s.repos.Inventory.GetItemBySKU(ctx, code)
+	}
+	if err != nil {
+		http.Error(w, "Error looking up inventory item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":  code,
+		"found": item != nil,
+		"item":  item,
+	})
+}
+
+// apiLookupTicketByTracking resolves a scanned tracking code. Ticket QR codes
+// hold the full tracking URL, so anything up to the last slash is ignored.
+func (s *Server) apiLookupTicketByTracking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	code := trackingCodeFromScan(getURLParam(r, "code"))
+
+	ticket, err := s.repos.Tickets.GetByTrackingCode(ctx, code)
+	if err != nil {
+		http.Error(w, "Error looking up ticket", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code":   code,
+		"found":  ticket != nil,
+		"ticket": nil,
+		"url":    "",
+	}
+	if ticket != nil {
+		response["ticket"] = map[string]interface{}{
+			"id":           ticket.ID,
+			"trackingCode": ticket.TrackingCode,
+			"status":       ticket.Status,
+		}
+		response["url"] = fmt.Sprintf("/tickets/%d", ticket.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// trackingCodeFromScan extracts the tracking code from a scanned value,
+// which may be the bare code or the tracking URL printed in the QR
+func trackingCodeFromScan(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		value = value[i+1:]
+	}
+	if i := strings.IndexAny(value, "?#"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"bicicletapp/internal/domain"
+)
+
+// auditPageSize is how many entries the log viewer shows per page
+const auditPageSize = 50
+
+// auditIgnoredFields change on every write or are derived (rendered QR
+// images) and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{"updatedAt": true, "qrCode": true}
+
+// audit records a privileged or data-changing action by the signed-in user.
+// before and after are the entity around the change (nil when it was created
+// or deleted); only fields that differ are stored. Failures are logged and
+// never block the action.
+func (s *Server) audit(r *http.Request, action, entityType string, entityID int64, before, after interface{}) {
+	var actorID int64
+	if claims := getUserClaims(r); claims != nil {
+		actorID = claims.UserID
+	}
+	s.auditAs(r, actorID, action, entityType, entityID, before, after)
+}
+
+// auditAs records an action for an explicit actor, for flows that run before
+// the user is signed in (login, password reset)
+func (s *Server) auditAs(r *http.Request, actorID int64, action, entityType string, entityID int64, before, after interface{}) {
+	entry := &domain.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    auditDiff(before, after),
+		IP:         clientIP(r),
+		RequestID:  middleware.GetReqID(r.Context()),
+	}
+	if err := s.repos.AuditLog.Record(r.Context(), entry); err != nil {
+		log.Printf("⚠️ Failed to record audit entry %s %s#%d: %v", action, entityType, entityID, err)
+	}
+}
+
+// auditDiff returns the JSON object of fields that differ between before and
+// after, each as {"from": ..., "to": ...}. Fields hidden from JSON (password
+// hashes, secrets) never appear.
+func auditDiff(before, after interface{}) string {
+	from, to := auditFields(before), auditFields(after)
+	changes := make(map[string]map[string]interface{})
+	for field, value := range to {
+		if old, ok := from[field]; (!ok || !reflect.DeepEqual(old, value)) && !auditIgnoredFields[field] {
+			changes[field] = map[string]interface{}{"from": old, "to": value}
+		}
+	}
+	for field, value := range from {
+		if _, ok := to[field]; !ok && !auditIgnoredFields[field] {
+			changes[field] = map[string]interface{}{"from": value, "to": nil}
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// auditFields flattens a value to its JSON fields
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		// Not an object: record it as a single value
+		var value interface{}
+		json.Unmarshal(encoded, &value)
+		return map[string]interface{}{"value": value}
+	}
+	return fields
+}
+
+// parseAuditFilter reads the viewer's filter form. ok is false when the actor
+// email matches no user, so nothing can match.
+func (s *Server) parseAuditFilter(r *http.Request) (domain.AuditFilter, bool) {
+	q := r.URL.Query()
+	filter := domain.AuditFilter{
+		Action:     strings.TrimSpace(q.Get("action")),
+		EntityType: q.Get("entity"),
+	}
+	filter.EntityID, _ = strconv.ParseInt(q.Get("entity_id"), 10, 64)
+	if from, err := time.ParseInLocation("2006-01-02", q.Get("from"), time.Local); err == nil {
+		filter.From = from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", q.Get("to"), time.Local); err == nil {
+		filter.To = to.AddDate(0, 0, 1) // inclusive of the whole day
+	}
+	if email := strings.TrimSpace(q.Get("actor")); email != "" {
+		actor, err := s.repos.Users.GetByEmail(r.Context(), email)
+		if err != nil || actor == nil {
+			return filter, false
+		}
+		filter.ActorID = actor.ID
+	}
+	return filter, true
+}
+
+// handleAuditLog shows the audit log with filters
+func (s *Server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	filter, ok := s.parseAuditFilter(r)
+	var entries []domain.AuditEntry
+	var total int
+	if ok {
+		filter.Limit = auditPageSize
+		filter.Offset = (page - 1) * auditPageSize
+		var err error
+		entries, err = s.repos.AuditLog.List(ctx, filter)
+		if err != nil {
+			http.Error(w, "Error loading audit log", http.StatusInternalServerError)
+			return
+		}
+		total, _ = s.repos.AuditLog.Count(ctx, filter)
+	}
+
+	entityLabels := make(map[string]string, len(domain.AuditEntityTypes))
+	for _, entityType := range domain.AuditEntityTypes {
+		entityLabels[entityType] = domain.AuditEntityLabel(entityType)
+	}
+
+	// Pagination links keep the current filters
+	query := r.URL.Query()
+	pageURL := func(n int) string {
+		query.Set("page", strconv.Itoa(n))
+		return "/admin/audit?" + query.Encode()
+	}
+	var prevURL, nextURL string
+	if page > 1 {
+		prevURL = pageURL(page - 1)
+	}
+	if page*auditPageSize < total {
+		nextURL = pageURL(page + 1)
+	}
+	query.Del("page")
+
+	data := s.newPageData(r, "Registro de Auditoría")
+	if !ok {
+		data.Flash = &FlashMessage{Type: "error", Message: "No hay ningún usuario con ese email"}
+	}
+	data.Data = map[string]interface{}{
+		"Entries":      entries,
+		"Total":        total,
+		"Page":         page,
+		"PrevURL":      prevURL,
+		"NextURL":      nextURL,
+		"ExportURL":    "/admin/audit.csv?" + query.Encode(),
+		"EntityTypes":  domain.AuditEntityTypes,
+		"EntityLabels": entityLabels,
+		"Filter":       r.URL.Query(),
+	}
+	s.render(w, r, "pages/admin/audit_log.html", data)
+}
+
+// handleExportAuditLog downloads the filtered audit log as CSV
+func (s *Server) handleExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := s.parseAuditFilter(r)
+	var entries []domain.AuditEntry
+	if ok {
+		var err error
+		entries, err = s.repos.AuditLog.List(r.Context(), filter)
+		if err != nil {
+			http.Error(w, "Error loading audit log", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=auditoria-%s.csv", time.Now().Format("2006-01-02")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "fecha", "actor_id", "actor_email", "accion", "entidad", "entidad_id", "cambios", "ip", "request_id"})
+	for _, e := range entries {
+		actorEmail := ""
+		if e.Actor != nil {
+			actorEmail = e.Actor.Email
+		}
+		writer.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Format(time.RFC3339),
+			strconv.FormatInt(e.ActorID, 10),
+			actorEmail,
+			e.Action,
+			e.EntityType,
+			strconv.FormatInt(e.EntityID, 10),
+			e.Changes,
+			e.IP,
+			e.RequestID,
+		})
+	}
+	writer.Flush()
+}
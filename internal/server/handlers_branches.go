@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+)
+
+// handleBranchesList shows the branches with their booking hours
+func (s *Server) handleBranchesList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	branches, err := s.repos.Branches.List(ctx, true)
+	if err != nil {
+		http.Error(w, "Error loading branches", http.StatusInternalServerError)
+		return
+	}
+	locations, _ := s.repos.Inventory.ListLocations(ctx)
+
+	data := s.newPageData(r, "Sucursales")
+	switch r.URL.Query().Get("error") {
+	case "name_required":
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre de la sucursal es obligatorio"}
+	case "invalid_slots":
+		data.Flash = &FlashMessage{Type: "error", Message: "Los horarios deben tener el formato HH:MM, separados por comas"}
+	case "create_failed":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se pudo crear la sucursal (¿ya existe una ubicación de stock con ese nombre?)"}
+	}
+	data.Data = map[string]interface{}{
+		"Branches":     branches,
+		"Locations":    locations,
+		"Weekdays":     domain.Weekdays,
+		"DefaultSlots": strings.ReplaceAll(domain.DefaultBranchSlots, ",", ", "),
+	}
+	s.render(w, r, "pages/admin/branches.html", data)
+}
+
+// handleCreateBranch opens a branch with its own stock location
+func (s *Server) handleCreateBranch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	branch := &domain.Branch{Active: true}
+	if errCode := parseBranchForm(r, branch); errCode != "" {
+		http.Redirect(w, r, "/admin/branches?error="+errCode, http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Branches.Create(r.Context(), branch); err != nil {
+		http.Redirect(w, r, "/admin/branches?error=create_failed", http.StatusSeeOther)
+		return
+	}
+	s.audit(r, "branch.create", domain.AuditEntityBranch, branch.ID, nil, branch)
+
+	http.Redirect(w, r, "/admin/branches", http.StatusSeeOther)
+}
+
+// handleUpdateBranch saves a branch's details, hours and stock location
+func (s *Server) handleUpdateBranch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	branch, err := s.repos.Branches.GetByID(ctx, id)
+	if err != nil || branch == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	before := *branch
+	if errCode := parseBranchForm(r, branch); errCode != "" {
+		http.Redirect(w, r, "/admin/branches?error="+errCode, http.StatusSeeOther)
+		return
+	}
+	branch.Active = r.FormValue("active") == "on"
+	if locationID, _ := strconv.ParseInt(r.FormValue("stock_location_id"), 10, 64); locationID != 0 {
+		branch.StockLocationID = locationID
+	}
+
+	if err := s.repos.Branches.Update(ctx, branch); err != nil {
+		http.Error(w, "Error updating branch", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "branch.update", domain.AuditEntityBranch, branch.ID, &before, branch)
+
+	http.Redirect(w, r, "/admin/branches", http.StatusSeeOther)
+}
+
+// parseBranchForm reads the branch form into branch and returns an error
+// code for the list page, or "" if the form is valid
+func parseBranchForm(r *http.Request, branch *domain.Branch) string {
+	branch.Name = strings.TrimSpace(r.FormValue("name"))
+	branch.Address = strings.TrimSpace(r.FormValue("address"))
+	branch.Phone = strings.TrimSpace(r.FormValue("phone"))
+	if branch.Name == "" {
+		return "name_required"
+	}
+
+	slots, ok := domain.NormalizeSlots(r.FormValue("slots"))
+	if !ok {
+		return "invalid_slots"
+	}
+	branch.Slots = slots
+
+	var days []time.Weekday
+	for _, value := range r.Form["open_days"] {
+		if day, err := strconv.Atoi(value); err == nil && day >= 0 && day <= 6 {
+			days = append(days, time.Weekday(day))
+		}
+	}
+	branch.OpenDays = domain.JoinWeekdays(days)
+	return ""
+}
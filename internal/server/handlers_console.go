@@ -0,0 +1,402 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	consoleCookieName = "console_token"
+	consoleAudience   = "console"
+	consoleSessionTTL = 12 * time.Hour
+
+	consoleAdminContextKey contextKey = "console_admin"
+)
+
+// setupConsoleRoutes configures the super-admin console
+func (p *Platform) setupConsoleRoutes() {
+	r := p.console.router
+
+	r.Handle("/static/*", p.console.staticHandler())
+	r.Get("/health", p.console.handleHealth)
+
+	r.Get("/login", p.handleConsoleLoginPage)
+	r.With(p.console.rateLimitMiddleware(20)).Post("/login", p.handleConsoleLogin)
+	r.Post("/logout", p.handleConsoleLogout)
+
+	r.Group(func(r chi.Router) {
+		r.Use(p.consoleAuthMiddleware)
+
+		r.Get("/", p.handleTenantsList)
+		r.Post("/tenants", p.handleCreateTenant)
+		r.Get("/tenants/{id}", p.handleEditTenantPage)
+		r.Post("/tenants/{id}", p.handleUpdateTenant)
+		r.Post("/tenants/{id}/{action:suspend|activate}", p.handleSetTenantStatus)
+	})
+}
+
+// consoleAuthMiddleware lets signed-in super admins through. Console tokens
+// carry their own audience so no tenant token is ever accepted here.
+func (p *Platform) consoleAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var admin *domain.SuperAdmin
+		if cookie, err := r.Cookie(consoleCookieName); err == nil {
+			claims := &jwt.RegisteredClaims{}
+			token, err := jwt.ParseWithClaims(cookie.Value, claims, func(token *jwt.Token) (interface{}, error) {
+				return []byte(p.config.JWT.Secret), nil
+			}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(consoleAudience))
+			if err == nil && token.Valid {
+				id, _ := strconv.ParseInt(claims.Subject, 10, 64)
+				admin, _ = p.admins.GetByID(r.Context(), id)
+			}
+		}
+		if admin == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), consoleAdminContextKey, admin)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// consoleAdmin returns the signed-in super admin, or nil outside the
+// authenticated console routes
+func consoleAdmin(r *http.Request) *domain.SuperAdmin {
+	admin, _ := r.Context().Value(consoleAdminContextKey).(*domain.SuperAdmin)
+	return admin
+}
+
+// pageData creates the PageData of a console page
+func (p *Platform) pageData(r *http.Request, title string) *PageData {
+	data := p.console.newPageData(r, title)
+	if admin := consoleAdmin(r); admin != nil {
+		data.ConsoleAdmin = admin.Email
+	}
+	return data
+}
+
+func (p *Platform) handleConsoleLoginPage(w http.ResponseWriter, r *http.Request) {
+	p.console.render(w, r, "pages/console/login.html", p.pageData(r, "Consola"))
+}
+
+// handleConsoleLogin signs a super admin in, with the same per-account
+// throttling as the workshop login
+func (p *Platform) handleConsoleLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	accountKey := loginKey(email)
+	if !p.console.allowLoginAttempt(w, r, accountKey, "pages/console/login.html") {
+		return
+	}
+
+	admin, err := p.admins.GetByEmail(r.Context(), email)
+	if err != nil || admin == nil || !checkPasswordHash(r.FormValue("password"), admin.PasswordHash) {
+		if wait := p.console.loginLockout.Fail(accountKey); wait > 0 {
+			log.Printf("🔒 Console login for %s locked for %s after repeated failures (from %s)", accountKey, wait, clientIP(r))
+			p.console.renderTooManyAttempts(w, r, "pages/console/login.html", wait)
+			return
+		}
+		data := p.pageData(r, "Consola")
+		data.Flash = &FlashMessage{Type: "error", Message: "Credenciales inválidas"}
+		p.console.render(w, r, "pages/console/login.html", data)
+		return
+	}
+	p.console.loginLockout.Reset(accountKey)
+
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.FormatInt(admin.ID, 10),
+		Audience:  jwt.ClaimStrings{consoleAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(consoleSessionTTL)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(p.config.JWT.Secret))
+	if err != nil {
+		http.Error(w, "Error starting session", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(consoleSessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   !p.config.Debug,
+		SameSite: http.SameSiteStrictMode,
+	})
+	log.Printf("🏢 Super admin %s signed in to the console (from %s)", admin.Email, clientIP(r))
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (p *Platform) handleConsoleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// handleTenantsList shows every hosted workshop
+func (p *Platform) handleTenantsList(w http.ResponseWriter, r *http.Request) {
+	tenants, err := p.tenants.List(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading tenants", http.StatusInternalServerError)
+		return
+	}
+
+	data := p.pageData(r, "Talleres")
+	data.Flash = tenantFlash(r)
+	data.Data = map[string]interface{}{
+		"Tenants":    tenants,
+		"RootDomain": p.config.Tenancy.RootDomain,
+	}
+	p.console.render(w, r, "pages/console/tenants.html", data)
+}
+
+// tenantFlash returns the message for the result codes of the console forms
+func tenantFlash(r *http.Request) *FlashMessage {
+	query := r.URL.Query()
+	switch query.Get("error") {
+	case "invalid_slug":
+		return &FlashMessage{Type: "error", Message: "El identificador debe tener entre 2 y 32 letras minúsculas, números o guiones"}
+	case "slug_taken":
+		return &FlashMessage{Type: "error", Message: "Ya existe un taller con ese identificador"}
+	case "name_required":
+		return &FlashMessage{Type: "error", Message: "El nombre del taller es obligatorio"}
+	case "invalid_hosts":
+		return &FlashMessage{Type: "error", Message: "Revisa los dominios: deben ser nombres de host válidos y distintos al de la consola"}
+	case "hosts_required":
+		return &FlashMessage{Type: "error", Message: "Indica al menos un dominio para el taller"}
+	case "host_taken":
+		return &FlashMessage{Type: "error", Message: "Uno de los dominios ya está asignado a otro taller"}
+	case "admin_required":
+		return &FlashMessage{Type: "error", Message: "Indica el nombre y email del administrador del taller"}
+	case "save_failed":
+		return &FlashMessage{Type: "error", Message: "No se pudo guardar el taller"}
+	case "open_failed":
+		return &FlashMessage{Type: "error", Message: "El taller quedó registrado pero no se pudo crear su base de datos. Revisa el log del servidor."}
+	}
+	if slug := query.Get("created"); slug != "" {
+		return &FlashMessage{Type: "success", Message: "Taller " + slug + " creado. Enviamos al administrador un enlace para elegir su contraseña."}
+	}
+	return nil
+}
+
+// handleCreateTenant registers a workshop, creates its database and invites
+// its first administrator
+func (p *Platform) handleCreateTenant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	tenant := &domain.Tenant{
+		Slug:               strings.ToLower(strings.TrimSpace(r.FormValue("slug"))),
+		Status:             domain.TenantStatusActive,
+		Surveys:            true,
+		EmailNotifications: true,
+	}
+	if !domain.ValidTenantSlug(tenant.Slug) {
+		http.Redirect(w, r, "/?error=invalid_slug", http.StatusSeeOther)
+		return
+	}
+	if errCode := p.parseTenantForm(r, tenant); errCode != "" {
+		http.Redirect(w, r, "/?error="+errCode, http.StatusSeeOther)
+		return
+	}
+	adminName := strings.TrimSpace(r.FormValue("admin_name"))
+	adminEmail := strings.ToLower(strings.TrimSpace(r.FormValue("admin_email")))
+	if adminName == "" || !strings.Contains(adminEmail, "@") {
+		http.Redirect(w, r, "/?error=admin_required", http.StatusSeeOther)
+		return
+	}
+
+	if err := p.tenants.Create(ctx, tenant); err != nil {
+		http.Redirect(w, r, "/?error="+tenantErrorCode(err), http.StatusSeeOther)
+		return
+	}
+	if err := p.reload(ctx); err != nil {
+		log.Printf("⚠️ Failed to reload tenants: %v", err)
+	}
+	log.Printf("🏢 Tenant %s created by %s", tenant.Slug, consoleAdmin(r).Email)
+
+	srv, err := p.server(tenant)
+	if err != nil {
+		log.Printf("⚠️ Failed to open tenant %s: %v", tenant.Slug, err)
+		http.Redirect(w, r, "/?error=open_failed", http.StatusSeeOther)
+		return
+	}
+	srv.inviteTenantAdmin(ctx, adminName, adminEmail)
+
+	http.Redirect(w, r, "/?created="+tenant.Slug, http.StatusSeeOther)
+}
+
+// inviteTenantAdmin creates the first administrator of a new workshop and
+// emails them a link to choose a password. A database that already has the
+// account (an imported workshop) is left as is.
+func (s *Server) inviteTenantAdmin(ctx context.Context, name, email string) {
+	if existing, _ := s.repos.Users.GetByEmail(ctx, email); existing != nil {
+		return
+	}
+	admin := &domain.User{
+		Name:          name,
+		Email:         email,
+		Role:          domain.RoleAdmin,
+		EmailVerified: true,
+	}
+	if err := s.repos.Users.Create(ctx, admin); err != nil {
+		log.Printf("⚠️ Failed to create administrator %s: %v", email, err)
+		return
+	}
+	s.sendAccountEmail(ctx, admin, domain.TokenPurposeInvite, domain.InviteTokenTTL, "/invite/",
+		"Tu taller en "+s.config.Business.Name, "Se creó la cuenta de administración de tu taller. Elige tu contraseña para empezar:")
+}
+
+func (p *Platform) handleEditTenantPage(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	tenant, err := p.tenants.GetByID(r.Context(), id)
+	if err != nil || tenant == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := p.pageData(r, tenant.Name)
+	data.Flash = tenantFlash(r)
+	if r.URL.Query().Get("msg") == "saved" {
+		data.Flash = &FlashMessage{Type: "success", Message: "Cambios guardados"}
+	}
+	data.Data = map[string]interface{}{
+		"Tenant":     tenant,
+		"URL":        p.tenantURL(tenant),
+		"RootDomain": p.config.Tenancy.RootDomain,
+	}
+	p.console.render(w, r, "pages/console/tenant_form.html", data)
+}
+
+// handleUpdateTenant saves a workshop's hosts, branding and features; its
+// running Server picks them up right away
+func (p *Platform) handleUpdateTenant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	tenant, err := p.tenants.GetByID(ctx, id)
+	if err != nil || tenant == nil {
+		http.NotFound(w, r)
+		return
+	}
+	target := "/tenants/" + strconv.FormatInt(tenant.ID, 10)
+
+	if errCode := p.parseTenantForm(r, tenant); errCode != "" {
+		http.Redirect(w, r, target+"?error="+errCode, http.StatusSeeOther)
+		return
+	}
+	if err := p.tenants.Update(ctx, tenant); err != nil {
+		http.Redirect(w, r, target+"?error="+tenantErrorCode(err), http.StatusSeeOther)
+		return
+	}
+	if err := p.reload(ctx); err != nil {
+		log.Printf("⚠️ Failed to reload tenants: %v", err)
+	}
+
+	http.Redirect(w, r, target+"?msg=saved", http.StatusSeeOther)
+}
+
+// handleSetTenantStatus suspends or reactivates a workshop. Suspended
+// workshops answer every request with a notice; their data is kept.
+func (p *Platform) handleSetTenantStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	tenant, err := p.tenants.GetByID(ctx, id)
+	if err != nil || tenant == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	tenant.Status = domain.TenantStatusActive
+	if getURLParam(r, "action") == "suspend" {
+		tenant.Status = domain.TenantStatusSuspended
+	}
+	if err := p.tenants.Update(ctx, tenant); err != nil {
+		http.Error(w, "Error updating tenant", http.StatusInternalServerError)
+		return
+	}
+	if err := p.reload(ctx); err != nil {
+		log.Printf("⚠️ Failed to reload tenants: %v", err)
+	}
+	log.Printf("🏢 Tenant %s is now %s (by %s)", tenant.Slug, tenant.Status, consoleAdmin(r).Email)
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// parseTenantForm reads the hosts, branding and features of the tenant form
+// and returns an error code for tenantFlash, or "" if the form is valid
+func (p *Platform) parseTenantForm(r *http.Request, tenant *domain.Tenant) string {
+	tenant.Name = strings.TrimSpace(r.FormValue("name"))
+	if tenant.Name == "" {
+		return "name_required"
+	}
+
+	hosts, ok := domain.NormalizeHosts(r.FormValue("hosts"))
+	if !ok {
+		return "invalid_hosts"
+	}
+	for _, host := range hosts {
+		if host == p.config.Tenancy.ConsoleHost {
+			return "invalid_hosts"
+		}
+	}
+	if len(hosts) == 0 && p.config.Tenancy.RootDomain == "" {
+		return "hosts_required"
+	}
+	tenant.Hosts = hosts
+
+	tenant.Tagline = strings.TrimSpace(r.FormValue("tagline"))
+	tenant.Logo = strings.TrimSpace(r.FormValue("logo"))
+	tenant.PrimaryColor = r.FormValue("primary_color")
+	tenant.SecondaryColor = r.FormValue("secondary_color")
+	tenant.AccentColor = r.FormValue("accent_color")
+	tenant.ContactEmail = strings.TrimSpace(r.FormValue("contact_email"))
+	tenant.ContactPhone = strings.TrimSpace(r.FormValue("contact_phone"))
+
+	tenant.Payments = r.FormValue("payments") == "on"
+	tenant.SMS = r.FormValue("sms") == "on"
+	tenant.Surveys = r.FormValue("surveys") == "on"
+	tenant.EmailNotifications = r.FormValue("email_notifications") == "on"
+	return ""
+}
+
+// tenantErrorCode maps a registry error to a tenantFlash code
+func tenantErrorCode(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateTenant):
+		return "slug_taken"
+	case errors.Is(err, repository.ErrHostTaken):
+		return "host_taken"
+	}
+	log.Printf("⚠️ Failed to save tenant: %v", err)
+	return "save_failed"
+}
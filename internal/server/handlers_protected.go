@@ -1,16 +1,21 @@
 package server
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
 
 	"github.com/skip2/go-qrcode"
 )
@@ -23,9 +28,16 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// Get recent bookings
 	bookings, _ := s.repos.Bookings.GetByCustomerID(ctx, claims.UserID, 5, 0)
 
+	// Bicycle transfers waiting for this customer
+	var incoming []domain.BicycleTransfer
+	if user, _ := s.repos.Users.GetByID(ctx, claims.UserID); user != nil {
+		incoming, _ = s.repos.Transfers.ListPendingForEmail(ctx, user.Email)
+	}
+
 	data := s.newPageData(r, "Mi Panel")
 	data.Data = map[string]interface{}{
-		"Bookings": bookings,
+		"Bookings":          bookings,
+		"IncomingTransfers": incoming,
 	}
 	s.render(w, r, "pages/customer/dashboard.html", data)
 }
@@ -59,6 +71,16 @@ func (s *Server) handleNewBookingPage(w http.ResponseWriter, r *http.Request) {
 	bicycles, _ := s.repos.Bicycles.GetByUserID(ctx, claims.UserID)
 
 	data := s.newPageData(r, "Nueva Reserva")
+	switch r.URL.Query().Get("error") {
+	case "serial_taken":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese N° de serie ya está registrado en otra cuenta. Contáctanos si la bicicleta es tuya."}
+	case "unverified":
+		data.Flash = &FlashMessage{Type: "warning", Message: "Debes verificar tu email antes de reservar un turno."}
+	case "invalid_slot":
+		data.Flash = &FlashMessage{Type: "error", Message: "Fecha u hora inválida para la sucursal elegida"}
+	case "slot_taken":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese horario ya fue reservado. Elige otro."}
+	}
 	data.Data = map[string]interface{}{
 		"Services": services,
 		"Brands":   brands,
@@ -78,6 +100,13 @@ func (s *Server) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only verified accounts can book
+	customer, err := s.repos.Users.GetByID(ctx, claims.UserID)
+	if err != nil || customer == nil || !customer.EmailVerified {
+		http.Redirect(w, r, "/bookings/new?error=unverified", http.StatusSeeOther)
+		return
+	}
+
 	serviceID, _ := strconv.ParseInt(r.FormValue("service_id"), 10, 64)
 	bicycleID, _ := strconv.ParseInt(r.FormValue("bicycle_id"), 10, 64)
 
@@ -96,9 +125,22 @@ func (s *Server) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
 			SerialNumber: serial,
 		}
 
-		if err := s.repos.Bicycles.Create(ctx, newBike); err != nil {
+		// A serial the customer already registered reuses that bicycle
+		if existing, _ := s.repos.Bicycles.GetBySerial(ctx, serial); existing != nil {
+			if existing.UserID != claims.UserID {
+				http.Redirect(w, r, "/bookings/new?error=serial_taken", http.StatusSeeOther)
+				return
+			}
+			newBike = existing
+		} else if err := s.repos.Bicycles.Create(ctx, newBike); err != nil {
+			if errors.Is(err, repository.ErrDuplicateSerial) {
+				http.Redirect(w, r, "/bookings/new?error=serial_taken", http.StatusSeeOther)
+				return
+			}
 			http.Error(w, "Error creating bicycle", http.StatusInternalServerError)
 			return
+		} else {
+			s.audit(r, "bicycle.create", domain.AuditEntityBicycle, newBike.ID, nil, newBike)
 		}
 		bicycleID = newBike.ID
 	}
@@ -110,9 +152,19 @@ func (s *Server) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
 	// Parse date and time
 	scheduledAt, err := time.Parse("2006-01-02 15:04", dateStr+" "+timeStr)
 	if err != nil {
-		data := s.newPageData(r, "Nueva Reserva")
-		data.Flash = &FlashMessage{Type: "error", Message: "Fecha u hora inválida"}
-		s.render(w, r, "pages/customer/booking_new.html", data)
+		http.Redirect(w, r, "/bookings/new?error=invalid_slot", http.StatusSeeOther)
+		return
+	}
+
+	// The slot must be within the branch's hours and still free
+	branchID, _ := strconv.ParseInt(r.FormValue("branch_id"), 10, 64)
+	branch, err := s.checkBookingSlot(ctx, branchID, scheduledAt)
+	if errors.Is(err, errSlotTaken) {
+		http.Redirect(w, r, "/bookings/new?error=slot_taken", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, "/bookings/new?error=invalid_slot", http.StatusSeeOther)
 		return
 	}
 
@@ -120,6 +172,7 @@ func (s *Server) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
 		CustomerID:  claims.UserID,
 		BicycleID:   bicycleID,
 		ServiceID:   serviceID,
+		BranchID:    branch.ID,
 		ScheduledAt: scheduledAt,
 		Status:      domain.BookingStatusPending,
 		Notes:       notes,
@@ -129,13 +182,49 @@ func (s *Server) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating booking", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "booking.create", domain.AuditEntityBooking, booking.ID, nil, booking)
+	s.emitBookingEvent(ctx, booking.ID, domain.WebhookEventBookingCreated)
 
 	http.Redirect(w, r, "/bookings", http.StatusSeeOther)
 }
 
+// Reasons a booking, a ticket status change or a ticket part is refused
+var (
+	errInvalidSlot         = errors.New("slot outside the branch's booking hours")
+	errSlotTaken           = errors.New("slot already booked")
+	errInvalidTransition   = errors.New("status change not allowed by the workflow")
+	errForbiddenTransition = errors.New("status change needs another permission")
+	errChecklistIncomplete = errors.New("required checklist items are not completed")
+	errWIPLimit            = errors.New("column is at its work-in-progress limit")
+	errInvalidPart         = errors.New("part needs a name or a known inventory item")
+)
+
+// checkBookingSlot returns the branch of a booking when scheduledAt is one of
+// its slots and nobody has booked it yet
+func (s *Server) checkBookingSlot(ctx context.Context, branchID int64, scheduledAt time.Time) (*domain.Branch, error) {
+	branch, _ := s.repos.Branches.GetByID(ctx, branchID)
+	if branch == nil || !branch.Active || !branch.HasSlot(scheduledAt) {
+		return nil, errInvalidSlot
+	}
+	startOfDay := time.Date(scheduledAt.Year(), scheduledAt.Month(), scheduledAt.Day(), 0, 0, 0, 0, scheduledAt.Location())
+	sameDay, err := s.repos.Bookings.GetByDateRange(ctx, branch.ID, startOfDay, startOfDay.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	taken := 0
+	for _, b := range sameDay {
+		if b.ScheduledAt.Equal(scheduledAt) {
+			taken++
+		}
+	}
+	if taken >= s.slotCapacity(ctx, branch.ID) {
+		return nil, errSlotTaken
+	}
+	return branch, nil
+}
+
 // handleBookingDetail shows booking details
 func (s *Server) handleBookingDetail(w http.ResponseWriter, r *http.Request) {
-	claims := getUserClaims(r)
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
@@ -145,8 +234,8 @@ func (s *Server) handleBookingDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Security check - customer can only see their own bookings
-	if claims.Role == domain.RoleCustomer && booking.CustomerID != claims.UserID {
+	// Customers only see their own bookings
+	if !s.canOn(r, domain.PermBookingsView, booking.CustomerID) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -164,7 +253,6 @@ func (s *Server) handleBookingDetail(w http.ResponseWriter, r *http.Request) {
 
 // handleCancelBooking cancels a booking
 func (s *Server) handleCancelBooking(w http.ResponseWriter, r *http.Request) {
-	claims := getUserClaims(r)
 	ctx := r.Context()
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
@@ -174,8 +262,8 @@ func (s *Server) handleCancelBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Security check
-	if claims.Role == domain.RoleCustomer && booking.CustomerID != claims.UserID {
+	// Customers only cancel their own bookings
+	if !s.canOn(r, domain.PermBookingsView, booking.CustomerID) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -184,6 +272,9 @@ func (s *Server) handleCancelBooking(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error cancelling booking", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "booking.cancel", domain.AuditEntityBooking, id,
+		map[string]string{"status": booking.Status}, map[string]string{"status": domain.BookingStatusCancelled})
+	s.emitBookingEvent(ctx, id, domain.WebhookEventBookingCancelled)
 
 	http.Redirect(w, r, "/bookings", http.StatusSeeOther)
 }
@@ -192,7 +283,7 @@ func (s *Server) handleCancelBooking(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleQuotesList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	quotes, err := s.repos.Quotes.List(ctx, "", 50, 0)
+	quotes, err := s.repos.Quotes.List(ctx, 0, "", 50, 0)
 	if err != nil {
 		http.Error(w, "Error loading quotes", http.StatusInternalServerError)
 		return
@@ -228,6 +319,8 @@ func (s *Server) handleApproveQuote(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error approving quote", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "quote.approve", domain.AuditEntityQuote, id, nil, map[string]string{"status": domain.QuoteStatusApproved})
+	s.emitQuoteEvent(ctx, id, domain.WebhookEventQuoteApproved)
 
 	http.Redirect(w, r, "/quotes/"+getURLParam(r, "id"), http.StatusSeeOther)
 }
@@ -248,6 +341,9 @@ func (s *Server) handleRejectQuote(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error rejecting quote", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "quote.reject", domain.AuditEntityQuote, id, nil,
+		map[string]string{"status": domain.QuoteStatusRejected, "reason": reason})
+	s.emitQuoteEvent(ctx, id, domain.WebhookEventQuoteRejected)
 
 	http.Redirect(w, r, "/quotes", http.StatusSeeOther)
 }
@@ -263,8 +359,21 @@ func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.renderProfile(w, r, user, nil)
+}
+
+// renderProfile renders the profile page with the user's active sessions
+func (s *Server) renderProfile(w http.ResponseWriter, r *http.Request, user *domain.User, flash *FlashMessage) {
+	claims := getUserClaims(r)
+	sessions, _ := s.repos.Sessions.ListActive(r.Context(), user.ID)
+
 	data := s.newPageData(r, "Mi Perfil")
-	data.Data = map[string]interface{}{"User": user}
+	data.Flash = flash
+	data.Data = map[string]interface{}{
+		"User":           user,
+		"Sessions":       sessions,
+		"CurrentSession": claims.SessionID,
+	}
 	s.render(w, r, "pages/customer/profile.html", data)
 }
 
@@ -284,18 +393,88 @@ func (s *Server) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := *user
 	user.Name = r.FormValue("name")
 	user.Phone = r.FormValue("phone")
 
+	// Validate the password change before saving anything
+	newPassword := r.FormValue("new_password")
+	if newPassword != "" {
+		var problem string
+		switch {
+		case !checkPasswordHash(r.FormValue("current_password"), user.PasswordHash):
+			problem = "La contraseña actual no es correcta"
+		case len(newPassword) < minPasswordLength:
+			problem = fmt.Sprintf("La nueva contraseña debe tener al menos %d caracteres", minPasswordLength)
+		case newPassword != r.FormValue("confirm_password"):
+			problem = "Las contraseñas no coinciden"
+		}
+		if problem != "" {
+			s.renderProfile(w, r, user, &FlashMessage{Type: "error", Message: problem})
+			return
+		}
+	}
+
 	if err := s.repos.Users.Update(ctx, user); err != nil {
 		http.Error(w, "Error updating profile", http.StatusInternalServerError)
 		return
 	}
+	if before.Name != user.Name || before.Phone != user.Phone {
+		s.audit(r, "user.update", domain.AuditEntityUser, user.ID, &before, user)
+	}
 
-	data := s.newPageData(r, "Mi Perfil")
-	data.Data = map[string]interface{}{"User": user}
-	data.Flash = &FlashMessage{Type: "success", Message: "Perfil actualizado"}
-	s.render(w, r, "pages/customer/profile.html", data)
+	flash := &FlashMessage{Type: "success", Message: "Perfil actualizado"}
+	if newPassword != "" {
+		hashedPassword, err := hashPassword(newPassword)
+		if err != nil {
+			http.Error(w, "Error processing password", http.StatusInternalServerError)
+			return
+		}
+		if err := s.repos.Users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			http.Error(w, "Error updating password", http.StatusInternalServerError)
+			return
+		}
+		// Keep this device signed in, sign out the rest
+		s.revokeUserSessions(ctx, user.ID, claims.SessionID)
+		s.audit(r, "user.password_change", domain.AuditEntityUser, user.ID, nil, nil)
+		flash.Message = "Contraseña actualizada. Cerramos tus sesiones en otros dispositivos."
+	}
+
+	s.renderProfile(w, r, user, flash)
+}
+
+// handleRevokeMySession signs out one of the user's own devices
+func (s *Server) handleRevokeMySession(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	session, err := s.repos.Sessions.GetByID(ctx, id)
+	if err != nil || session == nil || session.UserID != claims.UserID {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.repos.Sessions.Revoke(ctx, id); err != nil {
+		http.Error(w, "Error revoking session", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "session.revoke", domain.AuditEntitySession, id, nil, nil)
+
+	if id == claims.SessionID {
+		clearAuthCookie(w)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// handleRevokeAllMySessions signs the user out of every device, this one included
+func (s *Server) handleRevokeAllMySessions(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	s.revokeUserSessions(r.Context(), claims.UserID, 0)
+	s.audit(r, "user.sessions_revoke", domain.AuditEntityUser, claims.UserID, nil, nil)
+	clearAuthCookie(w)
+	http.Redirect(w, r, "/login?signed_out=1", http.StatusSeeOther)
 }
 
 // handleSurveyPage shows the survey form
@@ -346,6 +525,7 @@ func (s *Server) handleSubmitSurvey(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error submitting survey", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "survey.create", domain.AuditEntitySurvey, survey.ID, nil, survey)
 
 	data := s.newPageData(r, "¡Gracias!")
 	data.Flash = &FlashMessage{Type: "success", Message: "¡Gracias por tu opinión!"}
@@ -357,21 +537,31 @@ func (s *Server) handleSubmitSurvey(w http.ResponseWriter, r *http.Request) {
 // handleWorkshopDashboard shows technician dashboard
 func (s *Server) handleWorkshopDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	branchID := s.currentBranch(r)
 
 	// Get ticket counts by status
-	statusCounts, _ := s.repos.Tickets.CountByStatus(ctx)
+	statusCounts, _ := s.repos.Tickets.CountByStatus(ctx, branchID)
 
 	// Get recent tickets
-	tickets, _ := s.repos.Tickets.List(ctx, "", 10, 0)
+	tickets, _ := s.repos.Tickets.List(ctx, branchID, "", 10, 0)
 
 	// Get pending bookings
-	pendingBookings, _ := s.repos.Bookings.List(ctx, domain.BookingStatusPending, 10, 0)
+	pendingBookings, _ := s.repos.Bookings.List(ctx, branchID, domain.BookingStatusPending, 10, 0)
+
+	// Parts at or below their minimum stock
+	lowStock, _ := s.repos.Inventory.ListLowStock(ctx)
+
+	// Tickets late or about to be
+	atRisk := s.atRiskTickets(ctx, branchID)
 
 	data := s.newPageData(r, "Panel de Taller")
 	data.Data = map[string]interface{}{
 		"StatusCounts":    statusCounts,
 		"RecentTickets":   tickets,
 		"PendingBookings": pendingBookings,
+		"LowStock":        lowStock,
+		"AtRisk":          atRisk,
+		"Overloaded":      overloaded(s.workloads(ctx, branchID)),
 	}
 	s.render(w, r, "pages/technician/dashboard.html", data)
 }
@@ -381,7 +571,7 @@ func (s *Server) handleTicketsList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	status := r.URL.Query().Get("status")
-	tickets, err := s.repos.Tickets.List(ctx, status, 50, 0)
+	tickets, err := s.repos.Tickets.List(ctx, s.currentBranch(r), status, 50, 0)
 	if err != nil {
 		http.Error(w, "Error loading tickets", http.StatusInternalServerError)
 		return
@@ -401,7 +591,7 @@ func (s *Server) handleTicketDetail(w http.ResponseWriter, r *http.Request) {
 
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
 	ticket, err := s.repos.Tickets.GetByID(ctx, id)
-	if err != nil || ticket == nil {
+	if err != nil || ticket == nil || !s.inBranch(r, ticket.BranchID) {
 		http.NotFound(w, r)
 		return
 	}
@@ -410,8 +600,12 @@ func (s *Server) handleTicketDetail(w http.ResponseWriter, r *http.Request) {
 	booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
 
 	// Get bicycle details if present
+	var stolen *domain.StolenBike
 	if booking != nil && booking.BicycleID != 0 {
 		booking.Bicycle, _ = s.repos.Bicycles.GetByID(ctx, booking.BicycleID)
+		if booking.Bicycle != nil {
+			stolen, _ = s.repos.StolenBikes.GetActiveBySerial(ctx, booking.Bicycle.SerialNumber)
+		}
 	}
 
 	// Get status history
@@ -420,6 +614,12 @@ func (s *Server) handleTicketDetail(w http.ResponseWriter, r *http.Request) {
 	// Get ticket parts
 	parts, _ := s.repos.Tickets.GetTicketParts(ctx, id)
 
+	// Active inventory for the part picker
+	inventory, _ := s.repos.Inventory.ListItems(ctx, "", false)
+
+	// Purchase order lines this ticket is waiting on
+	orderedParts, _ := s.repos.Purchases.ListOpenLinesByTicket(ctx, id)
+
 	// Get quote if exists
 	quote, _ := s.repos.Quotes.GetByBookingID(ctx, ticket.BookingID)
 
@@ -429,24 +629,93 @@ func (s *Server) handleTicketDetail(w http.ResponseWriter, r *http.Request) {
 	errorType := r.URL.Query().Get("error")
 	if errorType == "invalid_transition" {
 		data.Flash = &FlashMessage{Type: "error", Message: "No puedes cambiar a ese estado (solo avance permitido)"}
+	} else if errorType == "forbidden_transition" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Tu rol no puede cobrar ni entregar bicicletas"}
 	} else if errorType == "update_failed" {
 		data.Flash = &FlashMessage{Type: "error", Message: "Error al actualizar el estado"}
-	}
-
-	// Get technicians list for admin assignment
-	claims := getUserClaims(r)
+	} else if errorType == "duplicate_serial" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese N° de serie ya está registrado en otra bicicleta"}
+	} else if errorType == "insufficient_stock" {
+		data.Flash = &FlashMessage{Type: "error", Message: "No hay stock suficiente de ese repuesto; el ticket pasó a Esperando Repuestos"}
+	} else if errorType == "timer_closed" {
+		data.Flash = &FlashMessage{Type: "error", Message: "El ticket ya está listo; registra el tiempo a mano"}
+	} else if errorType == "invalid_time_entry" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Revisa la fecha y la duración (hasta 24 horas, sin fechas futuras)"}
+	} else if errorType == "time_entry_locked" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Ese registro está en curso o ya se cobró"}
+	} else if errorType == "labor_rate" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Configura la tarifa de mano de obra antes de cobrarla"}
+	} else if errorType == "no_labor" {
+		data.Flash = &FlashMessage{Type: "error", Message: "No hay horas registradas sin cobrar"}
+	} else if errorType == "quote_closed" {
+		data.Flash = &FlashMessage{Type: "error", Message: "El presupuesto ya fue aprobado o rechazado; no admite nuevas líneas"}
+	} else if errorType == "attachment_missing" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Elige al menos un archivo"}
+	} else if errorType == "attachment_count" {
+		data.Flash = &FlashMessage{Type: "error", Message: fmt.Sprintf("Puedes subir hasta %d archivos a la vez", maxAttachmentFiles)}
+	} else if errorType == "attachment_size" {
+		data.Flash = &FlashMessage{Type: "error", Message: fmt.Sprintf("Un archivo supera los %d MB o la resolución máxima; los anteriores se guardaron", s.config.Storage.MaxUploadMB)}
+	} else if errorType == "attachment_type" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Solo se aceptan fotos JPEG o PNG y documentos PDF; los archivos anteriores se guardaron"}
+	} else if errorType == "attachment_failed" {
+		data.Flash = &FlashMessage{Type: "error", Message: "No se pudo guardar el archivo; intenta de nuevo"}
+	} else if errorType == "comment_empty" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Escribe el comentario antes de enviarlo"}
+	} else if errorType == "comment_long" {
+		data.Flash = &FlashMessage{Type: "error", Message: fmt.Sprintf("El comentario puede tener hasta %d caracteres", domain.MaxCommentLength)}
+	} else if errorType == "checklist_incomplete" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Completa los puntos obligatorios del checklist antes de marcar el ticket como listo"}
+	} else if errorType == "wip_limit" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Esa columna del tablero ya llegó a su límite de tickets"}
+	} else if errorType == "checklist_value" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Ingresa la medición como un número"}
+	} else if errorType == "checklist_photo" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Este punto necesita una foto JPEG o PNG"}
+	}
+	switch r.URL.Query().Get("msg") {
+	case "labor_billed":
+		data.Flash = &FlashMessage{Type: "success", Message: "Mano de obra agregada al presupuesto"}
+	case "attachments":
+		data.Flash = &FlashMessage{Type: "success", Message: "Archivos guardados"}
+	}
+
+	attachments, _ := s.repos.Attachments.ListByTicket(ctx, ticket.ID)
+	comments, _ := s.repos.Comments.ListByTicket(ctx, ticket.ID)
+	checklist, _ := s.repos.Checklists.ListByTicket(ctx, ticket.ID)
+
+	// Get technicians list for assignment, with the open work of each
 	var technicians []domain.User
-	if claims.Role == domain.RoleAdmin {
-		technicians, _ = s.repos.Users.List(ctx, domain.RoleTechnician, 100, 0)
+	loads := s.workloads(ctx, ticket.BranchID)
+	if s.can(r, domain.PermTicketsAssign) {
+		for _, load := range loads {
+			technicians = append(technicians, load.Technician)
+		}
 	}
 
 	data.Data = map[string]interface{}{
-		"Ticket":        ticket,
-		"Booking":       booking,
-		"StatusHistory": history,
-		"Parts":         parts,
-		"Quote":         quote,
-		"Technicians":   technicians,
+		"Ticket":           ticket,
+		"Booking":          booking,
+		"StatusHistory":    history,
+		"Parts":            parts,
+		"Quote":            quote,
+		"Technicians":      technicians,
+		"Workloads":        loads,
+		"TechnicianLoad":   workloadOf(loads, ticket.TechnicianID),
+		"CanEdit":          s.canEditTicket(r, ticket),
+		"Stolen":           stolen,
+		"Inventory":        inventory,
+		"OrderedParts":     orderedParts,
+		"SLA":              domain.ComputeSLA(ticket, history, time.Now()),
+		"Labor":            s.loadTicketLabor(ctx, ticket.ID, getUserClaims(r).UserID),
+		"Today":            time.Now().Format("2006-01-02"),
+		"Attachments":      attachments,
+		"AttachmentKinds":  domain.AttachmentKinds,
+		"MaxUploadMB":      s.config.Storage.MaxUploadMB,
+		"Comments":         comments,
+		"MaxComment":       domain.MaxCommentLength,
+		"Checklist":        checklist,
+		"ChecklistDone":    checklistDone(checklist),
+		"ChecklistPending": domain.PendingRequired(checklist),
 	}
 	s.render(w, r, "pages/technician/ticket_detail.html", data)
 }
@@ -460,7 +729,6 @@ func (s *Server) handleUpdateTicketStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	claims := getUserClaims(r)
 	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
 	status := r.FormValue("status")
 	notes := r.FormValue("notes") // Optional notes for the status change
@@ -472,62 +740,26 @@ func (s *Server) handleUpdateTicketStatus(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Security Check: Technician can only edit assigned tickets
-	if claims.Role == domain.RoleTechnician && ticket.TechnicianID != claims.UserID {
+	// Technicians can only edit assigned tickets
+	if !s.canEditTicket(r, ticket) {
 		http.Error(w, "Forbidden: You are not assigned to this ticket", http.StatusForbidden)
 		return
 	}
 
-	// Restrict state changes for technicians
-	if claims.Role == domain.RoleTechnician {
-		valid := false
-		// Define allowed transitions
-		switch ticket.Status {
-		case domain.TicketStatusReceived:
-			if status == domain.TicketStatusDiagnosing {
-				valid = true
-			}
-		case domain.TicketStatusDiagnosing:
-			if status == domain.TicketStatusInProgress || status == domain.TicketStatusWaitingParts || status == domain.TicketStatusReady {
-				valid = true
-			}
-		case domain.TicketStatusInProgress:
-			if status == domain.TicketStatusWaitingParts || status == domain.TicketStatusReady {
-				valid = true
-			}
-		case domain.TicketStatusWaitingParts:
-			if status == domain.TicketStatusInProgress || status == domain.TicketStatusReady {
-				valid = true
-			}
-		case domain.TicketStatusReady:
-			if status == domain.TicketStatusDelivered {
-				valid = true
-			}
-		case domain.TicketStatusDelivered:
-			// No changes allowed from delivered
-			valid = false
-		default:
-			// Allow initial transition if status is somehow unknown or empty?
-			// Assuming "received" is the start, but let's be strict.
-			// checking if just updating notes (same status)
-			if ticket.Status == status {
-				valid = true
-			}
-		}
-
-		// Allow keeping same status (for adding notes)
-		if ticket.Status == status {
-			valid = true
-		}
-
-		if !valid {
-			// Redirect back with error
-			http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=invalid_transition", http.StatusSeeOther)
-			return
-		}
-	}
-
-	if err := s.repos.Tickets.UpdateStatus(ctx, id, status, claims.UserID, notes); err != nil {
+	switch err := s.changeTicketStatus(r, ticket, status, notes); {
+	case errors.Is(err, errForbiddenTransition):
+		http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=forbidden_transition", http.StatusSeeOther)
+		return
+	case errors.Is(err, errInvalidTransition):
+		http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=invalid_transition", http.StatusSeeOther)
+		return
+	case errors.Is(err, errChecklistIncomplete):
+		http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=checklist_incomplete#checklist", http.StatusSeeOther)
+		return
+	case errors.Is(err, errWIPLimit):
+		http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=wip_limit", http.StatusSeeOther)
+		return
+	case err != nil:
 		http.Redirect(w, r, "/tickets/"+strconv.FormatInt(id, 10)+"?error=update_failed", http.StatusSeeOther)
 		return
 	}
@@ -535,36 +767,82 @@ func (s *Server) handleUpdateTicketStatus(w http.ResponseWriter, r *http.Request
 	http.Redirect(w, r, "/tickets/"+getURLParam(r, "id"), http.StatusSeeOther)
 }
 
-// handleAddTicketNotes adds notes to a ticket
-func (s *Server) handleAddTicketNotes(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// changeTicketStatus moves a ticket to status, recording notes in its
+// history. Without tickets.status_override only the workflow's next steps are
+// allowed, handing the bike over needs payments.take, and a ticket is only
+// ready once its required checklist items are done. The caller checks that
+// the user may edit the ticket.
+func (s *Server) changeTicketStatus(r *http.Request, ticket *domain.Ticket, status, notes string) error {
+	if !domain.ValidTicketStatus(status) {
+		return errInvalidTransition
+	}
 
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Error processing form", http.StatusBadRequest)
-		return
+	// Handing the bike over means charging the customer
+	if status == domain.TicketStatusDelivered && ticket.Status != status && !s.can(r, domain.PermPaymentsTake) {
+		return errForbiddenTransition
 	}
 
-	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
-	ticket, _ := s.repos.Tickets.GetByID(ctx, id)
-	if ticket == nil {
-		http.NotFound(w, r)
-		return
+	// Without the override, status changes follow the workflow; keeping the
+	// same status only adds a note
+	if !s.can(r, domain.PermTicketsStatusOverride) && ticket.Status != status && !workflowAllows(ticket.Status, status) {
+		return errInvalidTransition
 	}
+	return s.applyTicketStatus(r, ticket, status, notes)
+}
 
-	// Security Check: Technician can only edit assigned tickets
-	claims := getUserClaims(r)
-	if claims.Role == domain.RoleTechnician && ticket.TechnicianID != claims.UserID {
-		http.Error(w, "Forbidden: You are not assigned to this ticket", http.StatusForbidden)
-		return
+// systemTicketStatus moves a ticket on its own when something else changes,
+// like a part running out or arriving. The move is made in the name of the
+// current user but always follows the workflow, whatever their permissions.
+func (s *Server) systemTicketStatus(r *http.Request, ticket *domain.Ticket, status, notes string) error {
+	if !workflowAllows(ticket.Status, status) {
+		return errInvalidTransition
 	}
+	return s.applyTicketStatus(r, ticket, status, notes)
+}
 
-	ticket.Notes = r.FormValue("notes")
-	if err := s.repos.Tickets.Update(ctx, ticket); err != nil {
-		http.Error(w, "Error updating ticket", http.StatusInternalServerError)
-		return
+// workflowAllows tells whether the workflow lets a ticket go from one status
+// to another
+func workflowAllows(from, to string) bool {
+	switch from {
+	case domain.TicketStatusReceived:
+		return to == domain.TicketStatusDiagnosing
+	case domain.TicketStatusDiagnosing:
+		return to == domain.TicketStatusInProgress || to == domain.TicketStatusWaitingParts || to == domain.TicketStatusReady
+	case domain.TicketStatusInProgress:
+		return to == domain.TicketStatusWaitingParts || to == domain.TicketStatusReady
+	case domain.TicketStatusWaitingParts:
+		return to == domain.TicketStatusInProgress || to == domain.TicketStatusReady
+	case domain.TicketStatusReady:
+		return to == domain.TicketStatusDelivered
+	}
+	// No changes allowed from delivered
+	return false
+}
+
+// applyTicketStatus records a status change that has been allowed: it checks
+// the column limit and the checklist, writes the history, audits and handles
+// timers and events
+func (s *Server) applyTicketStatus(r *http.Request, ticket *domain.Ticket, status, notes string) error {
+	if err := s.checkWIPLimit(r.Context(), ticket, status); err != nil {
+		return err
+	}
+	// Not even the override skips the required checklist items
+	if err := s.checkChecklist(r.Context(), ticket, status); err != nil {
+		return err
 	}
 
-	http.Redirect(w, r, "/tickets/"+getURLParam(r, "id"), http.StatusSeeOther)
+	if err := s.repos.Tickets.UpdateStatus(r.Context(), ticket.ID, status, getUserClaims(r).UserID, notes); err != nil {
+		return err
+	}
+	s.audit(r, "ticket.status", domain.AuditEntityTicket, ticket.ID,
+		map[string]string{"status": ticket.Status}, map[string]string{"status": status, "note": notes})
+	if status != ticket.Status {
+		if status == domain.TicketStatusWaitingParts {
+			s.pauseTicketTimers(r.Context(), ticket.ID)
+		}
+		s.emitTicketStatusChanged(r.Context(), ticket.ID, status)
+	}
+	return nil
 }
 
 // handleCreateTicket creates a ticket from a booking
@@ -579,33 +857,35 @@ func (s *Server) handleCreateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate tracking code
-	trackingCode := generateTrackingCode()
-
-	// Generate QR code
-	baseURL := "http://localhost:" + strconv.Itoa(s.config.Server.Port)
-	trackingURL := baseURL + "/tracking/" + trackingCode
-	qrPNG, err := qrcode.Encode(trackingURL, qrcode.Medium, 256)
-	if err != nil {
-		http.Error(w, "Error generating QR code", http.StatusInternalServerError)
+	// The ticket stays at the branch the customer booked
+	if !s.inBranch(r, booking.BranchID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
 	ticket := &domain.Ticket{
 		BookingID:    bookingID,
 		TechnicianID: claims.UserID,
-		TrackingCode: trackingCode,
-		QRCode:       qrPNG,
+		BranchID:     booking.BranchID,
 		Status:       domain.TicketStatusReceived,
 	}
 
-	if err := s.repos.Tickets.Create(ctx, ticket); err != nil {
+	if err := s.createTicket(ctx, ticket); err != nil {
 		http.Error(w, "Error creating ticket", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ticket.create", domain.AuditEntityTicket, ticket.ID, nil, ticket)
+	s.emitTicketEvent(ctx, ticket.ID, domain.WebhookEventTicketCreated)
 
 	// Update booking status
 	s.repos.Bookings.UpdateStatus(ctx, bookingID, domain.BookingStatusConfirmed)
+	s.emitBookingEvent(ctx, bookingID, domain.WebhookEventBookingConfirmed)
+
+	if booking.BicycleID != 0 {
+		if bicycle, _ := s.repos.Bicycles.GetByID(ctx, booking.BicycleID); bicycle != nil {
+			s.checkStolenAtIntake(ctx, ticket.ID, bicycle.SerialNumber)
+		}
+	}
 
 	http.Redirect(w, r, "/tickets/"+strconv.FormatInt(ticket.ID, 10), http.StatusSeeOther)
 }
@@ -627,6 +907,9 @@ func (s *Server) handleNewQuotePage(w http.ResponseWriter, r *http.Request) {
 	ticketID := r.URL.Query().Get("ticket_id")
 
 	data := s.newPageData(r, "Nuevo Presupuesto")
+	if r.URL.Query().Get("error") == "discount_forbidden" {
+		data.Flash = &FlashMessage{Type: "error", Message: "Tu rol no puede aprobar descuentos: los precios no pueden ser negativos"}
+	}
 	data.Data = map[string]interface{}{
 		"Booking":  booking,
 		"Services": services,
@@ -639,7 +922,8 @@ func (s *Server) handleNewQuotePage(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCreateQuote(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	if err := r.ParseForm(); err != nil {
+	// The form is multipart when it carries diagnosis photos
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		http.Error(w, "Error processing form", http.StatusBadRequest)
 		return
 	}
@@ -656,6 +940,12 @@ func (s *Server) handleCreateQuote(w http.ResponseWriter, r *http.Request) {
 	for i := range descriptions {
 		qty, _ := strconv.Atoi(quantities[i])
 		price, _ := strconv.ParseFloat(prices[i], 64)
+
+		// Discount lines carry a negative price and need approval rights
+		if price < 0 && !s.can(r, domain.PermQuotesDiscount) {
+			http.Redirect(w, r, "/quotes/new/"+getURLParam(r, "bookingId")+"?error=discount_forbidden&ticket_id="+url.QueryEscape(r.FormValue("ticket_id")), http.StatusSeeOther)
+			return
+		}
 		itemTotal := float64(qty) * price
 		total += itemTotal
 
@@ -679,16 +969,36 @@ func (s *Server) handleCreateQuote(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating quote", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "quote.create", domain.AuditEntityQuote, quote.ID, nil, quote)
+	s.emitQuoteEvent(ctx, quote.ID, domain.WebhookEventQuoteCreated)
 
 	ticketID := r.FormValue("ticket_id")
 	if ticketID != "" {
-		http.Redirect(w, r, "/tickets/"+ticketID+"?quote_created=true&quote_id="+strconv.FormatInt(quote.ID, 10), http.StatusSeeOther)
+		redirect := "/tickets/" + ticketID + "?quote_created=true&quote_id=" + strconv.FormatInt(quote.ID, 10)
+		// Diagnosis photos sent with the quote are shown to the customer with it
+		if r.MultipartForm != nil && len(r.MultipartForm.File["files"]) > 0 {
+			id, _ := strconv.ParseInt(ticketID, 10, 64)
+			if ticket, _ := s.repos.Tickets.GetByID(ctx, id); ticket != nil && ticket.BookingID == bookingID {
+				if code := s.storeUploads(r, ticket, domain.AttachmentDiagnosis, "", true); code != "" {
+					redirect += "&error=" + code
+				}
+			}
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
 		return
 	}
 
 	http.Redirect(w, r, "/workshop", http.StatusSeeOther)
 }
 
+// withQuery appends a query parameter to a local redirect path
+func withQuery(path, param string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + param
+	}
+	return path + "?" + param
+}
+
 // generateTrackingCode generates a unique short tracking code
 func generateTrackingCode() string {
 	bytes := make([]byte, 4)
@@ -696,6 +1006,35 @@ func generateTrackingCode() string {
 	return hex.EncodeToString(bytes)
 }
 
+// createTicket stores a new ticket with a fresh tracking code and its QR.
+// Codes are unique across branches; a collision draws a new one. The
+// ticket gets a technician by the assignment strategy, an estimated ready
+// date unless it was promised one, and the checklist of its service.
+func (s *Server) createTicket(ctx context.Context, ticket *domain.Ticket) error {
+	s.assignTechnician(ctx, ticket)
+	if ticket.PromisedAt == nil {
+		ticket.PromisedAt = s.promiseReady(ctx, ticket)
+	}
+	for attempt := 0; ; attempt++ {
+		ticket.TrackingCode = generateTrackingCode()
+		qrPNG, err := qrcode.Encode(s.config.PublicURL()+"/tracking/"+ticket.TrackingCode, qrcode.Medium, 256)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code: %w", err)
+		}
+		ticket.QRCode = qrPNG
+		ticket.QRCodeBase64 = base64.StdEncoding.EncodeToString(qrPNG)
+
+		err = s.repos.Tickets.Create(ctx, ticket)
+		if errors.Is(err, repository.ErrDuplicateTrackingCode) && attempt < 5 {
+			continue
+		}
+		if err == nil {
+			s.applyChecklist(ctx, ticket)
+		}
+		return err
+	}
+}
+
 // handleUpdateBicycle updates bicycle details
 func (s *Server) handleUpdateBicycle(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -713,73 +1052,134 @@ func (s *Server) handleUpdateBicycle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update fields
+	before := *bicycle
 	bicycle.Color = r.FormValue("color")
 	bicycle.SerialNumber = r.FormValue("serial_number")
 	bicycle.Notes = r.FormValue("notes")
 
+	redirectTo := r.FormValue("redirect_to")
+	if redirectTo == "" {
+		redirectTo = "/workshop"
+	}
+
 	if err := s.repos.Bicycles.Update(ctx, bicycle); err != nil {
+		if errors.Is(err, repository.ErrDuplicateSerial) {
+			http.Redirect(w, r, withQuery(redirectTo, "error=duplicate_serial"), http.StatusSeeOther)
+			return
+		}
 		http.Error(w, "Error updating bicycle", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "bicycle.update", domain.AuditEntityBicycle, bicycle.ID, &before, bicycle)
 
-	redirectTo := r.FormValue("redirect_to")
-	if redirectTo == "" {
-		redirectTo = "/workshop"
-	}
 	http.Redirect(w, r, redirectTo, http.StatusSeeOther)
 }
 
 // handleCreateTicketPart adds a new part/item to the ticket checklist
 func (s *Server) handleCreateTicketPart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	ticketID, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
-	name := r.FormValue("name")
+	name := strings.TrimSpace(r.FormValue("name"))
+	itemID, _ := strconv.ParseInt(r.FormValue("inventory_item_id"), 10, 64)
+	quantity, _ := strconv.Atoi(r.FormValue("quantity"))
 
-	if name == "" {
-		http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticketID), http.StatusSeeOther)
+	// Technicians can only edit assigned tickets
+	ticket, _ := s.repos.Tickets.GetByID(ctx, ticketID)
+	if ticket == nil || !s.canEditTicket(r, ticket) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	// Security Check
-	claims := getUserClaims(r)
-	if claims.Role == domain.RoleTechnician {
-		ticket, _ := s.repos.Tickets.GetByID(r.Context(), ticketID)
-		if ticket != nil && ticket.TechnicianID != claims.UserID {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
+	if _, err := s.addTicketPart(r, ticket, name, itemID, quantity); err != nil && !errors.Is(err, errInvalidPart) {
+		// Log error
+		fmt.Printf("Error creating ticket part: %v\n", err)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticketID), http.StatusSeeOther)
+}
+
+// addTicketPart adds a part to a ticket, named after its inventory item when
+// name is empty. Parts from inventory come from the stock of the ticket's
+// branch, and adding one may leave other tickets waiting for parts.
+func (s *Server) addTicketPart(r *http.Request, ticket *domain.Ticket, name string, itemID int64, quantity int) (*domain.TicketPart, error) {
+	ctx := r.Context()
+
+	var item *domain.InventoryItem
+	if itemID != 0 {
+		item, _ = s.repos.Inventory.GetItem(ctx, itemID)
+		if item == nil {
+			return nil, errInvalidPart
+		}
+		if name == "" {
+			name = item.Name
 		}
 	}
 
+	if name == "" {
+		return nil, errInvalidPart
+	}
+
 	part := &domain.TicketPart{
-		TicketID: ticketID,
+		TicketID: ticket.ID,
 		Name:     name,
+		Quantity: quantity,
 	}
-
-	if err := s.repos.Tickets.CreateTicketPart(r.Context(), part); err != nil {
-		// Log error
-		fmt.Printf("Error creating ticket part: %v\n", err)
+	if item != nil {
+		part.InventoryItemID = item.ID
+		// Parts come from the stock of the ticket's branch
+		if branch, _ := s.repos.Branches.GetByID(ctx, ticket.BranchID); branch != nil {
+			part.LocationID = branch.StockLocationID
+		}
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticketID), http.StatusSeeOther)
+	if err := s.repos.Tickets.CreateTicketPart(ctx, part); err != nil {
+		return nil, err
+	}
+	s.audit(r, "ticket_part.create", domain.AuditEntityTicketPart, part.ID, nil, part)
+	if item != nil {
+		s.checkPartShortages(r, item.ID)
+	}
+	return part, nil
 }
 
 // handleToggleTicketPart toggles the status of a ticket part
 func (s *Server) handleToggleTicketPart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	ticketID, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
 	partID, _ := strconv.ParseInt(getURLParam(r, "partId"), 10, 64)
 
-	// Security Check
-	claims := getUserClaims(r)
-	if claims.Role == domain.RoleTechnician {
-		ticket, _ := s.repos.Tickets.GetByID(r.Context(), ticketID)
-		if ticket != nil && ticket.TechnicianID != claims.UserID {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
+	// Technicians can only edit assigned tickets
+	if ticket, _ := s.repos.Tickets.GetByID(ctx, ticketID); ticket == nil || !s.canEditTicket(r, ticket) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	part, _ := s.repos.Tickets.GetTicketPart(ctx, partID)
+	if part == nil || part.TicketID != ticketID {
+		http.NotFound(w, r)
+		return
 	}
 
-	if err := s.repos.Tickets.ToggleTicketPartStatus(r.Context(), partID); err != nil {
+	err := s.repos.Tickets.ToggleTicketPartStatus(ctx, partID)
+	if err != nil && !errors.Is(err, repository.ErrInsufficientStock) {
 		fmt.Printf("Error toggling part: %v\n", err)
 	}
+	if err == nil {
+		after, _ := s.repos.Tickets.GetTicketPart(ctx, partID)
+		s.audit(r, "ticket_part.toggle", domain.AuditEntityTicketPart, partID, part, after)
+	}
+	if part.InventoryItemID != 0 {
+		s.checkPartShortages(r, part.InventoryItemID)
+	}
+
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		if r.Header.Get("HX-Request") != "" {
+			http.Error(w, "Insufficient stock", http.StatusConflict)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/tickets/%d?error=insufficient_stock", ticketID), http.StatusSeeOther)
+		return
+	}
 
 	// Return generic 200 OK for AJAX or redirect
 	if r.Header.Get("HX-Request") != "" {
@@ -794,23 +1194,110 @@ func (s *Server) handleDeleteTicketPart(w http.ResponseWriter, r *http.Request)
 	ticketID, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
 	partID, _ := strconv.ParseInt(getURLParam(r, "partId"), 10, 64)
 
-	// Security Check
-	claims := getUserClaims(r)
-	if claims.Role == domain.RoleTechnician {
-		ticket, _ := s.repos.Tickets.GetByID(r.Context(), ticketID)
-		if ticket != nil && ticket.TechnicianID != claims.UserID {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
+	// Technicians can only edit assigned tickets
+	if ticket, _ := s.repos.Tickets.GetByID(r.Context(), ticketID); ticket == nil || !s.canEditTicket(r, ticket) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	part, _ := s.repos.Tickets.GetTicketPart(r.Context(), partID)
+	if part == nil || part.TicketID != ticketID {
+		http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticketID), http.StatusSeeOther)
+		return
 	}
 
 	if err := s.repos.Tickets.DeleteTicketPart(r.Context(), partID); err != nil {
 		fmt.Printf("Error deleting part: %v\n", err)
+	} else {
+		s.audit(r, "ticket_part.delete", domain.AuditEntityTicketPart, partID, part, nil)
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticketID), http.StatusSeeOther)
 }
 
+// checkPartShortages compares the pending demand for an inventory item with
+// its stock at each location. Tickets whose parts cannot be covered from
+// their location, oldest first, are moved to waiting_parts when the workflow
+// allows it, with the missing part in the status note.
+func (s *Server) checkPartShortages(r *http.Request, itemID int64) {
+	ctx := r.Context()
+	item, err := s.repos.Inventory.GetItem(ctx, itemID)
+	if err != nil || item == nil {
+		return
+	}
+	parts, err := s.repos.Tickets.GetOpenPartsByItem(ctx, itemID)
+	if err != nil {
+		log.Printf("⚠️ Failed to check shortages for item %d: %v", itemID, err)
+		return
+	}
+
+	levels, err := s.repos.Inventory.GetStockLevels(ctx, itemID)
+	if err != nil {
+		log.Printf("⚠️ Failed to check shortages for item %d: %v", itemID, err)
+		return
+	}
+	available := make(map[int64]int, len(levels))
+	for _, level := range levels {
+		available[level.LocationID] = level.Quantity
+	}
+
+	short := make(map[int64]bool)
+	for _, part := range parts {
+		location := part.LocationID
+		if location == 0 {
+			location = domain.DefaultStockLocationID
+		}
+		if part.Quantity <= available[location] {
+			available[location] -= part.Quantity
+			continue
+		}
+		available[location] = 0
+		if short[part.TicketID] {
+			continue
+		}
+		short[part.TicketID] = true
+
+		ticket, err := s.repos.Tickets.GetByID(ctx, part.TicketID)
+		if err != nil || ticket == nil {
+			continue
+		}
+		if !workflowAllows(ticket.Status, domain.TicketStatusWaitingParts) {
+			continue
+		}
+
+		note := fmt.Sprintf("Falta repuesto: %s (%s)", item.Name, item.SKU)
+		if err := s.systemTicketStatus(r, ticket, domain.TicketStatusWaitingParts, note); err != nil {
+			log.Printf("⚠️ Failed to move ticket %d to waiting_parts: %v", ticket.ID, err)
+			continue
+		}
+		log.Printf("📦 Ticket %s waiting for %s (%s)", ticket.TrackingCode, item.Name, item.SKU)
+	}
+}
+
+// notifyTicketCustomer sends a message to the owner of a ticket through the
+// channels enabled in the configuration
+func (s *Server) notifyTicketCustomer(ctx context.Context, ticket *domain.Ticket, subject, message string) {
+	booking, err := s.repos.Bookings.GetByID(ctx, ticket.BookingID)
+	if err != nil || booking == nil {
+		return
+	}
+	customer, err := s.repos.Users.GetByID(ctx, booking.CustomerID)
+	if err != nil || customer == nil {
+		return
+	}
+
+	if s.config.Features.EmailNotifications && customer.Email != "" {
+		if err := s.notifier.SendEmail(ctx, customer.Email, subject, message); err != nil {
+			log.Printf("⚠️ Failed to email %s about ticket %s: %v", customer.Email, ticket.TrackingCode, err)
+		}
+	}
+	if s.config.Features.SMS && customer.Phone != "" {
+		if err := s.notifier.SendSMS(ctx, customer.Phone, message); err != nil {
+			log.Printf("⚠️ Failed to text %s about ticket %s: %v", customer.Phone, ticket.TrackingCode, err)
+		}
+	}
+}
+
 // handleCreateBicycleFromBooking creates a new bicycle and links it to the booking
 func (s *Server) handleCreateBicycleFromBooking(w http.ResponseWriter, r *http.Request) {
 	bookingID, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
@@ -839,9 +1326,18 @@ func (s *Server) handleCreateBicycleFromBooking(w http.ResponseWriter, r *http.R
 	// For now we might just create it with basic info
 
 	if err := s.repos.Bicycles.Create(r.Context(), bicycle); err != nil {
+		if errors.Is(err, repository.ErrDuplicateSerial) {
+			redirectTo := r.FormValue("redirect_to")
+			if redirectTo == "" {
+				redirectTo = "/workshop"
+			}
+			http.Redirect(w, r, withQuery(redirectTo, "error=duplicate_serial"), http.StatusSeeOther)
+			return
+		}
 		http.Error(w, "Error creating bicycle", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "bicycle.create", domain.AuditEntityBicycle, bicycle.ID, nil, bicycle)
 
 	// Link to Booking
 	booking.BicycleID = bicycle.ID
@@ -949,6 +1445,23 @@ func (s *Server) handleCreateTicketDirect(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The bike is received at the branch the staff member is working in; in
+	// the consolidated view the form asks for it
+	branchID := s.currentBranch(r)
+	if branchID == 0 {
+		branchID, _ = strconv.ParseInt(r.FormValue("branch_id"), 10, 64)
+	}
+	if branch, _ := s.repos.Branches.GetByID(ctx, branchID); branch == nil || !branch.Active || !s.inBranch(r, branch.ID) {
+		services, _ := s.repos.Services.List(ctx)
+		data := s.newPageData(r, "Nuevo Ticket")
+		data.Flash = &FlashMessage{Type: "error", Message: "Selecciona la sucursal que recibe la bicicleta"}
+		data.Data = map[string]interface{}{
+			"Services": services,
+		}
+		s.render(w, r, "pages/technician/tickets_new.html", data)
+		return
+	}
+
 	email := r.FormValue("email")
 	name := r.FormValue("name")
 	phone := r.FormValue("phone")
@@ -960,17 +1473,14 @@ func (s *Server) handleCreateTicketDirect(w http.ResponseWriter, r *http.Request
 	}
 
 	if user == nil {
-		// Create new user
-		// Generate placeholder password
-		hashedPassword, _ := hashPassword("123456") // Simple default for walk-ins
-
+		// Create new user without a password; the invitation link lets the
+		// customer choose one (an empty hash never matches at login)
 		user = &domain.User{
-			Email:        email,
-			Name:         name,
-			Phone:        phone,
-			PasswordHash: hashedPassword,
-			Role:         domain.RoleCustomer,
-			CreatedAt:    time.Now(),
+			Email:     email,
+			Name:      name,
+			Phone:     phone,
+			Role:      domain.RoleCustomer,
+			CreatedAt: time.Now(),
 		}
 
 		if err := s.repos.Users.Create(ctx, user); err != nil {
@@ -979,74 +1489,89 @@ func (s *Server) handleCreateTicketDirect(w http.ResponseWriter, r *http.Request
 		}
 		// Fetch back to get ID (sqlite)
 		user, _ = s.repos.Users.GetByEmail(ctx, email)
+		if user != nil {
+			s.audit(r, "user.create", domain.AuditEntityUser, user.ID, nil, user)
+			s.sendInvitationEmail(ctx, user)
+		}
 	}
 
-	// 2. Create Bicycle (Always create new for this flow for now, or could check)
-	// Simplified: Always create for this "quick" flow as per plan
-	// Ideally we would search, but let's assume walk-in often brings the specific bike.
-	// We can add "Select existing" later or if we had JS.
-
-	// Check/Create Brand (Mocking dynamic creation or search would be better, but let's stick to simple text for now or existing brands)
-	// The repo expects IDs for brands/models. The UI sends text.
-	// We need logic to handle text input for Brand/Model.
-	// For MVP Session 10: Let's check if Brand exists by name, if not create?
-	// OR: Just store it as notes/text if we don't strictly enforce catalog?
-	// The Bicycle entity requires BrandID/ModelID.
-	// Let's quickly look up Brand by name (we need a repository method for that? or List and Iterate).
-	// To keep it robust without tons of new repo methods:
-	// We'll iterate all brands (cached or list) to find match.
-
-	brands, _ := s.repos.Brands.List(ctx)
-	var brandID int64
-	inputBrand := strings.TrimSpace(r.FormValue("brand"))
-
-	for _, b := range brands {
-		if strings.EqualFold(b.Name, inputBrand) {
-			brandID = b.ID
-			break
+	// 2. Reuse the bicycle if its serial is already registered, otherwise create it
+	serial := strings.TrimSpace(r.FormValue("serial"))
+	bicycle, err := s.repos.Bicycles.GetBySerial(ctx, serial)
+	if err != nil {
+		http.Error(w, "Error looking up bicycle", http.StatusInternalServerError)
+		return
+	}
+	if bicycle != nil && bicycle.UserID != user.ID {
+		services, _ := s.repos.Services.List(ctx)
+		data := s.newPageData(r, "Nuevo Ticket")
+		data.Flash = &FlashMessage{Type: "error", Message: "El N° de serie " + serial + " ya está registrado a nombre de otro cliente. Verifica los datos antes de ingresar la bicicleta."}
+		data.Data = map[string]interface{}{
+			"Services": services,
 		}
+		s.render(w, r, "pages/technician/tickets_new.html", data)
+		return
 	}
 
-	if brandID == 0 && inputBrand != "" {
-		// Create Brand (Auto-learn)
-		newBrand := &domain.Brand{Name: inputBrand}
-		s.repos.Brands.Create(ctx, newBrand)
-		brandID = newBrand.ID
-	}
+	if bicycle == nil {
+		// The repo expects IDs for brands/models but the UI sends text:
+		// match existing catalog entries by name and auto-create the rest.
+		brands, _ := s.repos.Brands.List(ctx)
+		var brandID int64
+		inputBrand := strings.TrimSpace(r.FormValue("brand"))
 
-	// Same for Model
-	var modelID int64
-	inputModel := strings.TrimSpace(r.FormValue("model"))
-	if brandID != 0 && inputModel != "" {
-		models, _ := s.repos.Models.GetByBrandID(ctx, brandID)
-		for _, m := range models {
-			if strings.EqualFold(m.Name, inputModel) {
-				modelID = m.ID
+		for _, b := range brands {
+			if strings.EqualFold(b.Name, inputBrand) {
+				brandID = b.ID
 				break
 			}
 		}
-		if modelID == 0 {
-			newModel := &domain.Model{BrandID: brandID, Name: inputModel}
-			s.repos.Models.Create(ctx, newModel)
-			modelID = newModel.ID
+
+		if brandID == 0 && inputBrand != "" {
+			// Create Brand (Auto-learn)
+			newBrand := &domain.Brand{Name: inputBrand}
+			if err := s.repos.Brands.Create(ctx, newBrand); err == nil {
+				s.audit(r, "brand.create", domain.AuditEntityBrand, newBrand.ID, nil, newBrand)
+			}
+			brandID = newBrand.ID
 		}
-	}
 
-	bicycle := &domain.Bicycle{
-		UserID:       user.ID,
-		BrandID:      brandID,
-		ModelID:      modelID,
-		Color:        r.FormValue("color"),
-		SerialNumber: r.FormValue("serial"),
-		Notes:        "Creado en recepción",
-		CreatedAt:    time.Now(),
-	}
+		// Same for Model
+		var modelID int64
+		inputModel := strings.TrimSpace(r.FormValue("model"))
+		if brandID != 0 && inputModel != "" {
+			models, _ := s.repos.Models.GetByBrandID(ctx, brandID)
+			for _, m := range models {
+				if strings.EqualFold(m.Name, inputModel) {
+					modelID = m.ID
+					break
+				}
+			}
+			if modelID == 0 {
+				newModel := &domain.Model{BrandID: brandID, Name: inputModel}
+				if err := s.repos.Models.Create(ctx, newModel); err == nil {
+					s.audit(r, "model.create", domain.AuditEntityModel, newModel.ID, nil, newModel)
+				}
+				modelID = newModel.ID
+			}
+		}
 
-	if err := s.repos.Bicycles.Create(ctx, bicycle); err != nil {
-		fmt.Printf("Error creating bicycle: %v\n", err)
-		// Proceed? Or Error? Let's error.
-		http.Error(w, "Error creating bicycle", http.StatusInternalServerError)
-		return
+		bicycle = &domain.Bicycle{
+			UserID:       user.ID,
+			BrandID:      brandID,
+			ModelID:      modelID,
+			Color:        r.FormValue("color"),
+			SerialNumber: serial,
+			Notes:        "Creado en recepción",
+			CreatedAt:    time.Now(),
+		}
+
+		if err := s.repos.Bicycles.Create(ctx, bicycle); err != nil {
+			fmt.Printf("Error creating bicycle: %v\n", err)
+			http.Error(w, "Error creating bicycle", http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, "bicycle.create", domain.AuditEntityBicycle, bicycle.ID, nil, bicycle)
 	}
 
 	// 3. Create Booking (Confirmed, Now)
@@ -1055,6 +1580,7 @@ func (s *Server) handleCreateTicketDirect(w http.ResponseWriter, r *http.Request
 		CustomerID:  user.ID,
 		BicycleID:   bicycle.ID,
 		ServiceID:   serviceID,
+		BranchID:    branchID,
 		ScheduledAt: time.Now(),
 		Status:      domain.BookingStatusConfirmed,
 		Notes:       r.FormValue("notes"),
@@ -1065,32 +1591,255 @@ func (s *Server) handleCreateTicketDirect(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Error creating booking", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "booking.create", domain.AuditEntityBooking, booking.ID, nil, booking)
+	s.emitBookingEvent(ctx, booking.ID, domain.WebhookEventBookingCreated)
 
 	// 4. Create Ticket (Received)
 	ticket := &domain.Ticket{
-		BookingID:    booking.ID,
-		TrackingCode: generateTrackingCode(), // We need to export or reuse this. It's unexported in snippets?
-		// Actually generateTrackingCode is in handlers_protected.go but lower case?
-		// I will assume it's available in package `server`.
+		BookingID: booking.ID,
+		BranchID:  booking.BranchID,
 		Status:    domain.TicketStatusReceived,
-		Notes:     r.FormValue("notes"),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	// Create QR
-	// Re-using logic from handleCreateTicket if possible, or copy-paste
-	// Copy-pasting small QR logic to be safe and independent
-	qrContent := fmt.Sprintf("https://bicicletapp.com/tracking/%s", ticket.TrackingCode)
-	png, _ := qrcode.Encode(qrContent, qrcode.Medium, 256)
-	ticket.QRCode = png
-	ticket.QRCodeBase64 = base64.StdEncoding.EncodeToString(png)
-
-	if err := s.repos.Tickets.Create(ctx, ticket); err != nil {
+	if err := s.createTicket(ctx, ticket); err != nil {
 		http.Error(w, "Error creating ticket", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "ticket.create", domain.AuditEntityTicket, ticket.ID, nil, ticket)
+	s.emitTicketEvent(ctx, ticket.ID, domain.WebhookEventTicketCreated)
 
-	// 5. Redirect
+	// 5. Check the stolen-bike registry before handing the bike to the workshop
+	s.checkStolenAtIntake(ctx, ticket.ID, bicycle.SerialNumber)
+
+	// 6. Redirect
 	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticket.ID), http.StatusSeeOther)
 }
+
+// checkStolenAtIntake records a sighting when a serial received at the
+// workshop is flagged in the stolen-bike registry. The alert is only shown to
+// staff on the ticket and admin dashboard, never in the public tracking page.
+func (s *Server) checkStolenAtIntake(ctx context.Context, ticketID int64, serial string) *domain.StolenBike {
+	stolen, err := s.repos.StolenBikes.GetActiveBySerial(ctx, serial)
+	if err != nil || stolen == nil {
+		return nil
+	}
+
+	if err := s.repos.StolenBikes.RecordSighting(ctx, stolen.ID, ticketID); err != nil {
+		log.Printf("⚠️ Could not record stolen bike sighting: %v", err)
+	}
+	log.Printf("🚨 Stolen bike serial %s received on ticket %d", stolen.SerialNumber, ticketID)
+	return stolen
+}
+
+// Customer bicycles and ownership transfers
+
+// handleMyBicycles lists the customer's bicycles and pending transfers
+func (s *Server) handleMyBicycles(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	ctx := r.Context()
+
+	user, err := s.repos.Users.GetByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	bicycles, _ := s.repos.Bicycles.GetByUserID(ctx, user.ID)
+	incoming, _ := s.repos.Transfers.ListPendingForEmail(ctx, user.Email)
+	outgoing, _ := s.repos.Transfers.ListPendingFromUser(ctx, user.ID)
+
+	// Index outgoing transfers by bicycle so the list can show their state
+	pendingByBike := make(map[int64]domain.BicycleTransfer)
+	for _, t := range outgoing {
+		pendingByBike[t.BicycleID] = t
+	}
+
+	data := s.newPageData(r, "Mis Bicicletas")
+	switch r.URL.Query().Get("msg") {
+	case "transfer_started":
+		data.Flash = &FlashMessage{Type: "success", Message: "Transferencia iniciada. El destinatario debe aceptarla desde su cuenta."}
+	case "transfer_rejected":
+		data.Flash = &FlashMessage{Type: "info", Message: "Transferencia rechazada"}
+	case "transfer_cancelled":
+		data.Flash = &FlashMessage{Type: "info", Message: "Transferencia cancelada"}
+	}
+	switch r.URL.Query().Get("error") {
+	case "invalid_email":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ingresa un email válido distinto al tuyo"}
+	case "transfer_pending":
+		data.Flash = &FlashMessage{Type: "error", Message: "Esta bicicleta ya tiene una transferencia pendiente"}
+	case "transfer_blocked":
+		data.Flash = &FlashMessage{Type: "error", Message: "Esta bicicleta no puede transferirse. Contáctanos para más información."}
+	case "transfer_failed":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se pudo completar la transferencia"}
+	}
+
+	data.Data = map[string]interface{}{
+		"Bicycles":      bicycles,
+		"Incoming":      incoming,
+		"PendingByBike": pendingByBike,
+	}
+	s.render(w, r, "pages/customer/bicycles.html", data)
+}
+
+// handleMyBicycleDetail shows a bicycle's service and ownership history.
+// Notes written by previous owners are never shown to the current one.
+func (s *Server) handleMyBicycleDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	bicycle, err := s.repos.Bicycles.GetByID(ctx, id)
+	if err != nil || bicycle == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !s.canOn(r, domain.PermBookingsView, bicycle.UserID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	bookings, _ := s.repos.Bookings.GetByBicycleID(ctx, bicycle.ID)
+	for i := range bookings {
+		if bookings[i].CustomerID != bicycle.UserID {
+			bookings[i].Notes = ""
+		}
+	}
+
+	owners, _ := s.repos.Transfers.GetOwnershipHistory(ctx, bicycle.ID)
+	pending, _ := s.repos.Transfers.GetPendingByBicycleID(ctx, bicycle.ID)
+
+	data := s.newPageData(r, "Mi Bicicleta")
+	if r.URL.Query().Get("msg") == "transfer_accepted" {
+		data.Flash = &FlashMessage{Type: "success", Message: "¡Bicicleta recibida! Ya aparece en tu cuenta con su historial de servicio."}
+	}
+	data.Data = map[string]interface{}{
+		"Bicycle":  bicycle,
+		"Bookings": bookings,
+		"Owners":   owners,
+		"Pending":  pending,
+	}
+	s.render(w, r, "pages/customer/bicycle_detail.html", data)
+}
+
+// handleStartBicycleTransfer lets the owner hand a bicycle over to another email
+func (s *Server) handleStartBicycleTransfer(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	bicycle, err := s.repos.Bicycles.GetByID(ctx, id)
+	if err != nil || bicycle == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if bicycle.UserID != claims.UserID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	owner, _ := s.repos.Users.GetByID(ctx, claims.UserID)
+	toEmail := strings.ToLower(strings.TrimSpace(r.FormValue("to_email")))
+	if !strings.Contains(toEmail, "@") || (owner != nil && strings.EqualFold(owner.Email, toEmail)) {
+		http.Redirect(w, r, "/bicycles?error=invalid_email", http.StatusSeeOther)
+		return
+	}
+
+	if pending, _ := s.repos.Transfers.GetPendingByBicycleID(ctx, bicycle.ID); pending != nil {
+		http.Redirect(w, r, "/bicycles?error=transfer_pending", http.StatusSeeOther)
+		return
+	}
+
+	// Bikes flagged in the stolen registry cannot change hands
+	if stolen, _ := s.repos.StolenBikes.GetActiveBySerial(ctx, bicycle.SerialNumber); stolen != nil {
+		http.Redirect(w, r, "/bicycles?error=transfer_blocked", http.StatusSeeOther)
+		return
+	}
+
+	transfer := &domain.BicycleTransfer{
+		BicycleID:  bicycle.ID,
+		FromUserID: claims.UserID,
+		ToEmail:    toEmail,
+	}
+	if err := s.repos.Transfers.Create(ctx, transfer); err != nil {
+		http.Error(w, "Error creating transfer", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "transfer.create", domain.AuditEntityTransfer, transfer.ID, nil, transfer)
+
+	http.Redirect(w, r, "/bicycles?msg=transfer_started", http.StatusSeeOther)
+}
+
+// handleRespondBicycleTransfer accepts or rejects an incoming transfer
+func (s *Server) handleRespondBicycleTransfer(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	transfer, err := s.repos.Transfers.GetByID(ctx, id)
+	if err != nil || transfer == nil || transfer.Status != domain.TransferStatusPending {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, _ := s.repos.Users.GetByID(ctx, claims.UserID)
+	if user == nil || !strings.EqualFold(user.Email, transfer.ToEmail) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if getURLParam(r, "action") == "reject" {
+		if err := s.repos.Transfers.UpdateStatus(ctx, id, domain.TransferStatusRejected); err != nil {
+			http.Error(w, "Error updating transfer", http.StatusInternalServerError)
+			return
+		}
+		s.audit(r, "transfer.reject", domain.AuditEntityTransfer, id,
+			map[string]string{"status": transfer.Status}, map[string]string{"status": domain.TransferStatusRejected})
+		http.Redirect(w, r, "/bicycles?msg=transfer_rejected", http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.Transfers.Accept(ctx, id, user.ID); err != nil {
+		log.Printf("⚠️ Bicycle transfer %d failed: %v", id, err)
+		http.Redirect(w, r, "/bicycles?error=transfer_failed", http.StatusSeeOther)
+		return
+	}
+	s.audit(r, "transfer.accept", domain.AuditEntityTransfer, id,
+		map[string]interface{}{"status": transfer.Status, "ownerId": transfer.FromUserID},
+		map[string]interface{}{"status": domain.TransferStatusAccepted, "ownerId": user.ID})
+
+	http.Redirect(w, r, fmt.Sprintf("/bicycles/%d?msg=transfer_accepted", transfer.BicycleID), http.StatusSeeOther)
+}
+
+// handleCancelBicycleTransfer lets the owner withdraw a pending transfer
+func (s *Server) handleCancelBicycleTransfer(w http.ResponseWriter, r *http.Request) {
+	claims := getUserClaims(r)
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	transfer, err := s.repos.Transfers.GetByID(ctx, id)
+	if err != nil || transfer == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if transfer.FromUserID != claims.UserID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := s.repos.Transfers.UpdateStatus(ctx, id, domain.TransferStatusCancelled); err != nil {
+		http.Error(w, "Error updating transfer", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "transfer.cancel", domain.AuditEntityTransfer, id,
+		map[string]string{"status": transfer.Status}, map[string]string{"status": domain.TransferStatusCancelled})
+
+	http.Redirect(w, r, "/bicycles?msg=transfer_cancelled", http.StatusSeeOther)
+}
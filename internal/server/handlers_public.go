@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
@@ -20,6 +24,58 @@ type PageData struct {
 	Flash     *FlashMessage
 	Data      interface{}
 	CSRFToken string
+	Branches  []domain.Branch // every branch, for the switcher and labels
+	BranchID  int64           // branch the staff member is working in; zero for all
+
+	// Pages of the platform console have their own navigation
+	Console      bool
+	ConsoleAdmin string // email of the signed-in super admin
+
+	role *domain.Role
+}
+
+// Can reports whether the signed-in user holds a permission, for templates:
+// {{if $.Can "tickets.assign"}}
+func (p *PageData) Can(permission string) bool {
+	return p.role.Has(permission)
+}
+
+// CanAdmin reports whether the signed-in user can open the admin panel
+func (p *PageData) CanAdmin() bool {
+	return p.role.HasAny(domain.AdminPermissions...)
+}
+
+// BranchName returns the name of a branch, for templates
+func (p *PageData) BranchName(id int64) string {
+	for _, b := range p.Branches {
+		if b.ID == id {
+			return b.Name
+		}
+	}
+	return ""
+}
+
+// ActiveBranches returns the branches that are open
+func (p *PageData) ActiveBranches() []domain.Branch {
+	var active []domain.Branch
+	for _, b := range p.Branches {
+		if b.Active {
+			active = append(active, b)
+		}
+	}
+	return active
+}
+
+// ShowBranches reports whether lists should name the branch of each row:
+// there is more than one branch and the page isn't scoped to one of them
+func (p *PageData) ShowBranches() bool {
+	return p.BranchID == 0 && len(p.Branches) > 1
+}
+
+// BranchSwitchable reports whether the signed-in user can switch branches:
+// more than one is open and they aren't pinned to a home branch
+func (p *PageData) BranchSwitchable() bool {
+	return p.User != nil && p.User.BranchID == 0 && p.role.IsStaff() && len(p.ActiveBranches()) > 1
 }
 
 // FlashMessage represents a flash message
@@ -32,12 +88,20 @@ type FlashMessage struct {
 func (s *Server) newPageData(r *http.Request, title string) *PageData {
 	claims := getUserClaims(r)
 
-	return &PageData{
-		Title:  title,
-		Config: s.config,
-		Year:   time.Now().Year(),
-		User:   claims,
+	data := &PageData{
+		Title:     title,
+		Config:    s.config,
+		Year:      time.Now().Year(),
+		User:      claims,
+		CSRFToken: csrfToken(r),
+		Console:   s.platformConsole,
+		role:      s.currentRole(r),
 	}
+	if claims != nil {
+		data.Branches, _ = s.repos.Branches.List(r.Context(), true)
+		data.BranchID = s.currentBranch(r)
+	}
+	return data
 }
 
 // render renders a template with the given data
@@ -74,6 +138,21 @@ func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := s.newPageData(r, "Iniciar Sesión")
+	q := r.URL.Query()
+	switch {
+	case q.Get("registered") != "":
+		data.Flash = &FlashMessage{Type: "success", Message: "¡Cuenta creada! Te enviamos un email para verificar tu dirección antes de ingresar."}
+	case q.Get("verified") != "":
+		data.Flash = &FlashMessage{Type: "success", Message: "Email verificado. Ya puedes iniciar sesión."}
+	case q.Get("password") != "":
+		data.Flash = &FlashMessage{Type: "success", Message: "Contraseña guardada. Ya puedes iniciar sesión."}
+	case q.Get("signed_out") != "":
+		data.Flash = &FlashMessage{Type: "info", Message: "Cerraste sesión en todos tus dispositivos."}
+	case q.Get("resent") != "":
+		data.Flash = &FlashMessage{Type: "info", Message: "Si tu cuenta está pendiente de verificación, te enviamos un nuevo enlace."}
+	case q.Get("error") == "invalid_token":
+		data.Flash = &FlashMessage{Type: "error", Message: "El enlace no es válido, ya fue usado o expiró."}
+	}
 	s.render(w, r, "pages/public/login.html", data)
 }
 
@@ -87,43 +166,109 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	email := r.FormValue("email")
 	password := r.FormValue("password")
 
+	// Throttle attempts per account, known or not, so the response doesn't
+	// reveal which emails exist
+	accountKey := loginKey(email)
+	if !s.allowLoginAttempt(w, r, accountKey, "pages/public/login.html") {
+		return
+	}
+
 	// Get user by email
 	ctx := r.Context()
 	user, err := s.repos.Users.GetByEmail(ctx, email)
-	if err != nil || user == nil {
+	if err != nil || user == nil || !checkPasswordHash(password, user.PasswordHash) {
+		if wait := s.loginLockout.Fail(accountKey); wait > 0 {
+			log.Printf("🔒 Login for %s locked for %s after repeated failures (from %s)", accountKey, wait, clientIP(r))
+			s.renderTooManyAttempts(w, r, "pages/public/login.html", wait)
+			return
+		}
 		data := s.newPageData(r, "Iniciar Sesión")
 		data.Flash = &FlashMessage{Type: "error", Message: "Credenciales inválidas"}
 		s.render(w, r, "pages/public/login.html", data)
 		return
 	}
 
-	// Check password
-	if !checkPasswordHash(password, user.PasswordHash) {
+	// Registered customers must confirm their email first
+	if !user.EmailVerified {
 		data := s.newPageData(r, "Iniciar Sesión")
-		data.Flash = &FlashMessage{Type: "error", Message: "Credenciales inválidas"}
+		data.Flash = &FlashMessage{Type: "warning", Message: "Debes verificar tu email antes de ingresar. Revisa tu bandeja de entrada."}
+		data.Data = map[string]interface{}{"UnverifiedEmail": user.Email}
 		s.render(w, r, "pages/public/login.html", data)
 		return
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(user)
-	if err != nil {
-		http.Error(w, "Error generating token", http.StatusInternalServerError)
+	// Accounts with two-factor authentication need a code before the session starts
+	if user.TOTPEnabled {
+		if err := s.setPendingTwoFactor(w, user); err != nil {
+			http.Error(w, "Error processing login", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
 		return
 	}
 
-	// Set auth cookie
-	maxAge := s.config.JWT.ExpirationHours * 3600
-	s.setAuthCookie(w, token, maxAge)
+	// Start a session and set the access and refresh token cookies
+	if err := s.startSession(w, r, user); err != nil {
+		http.Error(w, "Error starting session", http.StatusInternalServerError)
+		return
+	}
+	s.loginLockout.Reset(accountKey)
+	s.redirectAfterLogin(w, r, user)
+}
+
+// loginKey normalizes an email for per-account throttling
+func loginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// allowLoginAttempt enforces the account lockout and the per-account rate
+// limit, rendering the page with a 429 when the attempt is refused
+func (s *Server) allowLoginAttempt(w http.ResponseWriter, r *http.Request, accountKey, page string) bool {
+	if wait := s.loginLockout.Locked(accountKey); wait > 0 {
+		s.renderTooManyAttempts(w, r, page, wait)
+		return false
+	}
+	if ok, wait := s.loginLimiter.Allow(accountKey); !ok {
+		s.renderTooManyAttempts(w, r, page, wait)
+		return false
+	}
+	return true
+}
+
+// renderTooManyAttempts renders a login page with 429 and Retry-After
+func (s *Server) renderTooManyAttempts(w http.ResponseWriter, r *http.Request, page string, wait time.Duration) {
+	minutes := int(math.Ceil(wait.Minutes()))
+	message := "Demasiados intentos. Intenta de nuevo en 1 minuto."
+	if minutes > 1 {
+		message = fmt.Sprintf("Demasiados intentos. Intenta de nuevo en %d minutos.", minutes)
+	}
+
+	data := s.newPageData(r, "Iniciar Sesión")
+	data.Flash = &FlashMessage{Type: "error", Message: message}
+
+	setRetryAfter(w, wait)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	s.render(w, r, page, data)
+}
+
+// redirectAfterLogin sends the user to the home page of their role
+func (s *Server) redirectAfterLogin(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	http.Redirect(w, r, homePath(s.role(r.Context(), user.Role)), http.StatusSeeOther)
+}
 
-	// Redirect based on role
-	switch user.Role {
-	case "admin":
-		http.Redirect(w, r, "/admin", http.StatusSeeOther)
-	case "technician":
-		http.Redirect(w, r, "/workshop", http.StatusSeeOther)
+// homePath is the landing page for a role: the admin panel for admins, the
+// workshop for other staff and the customer dashboard for everyone else
+func homePath(role *domain.Role) string {
+	switch {
+	case role.Name == domain.RoleAdmin:
+		return "/admin"
+	case role.Has(domain.PermWorkshopView):
+		return "/workshop"
+	case role.HasAny(domain.AdminPermissions...):
+		return "/admin"
 	default:
-		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return "/dashboard"
 	}
 }
 
@@ -184,6 +329,8 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
+	s.auditAs(r, user.ID, "user.register", domain.AuditEntityUser, user.ID, nil, user)
+	s.sendVerificationEmail(ctx, user)
 
 	// Redirect to login with success message
 	http.Redirect(w, r, "/login?registered=1", http.StatusSeeOther)
@@ -191,6 +338,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 // handleLogout logs out the user
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.endSession(r)
 	clearAuthCookie(w)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -229,6 +377,20 @@ func (s *Server) handleTrackingStatus(w http.ResponseWriter, r *http.Request) {
 	// Get survey if exists
 	survey, _ := s.repos.Surveys.GetByTicketID(ctx, ticket.ID)
 
+	// The conversation without the workshop's internal remarks
+	comments, _ := s.repos.Comments.ListByTicket(ctx, ticket.ID)
+
+	// Photos the workshop chose to share; diagnosis ones go with the quote
+	attachments, _ := s.repos.Attachments.ListByTicket(ctx, ticket.ID)
+	var photos, diagnosisPhotos []domain.Attachment
+	for _, a := range attachmentsOf(attachments, "", true) {
+		if quote != nil && a.Kind == domain.AttachmentDiagnosis {
+			diagnosisPhotos = append(diagnosisPhotos, a)
+		} else {
+			photos = append(photos, a)
+		}
+	}
+
 	// Get active ad (Press Kit)
 	ad, _ := s.repos.Ads.GetRandomActive(ctx)
 	if ad != nil {
@@ -238,14 +400,34 @@ func (s *Server) handleTrackingStatus(w http.ResponseWriter, r *http.Request) {
 		}(ad.ID)
 	}
 
+	// The date we promised, moved back by any wait for parts
+	var readyBy *time.Time
+	if sla := domain.ComputeSLA(ticket, history, time.Now()); sla.ReadyAt == nil {
+		readyBy = sla.DueAt
+	}
+
 	data := s.newPageData(r, "Estado de tu Reparación")
+	switch r.URL.Query().Get("error") {
+	case "comment_empty":
+		data.Flash = &FlashMessage{Type: "error", Message: "Escribe tu mensaje antes de enviarlo"}
+	case "comment_long":
+		data.Flash = &FlashMessage{Type: "error", Message: fmt.Sprintf("El mensaje puede tener hasta %d caracteres", domain.MaxCommentLength)}
+	}
+	if r.URL.Query().Get("msg") == "comment_sent" {
+		data.Flash = &FlashMessage{Type: "success", Message: "Mensaje enviado al taller"}
+	}
 	data.Data = map[string]interface{}{
-		"Ticket":        ticket,
-		"StatusHistory": history,
-		"StatusMap":     statusMap,
-		"Quote":         quote,
-		"Survey":        survey,
-		"Ad":            ad,
+		"Ticket":          ticket,
+		"ReadyBy":         readyBy,
+		"StatusHistory":   history,
+		"StatusMap":       statusMap,
+		"Quote":           quote,
+		"Photos":          photos,
+		"DiagnosisPhotos": diagnosisPhotos,
+		"Comments":        customerComments(comments),
+		"MaxComment":      domain.MaxCommentLength,
+		"Survey":          survey,
+		"Ad":              ad,
 	}
 	s.render(w, r, "pages/public/tracking_result.html", data)
 }
@@ -292,6 +474,9 @@ func (s *Server) handlePublicApproveQuote(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Error aprobando presupuesto", http.StatusInternalServerError)
 		return
 	}
+	s.audit(r, "quote.approve", domain.AuditEntityQuote, id,
+		map[string]string{"status": quote.Status}, map[string]string{"status": domain.QuoteStatusApproved})
+	s.emitQuoteEvent(ctx, id, domain.WebhookEventQuoteApproved)
 
 	// Redirect back to tracking page (we need the ticket code)
 	// Since we don't have the ticket code handy in the URL params of this POST,
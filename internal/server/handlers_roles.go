@@ -0,0 +1,203 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// permissionGroup is a heading of the role editor with its permissions
+type permissionGroup struct {
+	Name        string
+	Permissions []domain.PermissionInfo
+}
+
+// permissionGroups groups domain.Permissions for the role editor
+func permissionGroups() []permissionGroup {
+	var groups []permissionGroup
+	for _, p := range domain.Permissions {
+		if len(groups) == 0 || groups[len(groups)-1].Name != p.Group {
+			groups = append(groups, permissionGroup{Name: p.Group})
+		}
+		groups[len(groups)-1].Permissions = append(groups[len(groups)-1].Permissions, p)
+	}
+	return groups
+}
+
+// grantablePermissions keeps the requested permissions the signed-in user
+// holds; nobody can hand out more than they have
+func (s *Server) grantablePermissions(r *http.Request, requested []string) []string {
+	current := s.currentRole(r)
+	var granted []string
+	for _, p := range requested {
+		if current.Has(p) {
+			granted = append(granted, p)
+		}
+	}
+	return granted
+}
+
+// handleRolesList shows the roles with their permissions
+func (s *Server) handleRolesList(w http.ResponseWriter, r *http.Request) {
+	roles, err := s.repos.Roles.List(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading roles", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Roles y Permisos")
+	switch r.URL.Query().Get("error") {
+	case "invalid":
+		data.Flash = &FlashMessage{Type: "error", Message: "El identificador debe tener 2 a 32 letras minúsculas, números o guiones bajos, y el nombre no puede estar vacío"}
+	case "duplicate":
+		data.Flash = &FlashMessage{Type: "error", Message: "Ya existe un rol con ese identificador"}
+	case "in_use":
+		data.Flash = &FlashMessage{Type: "error", Message: "No se puede eliminar un rol asignado a usuarios"}
+	}
+	data.Data = map[string]interface{}{
+		"Roles":            roles,
+		"PermissionGroups": permissionGroups(),
+	}
+	s.render(w, r, "pages/admin/roles.html", data)
+}
+
+// handleCreateRole creates a custom role
+func (s *Server) handleCreateRole(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	role := &domain.Role{
+		Name:  strings.ToLower(strings.TrimSpace(r.FormValue("name"))),
+		Label: strings.TrimSpace(r.FormValue("label")),
+	}
+	if !domain.ValidRoleName(role.Name) || role.Label == "" {
+		http.Redirect(w, r, "/admin/roles?error=invalid", http.StatusSeeOther)
+		return
+	}
+	role.SetPermissions(s.grantablePermissions(r, r.Form["permissions"]))
+
+	if err := s.repos.Roles.Create(r.Context(), role); err != nil {
+		if errors.Is(err, repository.ErrDuplicateRole) {
+			http.Redirect(w, r, "/admin/roles?error=duplicate", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Error creating role", http.StatusInternalServerError)
+		return
+	}
+	s.invalidateRoles()
+	s.audit(r, "role.create", domain.AuditEntityRole, 0, nil, role)
+
+	http.Redirect(w, r, "/admin/roles/"+url.PathEscape(role.Name)+"?msg=created", http.StatusSeeOther)
+}
+
+// handleEditRolePage shows the permission editor of a role
+func (s *Server) handleEditRolePage(w http.ResponseWriter, r *http.Request) {
+	role, err := s.repos.Roles.GetByName(r.Context(), getURLParam(r, "name"))
+	if err != nil || role == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	granted := make(map[string]bool, len(role.Permissions))
+	for _, p := range role.Permissions {
+		granted[p] = true
+	}
+	grantable := make(map[string]bool)
+	for _, p := range domain.Permissions {
+		grantable[p.Key] = s.can(r, p.Key)
+	}
+
+	data := s.newPageData(r, "Rol: "+role.Label)
+	switch r.URL.Query().Get("msg") {
+	case "created":
+		data.Flash = &FlashMessage{Type: "success", Message: "Rol creado"}
+	case "saved":
+		data.Flash = &FlashMessage{Type: "success", Message: "Permisos guardados. Se aplican de inmediato."}
+	}
+	if r.URL.Query().Get("error") == "invalid" {
+		data.Flash = &FlashMessage{Type: "error", Message: "El nombre no puede estar vacío"}
+	}
+	data.Data = map[string]interface{}{
+		"Role":             role,
+		"Granted":          granted,
+		"Grantable":        grantable,
+		"PermissionGroups": permissionGroups(),
+		"Editable":         role.Name != domain.RoleAdmin && s.canGrantRole(r, role.Name),
+	}
+	s.render(w, r, "pages/admin/role_form.html", data)
+}
+
+// handleUpdateRole saves a role's label and permissions. Changes apply on
+// the next request of every user holding the role.
+func (s *Server) handleUpdateRole(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	role, err := s.repos.Roles.GetByName(ctx, getURLParam(r, "name"))
+	if err != nil || role == nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Admin always holds every permission; other roles can only be edited by
+	// someone who holds everything they grant
+	if role.Name == domain.RoleAdmin || !s.canGrantRole(r, role.Name) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	label := strings.TrimSpace(r.FormValue("label"))
+	if label == "" {
+		http.Redirect(w, r, "/admin/roles/"+url.PathEscape(role.Name)+"?error=invalid", http.StatusSeeOther)
+		return
+	}
+
+	before := *role
+	before.Permissions = append([]string(nil), role.Permissions...)
+	role.Label = label
+	role.SetPermissions(s.grantablePermissions(r, r.Form["permissions"]))
+
+	if err := s.repos.Roles.Update(ctx, role); err != nil {
+		http.Error(w, "Error updating role", http.StatusInternalServerError)
+		return
+	}
+	s.invalidateRoles()
+	s.audit(r, "role.update", domain.AuditEntityRole, 0, &before, role)
+
+	http.Redirect(w, r, "/admin/roles/"+url.PathEscape(role.Name)+"?msg=saved", http.StatusSeeOther)
+}
+
+// handleDeleteRole removes a custom role nobody holds
+func (s *Server) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	role, err := s.repos.Roles.GetByName(ctx, getURLParam(r, "name"))
+	if err != nil || role == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if role.System || !s.canGrantRole(r, role.Name) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := s.repos.Roles.Delete(ctx, role.Name); err != nil {
+		if errors.Is(err, repository.ErrRoleInUse) {
+			http.Redirect(w, r, "/admin/roles?error=in_use", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Error deleting role", http.StatusInternalServerError)
+		return
+	}
+	s.invalidateRoles()
+	s.audit(r, "role.delete", domain.AuditEntityRole, 0, role, nil)
+
+	http.Redirect(w, r, "/admin/roles", http.StatusSeeOther)
+}
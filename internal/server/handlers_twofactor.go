@@ -0,0 +1,464 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+	"bicicletapp/internal/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+// twoFactorCookieName holds the signed proof that the password step passed
+const twoFactorCookieName = "mfa_pending"
+
+// twoFactorPendingTTL is how long the user has to enter the second factor
+const twoFactorPendingTTL = 5 * time.Minute
+
+// twoFactorClaims identify a user who passed the password step
+type twoFactorClaims struct {
+	UserID int64 `json:"userId"`
+	jwt.RegisteredClaims
+}
+
+// setPendingTwoFactor stores the password step result in a short-lived cookie
+func (s *Server) setPendingTwoFactor(w http.ResponseWriter, user *domain.User) error {
+	claims := &twoFactorClaims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "2fa",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorPendingTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    s.config.Business.Name,
+			Audience:  s.tokenAudience(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.JWT.Secret))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    token,
+		Path:     "/login",
+		MaxAge:   int(twoFactorPendingTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   !s.config.Debug,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// pendingTwoFactorUser returns the user waiting for the second factor, if any
+func (s *Server) pendingTwoFactorUser(r *http.Request) *domain.User {
+	cookie, err := r.Cookie(twoFactorCookieName)
+	if err != nil {
+		return nil
+	}
+	claims := &twoFactorClaims{}
+	token, err := jwt.ParseWithClaims(cookie.Value, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.config.JWT.Secret), nil
+	}, s.tokenParserOptions(jwt.WithSubject("2fa"))...)
+	if err != nil || !token.Valid {
+		return nil
+	}
+	user, err := s.repos.Users.GetByID(r.Context(), claims.UserID)
+	if err != nil || user == nil || !user.TOTPEnabled {
+		return nil
+	}
+	return user
+}
+
+func clearPendingTwoFactor(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    "",
+		Path:     "/login",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// checkSecondFactor accepts either a current TOTP code (each one only once)
+// or an unused recovery code
+func (s *Server) checkSecondFactor(ctx context.Context, user *domain.User, code string) bool {
+	if user.TOTPSecret == "" {
+		return false
+	}
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.repos.Users.RecordTOTPStep(ctx, user.ID, step)
+		return err == nil && fresh
+	}
+
+	if !user.TOTPEnabled {
+		return false
+	}
+	err := s.repos.Users.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err == nil {
+		log.Printf("🔑 User %d signed in with a recovery code", user.ID)
+		return true
+	}
+	if !errors.Is(err, repository.ErrInvalidToken) {
+		log.Printf("⚠️ Failed to check recovery code for user %d: %v", user.ID, err)
+	}
+	return false
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the new
+// plain codes, formatted xxxx-xxxx
+func (s *Server) issueRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, 0, domain.RecoveryCodeCount)
+	hashes := make([]string, 0, domain.RecoveryCodeCount)
+	for i := 0; i < domain.RecoveryCodeCount; i++ {
+		raw := make([]byte, 4)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+	if err := s.repos.Users.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// twoFactorRequired reports whether the admin policy requires 2FA for a
+// role. The technician policy covers every other staff role.
+func (s *Server) twoFactorRequired(ctx context.Context, role string) bool {
+	var key string
+	switch {
+	case role == domain.RoleAdmin:
+		key = domain.SettingRequire2FAAdmin
+	case s.role(ctx, role).IsStaff():
+		key = domain.SettingRequire2FATechnician
+	default:
+		return false
+	}
+	value, _ := s.repos.Settings.Get(ctx, key)
+	return value == "true"
+}
+
+// twoFactorPolicyMiddleware sends staff without 2FA to the enrollment page
+// when the policy requires it for their role
+func (s *Server) twoFactorPolicyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := getUserClaims(r)
+		if claims != nil && claims.APIKeyID == 0 && s.twoFactorRequired(r.Context(), claims.Role) {
+			user, err := s.repos.Users.GetByID(r.Context(), claims.UserID)
+			if err != nil || user == nil || !user.TOTPEnabled {
+				if isAPIRequest(r) {
+					writeAPIError(w, http.StatusForbidden, "two_factor_required", "Two-factor authentication must be enabled for this account")
+					return
+				}
+				http.Redirect(w, r, "/account/2fa?required=1", http.StatusSeeOther)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleTwoFactorLoginPage asks for the second factor after the password step
+func (s *Server) handleTwoFactorLoginPage(w http.ResponseWriter, r *http.Request) {
+	if s.pendingTwoFactorUser(r) == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	data := s.newPageData(r, "Verificación en dos pasos")
+	s.render(w, r, "pages/public/login_2fa.html", data)
+}
+
+// handleTwoFactorLogin completes the login with a TOTP or recovery code
+func (s *Server) handleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	user := s.pendingTwoFactorUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Codes share the account's login lockout, which is only reset once the
+	// whole login succeeds
+	accountKey := loginKey(user.Email)
+	if !s.allowLoginAttempt(w, r, accountKey, "pages/public/login_2fa.html") {
+		return
+	}
+	if !s.checkSecondFactor(r.Context(), user, r.FormValue("code")) {
+		if wait := s.loginLockout.Fail(accountKey); wait > 0 {
+			log.Printf("🔒 Login for %s locked for %s after repeated 2FA failures (from %s)", accountKey, wait, clientIP(r))
+			clearPendingTwoFactor(w)
+			s.renderTooManyAttempts(w, r, "pages/public/login.html", wait)
+			return
+		}
+		data := s.newPageData(r, "Verificación en dos pasos")
+		data.Flash = &FlashMessage{Type: "error", Message: "Código incorrecto"}
+		s.render(w, r, "pages/public/login_2fa.html", data)
+		return
+	}
+
+	clearPendingTwoFactor(w)
+	if err := s.startSession(w, r, user); err != nil {
+		http.Error(w, "Error starting session", http.StatusInternalServerError)
+		return
+	}
+	s.loginLockout.Reset(accountKey)
+	s.redirectAfterLogin(w, r, user)
+}
+
+// renderTwoFactorSetup renders the enrollment / management page
+func (s *Server) renderTwoFactorSetup(w http.ResponseWriter, r *http.Request, user *domain.User, recoveryCodes []string, flash *FlashMessage) {
+	ctx := r.Context()
+	data := s.newPageData(r, "Verificación en dos pasos")
+	data.Flash = flash
+
+	pageData := map[string]interface{}{
+		"User":          user,
+		"Required":      s.twoFactorRequired(ctx, user.Role),
+		"RecoveryCodes": recoveryCodes,
+	}
+	if user.TOTPEnabled {
+		left, _ := s.repos.Users.CountRecoveryCodes(ctx, user.ID)
+		pageData["RecoveryCodesLeft"] = left
+	} else {
+		png, err := qrcode.Encode(totp.URI(s.config.Business.Name, user.Email, user.TOTPSecret), qrcode.Medium, 256)
+		if err != nil {
+			http.Error(w, "Error generating QR code", http.StatusInternalServerError)
+			return
+		}
+		pageData["QRCode"] = base64.StdEncoding.EncodeToString(png)
+		pageData["Secret"] = groupSecret(user.TOTPSecret)
+	}
+	data.Data = pageData
+	s.render(w, r, "pages/customer/two_factor.html", data)
+}
+
+// groupSecret splits a base32 secret in blocks of four for manual entry
+func groupSecret(secret string) string {
+	var b strings.Builder
+	for i, r := range secret {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// currentUser loads the signed-in user
+func (s *Server) currentUser(r *http.Request) *domain.User {
+	claims := getUserClaims(r)
+	if claims == nil {
+		return nil
+	}
+	user, err := s.repos.Users.GetByID(r.Context(), claims.UserID)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// handleTwoFactorSetup shows the QR code to enroll, or the current status
+func (s *Server) handleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	user := s.currentUser(r)
+	if user == nil {
+		http.Error(w, "User not found", http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the pending secret across reloads so an already scanned QR works
+	if !user.TOTPEnabled && user.TOTPSecret == "" {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			http.Error(w, "Error generating secret", http.StatusInternalServerError)
+			return
+		}
+		if err := s.repos.Users.UpdateTOTP(r.Context(), user.ID, secret, false); err != nil {
+			http.Error(w, "Error saving secret", http.StatusInternalServerError)
+			return
+		}
+		user.TOTPSecret = secret
+	}
+
+	var flash *FlashMessage
+	if r.URL.Query().Get("required") != "" && !user.TOTPEnabled {
+		flash = &FlashMessage{Type: "warning", Message: "Tu rol requiere verificación en dos pasos. Actívala para continuar."}
+	}
+	s.renderTwoFactorSetup(w, r, user, nil, flash)
+}
+
+// handleEnableTwoFactor confirms enrollment with a code from the app
+func (s *Server) handleEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user := s.currentUser(r)
+	if user == nil || user.TOTPSecret == "" {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return
+	}
+	if user.TOTPEnabled {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return
+	}
+
+	if !s.checkSecondFactor(ctx, user, r.FormValue("code")) {
+		s.renderTwoFactorSetup(w, r, user, nil, &FlashMessage{Type: "error", Message: "Código incorrecto. Revisa la hora de tu teléfono e inténtalo de nuevo."})
+		return
+	}
+
+	if err := s.repos.Users.UpdateTOTP(ctx, user.ID, user.TOTPSecret, true); err != nil {
+		http.Error(w, "Error enabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	codes, err := s.issueRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Error generating recovery codes", http.StatusInternalServerError)
+		return
+	}
+	s.revokeUserSessions(ctx, user.ID, getUserClaims(r).SessionID)
+	s.audit(r, "user.2fa_enable", domain.AuditEntityUser, user.ID,
+		map[string]bool{"totpEnabled": false}, map[string]bool{"totpEnabled": true})
+
+	user.TOTPEnabled = true
+	s.renderTwoFactorSetup(w, r, user, codes, &FlashMessage{Type: "success", Message: "Verificación en dos pasos activada. Guarda tus códigos de recuperación."})
+}
+
+// handleRegenerateRecoveryCodes replaces the recovery codes
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user := s.currentUser(r)
+	if user == nil || !user.TOTPEnabled {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return
+	}
+	if !s.checkSecondFactor(ctx, user, r.FormValue("code")) {
+		s.renderTwoFactorSetup(w, r, user, nil, &FlashMessage{Type: "error", Message: "Código incorrecto"})
+		return
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Error generating recovery codes", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "user.2fa_recovery_codes", domain.AuditEntityUser, user.ID, nil, nil)
+	s.renderTwoFactorSetup(w, r, user, codes, &FlashMessage{Type: "success", Message: "Nuevos códigos generados. Los anteriores ya no sirven."})
+}
+
+// handleDisableTwoFactor turns 2FA off unless the policy requires it
+func (s *Server) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user := s.currentUser(r)
+	if user == nil || !user.TOTPEnabled {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return
+	}
+	if s.twoFactorRequired(ctx, user.Role) {
+		s.renderTwoFactorSetup(w, r, user, nil, &FlashMessage{Type: "error", Message: "Tu rol requiere verificación en dos pasos; no puede desactivarse."})
+		return
+	}
+	if !s.checkSecondFactor(ctx, user, r.FormValue("code")) {
+		s.renderTwoFactorSetup(w, r, user, nil, &FlashMessage{Type: "error", Message: "Código incorrecto"})
+		return
+	}
+
+	if err := s.disableTwoFactor(ctx, user.ID); err != nil {
+		http.Error(w, "Error disabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "user.2fa_disable", domain.AuditEntityUser, user.ID,
+		map[string]bool{"totpEnabled": true}, map[string]bool{"totpEnabled": false})
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
+
+// disableTwoFactor clears the secret and the recovery codes
+func (s *Server) disableTwoFactor(ctx context.Context, userID int64) error {
+	if err := s.repos.Users.UpdateTOTP(ctx, userID, "", false); err != nil {
+		return err
+	}
+	return s.repos.Users.ReplaceRecoveryCodes(ctx, userID, nil)
+}
+
+// handleAdminResetTwoFactor removes a user's 2FA (lost phone) and signs them
+// out so they enroll again on the next login
+func (s *Server) handleAdminResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	if user, _ := s.repos.Users.GetByID(ctx, id); user == nil || !s.canGrantRole(r, user.Role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err := s.disableTwoFactor(ctx, id); err != nil {
+		http.Error(w, "Error resetting two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	s.revokeUserSessions(ctx, id, 0)
+	s.audit(r, "user.2fa_reset", domain.AuditEntityUser, id, nil, map[string]bool{"totpEnabled": false})
+	http.Redirect(w, r, "/admin/users/"+strconv.FormatInt(id, 10)+"?msg=2fa_reset", http.StatusSeeOther)
+}
+
+// handleUpdateSecuritySettings saves the 2FA policy
+func (s *Server) handleUpdateSecuritySettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	before := map[string]string{}
+	after := map[string]string{}
+	for _, key := range []string{domain.SettingRequire2FAAdmin, domain.SettingRequire2FATechnician} {
+		value := "false"
+		if r.FormValue(key) == "on" {
+			value = "true"
+		}
+		before[key], _ = s.repos.Settings.Get(ctx, key)
+		if err := s.repos.Settings.Set(ctx, key, value); err != nil {
+			http.Error(w, "Error saving settings", http.StatusInternalServerError)
+			return
+		}
+		after[key] = value
+	}
+	s.audit(r, "setting.update", domain.AuditEntitySetting, 0, before, after)
+	http.Redirect(w, r, "/admin/settings?msg=security", http.StatusSeeOther)
+}
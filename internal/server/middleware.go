@@ -2,11 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 
 	"bicicletapp/internal/domain"
+	"bicicletapp/internal/ratelimit"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
@@ -17,57 +28,63 @@ type contextKey string
 
 const (
 	userContextKey contextKey = "user"
+	csrfContextKey contextKey = "csrf"
 )
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID int64  `json:"userId"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    int64  `json:"userId"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	BranchID  int64  `json:"branch,omitempty"` // home branch of staff pinned to one
+	SessionID int64  `json:"sid"`
 	jwt.RegisteredClaims
+
+	// Set instead of SessionID when the request came with an API key
+	APIKeyID int64    `json:"-"`
+	Scopes   []string `json:"-"`
 }
 
-// authMiddleware protects routes requiring authentication
+// authMiddleware protects routes requiring authentication. Access tokens are
+// only accepted while their session is active; when the access token is
+// missing or expired the refresh token cookie is rotated to issue a new one.
+// Under /api/ it also accepts API keys and answers failures with JSON.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(domain.APIKeyHeader); key != "" && isAPIRequest(r) {
+			claims := s.authenticateAPIKey(r.Context(), key)
+			if claims == nil {
+				writeAPIError(w, http.StatusUnauthorized, "invalid_api_key", "Unknown, expired or revoked API key")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, claims)))
+			return
+		}
+
 		// Try to get token from cookie first
 		cookie, err := r.Cookie("auth_token")
 		var tokenString string
 
 		if err == nil {
 			tokenString = cookie.Value
-		} else {
-			// Fallback to Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Redirect(w, r, "/login", http.StatusSeeOther)
-				return
-			}
-
+		} else if authHeader := r.Header.Get("Authorization"); authHeader != "" {
 			// Bearer token format
 			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Redirect(w, r, "/login", http.StatusSeeOther)
-				return
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				tokenString = parts[1]
 			}
-			tokenString = parts[1]
 		}
 
-		// Parse and validate the token
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(s.config.JWT.Secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			// Clear invalid cookie
-			http.SetCookie(w, &http.Cookie{
-				Name:     "auth_token",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+		claims := s.validateAccessToken(r.Context(), tokenString)
+		if claims == nil {
+			claims = s.refreshSession(w, r)
+		}
+		if claims == nil {
+			clearAuthCookie(w)
+			if isAPIRequest(r) {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Missing, invalid or expired access token")
+				return
+			}
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
@@ -78,38 +95,45 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// roleMiddleware restricts access based on user role
-func (s *Server) roleMiddleware(allowedRoles ...string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims := getUserClaims(r)
-			if claims == nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+// validateAccessToken parses an access token and checks that its session is
+// still active, so revoked sessions stop working immediately
+func (s *Server) validateAccessToken(ctx context.Context, tokenString string) *Claims {
+	if tokenString == "" {
+		return nil
+	}
 
-			// Check if user's role is in allowed roles
-			allowed := false
-			for _, role := range allowedRoles {
-				if claims.Role == role {
-					allowed = true
-					break
-				}
-			}
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.config.JWT.Secret), nil
+	}, s.tokenParserOptions()...)
+	if err != nil || !token.Valid || claims.SessionID == 0 {
+		return nil
+	}
 
-			// Admin always has access
-			if claims.Role == domain.RoleAdmin {
-				allowed = true
-			}
+	session, err := s.repos.Sessions.GetByID(ctx, claims.SessionID)
+	if err != nil || session == nil || !session.Active() || session.UserID != claims.UserID {
+		return nil
+	}
+	return claims
+}
 
-			if !allowed {
-				http.Error(w, "Forbidden", http.StatusForbidden)
-				return
-			}
+// tokenAudience returns the audience written to the tokens this server
+// issues (see config.JWT.Audience)
+func (s *Server) tokenAudience() jwt.ClaimStrings {
+	if s.config.JWT.Audience == "" {
+		return nil
+	}
+	return jwt.ClaimStrings{s.config.JWT.Audience}
+}
 
-			next.ServeHTTP(w, r)
-		})
+// tokenParserOptions returns the checks every token this server accepts must
+// pass, plus any extra ones
+func (s *Server) tokenParserOptions(extra ...jwt.ParserOption) []jwt.ParserOption {
+	options := append([]jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name})}, extra...)
+	if s.config.JWT.Audience != "" {
+		options = append(options, jwt.WithAudience(s.config.JWT.Audience))
 	}
+	return options
 }
 
 // getUserClaims extracts user claims from request context
@@ -121,23 +145,30 @@ func getUserClaims(r *http.Request) *Claims {
 	return claims
 }
 
-// generateToken creates a new JWT token for a user
-func (s *Server) generateToken(user *domain.User) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(s.config.JWT.ExpirationHours) * time.Hour)
+// generateToken creates a short-lived access token bound to a session
+func (s *Server) generateToken(user *domain.User, sessionID int64) (string, *Claims, error) {
+	expirationTime := time.Now().Add(s.accessTokenTTL())
 
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		BranchID:  user.BranchID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    s.config.Business.Name,
+			Audience:  s.tokenAudience(),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWT.Secret))
+	signed, err := token.SignedString([]byte(s.config.JWT.Secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
 // setAuthCookie sets the authentication cookie
@@ -153,56 +184,226 @@ func (s *Server) setAuthCookie(w http.ResponseWriter, token string, maxAge int)
 	})
 }
 
-// clearAuthCookie removes the authentication cookie
-func clearAuthCookie(w http.ResponseWriter) {
+// setRefreshCookie sets the refresh token cookie
+func (s *Server) setRefreshCookie(w http.ResponseWriter, token string, maxAge int) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    "",
+		Name:     refreshCookieName,
+		Value:    token,
 		Path:     "/",
-		MaxAge:   -1,
+		MaxAge:   maxAge,
 		HttpOnly: true,
+		Secure:   !s.config.Debug,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearAuthCookie removes the access and refresh token cookies
+func clearAuthCookie(w http.ResponseWriter) {
+	for _, name := range []string{"auth_token", refreshCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+}
+
+// limitRequestBody caps every request body at maxRequestBytes, so neither
+// the form parsing of the CSRF check nor an upload handler spools more than
+// an upload may hold to memory or disk
+func (s *Server) limitRequestBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestBytes(r))
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
-// csrfMiddleware adds CSRF protection for forms
+// csrfCookieName holds the browser's CSRF token
+const csrfCookieName = "csrf_token"
+
+// csrfExemptPrefixes are routes called by other servers, which authenticate
+// with their own signatures instead of cookies, and the API's token
+// endpoints, which take credentials in the body and set no cookies
+var csrfExemptPrefixes = []string{"/webhooks/", apiV1Prefix + "/auth/"}
+
+// csrfMiddleware implements signed double-submit CSRF protection. Every
+// browser gets a token cookie signed with the server secret; templates embed
+// the same token (PageData.CSRFToken) in forms and the X-CSRF-Token header,
+// and state-changing requests must echo it back. Requests carrying an
+// Authorization header are exempt: browsers can't attach one cross-site.
 func (s *Server) csrfMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ""
+		if cookie, err := r.Cookie(csrfCookieName); err == nil && s.validCSRFToken(cookie.Value) {
+			token = cookie.Value
+		} else {
+			token = s.newCSRFToken()
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   !s.config.Debug,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
 		// Only check for state-changing methods
 		if r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" || r.Method == "PATCH" {
-			// Check for CSRF token in form or header
-			formToken := r.FormValue("csrf_token")
-			headerToken := r.Header.Get("X-CSRF-Token")
-			
-			csrfToken := formToken
-			if csrfToken == "" {
-				csrfToken = headerToken
-			}
-
-			// Validate CSRF token (stored in cookie)
-			cookie, err := r.Cookie("csrf_token")
-			if err != nil || cookie.Value != csrfToken {
-				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
-				return
+			if !csrfExempt(r) {
+				// Check for CSRF token in header or form
+				submitted := r.Header.Get("X-CSRF-Token")
+				if submitted == "" {
+					if err := r.ParseMultipartForm(32 << 20); errors.As(err, new(*http.MaxBytesError)) {
+						http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+						return
+					}
+					submitted = r.FormValue("csrf_token")
+				}
+				if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+					if strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+						writeAPIError(w, http.StatusForbidden, "invalid_csrf_token", "Cookie-authenticated requests need the X-CSRF-Token header")
+						return
+					}
+					http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+					return
+				}
 			}
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), csrfContextKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// rateLimitMiddleware implements basic rate limiting
-// For production, consider using a more robust solution
+func csrfExempt(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" || r.Header.Get(domain.APIKeyHeader) != "" {
+		return true
+	}
+	for _, prefix := range csrfExemptPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newCSRFToken returns a random value and its signature
+func (s *Server) newCSRFToken() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	value := hex.EncodeToString(raw)
+	return value + "." + s.csrfSignature(value)
+}
+
+// validCSRFToken checks the signature so only tokens issued by this server
+// (not ones planted by a sibling subdomain) are accepted
+func (s *Server) validCSRFToken(token string) bool {
+	value, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.csrfSignature(value)))
+}
+
+func (s *Server) csrfSignature(value string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.JWT.Secret))
+	mac.Write([]byte("csrf:" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// csrfToken returns the token for templates and scripts
+func csrfToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey).(string)
+	return token
+}
+
+// rateLimitMiddleware limits each client IP to requestsPerMinute requests
+// (all at once at most) on the routes it wraps. Each call gets its own
+// bounded in-memory limiter.
 func (s *Server) rateLimitMiddleware(requestsPerMinute int) func(http.Handler) http.Handler {
-	// Simple in-memory rate limiter using chi's built-in throttle
-	// For production with multiple instances, use Redis-based rate limiting
+	limiter := ratelimit.NewLimiter(float64(requestsPerMinute), requestsPerMinute, maxRateLimitKeys)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Basic implementation - for production use a proper rate limiter
+			if ok, wait := limiter.Allow(clientIP(r)); !ok {
+				setRetryAfter(w, wait)
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIP sets the request's RemoteAddr to the client's IP when the request
+// comes through one of the trusted proxies. X-Forwarded-For is read from the
+// right, skipping the proxies' own addresses, so a client can't pick its IP
+// by sending the header itself; X-Real-IP is used when there is none. Other
+// requests keep the address they connected from.
+func realIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddr(clientIP(r))
+			if err != nil || !isTrusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := ""
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				client = addr.Unmap().String()
+				if !isTrusted(addr) {
+					break
+				}
+			}
+			if client == "" {
+				if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+					client = addr.Unmap().String()
+				}
+			}
+			if client != "" {
+				r.RemoteAddr = client
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// clientIP returns the request's IP without the port. realIP has already
+// applied X-Forwarded-For / X-Real-IP from trusted proxies.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
 // loggingMiddleware logs request details (extended version)
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
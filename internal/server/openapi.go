@@ -0,0 +1,294 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"bicicletapp/internal/domain"
+)
+
+// apiOperation describes one API endpoint: how to route it and how to
+// document it
+type apiOperation struct {
+	Method  string
+	Path    string // chi pattern relative to the API prefix; {name} segments are path parameters
+	ID      string // OpenAPI operationId
+	Tag     string
+	Summary string
+
+	Public      bool     // no access token needed
+	Permissions []string // at least one is required
+	Scope       string   // required of API keys, on top of their creator's permissions
+	RateLimit   int      // requests per minute per IP, zero for none
+
+	Params   []apiParam  // query parameters (pagination ones are implied by List)
+	Request  interface{} // sample of the JSON body, nil for none
+	Response interface{} // sample of the JSON response (of each item when List), nil for none
+	List     bool        // paginated with limit and cursor
+	Status   int         // success status, 200 when zero
+	IfMatch  bool        // honours If-Match against the resource's ETag
+
+	Handler http.HandlerFunc
+}
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	Name        string
+	Description string
+	Integer     bool
+}
+
+// apiGetOpenAPI serves the OpenAPI document of version 1 of the API
+func (s *Server) apiGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, s.openAPIDocument())
+}
+
+// openAPIDocument builds the OpenAPI 3 description of version 1 of the API
+// from its operation table, with the schemas derived from the Go types'
+// json tags
+func (s *Server) openAPIDocument() map[string]interface{} {
+	schemas := &schemaRegistry{schemas: map[string]interface{}{}}
+	errorRef := schemas.ref(reflect.TypeOf(apiErrorBody{}))
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorRef}},
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range s.apiV1Operations() {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+
+		var params []interface{}
+		for _, name := range pathParams(op.Path) {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "integer", "format": "int64"},
+			})
+		}
+		query := op.Params
+		if op.List {
+			query = append(query[:len(query):len(query)],
+				apiParam{Name: "limit", Integer: true, Description: "Page size, 20 by default and 100 at most"},
+				apiParam{Name: "cursor", Description: "nextCursor of the previous page"})
+		}
+		for _, p := range query {
+			schema := map[string]interface{}{"type": "string"}
+			if p.Integer {
+				schema = map[string]interface{}{"type": "integer"}
+			}
+			params = append(params, map[string]interface{}{
+				"name": p.Name, "in": "query", "description": p.Description, "schema": schema,
+			})
+		}
+		if op.IfMatch {
+			params = append(params, map[string]interface{}{
+				"name": "If-Match", "in": "header", "schema": map[string]interface{}{"type": "string"},
+				"description": "ETag of the resource as last read; the change fails with 412 if it changed since",
+			})
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if op.Response != nil {
+			schema := schemas.ref(reflect.TypeOf(op.Response))
+			if op.List {
+				schema = map[string]interface{}{
+					"type":     "object",
+					"required": []string{"data"},
+					"properties": map[string]interface{}{
+						"data":       map[string]interface{}{"type": "array", "items": schema},
+						"nextCursor": map[string]interface{}{"type": "string", "description": "Absent on the last page"},
+					},
+				}
+			}
+			success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+		}
+		responses := map[string]interface{}{
+			strconv.Itoa(status): success,
+			"default":            errorResponse("Error"),
+		}
+		if op.Method == http.MethodGet {
+			success["headers"] = map[string]interface{}{
+				"ETag": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+			responses["304"] = map[string]interface{}{"description": "Not modified (If-None-Match matched the ETag)"}
+		}
+		if !op.Public {
+			responses["401"] = errorResponse("Missing, invalid or expired access token or API key")
+		}
+
+		operation := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+			"responses":   responses,
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{"application/json": map[string]interface{}{
+					"schema": schemas.ref(reflect.TypeOf(op.Request)),
+				}},
+			}
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+		var requires []string
+		if len(op.Permissions) > 0 {
+			requires = append(requires, "the permission "+strings.Join(op.Permissions, " or "))
+		}
+		if op.Scope != "" {
+			requires = append(requires, "the scope "+op.Scope+" for API keys")
+			operation["x-api-key-scope"] = op.Scope
+		}
+		if len(requires) > 0 {
+			operation["description"] = "Requires " + strings.Join(requires, " and ") + "."
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   s.config.Business.Name + " API",
+			"version": "1.0.0",
+			"description": "Errors always come as {\"error\": {\"code\", \"message\"}}. Lists return " +
+				"{\"data\", \"nextCursor\"}; pass nextCursor back as ?cursor= for the next page. " +
+				"GET responses carry an ETag for If-None-Match, and changes to a resource accept If-Match. " +
+				"Integrations authenticate with an API key in the " + domain.APIKeyHeader + " header instead of a token.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": s.config.PublicURL() + apiV1Prefix}},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": domain.APIKeyHeader,
+					"description": "Issued by an admin; limited to its scopes"},
+			},
+		},
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)[^}]*\}`)
+
+// pathParams returns the names of the {parameters} of a route pattern
+func pathParams(path string) []string {
+	var names []string
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// schemaRegistry collects the named schemas of the document
+type schemaRegistry struct {
+	schemas map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref returns the schema of t: a reference for named structs, whose schema
+// goes to the registry, and an inline schema for everything else
+func (g *schemaRegistry) ref(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = map[string]interface{}{} // placeholder for recursive types
+			g.schemas[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case t.Kind() == reflect.Struct:
+		return g.object(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.ref(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.ref(t.Elem())}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// object returns the schema of a struct from its exported fields' json tags.
+// Fields without omitempty are required.
+func (g *schemaRegistry) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.ref(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaName names the schema of a type: its Go name without the api prefix
+// of request and response types
+func schemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"bicicletapp/internal/domain"
+)
+
+// roleCache keeps every role in memory; roles change rarely and are checked
+// on every staff request
+type roleCache struct {
+	mu    sync.RWMutex
+	roles map[string]*domain.Role
+}
+
+// role returns a role by name. Unknown roles come back as an empty role that
+// grants nothing.
+func (s *Server) role(ctx context.Context, name string) *domain.Role {
+	if role, ok := s.lookupRole(ctx, name); ok {
+		return role
+	}
+	return &domain.Role{Name: name}
+}
+
+// lookupRole returns a role by name, loading the cache on first use
+func (s *Server) lookupRole(ctx context.Context, name string) (*domain.Role, bool) {
+	s.roleCache.mu.RLock()
+	roles := s.roleCache.roles
+	s.roleCache.mu.RUnlock()
+
+	if roles == nil {
+		list, err := s.repos.Roles.List(ctx)
+		if err != nil {
+			log.Printf("⚠️ Failed to load roles: %v", err)
+			return nil, false
+		}
+		roles = make(map[string]*domain.Role, len(list))
+		for i := range list {
+			roles[list[i].Name] = &list[i]
+		}
+		s.roleCache.mu.Lock()
+		s.roleCache.roles = roles
+		s.roleCache.mu.Unlock()
+	}
+
+	role, ok := roles[name]
+	return role, ok
+}
+
+// invalidateRoles drops the cache after a role is created, edited or deleted
+func (s *Server) invalidateRoles() {
+	s.roleCache.mu.Lock()
+	s.roleCache.roles = nil
+	s.roleCache.mu.Unlock()
+}
+
+// currentRole returns the role of the signed-in user, or nil
+func (s *Server) currentRole(r *http.Request) *domain.Role {
+	claims := getUserClaims(r)
+	if claims == nil {
+		return nil
+	}
+	return s.role(r.Context(), claims.Role)
+}
+
+// can reports whether the signed-in user holds a permission
+func (s *Server) can(r *http.Request, permission string) bool {
+	return s.currentRole(r).Has(permission)
+}
+
+// canOn reports whether the signed-in user holds a permission on a resource
+// owned by ownerID, applying the ownership rules of domain.OwnershipRules
+func (s *Server) canOn(r *http.Request, permission string, ownerID int64) bool {
+	claims := getUserClaims(r)
+	if claims == nil {
+		return false
+	}
+	return s.role(r.Context(), claims.Role).CanOn(permission, ownerID, claims.UserID)
+}
+
+// canGrantRole reports whether the signed-in user may give a role to someone
+// (or manage someone who holds it): the role must exist and grant nothing the
+// user lacks, so user management can't be used to escalate privileges.
+// Ownership-limited permissions only reach the holder's own resources and
+// don't count.
+func (s *Server) canGrantRole(r *http.Request, name string) bool {
+	target, ok := s.lookupRole(r.Context(), name)
+	current := s.currentRole(r)
+	if !ok || current == nil {
+		return false
+	}
+	if current.Name == domain.RoleAdmin {
+		return true
+	}
+	if target.Name == domain.RoleAdmin {
+		return false
+	}
+	for _, permission := range target.Permissions {
+		if _, limited := domain.OwnershipRules[permission]; !limited && !current.Has(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// requirePermission lets the request through when the user holds at least
+// one of the permissions
+func (s *Server) requirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if getUserClaims(r) == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !s.currentRole(r).HasAny(permissions...) {
+				if isAPIRequest(r) {
+					writeAPIError(w, http.StatusForbidden, "forbidden", "Missing permission: "+strings.Join(permissions, " or "))
+					return
+				}
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// staffUsers lists the users whose role can work tickets, for assignment.
+// A non-zero branchID keeps only staff working at that branch.
+func (s *Server) staffUsers(ctx context.Context, branchID int64) []domain.User {
+	roles, err := s.repos.Roles.List(ctx)
+	if err != nil {
+		return nil
+	}
+	var users []domain.User
+	for i := range roles {
+		if roles[i].Name == domain.RoleAdmin || !roles[i].Has(domain.PermTicketsEdit) {
+			continue
+		}
+		list, _ := s.repos.Users.List(ctx, roles[i].Name, 100, 0)
+		for j := range list {
+			if branchID == 0 || worksAt(&list[j], branchID) {
+				users = append(users, list[j])
+			}
+		}
+	}
+	return users
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"bicicletapp/internal/config"
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/domain/notifications"
+	"bicicletapp/internal/ratelimit"
+	"bicicletapp/internal/repository"
+	"bicicletapp/internal/templates"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TenantOpener opens the database at path, creating and migrating it if
+// needed, and returns its repositories and a way to close it
+type TenantOpener func(path string) (*repository.Repositories, io.Closer, error)
+
+// Platform hosts several independent workshops (tenants) on one deployment.
+// Every tenant runs its own Server over its own SQLite file, with its own
+// branding, feature toggles and JWT audience, so no data or session crosses
+// from one to another. Requests are routed by hostname; the super-admin
+// console that creates and suspends tenants answers on the console host.
+type Platform struct {
+	config    *config.Config
+	tenants   repository.TenantRepository
+	admins    repository.SuperAdminRepository
+	open      TenantOpener
+	templates *templates.Manager
+	notifier  notifications.Notifier
+	console   *Server
+	http      *http.Server
+
+	mu      sync.RWMutex
+	byHost  map[string]*domain.Tenant // registry snapshot, refreshed on every change
+	bySlug  map[string]*domain.Tenant
+	servers map[int64]*tenantServer // opened lazily on the first request
+}
+
+// tenantServer is a running tenant: its database stays open for the life of
+// the process while the Server is rebuilt when the tenant's settings change.
+// The webhook dispatcher and the login limits outlive those rebuilds, so an
+// edit doesn't lift a brute-force lockout.
+type tenantServer struct {
+	repos        *repository.Repositories
+	closer       io.Closer
+	tenant       domain.Tenant // the settings server was built with
+	server       *Server
+	webhooks     *webhookDispatcher
+	loginLimiter *ratelimit.Limiter
+	loginLockout *ratelimit.Lockout
+}
+
+// newServer builds the tenant's Server around the state that outlives it
+func (p *Platform) newServer(tenant *domain.Tenant, ts *tenantServer) *Server {
+	srv := New(p.tenantConfig(tenant), ts.repos, p.templates, p.notifier)
+	srv.webhooks = ts.webhooks
+	srv.loginLimiter = ts.loginLimiter
+	srv.loginLockout = ts.loginLockout
+	ts.tenant = *tenant
+	return srv
+}
+
+// NewPlatform creates the multi-tenant host. cfg holds the platform-wide
+// settings (listen address, JWT secret, defaults); each tenant's Server gets
+// a copy with its own branding, features, base URL and audience.
+func NewPlatform(cfg *config.Config, tenants repository.TenantRepository, admins repository.SuperAdminRepository,
+	open TenantOpener, tmpl *templates.Manager, notifier notifications.Notifier) (*Platform, error) {
+	p := &Platform{
+		config:    cfg,
+		tenants:   tenants,
+		admins:    admins,
+		open:      open,
+		templates: tmpl,
+		notifier:  notifier,
+		servers:   make(map[int64]*tenantServer),
+		console: &Server{
+			config:          cfg,
+			templates:       tmpl,
+			notifier:        notifier,
+			router:          chi.NewRouter(),
+			loginLimiter:    newLoginLimiter(),
+			loginLockout:    newLoginLockout(),
+			platformConsole: true,
+		},
+	}
+	if err := p.reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	p.console.setupMiddleware()
+	p.setupConsoleRoutes()
+
+	p.http = &http.Server{
+		Addr:         cfg.Address(),
+		Handler:      p,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	p.http.RegisterOnShutdown(p.closeStreams)
+	return p, nil
+}
+
+// Run starts serving every tenant and the console, and closes the tenant
+// databases once the server has shut down
+func (p *Platform) Run() error {
+	defer p.close()
+	return serve(p.http, p.config)
+}
+
+// ServeHTTP routes a request to the console or to the tenant of its host
+func (p *Platform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := requestHost(r)
+	if host == p.config.Tenancy.ConsoleHost {
+		p.console.router.ServeHTTP(w, r)
+		return
+	}
+
+	tenant := p.tenantForHost(host)
+	if tenant == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !tenant.Active() {
+		p.renderSuspended(w, tenant)
+		return
+	}
+
+	srv, err := p.server(tenant)
+	if err != nil {
+		log.Printf("⚠️ Failed to open tenant %s: %v", tenant.Slug, err)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	srv.router.ServeHTTP(w, r)
+}
+
+// requestHost returns the lowercased host of a request without the port
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// tenantForHost finds the tenant served on host: one of its hostnames, or
+// <slug>.<root domain>
+func (p *Platform) tenantForHost(host string) *domain.Tenant {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if tenant := p.byHost[host]; tenant != nil {
+		return tenant
+	}
+	if root := p.config.Tenancy.RootDomain; root != "" {
+		if slug, ok := strings.CutSuffix(host, "."+root); ok && !strings.Contains(slug, ".") {
+			return p.bySlug[slug]
+		}
+	}
+	return nil
+}
+
+// reload refreshes the registry snapshot and rebuilds the Servers of running
+// tenants so changes to branding or features apply right away
+func (p *Platform) reload(ctx context.Context) error {
+	tenants, err := p.tenants.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load tenants: %w", err)
+	}
+
+	byHost := make(map[string]*domain.Tenant)
+	bySlug := make(map[string]*domain.Tenant)
+	for i := range tenants {
+		tenant := &tenants[i]
+		bySlug[tenant.Slug] = tenant
+		for _, host := range tenant.Hosts {
+			byHost[host] = tenant
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byHost = byHost
+	p.bySlug = bySlug
+	// Only tenants whose settings changed are rebuilt; the others keep their
+	// Server and its per-IP limits
+	for _, tenant := range bySlug {
+		if ts := p.servers[tenant.ID]; ts != nil && !reflect.DeepEqual(ts.tenant, *tenant) {
+			ts.server = p.newServer(tenant, ts)
+		}
+	}
+	return nil
+}
+
+// server returns the Server of a tenant, opening its database on first use
+func (p *Platform) server(tenant *domain.Tenant) (*Server, error) {
+	// reload swaps ts.server under the write lock, so it is read under the
+	// read lock
+	p.mu.RLock()
+	var srv *Server
+	if ts := p.servers[tenant.ID]; ts != nil {
+		srv = ts.server
+	}
+	p.mu.RUnlock()
+	if srv != nil {
+		return srv, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ts := p.servers[tenant.ID]; ts != nil {
+		return ts.server, nil
+	}
+
+	repos, closer, err := p.open(p.config.TenantDatabasePath(tenant.Slug))
+	if err != nil {
+		return nil, err
+	}
+	ts := &tenantServer{
+		repos:        repos,
+		closer:       closer,
+		webhooks:     newWebhookDispatcher(repos.Webhooks),
+		loginLimiter: newLoginLimiter(),
+		loginLockout: newLoginLockout(),
+	}
+	ts.server = p.newServer(tenant, ts)
+	go ts.webhooks.run()
+	p.servers[tenant.ID] = ts
+	log.Printf("🏪 Tenant %s opened", tenant.Slug)
+	return ts.server, nil
+}
+
+// tenantConfig derives a tenant's configuration from the platform's
+func (p *Platform) tenantConfig(tenant *domain.Tenant) *config.Config {
+	cfg := *p.config
+	cfg.Database.Path = p.config.TenantDatabasePath(tenant.Slug)
+	cfg.Server.BaseURL = p.tenantURL(tenant)
+	cfg.JWT.Audience = tenant.Slug
+	cfg.Storage.Prefix = path.Join(p.config.Storage.Prefix, "tenants", tenant.Slug) + "/"
+	cfg.Business = config.Business{
+		Name:           tenant.Name,
+		Tagline:        tenant.Tagline,
+		Logo:           tenant.Logo,
+		PrimaryColor:   tenant.PrimaryColor,
+		SecondaryColor: tenant.SecondaryColor,
+		AccentColor:    tenant.AccentColor,
+		ContactEmail:   tenant.ContactEmail,
+		ContactPhone:   tenant.ContactPhone,
+	}
+	cfg.Features = config.Features{
+		Payments:           tenant.Payments,
+		SMS:                tenant.SMS,
+		Surveys:            tenant.Surveys,
+		EmailNotifications: tenant.EmailNotifications,
+	}
+	return &cfg
+}
+
+// tenantURL returns the public address of a tenant for links sent by email:
+// its first hostname (or <slug>.<root domain>) with the scheme and port of
+// the platform's base URL
+func (p *Platform) tenantURL(tenant *domain.Tenant) string {
+	host := tenant.Slug + "." + p.config.Tenancy.RootDomain
+	if len(tenant.Hosts) > 0 {
+		host = tenant.Hosts[0]
+	}
+
+	base, err := url.Parse(p.config.PublicURL())
+	if err != nil || base.Host == "" {
+		return "https://" + host
+	}
+	if port := base.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return base.Scheme + "://" + host
+}
+
+// renderSuspended tells visitors of a suspended tenant that it's offline
+func (p *Platform) renderSuspended(w http.ResponseWriter, tenant *domain.Tenant) {
+	data := &PageData{
+		Title:  "Servicio suspendido",
+		Config: p.tenantConfig(tenant),
+		Year:   time.Now().Year(),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := p.templates.Render(w, "pages/public/suspended.html", data); err != nil {
+		log.Printf("⚠️ Failed to render suspended page: %v", err)
+	}
+}
+
+// closeStreams ends the live streams of every opened tenant
+func (p *Platform) closeStreams() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, ts := range p.servers {
+		ts.server.ticketEvents.close()
+	}
+}
+
+// close closes the database of every opened tenant
+func (p *Platform) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ts := range p.servers {
+		ts.closer.Close()
+	}
+}
@@ -22,19 +22,35 @@ func (s *Server) setupRoutes() {
 	// Health check endpoint
 	r.Get("/health", s.handleHealth)
 
-	// Public routes
+	// Public routes. Endpoints open to guessing or abuse are rate limited per IP
+	// (requests per minute).
 	r.Group(func(r chi.Router) {
 		r.Get("/", s.handleHome)
 		r.Get("/login", s.handleLoginPage)
-		r.Post("/login", s.handleLogin)
+		r.With(s.rateLimitMiddleware(20)).Post("/login", s.handleLogin)
+		r.Get("/login/2fa", s.handleTwoFactorLoginPage)
+		r.With(s.rateLimitMiddleware(20)).Post("/login/2fa", s.handleTwoFactorLogin)
 		r.Get("/register", s.handleRegisterPage)
-		r.Post("/register", s.handleRegister)
-		r.Get("/logout", s.handleLogout)
+		r.With(s.rateLimitMiddleware(5)).Post("/register", s.handleRegister)
+		r.Post("/logout", s.handleLogout)
+
+		// Account recovery, verification and invitations
+		r.Get("/forgot-password", s.handleForgotPasswordPage)
+		r.With(s.rateLimitMiddleware(5)).Post("/forgot-password", s.handleForgotPassword)
+		r.Get("/reset-password/{token}", s.handleSetPasswordPage)
+		r.Post("/reset-password/{token}", s.handleSetPassword)
+		r.Get("/invite/{token}", s.handleSetPasswordPage)
+		r.Post("/invite/{token}", s.handleSetPassword)
+		r.Get("/verify-email/{token}", s.handleVerifyEmail)
+		r.With(s.rateLimitMiddleware(5)).Post("/verify-email/resend", s.handleResendVerification)
 
 		// Public tracking
 		r.Get("/tracking", s.handleTrackingPage)
-		r.Get("/tracking/{code}", s.handleTrackingStatus)
-		r.Post("/tracking/{code}/survey", s.handlePublicSubmitSurvey)
+		r.With(s.rateLimitMiddleware(30)).Get("/tracking/{code}", s.handleTrackingStatus)
+		r.With(s.rateLimitMiddleware(30)).Get("/tracking/{code}/events", s.handleTrackingEvents)
+		r.With(s.rateLimitMiddleware(120)).Get("/tracking/{code}/attachments/{attachmentId}", s.handleTrackingAttachment)
+		r.With(s.rateLimitMiddleware(5)).Post("/tracking/{code}/survey", s.handlePublicSubmitSurvey)
+		r.With(s.rateLimitMiddleware(5)).Post("/tracking/{code}/comments", s.handlePublicReplyComment)
 		r.Post("/tracking/quote/{id}/approve", s.handlePublicApproveQuote)
 		r.Get("/ad/{id}/click", s.handleAdClick)
 
@@ -64,122 +80,328 @@ func (s *Server) setupRoutes() {
 		// Profile
 		r.Get("/profile", s.handleProfile)
 		r.Post("/profile", s.handleUpdateProfile)
+		r.Post("/profile/sessions/{id}/revoke", s.handleRevokeMySession)
+		r.Post("/profile/sessions/revoke-all", s.handleRevokeAllMySessions)
 
 		// Surveys
 		r.Get("/survey/{ticketId}", s.handleSurveyPage)
-		r.Post("/survey/{ticketId}", s.handleSubmitSurvey)
+		r.With(s.rateLimitMiddleware(5)).Post("/survey/{ticketId}", s.handleSubmitSurvey)
+
+		// Bicycles and ownership transfers
+		r.Get("/bicycles", s.handleMyBicycles)
+		r.Get("/bicycles/{id}", s.handleMyBicycleDetail)
+		r.Post("/bicycles/{id}/transfer", s.handleStartBicycleTransfer)
+		r.Post("/transfers/{id}/cancel", s.handleCancelBicycleTransfer)
+		r.Post("/transfers/{id}/{action:accept|reject}", s.handleRespondBicycleTransfer)
 	})
 
-	// Protected routes - Technician
+	// Protected routes - Workshop. Each action needs its own permission on
+	// top of workshop access; ownership (assigned tickets) is checked in the
+	// handlers through domain.OwnershipRules.
 	r.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware)
-		r.Use(s.roleMiddleware(domain.RoleTechnician, domain.RoleAdmin))
+		r.Use(s.requirePermission(domain.PermWorkshopView))
+		r.Use(s.twoFactorPolicyMiddleware)
 
 		// Workshop routes
 		r.Get("/workshop", s.handleWorkshopDashboard)
+		r.Get("/workshop/events", s.handleWorkshopEvents)
+		r.Get("/workshop/board", s.handleWorkshopBoard)
+		r.Get("/workshop/capacity", s.handleCapacity)
 
-		// Direct Ticket Creation (Walk-in)
-		r.Get("/tickets/new", s.handleNewTicketPage)
-		r.Post("/tickets/create_direct", s.handleCreateTicketDirect)
-
-		// Ticket management
+		// Ticket views and labels
 		r.Get("/tickets", s.handleTicketsList)
 		r.Get("/tickets/{id}", s.handleTicketDetail)
-		r.Post("/tickets/{id}/status", s.handleUpdateTicketStatus)
-		r.Post("/tickets/{id}/notes", s.handleAddTicketNotes)
-
-		// Create ticket from booking
-		r.Post("/bookings/{id}/ticket", s.handleCreateTicket)
+		r.Get("/tickets/{id}/label", s.handleTicketLabel)
+		r.Get("/tickets/{id}/quote", s.handleTicketQuote)
+		r.Get("/tickets/{id}/condition", s.handleConditionReport)
+		r.Get("/tickets/{id}/attachments/{attachmentId}", s.handleAttachmentFile)
+
+		// Direct ticket creation (walk-in) and tickets from bookings
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermTicketsCreate))
+			r.Get("/tickets/new", s.handleNewTicketPage)
+			r.Post("/tickets/create_direct", s.handleCreateTicketDirect)
+			r.Post("/bookings/{id}/ticket", s.handleCreateTicket)
+		})
+
+		// Working a ticket: status, comments, parts, time, attachments and checklist
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermTicketsEdit))
+			r.Post("/tickets/{id}/status", s.handleUpdateTicketStatus)
+			r.Post("/workshop/board/tickets/{id}/move", s.handleBoardMove)
+			r.Post("/tickets/{id}/comments", s.handleAddTicketComment)
+			r.Post("/tickets/{id}/parts", s.handleCreateTicketPart)
+			r.Post("/tickets/{id}/parts/{partId}/toggle", s.handleToggleTicketPart)
+			r.Post("/tickets/{id}/parts/{partId}/delete", s.handleDeleteTicketPart)
+			r.Post("/tickets/{id}/timer/start", s.handleStartTimer)
+			r.Post("/tickets/{id}/timer/pause", s.handlePauseTimer)
+			r.Post("/tickets/{id}/timer/stop", s.handleStopTimer)
+			r.Post("/tickets/{id}/time", s.handleCreateTimeEntry)
+			r.Post("/tickets/{id}/time/{entryId}/delete", s.handleDeleteTimeEntry)
+			r.Post("/tickets/{id}/attachments", s.handleUploadAttachments)
+			r.Post("/tickets/{id}/attachments/{attachmentId}/visibility", s.handleToggleAttachment)
+			r.Post("/tickets/{id}/attachments/{attachmentId}/delete", s.handleDeleteAttachment)
+			r.Post("/tickets/{id}/checklist/{itemId}", s.handleCompleteChecklistItem)
+			r.Post("/tickets/{id}/checklist/{itemId}/reopen", s.handleReopenChecklistItem)
+		})
 
 		// Create quote
-		r.Get("/quotes/new/{bookingId}", s.handleNewQuotePage)
-		r.Post("/quotes/new/{bookingId}", s.handleCreateQuote)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermQuotesCreate))
+			r.Get("/quotes/new/{bookingId}", s.handleNewQuotePage)
+			r.Post("/quotes/new/{bookingId}", s.handleCreateQuote)
+			r.Post("/tickets/{id}/labor/bill", s.handleBillLabor)
+		})
 
 		// Bicycle management
-		r.Post("/bicycles/{id}/update", s.handleUpdateBicycle)
-		r.Post("/bookings/{id}/bicycle", s.handleCreateBicycleFromBooking)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermBicyclesEdit))
+			r.Post("/bicycles/{id}/update", s.handleUpdateBicycle)
+			r.Post("/bookings/{id}/bicycle", s.handleCreateBicycleFromBooking)
+		})
+	})
 
-		// Ticket Parts
-		r.Post("/tickets/{id}/parts", s.handleCreateTicketPart)
-		r.Post("/tickets/{id}/parts/{partId}/toggle", s.handleToggleTicketPart)
-		r.Post("/tickets/{id}/parts/{partId}/delete", s.handleDeleteTicketPart)
+	// Two-factor enrollment for staff (outside the 2FA policy so it stays reachable)
+	r.Group(func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.requirePermission(domain.StaffPermissions...))
 
-		// Label
-		r.Get("/tickets/{id}/label", s.handleTicketLabel)
-		r.Get("/tickets/{id}/quote", s.handleTicketQuote)
+		r.Get("/account/2fa", s.handleTwoFactorSetup)
+		r.Post("/account/2fa/enable", s.handleEnableTwoFactor)
+		r.Post("/account/2fa/disable", s.handleDisableTwoFactor)
+		r.Post("/account/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+	})
+
+	// Branch switcher for staff
+	r.Group(func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.requirePermission(domain.StaffPermissions...))
+		r.Use(s.twoFactorPolicyMiddleware)
+
+		r.Post("/branch", s.handleSwitchBranch)
 	})
 
-	// Protected routes - Admin only
+	// Protected routes - Admin panel. The dashboard opens with any admin
+	// permission; each section needs its own.
 	r.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware)
-		r.Use(s.roleMiddleware(domain.RoleAdmin))
+		r.Use(s.requirePermission(domain.AdminPermissions...))
+		r.Use(s.twoFactorPolicyMiddleware)
 
 		// Admin dashboard
 		r.Get("/admin", s.handleAdminDashboard)
 
-		// User management
-		r.Get("/admin/users", s.handleUsersList)
-		r.Get("/admin/users/new", s.handleNewUserPage)
-		r.Post("/admin/users", s.handleCreateUser)
-		r.Get("/admin/users/{id}", s.handleEditUserPage)
-		r.Post("/admin/users/{id}", s.handleUpdateUser)
-		r.Post("/admin/users/{id}/delete", s.handleDeleteUser)
+		// User management and sessions
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermUsersManage))
+			r.Get("/admin/users", s.handleUsersList)
+			r.Get("/admin/users/new", s.handleNewUserPage)
+			r.Post("/admin/users", s.handleCreateUser)
+			r.Get("/admin/users/{id}", s.handleEditUserPage)
+			r.Post("/admin/users/{id}", s.handleUpdateUser)
+			r.Post("/admin/users/{id}/delete", s.handleDeleteUser)
+			r.Post("/admin/users/{id}/sessions/revoke", s.handleAdminRevokeUserSessions)
+			r.Post("/admin/users/{id}/2fa/reset", s.handleAdminResetTwoFactor)
+
+			r.Get("/admin/sessions", s.handleSessionsList)
+			r.Post("/admin/sessions/{id}/revoke", s.handleAdminRevokeSession)
+		})
+
+		// API keys for integrations
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermAPIKeysManage))
+			r.Get("/admin/api-keys", s.handleAPIKeysList)
+			r.Post("/admin/api-keys", s.handleCreateAPIKey)
+			r.Post("/admin/api-keys/{id}/revoke", s.handleRevokeAPIKey)
+		})
+
+		// Outbound webhooks and their delivery log
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermWebhooksManage))
+			r.Get("/admin/webhooks", s.handleWebhooksList)
+			r.Post("/admin/webhooks", s.handleCreateWebhook)
+			r.Get("/admin/webhooks/{id}", s.handleWebhookDetail)
+			r.Post("/admin/webhooks/{id}", s.handleUpdateWebhook)
+			r.Post("/admin/webhooks/{id}/secret", s.handleRotateWebhookSecret)
+			r.Post("/admin/webhooks/{id}/delete", s.handleDeleteWebhook)
+			r.Post("/admin/webhooks/{id}/deliveries/{deliveryId}/replay", s.handleReplayWebhookDelivery)
+		})
+
+		// Roles and permissions
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermRolesManage))
+			r.Get("/admin/roles", s.handleRolesList)
+			r.Post("/admin/roles", s.handleCreateRole)
+			r.Get("/admin/roles/{name}", s.handleEditRolePage)
+			r.Post("/admin/roles/{name}", s.handleUpdateRole)
+			r.Post("/admin/roles/{name}/delete", s.handleDeleteRole)
+		})
+
+		// Branches and their hours
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermBranchesManage))
+			r.Get("/admin/branches", s.handleBranchesList)
+			r.Post("/admin/branches", s.handleCreateBranch)
+			r.Post("/admin/branches/{id}", s.handleUpdateBranch)
+		})
+
+		// Audit log
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermAuditView))
+			r.Get("/admin/audit", s.handleAuditLog)
+			r.Get("/admin/audit.csv", s.handleExportAuditLog)
+		})
 
 		// Catalog management
-		r.Get("/admin/brands", s.handleBrandsList)
-		r.Get("/admin/brands/new", s.handleNewBrandPage)
-		r.Post("/admin/brands", s.handleCreateBrand)
-		r.Get("/admin/brands/{id}", s.handleEditBrandPage)
-		r.Post("/admin/brands/{id}", s.handleUpdateBrand)
-		r.Post("/admin/brands/{id}/delete", s.handleDeleteBrand)
-
-		r.Get("/admin/models", s.handleModelsList)
-		r.Get("/admin/models/new", s.handleNewModelPage)
-		r.Post("/admin/models", s.handleCreateModel)
-		r.Get("/admin/models/{id}", s.handleEditModelPage)
-		r.Post("/admin/models/{id}", s.handleUpdateModel)
-		r.Post("/admin/models/{id}/delete", s.handleDeleteModel)
-
-		r.Get("/admin/services", s.handleServicesList)
-		r.Get("/admin/services/new", s.handleNewServicePage)
-		r.Post("/admin/services", s.handleCreateService)
-		r.Get("/admin/services/{id}", s.handleEditServicePage)
-		r.Post("/admin/services/{id}", s.handleUpdateService)
-		r.Post("/admin/services/{id}/delete", s.handleDeleteService)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermCatalogManage))
+			r.Get("/admin/brands", s.handleBrandsList)
+			r.Get("/admin/brands/new", s.handleNewBrandPage)
+			r.Post("/admin/brands", s.handleCreateBrand)
+			r.Get("/admin/brands/{id}", s.handleEditBrandPage)
+			r.Post("/admin/brands/{id}", s.handleUpdateBrand)
+			r.Post("/admin/brands/{id}/delete", s.handleDeleteBrand)
+
+			r.Get("/admin/models", s.handleModelsList)
+			r.Get("/admin/models/new", s.handleNewModelPage)
+			r.Post("/admin/models", s.handleCreateModel)
+			r.Get("/admin/models/{id}", s.handleEditModelPage)
+			r.Post("/admin/models/{id}", s.handleUpdateModel)
+			r.Post("/admin/models/{id}/delete", s.handleDeleteModel)
+
+			r.Get("/admin/services", s.handleServicesList)
+			r.Get("/admin/services/new", s.handleNewServicePage)
+			r.Post("/admin/services", s.handleCreateService)
+			r.Get("/admin/services/{id}", s.handleEditServicePage)
+			r.Post("/admin/services/{id}", s.handleUpdateService)
+			r.Post("/admin/services/{id}/delete", s.handleDeleteService)
+
+			r.Get("/admin/checklists", s.handleChecklistsList)
+			r.Post("/admin/checklists", s.handleCreateChecklist)
+			r.Get("/admin/checklists/{id}", s.handleChecklistPage)
+			r.Post("/admin/checklists/{id}", s.handleRenameChecklist)
+			r.Post("/admin/checklists/{id}/delete", s.handleDeleteChecklist)
+			r.Post("/admin/checklists/{id}/items", s.handleAddChecklistItem)
+			r.Post("/admin/checklists/{id}/items/{itemId}/delete", s.handleDeleteChecklistItem)
+		})
 
 		// Reports
-		r.Get("/admin/reports", s.handleReportsDashboard)
-		r.Get("/admin/reports/bookings", s.handleBookingsReport)
-		r.Get("/admin/reports/revenue", s.handleRevenueReport)
-		r.Get("/admin/reports/surveys", s.handleSurveysReport)
-
-		// Ticket management
-		r.Get("/admin/tickets", s.handleAdminTicketsList)
-		r.Post("/admin/tickets/{id}/technician", s.handleAdminUpdateTicketTechnician)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermReportsView))
+			r.Get("/admin/reports", s.handleReportsDashboard)
+			r.Get("/admin/reports/bookings", s.handleBookingsReport)
+			r.Get("/admin/reports/revenue", s.handleRevenueReport)
+			r.Get("/admin/reports/surveys", s.handleSurveysReport)
+			r.Get("/admin/reports/turnaround", s.handleTurnaroundReport)
+			r.Get("/admin/reports/labor", s.handleLaborReport)
+		})
+
+		// Ticket assignment
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermTicketsAssign))
+			r.Get("/admin/tickets", s.handleAdminTicketsList)
+			r.Post("/admin/tickets/{id}/technician", s.handleAdminUpdateTicketTechnician)
+		})
 
 		// Settings
-		r.Get("/admin/settings", s.handleSettings)
-		r.Post("/admin/settings", s.handleUpdateSettings)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermSettingsManage))
+			r.Get("/admin/settings", s.handleSettings)
+			r.Post("/admin/settings", s.handleUpdateSettings)
+			r.Post("/admin/settings/security", s.handleUpdateSecuritySettings)
+			r.Post("/admin/settings/board", s.handleUpdateBoardSettings)
+			r.Post("/admin/settings/labor", s.handleUpdateLaborSettings)
+			r.Post("/admin/settings/assignment", s.handleUpdateAssignmentSettings)
+		})
 
 		// Ad management (Press Kit)
-		r.Get("/admin/ads", s.handleAdsList)
-		r.Post("/admin/ads", s.handleCreateAd)
-		r.Post("/admin/ads/{id}/update", s.handleUpdateAd)
-		r.Post("/admin/ads/{id}/delete", s.handleDeleteAd)
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermAdsManage))
+			r.Get("/admin/ads", s.handleAdsList)
+			r.Post("/admin/ads", s.handleCreateAd)
+			r.Post("/admin/ads/{id}/update", s.handleUpdateAd)
+			r.Post("/admin/ads/{id}/delete", s.handleDeleteAd)
+		})
+
+		// Parts inventory
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermInventoryManage))
+			r.Get("/admin/inventory", s.handleInventoryList)
+			r.Post("/admin/inventory", s.handleCreateInventoryItem)
+			r.Post("/admin/inventory/locations", s.handleCreateStockLocation)
+			r.Get("/admin/inventory/labels", s.handleInventoryLabels)
+			r.Get("/admin/inventory/{id}/barcode.png", s.handleInventoryBarcode)
+			r.Get("/admin/inventory/{id}", s.handleInventoryItemPage)
+			r.Post("/admin/inventory/{id}", s.handleUpdateInventoryItem)
+			r.Post("/admin/inventory/{id}/stock", s.handleAdjustInventoryStock)
+			r.Post("/admin/inventory/{id}/delete", s.handleDeleteInventoryItem)
+		})
+
+		// Suppliers and purchase orders
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermPurchasingManage))
+			r.Get("/admin/suppliers", s.handleSuppliersList)
+			r.Post("/admin/suppliers", s.handleCreateSupplier)
+			r.Post("/admin/suppliers/{id}", s.handleUpdateSupplier)
+			r.Get("/admin/purchase-orders", s.handlePurchaseOrdersList)
+			r.Post("/admin/purchase-orders", s.handleCreatePurchaseOrder)
+			r.Get("/admin/purchase-orders/{id}", s.handlePurchaseOrderDetail)
+			r.Post("/admin/purchase-orders/{id}/lines", s.handleAddPurchaseOrderLine)
+			r.Post("/admin/purchase-orders/{id}/lines/{lineId}/delete", s.handleDeletePurchaseOrderLine)
+			r.Post("/admin/purchase-orders/{id}/lines/{lineId}/tickets", s.handleLinkPurchaseOrderTicket)
+			r.Post("/admin/purchase-orders/{id}/lines/{lineId}/tickets/{ticketId}/delete", s.handleUnlinkPurchaseOrderTicket)
+			r.Post("/admin/purchase-orders/{id}/send", s.handleSendPurchaseOrder)
+			r.Post("/admin/purchase-orders/{id}/receive", s.handleReceivePurchaseOrder)
+			r.Get("/admin/purchase-orders/{id}/csv", s.handleExportPurchaseOrderCSV)
+			r.Get("/admin/purchase-orders/{id}/pdf", s.handleExportPurchaseOrderPDF)
+		})
+
+		// Stolen bike registry
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermStolenManage))
+			r.Get("/admin/stolen", s.handleStolenBikesList)
+			r.Post("/admin/stolen", s.handleCreateStolenBike)
+			r.Post("/admin/stolen/import", s.handleImportStolenBikes)
+			r.Post("/admin/stolen/{id}/recover", s.handleRecoverStolenBike)
+			r.Post("/admin/stolen/{id}/delete", s.handleDeleteStolenBike)
+			r.Post("/admin/bicycles/{id}/stolen", s.handleReportBicycleStolen)
+		})
 	})
 
-	// API routes (for AJAX calls)
+	// Versioned JSON API, authenticated with bearer tokens
+	s.setupAPIv1Routes(r)
+
+	// API routes (for AJAX calls; API keys reach them with the scope given)
 	r.Route("/api", func(r chi.Router) {
 		r.Use(s.authMiddleware)
 
 		// Models by brand (for cascading dropdowns)
-		r.Get("/brands/{brandId}/models", s.apiGetModelsByBrand)
+		r.With(s.requireScope(domain.ScopeReadCatalog)).Get("/brands/{brandId}/models", s.apiGetModelsByBrand)
 
 		// Available time slots
-		r.Get("/bookings/slots", s.apiGetAvailableSlots)
+		r.With(s.requireScope(domain.ScopeReadBookings)).Get("/bookings/slots", s.apiGetAvailableSlots)
 
 		// Ticket status updates
-		r.Get("/tickets/{id}/status", s.apiGetTicketStatus)
+		r.With(s.requireScope(domain.ScopeReadTickets)).Get("/tickets/{id}/status", s.apiGetTicketStatus)
+
+		// Staff-only lookups
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermWorkshopView))
+			r.Use(s.twoFactorPolicyMiddleware)
+
+			// Serial lookup at intake
+			r.With(s.requireScope(domain.ScopeReadBicycles)).Get("/bicycles/lookup", s.apiLookupBicycleBySerial)
+
+			// Barcode scanning
+			r.With(s.requireScope(domain.ScopeReadTickets)).Get("/tickets/tracking/{code}", s.apiLookupTicketByTracking)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(s.requirePermission(domain.PermWorkshopView, domain.PermInventoryManage))
+			r.Use(s.twoFactorPolicyMiddleware)
+
+			r.With(s.requireScope(domain.ScopeReadInventory)).Get("/inventory/ean/{code}", s.apiLookupInventoryByEAN)
+		})
 	})
 }
 
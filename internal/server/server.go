@@ -12,7 +12,10 @@ import (
 	"time"
 
 	"bicicletapp/internal/config"
+	"bicicletapp/internal/domain/notifications"
+	"bicicletapp/internal/ratelimit"
 	"bicicletapp/internal/repository"
+	"bicicletapp/internal/storage"
 	"bicicletapp/internal/templates"
 
 	"github.com/go-chi/chi/v5"
@@ -24,17 +27,59 @@ type Server struct {
 	config    *config.Config
 	repos     *repository.Repositories
 	templates *templates.Manager
+	notifier  notifications.Notifier
 	router    *chi.Mux
 	http      *http.Server
+
+	// Login brute-force protection, keyed by normalized email
+	loginLimiter *ratelimit.Limiter
+	loginLockout *ratelimit.Lockout
+
+	// Roles and their permissions
+	roleCache roleCache
+
+	// Sends queued webhook deliveries; started by Run or by the Platform
+	webhooks *webhookDispatcher
+
+	// Ticket status changes for the live tracking and workshop streams
+	ticketEvents *ticketHub
+
+	// Where ticket photos and documents are kept
+	storage storage.Storage
+
+	// Set on the Server a Platform uses for its console: it has no
+	// repositories and only provides middleware and page rendering
+	platformConsole bool
+}
+
+// maxRateLimitKeys bounds the memory used by each limiter
+const maxRateLimitKeys = 10000
+
+// newLoginLimiter allows 10 login attempts per account per minute
+func newLoginLimiter() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(10, 10, maxRateLimitKeys)
+}
+
+// newLoginLockout locks an account for 1 minute after 5 consecutive
+// failures, doubling on each new lock up to 1 hour
+func newLoginLockout() *ratelimit.Lockout {
+	return ratelimit.NewLockout(5, time.Minute, time.Hour, maxRateLimitKeys)
 }
 
 // New creates a new server instance
-func New(cfg *config.Config, repos *repository.Repositories, tmpl *templates.Manager) *Server {
+func New(cfg *config.Config, repos *repository.Repositories, tmpl *templates.Manager, notifier notifications.Notifier) *Server {
 	s := &Server{
 		config:    cfg,
 		repos:     repos,
 		templates: tmpl,
+		notifier:  notifier,
 		router:    chi.NewRouter(),
+
+		loginLimiter: newLoginLimiter(),
+		loginLockout: newLoginLockout(),
+		webhooks:     newWebhookDispatcher(repos.Webhooks),
+		ticketEvents: withTicketEvents(repos),
+		storage:      newStorage(cfg),
 	}
 
 	s.setupMiddleware()
@@ -47,20 +92,28 @@ func New(cfg *config.Config, repos *repository.Repositories, tmpl *templates.Man
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	s.http.RegisterOnShutdown(s.ticketEvents.close)
 
 	return s
 }
 
 // Run starts the server and handles graceful shutdown
 func (s *Server) Run() error {
+	go s.webhooks.run()
+	return serve(s.http, s.config)
+}
+
+// serve listens until the process is told to stop, then gives outstanding
+// requests a deadline to complete
+func serve(srv *http.Server, cfg *config.Config) error {
 	// Channel to listen for errors from the server
 	serverErrors := make(chan error, 1)
 
 	// Start the server in a goroutine
 	go func() {
-		log.Printf("🚀 Server starting on %s", s.config.Address())
-		log.Printf("📁 Debug mode: %v", s.config.Debug)
-		serverErrors <- s.http.ListenAndServe()
+		log.Printf("🚀 Server starting on %s", cfg.Address())
+		log.Printf("📁 Debug mode: %v", cfg.Debug)
+		serverErrors <- srv.ListenAndServe()
 	}()
 
 	// Channel to listen for OS signals
@@ -80,9 +133,9 @@ func (s *Server) Run() error {
 		defer cancel()
 
 		// Attempt graceful shutdown
-		if err := s.http.Shutdown(ctx); err != nil {
+		if err := srv.Shutdown(ctx); err != nil {
 			log.Printf("❌ Graceful shutdown failed: %v", err)
-			if err := s.http.Close(); err != nil {
+			if err := srv.Close(); err != nil {
 				return fmt.Errorf("failed to close server: %w", err)
 			}
 		}
@@ -95,8 +148,11 @@ func (s *Server) Run() error {
 
 // setupMiddleware configures global middleware
 func (s *Server) setupMiddleware() {
-	// Real IP detection (important for logging behind proxies)
-	s.router.Use(middleware.RealIP)
+	// Real IP detection behind the configured proxies (important for logging
+	// and per-IP rate limits). The proxies were checked when the
+	// configuration loaded.
+	proxies, _ := s.config.TrustedProxyPrefixes()
+	s.router.Use(realIP(proxies))
 
 	// Request logging
 	s.router.Use(middleware.Logger)
@@ -110,11 +166,33 @@ func (s *Server) setupMiddleware() {
 	// Security headers
 	s.router.Use(s.securityHeaders)
 
+	// Bounded request bodies, before the CSRF check parses any form
+	s.router.Use(s.limitRequestBody)
+
+	// CSRF tokens for every page, checked on state-changing requests
+	s.router.Use(s.csrfMiddleware)
+
 	// Response compression (level 5 is a good balance)
 	s.router.Use(middleware.Compress(5))
 
 	// Timeout for requests
-	s.router.Use(middleware.Timeout(30 * time.Second))
+	s.router.Use(requestTimeout(30 * time.Second))
+}
+
+// requestTimeout is middleware.Timeout for everything but event streams,
+// which stay open until the client leaves
+func requestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		timed := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
 }
 
 // securityHeaders adds security-related headers to all responses
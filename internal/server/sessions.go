@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// refreshCookieName holds the rotating refresh token
+const refreshCookieName = "refresh_token"
+
+// refreshReuseGrace lets requests that raced a rotation (e.g. parallel
+// fetches right after the access token expired) use the previous refresh
+// token. Reusing it after this window is treated as theft.
+const refreshReuseGrace = 30 * time.Second
+
+// accessTokenTTL is the lifetime of access tokens
+func (s *Server) accessTokenTTL() time.Duration {
+	return time.Duration(s.config.JWT.AccessTokenMinutes) * time.Minute
+}
+
+// sessionTTL is how long an unused session stays valid
+func (s *Server) sessionTTL() time.Duration {
+	return time.Duration(s.config.JWT.ExpirationHours) * time.Hour
+}
+
+// startSession creates a session for the user and sets the access and
+// refresh token cookies
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *domain.User) error {
+	token, refresh, err := s.createSession(r, user)
+	if err != nil {
+		return err
+	}
+	s.setAuthCookie(w, token, int(s.accessTokenTTL().Seconds()))
+	s.setRefreshCookie(w, refresh, int(s.sessionTTL().Seconds()))
+	return nil
+}
+
+// createSession records a new session for the user and returns its access
+// and refresh tokens
+func (s *Server) createSession(r *http.Request, user *domain.User) (token, refresh string, err error) {
+	refresh, err = newRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := &domain.Session{
+		UserID:      user.ID,
+		RefreshHash: hashToken(refresh),
+		UserAgent:   userAgent,
+		IP:          clientIP(r),
+		ExpiresAt:   time.Now().Add(s.sessionTTL()),
+	}
+	if err := s.repos.Sessions.Create(r.Context(), session); err != nil {
+		return "", "", err
+	}
+	s.auditAs(r, user.ID, "session.login", domain.AuditEntitySession, session.ID, nil, nil)
+
+	token, _, err = s.generateToken(user, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refresh, nil
+}
+
+// refreshSession rotates the refresh token cookie and issues a new access
+// token. It returns nil when there is no usable refresh token.
+func (s *Server) refreshSession(w http.ResponseWriter, r *http.Request) *Claims {
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	token, refresh, claims := s.renewSession(r.Context(), cookie.Value)
+	if claims == nil {
+		return nil
+	}
+	s.setAuthCookie(w, token, int(s.accessTokenTTL().Seconds()))
+	if refresh != "" {
+		s.setRefreshCookie(w, refresh, int(s.sessionTTL().Seconds()))
+	}
+	return claims
+}
+
+// renewSession issues a new access token for the session of a refresh token
+// and rotates it. refresh is empty when a request that raced a rotation got
+// a new access token but keeps the refresh token it already has. claims is
+// nil when the refresh token is not usable.
+func (s *Server) renewSession(ctx context.Context, refreshToken string) (token, refresh string, claims *Claims) {
+	hash := hashToken(refreshToken)
+	session, err := s.repos.Sessions.GetByRefreshHash(ctx, hash)
+	if err != nil || session == nil || !session.Active() {
+		return "", "", nil
+	}
+
+	user, err := s.repos.Users.GetByID(ctx, session.UserID)
+	if err != nil || user == nil {
+		return "", "", nil
+	}
+
+	if session.RefreshHash != hash {
+		// A rotated token came back. Within the grace window it's a request
+		// that raced the rotation; otherwise someone else holds a copy.
+		if session.RotatedAt == nil || time.Since(*session.RotatedAt) > refreshReuseGrace {
+			log.Printf("⚠️ Rotated refresh token reused for session %d (user %d), revoking", session.ID, session.UserID)
+			s.repos.Sessions.Revoke(ctx, session.ID)
+			return "", "", nil
+		}
+		token, claims, err := s.generateToken(user, session.ID)
+		if err != nil {
+			return "", "", nil
+		}
+		return token, "", claims
+	}
+
+	refresh, err = newRandomToken()
+	if err != nil {
+		return "", "", nil
+	}
+	if err := s.repos.Sessions.Rotate(ctx, session.ID, hash, hashToken(refresh), time.Now().Add(s.sessionTTL())); err != nil {
+		if !errors.Is(err, repository.ErrInvalidToken) {
+			log.Printf("⚠️ Failed to rotate session %d: %v", session.ID, err)
+		}
+		return "", "", nil
+	}
+
+	token, claims, err = s.generateToken(user, session.ID)
+	if err != nil {
+		return "", "", nil
+	}
+	return token, refresh, claims
+}
+
+// endSession revokes the session behind the request's refresh token
+func (s *Server) endSession(r *http.Request) {
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+	s.revokeSession(r, cookie.Value)
+}
+
+// revokeSession revokes the session of a refresh token, if any
+func (s *Server) revokeSession(r *http.Request, refreshToken string) {
+	session, err := s.repos.Sessions.GetByRefreshHash(r.Context(), hashToken(refreshToken))
+	if err == nil && session != nil {
+		s.repos.Sessions.Revoke(r.Context(), session.ID)
+		s.auditAs(r, session.UserID, "session.logout", domain.AuditEntitySession, session.ID, nil, nil)
+	}
+}
+
+// revokeUserSessions signs the user out everywhere except keepSessionID
+// (zero revokes every session). Used after password and role changes.
+func (s *Server) revokeUserSessions(ctx context.Context, userID, keepSessionID int64) {
+	if err := s.repos.Sessions.RevokeAllForUser(ctx, userID, keepSessionID); err != nil {
+		log.Printf("⚠️ Failed to revoke sessions of user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"bicicletapp/internal/domain"
+)
+
+// turnaroundReportDays is the period the turnaround report covers by default
+const turnaroundReportDays = 30
+
+// promiseReady estimates when a new ticket will be ready: the branch's queue
+// shared among its staff, then the ticket's own service, counted in the
+// branch's working hours
+func (s *Server) promiseReady(ctx context.Context, ticket *domain.Ticket) *time.Time {
+	branchID := ticket.BranchID
+	if branchID == 0 {
+		branchID = domain.DefaultBranchID
+	}
+
+	hours := domain.DefaultServiceHours
+	if booking, _ := s.repos.Bookings.GetByID(ctx, ticket.BookingID); booking != nil {
+		if service, _ := s.repos.Services.GetByID(ctx, booking.ServiceID); service != nil && service.EstimatedHours > 0 {
+			hours = service.EstimatedHours
+		}
+	}
+	queue, err := s.repos.Tickets.QueueHours(ctx, branchID)
+	if err != nil {
+		log.Printf("⚠️ Failed to estimate the ready date of a ticket: %v", err)
+		return nil
+	}
+	staff := len(s.staffUsers(ctx, branchID))
+	if staff == 0 {
+		staff = 1
+	}
+	hours += queue / float64(staff)
+
+	promised := time.Now().Add(time.Duration(hours * float64(time.Hour)))
+	if branch, _ := s.repos.Branches.GetByID(ctx, branchID); branch != nil {
+		promised = branch.AddWorkingHours(time.Now(), hours)
+	}
+	return &promised
+}
+
+// slaTicket is a ticket with its turnaround measured
+type slaTicket struct {
+	domain.Ticket
+	SLA *domain.TicketSLA
+}
+
+// measureTickets computes the SLA of each ticket
+func (s *Server) measureTickets(ctx context.Context, tickets []domain.Ticket) ([]slaTicket, error) {
+	ids := make([]int64, len(tickets))
+	for i := range tickets {
+		ids[i] = tickets[i].ID
+	}
+	histories, err := s.repos.Tickets.GetStatusHistories(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	measured := make([]slaTicket, len(tickets))
+	for i := range tickets {
+		measured[i] = slaTicket{Ticket: tickets[i], SLA: domain.ComputeSLA(&tickets[i], histories[tickets[i].ID], now)}
+	}
+	return measured, nil
+}
+
+// atRiskTickets returns the open tickets of a branch that are late or about
+// to be, the soonest due first
+func (s *Server) atRiskTickets(ctx context.Context, branchID int64) []slaTicket {
+	open, err := s.repos.Tickets.ListOpen(ctx, branchID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load open tickets: %v", err)
+		return nil
+	}
+	measured, err := s.measureTickets(ctx, open)
+	if err != nil {
+		log.Printf("⚠️ Failed to measure open tickets: %v", err)
+		return nil
+	}
+
+	var atRisk []slaTicket
+	for _, t := range measured {
+		if t.SLA.State == domain.SLAAtRisk || t.SLA.State == domain.SLABreached {
+			atRisk = append(atRisk, t)
+		}
+	}
+	sort.SliceStable(atRisk, func(i, j int) bool { return atRisk[i].SLA.DueAt.Before(*atRisk[j].SLA.DueAt) })
+	return atRisk
+}
+
+// handleTurnaroundReport shows the average turnaround of the tickets received
+// in a period that are ready, per service and per technician
+func (s *Server) handleTurnaroundReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -turnaroundReportDays)
+	if t, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("from"), time.Local); err == nil {
+		from = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("to"), time.Local); err == nil {
+		to = t.AddDate(0, 0, 1) // the whole last day
+	}
+
+	tickets, err := s.repos.Tickets.ListReceivedBetween(ctx, s.currentBranch(r), from, to)
+	if err != nil {
+		http.Error(w, "Error loading tickets", http.StatusInternalServerError)
+		return
+	}
+	measured, err := s.measureTickets(ctx, tickets)
+	if err != nil {
+		http.Error(w, "Error loading ticket history", http.StatusInternalServerError)
+		return
+	}
+
+	total := &domain.TurnaroundStat{Name: "Total"}
+	byService := map[string]*domain.TurnaroundStat{}
+	byTechnician := map[string]*domain.TurnaroundStat{}
+	group := func(groups map[string]*domain.TurnaroundStat, name string) *domain.TurnaroundStat {
+		if groups[name] == nil {
+			groups[name] = &domain.TurnaroundStat{Name: name}
+		}
+		return groups[name]
+	}
+	inWorkshop := 0
+	for _, t := range measured {
+		if t.SLA.ReadyAt == nil {
+			inWorkshop++
+			continue
+		}
+		service, technician := "Sin servicio", "Sin asignar"
+		if t.Booking != nil && t.Booking.Service != nil {
+			service = t.Booking.Service.Name
+		}
+		if t.Technician != nil {
+			technician = t.Technician.Name
+		}
+		total.Add(t.SLA)
+		group(byService, service).Add(t.SLA)
+		group(byTechnician, technician).Add(t.SLA)
+	}
+
+	data := s.newPageData(r, "Reporte de Tiempos de Entrega")
+	data.Data = map[string]interface{}{
+		"FromDate":     from.Format("2006-01-02"),
+		"ToDate":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"Total":        total,
+		"ByService":    sortedStats(byService),
+		"ByTechnician": sortedStats(byTechnician),
+		"InWorkshop":   inWorkshop,
+	}
+	s.render(w, r, "pages/admin/report_turnaround.html", data)
+}
+
+// sortedStats lists the groups of a report by name
+func sortedStats(groups map[string]*domain.TurnaroundStat) []*domain.TurnaroundStat {
+	stats := make([]*domain.TurnaroundStat, 0, len(groups))
+	for _, stat := range groups {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+const (
+	// Events kept for clients that reconnect with Last-Event-ID
+	ticketEventHistory = 256
+	// Events queued per subscriber; one that falls this far behind is
+	// dropped and catches up from the history when it reconnects
+	ticketEventBuffer = 16
+	// Open streams per workshop, so public tracking pages cannot exhaust
+	// the process
+	maxTicketSubscribers = 500
+
+	sseHeartbeat    = 20 * time.Second
+	sseWriteTimeout = 10 * time.Second
+	sseRetry        = 3 * time.Second
+)
+
+var errTicketHubFull = errors.New("too many open ticket streams")
+
+// ticketStatusEvent is a ticket status change as seen by the live streams
+type ticketStatusEvent struct {
+	ID             string    `json:"-"` // "<epoch>-<seq>", sent as the SSE id
+	seq            uint64    // position in the hub, increasing from 1
+	TicketID       int64     `json:"ticketId"`
+	TrackingCode   string    `json:"trackingCode"`
+	BranchID       int64     `json:"branchId"`
+	Status         string    `json:"status"`
+	StatusLabel    string    `json:"statusLabel"`
+	PreviousStatus string    `json:"previousStatus"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// publicView is what the tracking page stream shows: the customer already
+// knows the code and only needs the new status
+func (e *ticketStatusEvent) publicView() interface{} {
+	return map[string]interface{}{
+		"trackingCode": e.TrackingCode,
+		"status":       e.Status,
+		"statusLabel":  e.StatusLabel,
+		"updatedAt":    e.UpdatedAt,
+	}
+}
+
+// ticketSubscriber is an open stream. Its channel is closed when the hub
+// drops it, either because it fell behind or because the hub closed.
+type ticketSubscriber struct {
+	events chan ticketStatusEvent
+	match  func(*ticketStatusEvent) bool
+}
+
+// ticketHub is the in-process pub/sub of ticket status changes of one
+// workshop. Publishing never blocks: it runs inside the request that
+// changed the status, so a slow stream must not hold it up.
+type ticketHub struct {
+	// epoch tells event IDs of this process apart from those of a previous
+	// one, whose history is gone
+	epoch string
+
+	mu          sync.Mutex
+	seq         uint64
+	history     []ticketStatusEvent // oldest first, at most ticketEventHistory
+	subscribers map[*ticketSubscriber]struct{}
+	closed      bool
+}
+
+func newTicketHub() *ticketHub {
+	return &ticketHub{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: make(map[*ticketSubscriber]struct{}),
+	}
+}
+
+// publish records an event and queues it for every matching subscriber
+func (h *ticketHub) publish(event ticketStatusEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	event.seq = h.seq
+	event.ID = fmt.Sprintf("%s-%d", h.epoch, h.seq)
+	if len(h.history) == ticketEventHistory {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, event)
+
+	for sub := range h.subscribers {
+		if !sub.match(&event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// subscribe opens a stream of the events that match. With a lastEventID it
+// also returns the matching events published after it; resync is true when
+// those cannot be told (the ID is from another process or older than the
+// history) and the client has to reload its state instead.
+func (h *ticketHub) subscribe(lastEventID string, match func(*ticketStatusEvent) bool) (sub *ticketSubscriber, missed []ticketStatusEvent, resync bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || len(h.subscribers) >= maxTicketSubscribers {
+		return nil, nil, false, errTicketHubFull
+	}
+
+	if lastEventID != "" {
+		epoch, seqStr, _ := strings.Cut(lastEventID, "-")
+		last, parseErr := strconv.ParseUint(seqStr, 10, 64)
+		oldest := h.seq + 1
+		if len(h.history) > 0 {
+			oldest = h.history[0].seq
+		}
+		switch {
+		case parseErr != nil || epoch != h.epoch || last > h.seq || last+1 < oldest:
+			resync = true
+		default:
+			for _, event := range h.history {
+				if event.seq > last && match(&event) {
+					missed = append(missed, event)
+				}
+			}
+		}
+	}
+
+	sub = &ticketSubscriber{
+		events: make(chan ticketStatusEvent, ticketEventBuffer),
+		match:  match,
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, missed, resync, nil
+}
+
+// lastID is the ID of the latest event, or "" when there is none yet
+func (h *ticketHub) lastID() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.seq == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", h.epoch, h.seq)
+}
+
+func (h *ticketHub) unsubscribe(sub *ticketSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// close ends every open stream so a graceful shutdown does not wait on them
+func (h *ticketHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// liveTicketRepo publishes every status change made through UpdateStatus
+type liveTicketRepo struct {
+	repository.TicketRepository
+	hub *ticketHub
+}
+
+// withTicketEvents makes repos publish ticket status changes and returns the
+// hub they go to. A Platform rebuilds a tenant's Server over the same
+// repositories, which keep their hub and so the open streams.
+func withTicketEvents(repos *repository.Repositories) *ticketHub {
+	if live, ok := repos.Tickets.(*liveTicketRepo); ok {
+		return live.hub
+	}
+	hub := newTicketHub()
+	repos.Tickets = &liveTicketRepo{TicketRepository: repos.Tickets, hub: hub}
+	return hub
+}
+
+func (r *liveTicketRepo) UpdateStatus(ctx context.Context, id int64, status string, changedBy int64, notes string) error {
+	before, err := r.TicketRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.TicketRepository.UpdateStatus(ctx, id, status, changedBy, notes); err != nil {
+		return err
+	}
+	if before != nil {
+		r.hub.publish(ticketStatusEvent{
+			TicketID:       id,
+			TrackingCode:   before.TrackingCode,
+			BranchID:       before.BranchID,
+			Status:         status,
+			StatusLabel:    domain.TicketStatusLabel(status),
+			PreviousStatus: before.Status,
+			UpdatedAt:      time.Now(),
+		})
+	}
+	return nil
+}
+
+// isEventStream reports whether the client asked for Server-Sent Events, as
+// EventSource always does
+func isEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// handleTrackingEvents streams the status changes of one ticket to its public
+// tracking page
+func (s *Server) handleTrackingEvents(w http.ResponseWriter, r *http.Request) {
+	ticket, err := s.repos.Tickets.GetByTrackingCode(r.Context(), getURLParam(r, "code"))
+	if err != nil || ticket == nil {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	s.streamTicketEvents(w, r, func(e *ticketStatusEvent) bool {
+		return e.TicketID == ticket.ID
+	}, (*ticketStatusEvent).publicView)
+}
+
+// handleWorkshopEvents streams the status changes of every ticket in the
+// current branch to the workshop board
+func (s *Server) handleWorkshopEvents(w http.ResponseWriter, r *http.Request) {
+	branchID := s.currentBranch(r)
+	s.streamTicketEvents(w, r, func(e *ticketStatusEvent) bool {
+		return branchID == 0 || e.BranchID == branchID
+	}, func(e *ticketStatusEvent) interface{} {
+		return e
+	})
+}
+
+// streamTicketEvents writes the matching events as Server-Sent Events until
+// the client leaves, falls behind or the server shuts down. Each event is a
+// "status" event; "resync" asks the client to reload because the events it
+// missed are gone.
+func (s *Server) streamTicketEvents(w http.ResponseWriter, r *http.Request, match func(*ticketStatusEvent) bool, view func(*ticketStatusEvent) interface{}) {
+	sub, missed, resync, err := s.ticketEvents.subscribe(r.Header.Get("Last-Event-ID"), match)
+	if err != nil {
+		setRetryAfter(w, sseRetry)
+		http.Error(w, "Too many open streams", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.ticketEvents.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep proxies from holding events back
+	w.WriteHeader(http.StatusOK)
+
+	// write sends one frame with its own deadline, since the server's write
+	// timeout would otherwise end the stream
+	write := func(frame string) bool {
+		rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	send := func(event *ticketStatusEvent) bool {
+		data, err := json.Marshal(view(event))
+		if err != nil {
+			return false
+		}
+		return write(fmt.Sprintf("id: %s\nevent: status\ndata: %s\n\n", event.ID, data))
+	}
+
+	if !write(fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())) {
+		return
+	}
+	if resync {
+		if !write(fmt.Sprintf("id: %s\nevent: resync\ndata: {}\n\n", s.ticketEvents.lastID())) {
+			return
+		}
+	}
+	for i := range missed {
+		if !send(&missed[i]) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if !send(&event) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// maxManualEntry is the longest stretch of work a manual entry can log
+const maxManualEntry = 24 * time.Hour
+
+// laborRate returns the hourly rate logged labor is billed at; zero when
+// labor is not billed
+func (s *Server) laborRate(ctx context.Context) float64 {
+	value, _ := s.repos.Settings.Get(ctx, domain.LaborRateSetting)
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// ticketLabor is the time logged on a ticket, for its detail page
+type ticketLabor struct {
+	Entries  []domain.TimeEntry
+	Timer    string // the current user's timer state
+	Total    time.Duration
+	Unbilled time.Duration
+	Rate     float64
+}
+
+// UnbilledHours is the unbilled time rounded for billing
+func (l *ticketLabor) UnbilledHours() float64 {
+	return domain.LaborHours(l.Unbilled)
+}
+
+// UnbilledAmount is what the unbilled time is worth at the current rate
+func (l *ticketLabor) UnbilledAmount() float64 {
+	return l.UnbilledHours() * l.Rate
+}
+
+// loadTicketLabor sums the time logged on a ticket
+func (s *Server) loadTicketLabor(ctx context.Context, ticketID, userID int64) *ticketLabor {
+	entries, err := s.repos.TimeEntries.ListByTicket(ctx, ticketID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load time entries of ticket %d: %v", ticketID, err)
+	}
+
+	labor := &ticketLabor{Entries: entries, Rate: s.laborRate(ctx)}
+	var own []domain.TimeEntry
+	for i := range entries {
+		labor.Total += entries[i].Duration()
+		if !entries[i].Running() && entries[i].QuoteID == 0 {
+			labor.Unbilled += entries[i].Duration()
+		}
+		if entries[i].TechnicianID == userID {
+			own = append(own, entries[i])
+		}
+	}
+	labor.Timer = domain.TimerState(own)
+	return labor
+}
+
+// pauseTicketTimers pauses every running timer of a ticket that started
+// waiting for parts, since that wait is not work
+func (s *Server) pauseTicketTimers(ctx context.Context, ticketID int64) {
+	if _, err := s.repos.TimeEntries.EndRunning(ctx, 0, ticketID, domain.TimeEntryEndWaitingParts); err != nil {
+		log.Printf("⚠️ Failed to pause the timers of ticket %d: %v", ticketID, err)
+	}
+}
+
+// ticketForWork loads the ticket of a request that works on it (time
+// tracking, attachments, comments), writing the error response when the user
+// cannot work on it
+func (s *Server) ticketForWork(w http.ResponseWriter, r *http.Request) *domain.Ticket {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ticket, err := s.repos.Tickets.GetByID(r.Context(), id)
+	if err != nil || ticket == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+	if !s.canEditTicket(r, ticket) {
+		http.Error(w, "Forbidden: You are not assigned to this ticket", http.StatusForbidden)
+		return nil
+	}
+	return ticket
+}
+
+// handleStartTimer starts or resumes the user's timer on a ticket. A
+// technician works one ticket at a time, so their timers on other tickets
+// pause.
+func (s *Server) handleStartTimer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+	if ticket.Status == domain.TicketStatusReady || ticket.Status == domain.TicketStatusDelivered {
+		http.Redirect(w, r, redirect+"?error=timer_closed", http.StatusSeeOther)
+		return
+	}
+
+	userID := getUserClaims(r).UserID
+	if _, err := s.repos.TimeEntries.EndRunning(ctx, userID, 0, domain.TimeEntryEndPause); err != nil {
+		http.Error(w, "Error pausing timers", http.StatusInternalServerError)
+		return
+	}
+	entry := &domain.TimeEntry{TicketID: ticket.ID, TechnicianID: userID, StartedAt: time.Now()}
+	if err := s.repos.TimeEntries.Create(ctx, entry); err != nil {
+		http.Error(w, "Error starting timer", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handlePauseTimer pauses the user's timer on a ticket
+func (s *Server) handlePauseTimer(w http.ResponseWriter, r *http.Request) {
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+	if _, err := s.repos.TimeEntries.EndRunning(r.Context(), getUserClaims(r).UserID, ticket.ID, domain.TimeEntryEndPause); err != nil {
+		http.Error(w, "Error pausing timer", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticket.ID), http.StatusSeeOther)
+}
+
+// handleStopTimer stops the user's timer on a ticket, running or paused
+func (s *Server) handleStopTimer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+
+	userID := getUserClaims(r).UserID
+	ended, err := s.repos.TimeEntries.EndRunning(ctx, userID, ticket.ID, domain.TimeEntryEndStop)
+	if err != nil {
+		http.Error(w, "Error stopping timer", http.StatusInternalServerError)
+		return
+	}
+	if ended == 0 {
+		// A paused timer stops by marking its last stretch
+		entries, _ := s.repos.TimeEntries.ListByTicket(ctx, ticket.ID)
+		for _, e := range entries {
+			if e.TechnicianID != userID || e.Manual() {
+				continue
+			}
+			if domain.TimerState([]domain.TimeEntry{e}) == domain.TimerPaused {
+				if err := s.repos.TimeEntries.SetEndReason(ctx, e.ID, domain.TimeEntryEndStop); err != nil {
+					http.Error(w, "Error stopping timer", http.StatusInternalServerError)
+					return
+				}
+			}
+			break
+		}
+	}
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticket.ID), http.StatusSeeOther)
+}
+
+// handleCreateTimeEntry logs work done without a timer. Those who assign
+// tickets can log it for another technician.
+func (s *Server) handleCreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+
+	day, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), time.Local)
+	hours, _ := strconv.Atoi(r.FormValue("hours"))
+	minutes, _ := strconv.Atoi(r.FormValue("minutes"))
+	duration := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if err != nil || day.After(time.Now()) || hours < 0 || minutes < 0 || duration <= 0 || duration > maxManualEntry {
+		http.Redirect(w, r, redirect+"?error=invalid_time_entry", http.StatusSeeOther)
+		return
+	}
+
+	technicianID := getUserClaims(r).UserID
+	if id, _ := strconv.ParseInt(r.FormValue("technician_id"), 10, 64); id != 0 && id != technicianID {
+		if !s.can(r, domain.PermTicketsAssign) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		technician, _ := s.repos.Users.GetByID(ctx, id)
+		if technician == nil || !worksAt(technician, ticket.BranchID) {
+			http.Redirect(w, r, redirect+"?error=invalid_time_entry", http.StatusSeeOther)
+			return
+		}
+		technicianID = id
+	}
+
+	endedAt := day.Add(duration)
+	entry := &domain.TimeEntry{
+		TicketID:     ticket.ID,
+		TechnicianID: technicianID,
+		StartedAt:    day,
+		EndedAt:      &endedAt,
+		EndReason:    domain.TimeEntryEndManual,
+		Notes:        r.FormValue("notes"),
+	}
+	if err := s.repos.TimeEntries.Create(ctx, entry); err != nil {
+		http.Error(w, "Error saving time entry", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "time_entry.create", domain.AuditEntityTimeEntry, entry.ID, nil, entry)
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// handleDeleteTimeEntry removes an ended, unbilled entry. Technicians remove
+// their own; tickets.edit_any removes anyone's.
+func (s *Server) handleDeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ticket := s.ticketForWork(w, r)
+	if ticket == nil {
+		return
+	}
+
+	entryID, _ := strconv.ParseInt(getURLParam(r, "entryId"), 10, 64)
+	entry, err := s.repos.TimeEntries.GetByID(ctx, entryID)
+	if err != nil || entry == nil || entry.TicketID != ticket.ID {
+		http.NotFound(w, r)
+		return
+	}
+	if entry.TechnicianID != getUserClaims(r).UserID && !s.can(r, domain.PermTicketsEditAny) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if entry.Running() || entry.QuoteID != 0 {
+		http.Redirect(w, r, fmt.Sprintf("/tickets/%d?error=time_entry_locked", ticket.ID), http.StatusSeeOther)
+		return
+	}
+
+	if err := s.repos.TimeEntries.Delete(ctx, entry.ID); err != nil {
+		http.Error(w, "Error deleting time entry", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "time_entry.delete", domain.AuditEntityTimeEntry, entry.ID, entry, nil)
+	http.Redirect(w, r, fmt.Sprintf("/tickets/%d", ticket.ID), http.StatusSeeOther)
+}
+
+// handleBillLabor adds the unbilled time of a ticket to its quote as a labor
+// line at the configured rate, creating the quote if there is none. Approved
+// or rejected quotes are closed to new lines.
+func (s *Server) handleBillLabor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	ticket, err := s.repos.Tickets.GetByID(ctx, id)
+	if err != nil || ticket == nil || !s.inBranch(r, ticket.BranchID) {
+		http.NotFound(w, r)
+		return
+	}
+	redirect := fmt.Sprintf("/tickets/%d", ticket.ID)
+
+	rate := s.laborRate(ctx)
+	if rate == 0 {
+		http.Redirect(w, r, redirect+"?error=labor_rate", http.StatusSeeOther)
+		return
+	}
+
+	quote, before, err := s.repos.TimeEntries.BillLabor(ctx, ticket.ID, ticket.BookingID, rate, time.Now().AddDate(0, 0, 7)) // 7 days validity
+	switch {
+	case errors.Is(err, repository.ErrNothingToBill):
+		http.Redirect(w, r, redirect+"?error=no_labor", http.StatusSeeOther)
+		return
+	case errors.Is(err, repository.ErrQuoteClosed):
+		http.Redirect(w, r, redirect+"?error=quote_closed", http.StatusSeeOther)
+		return
+	case err != nil:
+		log.Printf("⚠️ Failed to bill labor of ticket %d: %v", ticket.ID, err)
+		http.Error(w, "Error billing labor", http.StatusInternalServerError)
+		return
+	}
+	if before == nil {
+		s.audit(r, "quote.create", domain.AuditEntityQuote, quote.ID, nil, quote)
+		s.emitQuoteEvent(ctx, quote.ID, domain.WebhookEventQuoteCreated)
+	} else {
+		s.audit(r, "quote.labor", domain.AuditEntityQuote, quote.ID, before, quote)
+	}
+	http.Redirect(w, r, redirect+"?msg=labor_billed", http.StatusSeeOther)
+}
+
+// handleLaborReport compares the estimated and logged hours per service of
+// the tickets received in a period
+func (s *Server) handleLaborReport(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -turnaroundReportDays)
+	if t, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("from"), time.Local); err == nil {
+		from = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("to"), time.Local); err == nil {
+		to = t.AddDate(0, 0, 1) // the whole last day
+	}
+
+	stats, err := s.repos.TimeEntries.LaborByService(r.Context(), s.currentBranch(r), from, to)
+	if err != nil {
+		http.Error(w, "Error loading labor", http.StatusInternalServerError)
+		return
+	}
+	total := domain.LaborStat{ServiceName: "Total"}
+	for _, stat := range stats {
+		total.Tickets += stat.Tickets
+		total.EstimatedHours += stat.EstimatedHours
+		total.ActualHours += stat.ActualHours
+	}
+
+	data := s.newPageData(r, "Reporte de Mano de Obra")
+	data.Data = map[string]interface{}{
+		"FromDate": from.Format("2006-01-02"),
+		"ToDate":   to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"Stats":    stats,
+		"Total":    total,
+	}
+	s.render(w, r, "pages/admin/report_labor.html", data)
+}
+
+// handleUpdateLaborSettings saves the hourly rate labor is billed at
+func (s *Server) handleUpdateLaborSettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	value := ""
+	if rate, err := strconv.ParseFloat(r.FormValue("labor_hourly_rate"), 64); err == nil && rate > 0 {
+		value = strconv.FormatFloat(rate, 'f', 2, 64)
+	}
+	before, _ := s.repos.Settings.Get(ctx, domain.LaborRateSetting)
+	if err := s.repos.Settings.Set(ctx, domain.LaborRateSetting, value); err != nil {
+		http.Error(w, "Error saving settings", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "setting.update", domain.AuditEntitySetting, 0,
+		map[string]string{domain.LaborRateSetting: before}, map[string]string{domain.LaborRateSetting: value})
+	http.Redirect(w, r, "/admin/settings?msg=labor", http.StatusSeeOther)
+}
@@ -0,0 +1,586 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"bicicletapp/internal/domain"
+	"bicicletapp/internal/repository"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook's secret, prefixed "sha256=".
+const (
+	webhookEventHeader     = "X-Bicicletapp-Event"
+	webhookEventIDHeader   = "X-Bicicletapp-Event-Id"
+	webhookDeliveryHeader  = "X-Bicicletapp-Delivery"
+	webhookTimestampHeader = "X-Bicicletapp-Timestamp"
+	webhookSignatureHeader = "X-Bicicletapp-Signature"
+)
+
+const (
+	webhookTimeout       = 10 * time.Second
+	webhookLease         = 2 * time.Minute // longer than webhookTimeout
+	webhookSweepInterval = 30 * time.Second
+	webhookBatchSize     = 20
+	webhookMaxResponse   = 1024 // bytes of the response body kept in the log
+	webhookPageSize      = 50
+)
+
+// webhookRetryDelays are the waits after each failed attempt; a delivery
+// that still fails after the last one is given up
+var webhookRetryDelays = []time.Duration{
+	time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 12 * time.Hour,
+}
+
+// webhookDispatcher sends pending deliveries: right after an event is
+// queued, and every webhookSweepInterval for retries and for deliveries left
+// over from a previous run. It lives as long as its database, so a tenant's
+// Server can be rebuilt without starting a second one.
+type webhookDispatcher struct {
+	webhooks repository.WebhookRepository
+	client   *http.Client
+	wake     chan struct{}
+}
+
+func newWebhookDispatcher(webhooks repository.WebhookRepository) *webhookDispatcher {
+	return &webhookDispatcher{
+		webhooks: webhooks,
+		client:   newWebhookClient(),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// errWebhookAddress is returned when a webhook URL leads to an address on
+// the server's own network
+var errWebhookAddress = errors.New("webhook address is not public")
+
+// nonPublicPrefixes are ranges reserved for special use that the checks of
+// netip.Addr don't cover
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which embeds IPv4 addresses
+}
+
+// publicAddress reports whether webhooks may be sent to addr: loopback,
+// private, link-local (cloud metadata services live there) and other
+// special-use addresses are refused
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// newWebhookClient returns the client deliveries are sent with. Webhook URLs
+// are set by workshop admins, so the address each connection resolves to is
+// checked when dialing, which also covers DNS names pointing inside, and
+// redirects are not followed.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if addr, err := netip.ParseAddr(host); err != nil || !publicAddress(addr) {
+				return errWebhookAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// run sends due deliveries until the process exits
+func (d *webhookDispatcher) run() {
+	ticker := time.NewTicker(webhookSweepInterval)
+	defer ticker.Stop()
+	for {
+		d.sweep(context.Background())
+		select {
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify asks for a sweep without waiting for it
+func (d *webhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sweep sends every delivery that is due
+func (d *webhookDispatcher) sweep(ctx context.Context) {
+	for {
+		deliveries, err := d.webhooks.ClaimDueDeliveries(ctx, time.Now(), webhookLease, webhookBatchSize)
+		if err != nil {
+			log.Printf("⚠️ Failed to load webhook deliveries: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		for i := range deliveries {
+			d.attempt(ctx, &deliveries[i])
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry
+// when it fails
+func (d *webhookDispatcher) attempt(ctx context.Context, delivery *domain.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	webhook, err := d.webhooks.GetByID(ctx, delivery.WebhookID)
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case webhook == nil || !webhook.Active:
+		delivery.Error = "webhook deleted or disabled"
+		delivery.Attempts = len(webhookRetryDelays) + 1 // don't retry
+	default:
+		status, body, err := d.send(ctx, webhook, delivery, now)
+		delivery.ResponseStatus = status
+		delivery.ResponseBody = body
+		if err != nil {
+			delivery.Error = err.Error()
+		} else if status < 200 || status > 299 {
+			delivery.Error = "unexpected status " + strconv.Itoa(status)
+		}
+	}
+
+	switch {
+	case delivery.Error == "":
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts > len(webhookRetryDelays):
+		delivery.Status = domain.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		log.Printf("⚠️ Webhook delivery %d (%s) failed for good: %s", delivery.ID, delivery.Event, delivery.Error)
+	default:
+		delivery.Status = domain.WebhookDeliveryPending
+		next := now.Add(webhookRetryDelays[delivery.Attempts-1])
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.webhooks.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("⚠️ Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send posts the payload and returns the response status and the start of
+// its body
+func (d *webhookDispatcher) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery, now time.Time) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BicicletAPP-Webhooks/1")
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookEventIDHeader, delivery.EventID)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+webhookSignature(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponse))
+	return resp.StatusCode, string(bytes.ToValidUTF8(body, nil)), nil
+}
+
+// webhookSignature signs a delivery the way receivers are told to check it
+func webhookSignature(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// emitWebhook queues an event for every active webhook subscribed to it.
+// Failures are logged and never block the action that caused the event.
+func (s *Server) emitWebhook(ctx context.Context, event string, data interface{}) {
+	webhooks, err := s.repos.Webhooks.ListForEvent(ctx, event)
+	if err != nil {
+		log.Printf("⚠️ Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		log.Printf("⚠️ Failed to generate webhook event ID: %v", err)
+		return
+	}
+	eventID := "evt_" + hex.EncodeToString(raw)
+	now := time.Now()
+	payload, err := json.Marshal(domain.WebhookEvent{
+		ID:        eventID,
+		Type:      event,
+		Version:   domain.WebhookEventVersion,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to encode webhook event %s: %v", event, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.repos.Webhooks.CreateDelivery(ctx, delivery); err != nil {
+			log.Printf("⚠️ Failed to queue webhook %d for %s: %v", webhook.ID, event, err)
+		}
+	}
+	s.webhooks.notify()
+}
+
+// emitTicketEvent sends ticket events with the ticket and its latest status
+// change
+func (s *Server) emitTicketEvent(ctx context.Context, ticketID int64, events ...string) {
+	ticket, err := s.repos.Tickets.GetByID(ctx, ticketID)
+	if err != nil || ticket == nil {
+		return
+	}
+	ticket.QRCodeBase64 = "" // receivers can build it from the tracking code
+	data := domain.WebhookTicketData{Ticket: ticket}
+	if history, _ := s.repos.Tickets.GetStatusHistory(ctx, ticketID); len(history) > 0 {
+		data.History = &history[len(history)-1]
+	}
+	for _, event := range events {
+		s.emitWebhook(ctx, event, data)
+	}
+}
+
+// emitTicketStatusChanged sends ticket.status_changed, and ticket.ready when
+// that is the new status
+func (s *Server) emitTicketStatusChanged(ctx context.Context, ticketID int64, status string) {
+	if status == domain.TicketStatusReady {
+		s.emitTicketEvent(ctx, ticketID, domain.WebhookEventTicketStatusChanged, domain.WebhookEventTicketReady)
+		return
+	}
+	s.emitTicketEvent(ctx, ticketID, domain.WebhookEventTicketStatusChanged)
+}
+
+// emitBookingEvent sends a booking event with the booking as it is now
+func (s *Server) emitBookingEvent(ctx context.Context, bookingID int64, event string) {
+	booking, err := s.repos.Bookings.GetByID(ctx, bookingID)
+	if err != nil || booking == nil {
+		return
+	}
+	s.emitWebhook(ctx, event, domain.WebhookBookingData{Booking: booking})
+}
+
+// emitQuoteEvent sends a quote event with the quote as it is now
+func (s *Server) emitQuoteEvent(ctx context.Context, quoteID int64, event string) {
+	quote, err := s.repos.Quotes.GetByID(ctx, quoteID)
+	if err != nil || quote == nil {
+		return
+	}
+	s.emitWebhook(ctx, event, domain.WebhookQuoteData{Quote: quote})
+}
+
+// newWebhookSecret returns a signing secret for a webhook
+func newWebhookSecret() (string, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// handleWebhooksList shows the webhooks and the form to register one
+func (s *Server) handleWebhooksList(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.repos.Webhooks.List(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Webhooks")
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		data.Flash = webhookFormFlash(errCode)
+	}
+	data.Data = map[string]interface{}{
+		"Webhooks": webhooks,
+		"Events":   domain.WebhookEvents,
+	}
+	s.render(w, r, "pages/admin/webhooks.html", data)
+}
+
+// webhookFormFlash maps the error codes of the webhook forms to a message
+func webhookFormFlash(errCode string) *FlashMessage {
+	switch errCode {
+	case "name_required":
+		return &FlashMessage{Type: "error", Message: "El nombre del webhook es obligatorio"}
+	case "invalid_url":
+		return &FlashMessage{Type: "error", Message: "La URL debe empezar con http:// o https://"}
+	case "private_url":
+		return &FlashMessage{Type: "error", Message: "La URL debe apuntar a un servidor público, no a una dirección local o privada"}
+	case "events_required":
+		return &FlashMessage{Type: "error", Message: "Elige al menos un evento"}
+	}
+	return nil
+}
+
+// handleCreateWebhook registers an endpoint with a new signing secret
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+
+	webhook := &domain.Webhook{Active: true}
+	if errCode := parseWebhookForm(r, webhook); errCode != "" {
+		http.Redirect(w, r, "/admin/webhooks?error="+errCode, http.StatusSeeOther)
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		http.Error(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+	webhook.Secret = secret
+
+	if err := s.repos.Webhooks.Create(r.Context(), webhook); err != nil {
+		http.Error(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "webhook.create", domain.AuditEntityWebhook, webhook.ID, nil, webhook)
+
+	http.Redirect(w, r, "/admin/webhooks/"+strconv.FormatInt(webhook.ID, 10), http.StatusSeeOther)
+}
+
+// parseWebhookForm reads the webhook form into webhook and returns an error
+// code for the page, or "" if the form is valid
+func parseWebhookForm(r *http.Request, webhook *domain.Webhook) string {
+	webhook.Name = strings.TrimSpace(r.FormValue("name"))
+	webhook.URL = strings.TrimSpace(r.FormValue("url"))
+	webhook.SetEvents(r.Form["events"])
+	if webhook.Name == "" {
+		return "name_required"
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "invalid_url"
+	}
+	// Other names that resolve inside are refused when sending
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if addr, err := netip.ParseAddr(host); (err == nil && !publicAddress(addr)) ||
+		host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "private_url"
+	}
+	if len(webhook.Events) == 0 {
+		return "events_required"
+	}
+	return ""
+}
+
+// handleWebhookDetail shows a webhook's settings, secret and recent
+// deliveries
+func (s *Server) handleWebhookDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhook := s.webhookFromURL(r)
+	if webhook == nil {
+		http.NotFound(w, r)
+		return
+	}
+	deliveries, err := s.repos.Webhooks.ListDeliveries(ctx, webhook.ID, webhookPageSize)
+	if err != nil {
+		http.Error(w, "Error loading deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	data := s.newPageData(r, "Webhook "+webhook.Name)
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		data.Flash = webhookFormFlash(errCode)
+	}
+	switch r.URL.Query().Get("msg") {
+	case "replayed":
+		data.Flash = &FlashMessage{Type: "success", Message: "Entrega reenviada a la cola"}
+	case "secret_rotated":
+		data.Flash = &FlashMessage{Type: "success", Message: "Secreto regenerado. Actualízalo en el sistema receptor."}
+	}
+	data.Data = map[string]interface{}{
+		"Webhook":    webhook,
+		"Deliveries": deliveries,
+		"Events":     domain.WebhookEvents,
+		"Headers": map[string]string{
+			"Event":     webhookEventHeader,
+			"EventID":   webhookEventIDHeader,
+			"Timestamp": webhookTimestampHeader,
+			"Signature": webhookSignatureHeader,
+		},
+	}
+	s.render(w, r, "pages/admin/webhook_detail.html", data)
+}
+
+// webhookFromURL loads the webhook named by the {id} URL parameter
+func (s *Server) webhookFromURL(r *http.Request) *domain.Webhook {
+	id, _ := strconv.ParseInt(getURLParam(r, "id"), 10, 64)
+	webhook, err := s.repos.Webhooks.GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("⚠️ Failed to load webhook %d: %v", id, err)
+	}
+	return webhook
+}
+
+// handleUpdateWebhook saves a webhook's name, URL, events and state
+func (s *Server) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error processing form", http.StatusBadRequest)
+		return
+	}
+	webhook := s.webhookFromURL(r)
+	if webhook == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	before := *webhook
+	before.Events = append([]string(nil), webhook.Events...)
+	path := "/admin/webhooks/" + strconv.FormatInt(webhook.ID, 10)
+	if errCode := parseWebhookForm(r, webhook); errCode != "" {
+		http.Redirect(w, r, path+"?error="+errCode, http.StatusSeeOther)
+		return
+	}
+	webhook.Active = r.FormValue("active") == "on"
+
+	if err := s.repos.Webhooks.Update(r.Context(), webhook); err != nil {
+		http.Error(w, "Error updating webhook", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "webhook.update", domain.AuditEntityWebhook, webhook.ID, &before, webhook)
+
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+// handleRotateWebhookSecret replaces a webhook's signing secret
+func (s *Server) handleRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	webhook := s.webhookFromURL(r)
+	if webhook == nil {
+		http.NotFound(w, r)
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		http.Error(w, "Error rotating secret", http.StatusInternalServerError)
+		return
+	}
+	webhook.Secret = secret
+	if err := s.repos.Webhooks.Update(r.Context(), webhook); err != nil {
+		http.Error(w, "Error rotating secret", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "webhook.rotate_secret", domain.AuditEntityWebhook, webhook.ID, nil, nil)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/webhooks/%d?msg=secret_rotated", webhook.ID), http.StatusSeeOther)
+}
+
+// handleDeleteWebhook removes a webhook and its delivery log
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook := s.webhookFromURL(r)
+	if webhook == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.repos.Webhooks.Delete(r.Context(), webhook.ID); err != nil {
+		http.Error(w, "Error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "webhook.delete", domain.AuditEntityWebhook, webhook.ID, webhook, nil)
+
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+}
+
+// handleReplayWebhookDelivery queues the event of a past delivery again, as
+// a new delivery with the same event ID so receivers can deduplicate
+func (s *Server) handleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	webhook := s.webhookFromURL(r)
+	deliveryID, _ := strconv.ParseInt(getURLParam(r, "deliveryId"), 10, 64)
+	original, err := s.repos.Webhooks.GetDelivery(ctx, deliveryID)
+	if webhook == nil || err != nil || original == nil || original.WebhookID != webhook.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	replay := &domain.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        domain.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.repos.Webhooks.CreateDelivery(ctx, replay); err != nil {
+		http.Error(w, "Error replaying delivery", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r, "webhook.replay", domain.AuditEntityWebhook, webhook.ID, nil,
+		map[string]interface{}{"deliveryId": original.ID, "eventId": original.EventID})
+	s.webhooks.notify()
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/webhooks/%d?msg=replayed", webhook.ID), http.StatusSeeOther)
+}
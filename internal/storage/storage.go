@@ -0,0 +1,105 @@
+// Package storage keeps uploaded files, such as ticket photos, by key: on
+// local disk or in an S3-compatible bucket. Keys are slash-separated paths
+// chosen by the caller.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key that holds no file
+var ErrNotFound = errors.New("stored file not found")
+
+// Storage keeps files by key
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get fails with ErrNotFound when nothing is stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when nothing is stored under key
+	Delete(ctx context.Context, key string) error
+}
+
+// Local keeps files under a directory of the local disk
+type Local struct {
+	dir string
+}
+
+// NewLocal creates a Local storage rooted at dir, which is created on the
+// first Put
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+// path maps a key to a file under the root, refusing keys that would
+// escape it
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.FromSlash(key)
+	if key == "" || !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(l.dir, clean), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage dir: %w", err)
+	}
+	// Written aside and renamed so a reader never sees half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write stored file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write stored file: %w", err)
+	}
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stored file: %w", err)
+	}
+	return file, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete stored file: %w", err)
+	}
+	return nil
+}
+
+// JoinKey joins key segments with slashes, dropping empty ones and the
+// slashes around each
+func JoinKey(parts ...string) string {
+	var segments []string
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); part != "" {
+			segments = append(segments, part)
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -37,6 +38,8 @@ func NewManager(dir string, debug bool) (*Manager, error) {
 		funcMap: template.FuncMap{
 			"formatDate":        formatDate,
 			"formatTime":        formatTime,
+			"age":               age,
+			"formatDuration":    formatDuration,
 			"formatMoney":       formatMoney,
 			"safeHTML":          safeHTML,
 			"add":               add,
@@ -45,8 +48,13 @@ func NewManager(dir string, debug bool) (*Manager, error) {
 			"div":               div,
 			"statusBadge":       statusBadge,
 			"ticketStatusLabel": ticketStatusLabel,
+			"slaLabel":          slaLabel,
+			"attachmentKind":    attachmentKindLabel,
+			"checklistType":     checklistTypeLabel,
 			"statusLabel":       statusLabel,
+			"purchaseStatus":    purchaseStatusLabel,
 			"whatsappLink":      whatsappLink,
+			"join":              strings.Join,
 		},
 	}
 
@@ -213,6 +221,40 @@ func formatTime(t time.Time) string {
 	return t.Format("15:04")
 }
 
+// age is how long ago t was, in its largest whole unit: "3 d", "5 h", "12 min"
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%d d", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%d h", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%d min", int(d/time.Minute))
+	}
+}
+
+// formatDuration spells d in its two largest units: "2 d 3 h", "5 h 10 min"
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "0 min"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d d %d h", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%d h %d min", hours, minutes)
+	default:
+		return fmt.Sprintf("%d min", minutes)
+	}
+}
+
 func formatMoney(amount float64) string {
 	return fmt.Sprintf("$%.2f", amount)
 }
@@ -251,6 +293,9 @@ func statusBadge(status string) string {
 		"cancelled":     "error",
 		"approved":      "success",
 		"rejected":      "error",
+		// Purchase order status
+		"sent":               "primary",
+		"partially_received": "warning",
 	}
 	if badge, ok := badges[status]; ok {
 		return badge
@@ -275,6 +320,44 @@ func ticketStatusLabel(status string) string {
 	return status
 }
 
+func attachmentKindLabel(kind string) string {
+	labels := map[string]string{
+		"intake":    "Estado al ingreso",
+		"diagnosis": "Diagnóstico",
+		"other":     "Otro",
+	}
+	if label, ok := labels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+func checklistTypeLabel(itemType string) string {
+	labels := map[string]string{
+		"check":       "Verificación",
+		"measurement": "Medición",
+		"photo":       "Foto",
+	}
+	if label, ok := labels[itemType]; ok {
+		return label
+	}
+	return itemType
+}
+
+// slaLabel translates a ticket SLA state to Spanish
+func slaLabel(state string) string {
+	labels := map[string]string{
+		"on_track": "🟢 En plazo",
+		"at_risk":  "🟠 En riesgo",
+		"breached": "🔴 Vencido",
+		"met":      "✅ Cumplido",
+	}
+	if label, ok := labels[state]; ok {
+		return label
+	}
+	return state
+}
+
 // statusLabel translates booking and quote status to Spanish
 func statusLabel(status string) string {
 	labels := map[string]string{
@@ -316,3 +399,17 @@ func whatsappLink(phone, message string) string {
 	encodedMsg := template.URLQueryEscaper(message)
 	return "https://wa.me/" + cleanPhone + "?text=" + encodedMsg
 }
+
+// purchaseStatusLabel translates purchase order status to Spanish
+func purchaseStatusLabel(status string) string {
+	labels := map[string]string{
+		"draft":              "📝 Borrador",
+		"sent":               "📤 Enviada",
+		"partially_received": "📦 Recibida Parcialmente",
+		"received":           "✅ Recibida",
+	}
+	if label, ok := labels[status]; ok {
+		return label
+	}
+	return status
+}
@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of RFC 6238 appendix B, "12345678901234567890"
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeAtRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	cases := []struct {
+		unix int64
+		rfc  string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, c := range cases {
+		want := c.rfc[len(c.rfc)-Digits:]
+		got, err := CodeAt(rfcSecret, Step(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("code at %d = %s, want %s", c.unix, got, want)
+		}
+	}
+}
+
+func TestCodeAtInvalidSecret(t *testing.T) {
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateDriftWindow(t *testing.T) {
+	const step int64 = 40000000
+	codeAt := func(s int64) string {
+		code, err := CodeAt(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	start := time.Unix(step*Period, 0)
+	last := start.Add(Period*time.Second - time.Second)
+
+	cases := []struct {
+		name string
+		at   time.Time
+		code int64
+		ok   bool
+	}{
+		{"current step at its start", start, step, true},
+		{"current step at its last second", last, step, true},
+		{"previous step at the start", start, step - 1, true},
+		{"next step at the start", start, step + 1, true},
+		{"two steps back at the start", start, step - 2, false},
+		{"two steps ahead at the start", start, step + 2, false},
+		{"next step at the last second", last, step + 1, true},
+		{"two steps ahead at the last second", last, step + 2, false},
+		{"next step one second before it starts", start.Add(-time.Second), step, true},
+		{"two steps back one second before the start", start.Add(-time.Second), step - 2, true},
+		{"two steps ahead one second before the start", start.Add(-time.Second), step + 1, false},
+		{"previous step once the next starts", last.Add(time.Second), step - 1, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matched, ok := Validate(rfcSecret, codeAt(c.code), c.at)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, want %v", ok, c.ok)
+			}
+			if ok && matched != c.code {
+				t.Errorf("matched step %d, want %d", matched, c.code)
+			}
+		})
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := CodeAt(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Validate(rfcSecret, " "+code[:3]+" "+code[3:]+" ", now); !ok {
+		t.Error("code with spaces refused")
+	}
+	if _, ok := Validate(rfcSecret, code[:Digits-1], now); ok {
+		t.Error("short code accepted")
+	}
+	if _, ok := Validate(rfcSecret, code+"0", now); ok {
+		t.Error("long code accepted")
+	}
+	if _, ok := Validate("invalid secret!", code, now); ok {
+		t.Error("code accepted with an invalid secret")
+	}
+}
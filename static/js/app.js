@@ -1,6 +1,20 @@
 // BicicletAPP - Frontend JavaScript
 // Minimal JS for enhanced UX
 
+// Send the CSRF token with every same-origin request that changes state
+const CSRF_TOKEN = document.querySelector('meta[name="csrf-token"]')?.content || '';
+const nativeFetch = window.fetch.bind(window);
+window.fetch = function (resource, options = {}) {
+    const method = (options.method || (resource instanceof Request ? resource.method : 'GET')).toUpperCase();
+    const url = new URL(resource instanceof Request ? resource.url : resource, window.location.href);
+    if (CSRF_TOKEN && !['GET', 'HEAD', 'OPTIONS'].includes(method) && url.origin === window.location.origin) {
+        const headers = new Headers(options.headers || (resource instanceof Request ? resource.headers : undefined));
+        headers.set('X-CSRF-Token', CSRF_TOKEN);
+        options = { ...options, headers };
+    }
+    return nativeFetch(resource, options);
+};
+
 document.addEventListener('DOMContentLoaded', function () {
     // Auto-hide flash messages after 5 seconds
     const flashMessages = document.querySelectorAll('.flash');
@@ -21,12 +35,31 @@ document.addEventListener('DOMContentLoaded', function () {
         });
     });
 
-    // Auto-refresh tracking status every 30 seconds
+    // Live tracking status: reload when the ticket changes, or every 30
+    // seconds where the browser has no EventSource
     const trackingStatus = document.querySelector('.tracking-result');
     if (trackingStatus) {
-        setInterval(() => {
-            location.reload();
-        }, 30000);
+        if (window.EventSource && trackingStatus.dataset.events) {
+            const events = new EventSource(trackingStatus.dataset.events);
+            events.addEventListener('status', () => location.reload());
+            events.addEventListener('resync', () => location.reload());
+        } else {
+            setInterval(() => {
+                location.reload();
+            }, 30000);
+        }
+    }
+
+    // Live workshop board
+    const workshopBoard = document.querySelector('[data-workshop-events]');
+    if (workshopBoard && window.EventSource) {
+        initWorkshopBoard(workshopBoard);
+    }
+
+    // Kanban board
+    const ticketBoard = document.querySelector('[data-board]');
+    if (ticketBoard) {
+        initTicketBoard(ticketBoard);
     }
 
     // Date picker min date = today
@@ -51,6 +84,11 @@ document.addEventListener('DOMContentLoaded', function () {
         });
     }
 
+    // Barcode scanning on staff pages
+    if (document.body.hasAttribute('data-scanner')) {
+        initBarcodeScanner();
+    }
+
     // Mobile menu toggle
     const menuToggle = document.querySelector('.menu-toggle');
     const nav = document.querySelector('nav ul:last-child');
@@ -105,3 +143,261 @@ async function api(endpoint, options = {}) {
 
     return response.json();
 }
+
+// Live workshop board
+
+// Keeps the status counters and the recent tickets table of the workshop
+// dashboard up to date from its event stream. The browser reconnects on its
+// own, sending the last event ID so no change is missed.
+const WORKSHOP_RECENT_TICKETS = 10;
+
+function initWorkshopBoard(board) {
+    const events = new EventSource(board.dataset.workshopEvents);
+
+    events.addEventListener('status', function (e) {
+        const ticket = JSON.parse(e.data);
+
+        const adjust = (status, delta) => {
+            const counter = board.querySelector(`[data-status-count="${status}"]`);
+            if (counter) {
+                counter.textContent = Math.max(0, (parseInt(counter.textContent, 10) || 0) + delta);
+            }
+        };
+        adjust(ticket.previousStatus, -1);
+        adjust(ticket.status, 1);
+
+        const tbody = board.querySelector('[data-recent-tickets]');
+        if (!tbody) {
+            return;
+        }
+        let row = tbody.querySelector(`tr[data-ticket-id="${ticket.ticketId}"]`);
+        if (!row) {
+            row = document.createElement('tr');
+            row.dataset.ticketId = ticket.ticketId;
+            const link = document.createElement('a');
+            link.href = `/tickets/${ticket.ticketId}`;
+            link.textContent = ticket.trackingCode;
+            row.insertCell().appendChild(link);
+            row.insertCell().appendChild(document.createElement('span'));
+            row.insertCell();
+        }
+        const badge = row.cells[1].querySelector('span');
+        badge.className = `badge badge-${ticket.status}`;
+        badge.textContent = ticket.statusLabel;
+        row.cells[2].textContent = formatDate(ticket.updatedAt);
+
+        // Most recently updated first, as the server lists them
+        tbody.prepend(row);
+        while (tbody.rows.length > WORKSHOP_RECENT_TICKETS) {
+            tbody.deleteRow(-1);
+        }
+    });
+
+    // Changes were missed while disconnected: start over from the server
+    events.addEventListener('resync', () => location.reload());
+}
+
+// Kanban board
+
+// Cards move by dragging them to another column or with their select. The
+// server checks every move against the workflow and the column limits and
+// answers with the new counts; a refused move leaves the card where it was.
+// Moves made by others arrive through the workshop event stream.
+function initTicketBoard(board) {
+    const errorBox = document.getElementById('board-error');
+    const column = status => board.querySelector(`[data-column="${status}"]`);
+
+    const showError = message => {
+        errorBox.textContent = message;
+        errorBox.hidden = !message;
+    };
+
+    // Delivered tickets leave the board after a few days, so that column
+    // counts its cards instead of using the server's total
+    const setCount = (status, count) => {
+        const col = column(status);
+        if (!col) {
+            return;
+        }
+        if (status === 'delivered') {
+            count = col.querySelectorAll('.board-card').length;
+        }
+        col.querySelector('[data-column-count]').textContent = count;
+        const limit = parseInt(col.dataset.limit, 10);
+        col.classList.toggle('board-column-full', limit > 0 && count >= limit);
+        col.classList.toggle('board-column-over', limit > 0 && count > limit);
+    };
+    const countOf = status => parseInt(column(status)?.querySelector('[data-column-count]').textContent, 10) || 0;
+
+    const place = (card, status) => {
+        const target = column(status);
+        const select = card.querySelector('.board-card-move');
+        if (select) {
+            select.value = target ? status : card.dataset.status;
+        }
+        const previous = card.dataset.status;
+        if (!target || previous === status) {
+            return;
+        }
+        card.dataset.status = status;
+        target.appendChild(card);
+        setCount(previous, countOf(previous) - 1);
+        setCount(status, countOf(status) + 1);
+    };
+
+    const move = async (card, status) => {
+        if (card.dataset.status === status) {
+            return;
+        }
+        showError('');
+        try {
+            const response = await fetch(`/workshop/board/tickets/${card.dataset.ticketId}/move`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ status: status, notes: '' })
+            });
+            const body = await response.json();
+            if (!response.ok) {
+                showError(body.error ? body.error.message : 'No se pudo mover el ticket');
+                place(card, card.dataset.status); // put the select back
+                return;
+            }
+            place(card, body.status);
+            Object.keys(body.counts || {}).forEach(s => setCount(s, body.counts[s]));
+        } catch (err) {
+            showError('No se pudo mover el ticket');
+            place(card, card.dataset.status);
+        }
+    };
+
+    let dragged = null;
+    board.addEventListener('dragstart', e => {
+        dragged = e.target.closest('.board-card');
+        if (dragged) {
+            e.dataTransfer.effectAllowed = 'move';
+            e.dataTransfer.setData('text/plain', dragged.dataset.ticketId);
+        }
+    });
+    board.addEventListener('dragend', () => {
+        dragged = null;
+        board.querySelectorAll('.board-drop').forEach(col => col.classList.remove('board-drop'));
+    });
+    board.querySelectorAll('[data-column]').forEach(col => {
+        col.addEventListener('dragover', e => {
+            if (dragged) {
+                e.preventDefault();
+                col.classList.add('board-drop');
+            }
+        });
+        col.addEventListener('dragleave', () => col.classList.remove('board-drop'));
+        col.addEventListener('drop', e => {
+            e.preventDefault();
+            col.classList.remove('board-drop');
+            if (dragged) {
+                move(dragged, col.dataset.column);
+            }
+        });
+    });
+    board.addEventListener('change', e => {
+        if (e.target.classList.contains('board-card-move')) {
+            move(e.target.closest('.board-card'), e.target.value);
+        }
+    });
+
+    if (window.EventSource && board.dataset.events) {
+        const events = new EventSource(board.dataset.events);
+        events.addEventListener('status', function (e) {
+            const ticket = JSON.parse(e.data);
+            const card = board.querySelector(`.board-card[data-ticket-id="${ticket.ticketId}"]`);
+            if (card) {
+                place(card, ticket.status);
+                return;
+            }
+            // Not on this board (filtered out): only the totals change
+            setCount(ticket.previousStatus, countOf(ticket.previousStatus) - 1);
+            setCount(ticket.status, countOf(ticket.status) + 1);
+        });
+        events.addEventListener('resync', () => location.reload());
+    }
+}
+
+// Barcode scanning
+
+// Keyboard-wedge scanners type the code as a fast burst of keys followed by
+// Enter. Bursts of at least SCAN_MIN_LENGTH characters are taken as scans;
+// fields marked data-scan-field keep the scanned text instead.
+const SCAN_MAX_GAP = 50; // ms between keystrokes of a scan
+const SCAN_MIN_LENGTH = 6;
+
+function initBarcodeScanner() {
+    let buffer = '';
+    let last = 0;
+
+    document.addEventListener('keydown', function (e) {
+        const now = Date.now();
+        if (now - last > SCAN_MAX_GAP) {
+            buffer = '';
+        }
+        last = now;
+
+        if (e.key !== 'Enter') {
+            if (e.key.length === 1) {
+                buffer += e.key;
+            }
+            return;
+        }
+
+        const code = buffer;
+        buffer = '';
+        if (code.length < SCAN_MIN_LENGTH) {
+            return;
+        }
+
+        // Don't let the scanner's Enter submit whatever form has focus
+        e.preventDefault();
+
+        const target = e.target;
+        if (target.matches && target.matches('[data-scan-field]')) {
+            return;
+        }
+        if (typeof target.value === 'string' && target.value.endsWith(code)) {
+            target.value = target.value.slice(0, -code.length);
+        }
+        handleScan(code);
+    });
+}
+
+// handleScan resolves a scanned code. Pages that accept parts define
+// window.onPartScan(item) and get first try at inventory barcodes; ticket
+// QR codes (tracking URLs) and tracking codes open the ticket.
+async function handleScan(code) {
+    const isTrackingURL = code.includes('/tracking/');
+    try {
+        if (!isTrackingURL && typeof window.onPartScan === 'function') {
+            const part = await api(`/api/inventory/ean/${encodeURIComponent(code)}`);
+            if (part.found) {
+                window.onPartScan(part.item);
+                return;
+            }
+        }
+
+        const trackingCode = code.split('/').pop();
+        const ticket = await api(`/api/tickets/tracking/${encodeURIComponent(trackingCode)}`);
+        if (ticket.found) {
+            window.location.href = ticket.url;
+            return;
+        }
+    } catch (err) {
+        console.error(err);
+    }
+    showScanMessage(`Código no reconocido: ${code}`, 'error');
+}
+
+// showScanMessage shows a flash message at the top of the page
+function showScanMessage(message, type) {
+    const flash = document.createElement('article');
+    flash.className = `flash flash-${type || 'info'}`;
+    flash.textContent = message;
+    document.querySelector('main').prepend(flash);
+    setTimeout(() => flash.remove(), 4000);
+}